@@ -2,174 +2,388 @@ package main
 
 import (
 	"context"
-	"encoding/csv"
-	"encoding/json"
-	"errors"
+	"flag"
 	"fmt"
-	"io"
 	"log"
 	"net/http"
 	"os"
+	"os/signal"
+	"path"
+	"path/filepath"
 	"strconv"
+	"strings"
+	"sync"
+	"syscall"
 	"time"
+	dbentity "usage-lakehouse/internal/db/entity"
 	"usage-lakehouse/internal/handler"
+	"usage-lakehouse/internal/iceberg"
+	"usage-lakehouse/internal/jobs"
+	"usage-lakehouse/internal/lake"
+	"usage-lakehouse/internal/lakeexport"
 	"usage-lakehouse/internal/model"
+	"usage-lakehouse/internal/objectstore"
+	"usage-lakehouse/internal/payloadarchive"
+	"usage-lakehouse/internal/premisecode"
 	"usage-lakehouse/internal/repository"
+	"usage-lakehouse/internal/vee"
 
 	"github.com/jackc/pgx/v5/pgxpool"
 
 	"github.com/go-chi/chi/v5"
 	_ "github.com/lib/pq"
-	"github.com/xitongsys/parquet-go-source/s3"
-	"github.com/xitongsys/parquet-go/writer"
 )
 
-type JSONRequest struct {
-	Data []model.UsageData `json:"data"`
+type repositories struct {
+	account                 repository.AccountRepository
+	powerRegion             repository.PowerRegionRepository
+	tdsp                    repository.TDSPRepository
+	premise                 repository.PremiseRepository
+	meter                   repository.MeterRepository
+	usageTransactionPurpose repository.UsageTransactionPurposeRepository
+	transactionType         repository.TransactionTypeRepository
+	transactionSubType      repository.TransactionSubTypeRepository
+	transferDetailType      repository.PowerRegionUsageTransactionProductTransferDetailTypeRepository
+	usageTransaction        repository.UsageTransactionRepository
+	ediAcknowledgement      repository.EDIAcknowledgementRepository
+	reconciliationException repository.UsageReconciliationExceptionRepository
+	quarantine              repository.UsageTransactionQuarantineRepository
+	ingestionJob            repository.IngestionJobRepository
+	rawPayload              repository.RawPayloadRepository
+	meterUsage              repository.MeterUsageRepository
+	intervalException       repository.MeterIntervalExceptionRepository
+	powerRegionChannel      repository.PowerRegionChannelRepository
+	icebergTable            repository.IcebergTableRepository
+	lakeExport              repository.LakeExportRepository
 }
 
-type Response struct {
-	Message  string `json:"message"`
-	S3Object string `json:"s3_object,omitempty"`
+func newRepositories(dbpool *pgxpool.Pool) repositories {
+	return repositories{
+		account:                 repository.NewAccountRepository(dbpool),
+		powerRegion:             repository.NewPowerRegionRepository(dbpool),
+		tdsp:                    repository.NewTDSPRepository(dbpool),
+		premise:                 repository.NewPremiseRepository(dbpool),
+		meter:                   repository.NewMeterRepository(dbpool),
+		usageTransactionPurpose: repository.NewUsageTransactionPurposeRepository(dbpool),
+		transactionType:         repository.NewTransactionTypeRepository(dbpool),
+		transactionSubType:      repository.NewTransactionSubTypeRepository(dbpool),
+		transferDetailType:      repository.NewPowerRegionUsageTransactionProductTransferDetailTypeRepository(dbpool),
+		usageTransaction:        repository.NewUsageTransactionRepository(dbpool),
+		ediAcknowledgement:      repository.NewEDIAcknowledgementRepository(dbpool),
+		reconciliationException: repository.NewUsageReconciliationExceptionRepository(dbpool),
+		quarantine:              repository.NewUsageTransactionQuarantineRepository(dbpool),
+		ingestionJob:            repository.NewIngestionJobRepository(dbpool),
+		rawPayload:              repository.NewRawPayloadRepository(dbpool),
+		meterUsage:              repository.NewMeterUsageRepository(dbpool),
+		intervalException:       repository.NewMeterIntervalExceptionRepository(dbpool),
+		powerRegionChannel:      repository.NewPowerRegionChannelRepository(dbpool),
+		icebergTable:            repository.NewIcebergTableRepository(dbpool),
+		lakeExport:              repository.NewLakeExportRepository(dbpool),
+	}
 }
 
-func parseCSV(r io.Reader) ([]model.UsageData, error) {
-	reader := csv.NewReader(r)
+// newRouter builds the API and registers the handlers that process queued ingestion jobs with pool.
+func newRouter(repos repositories, pool *jobs.Pool, archive *payloadarchive.Archive, usageLake *lake.Writer[model.UsageRow]) *chi.Mux {
+	accountHandler := handler.NewAccountHandler(repos.account)
+	premiseCodeValidator := premisecode.NewValidator()
+	premiseHandler := handler.NewPremiseHandler(repos.premise, repos.tdsp, premiseCodeValidator)
+	ediMonthlyUsageHandler := handler.NewEDIMonthlyUsageHandler(repos.account, repos.powerRegion, repos.tdsp, repos.premise, repos.meter, repos.usageTransactionPurpose, repos.transactionType, repos.transactionSubType, repos.transferDetailType, repos.usageTransaction, repos.ediAcknowledgement, repos.quarantine, repos.ingestionJob, repos.powerRegionChannel, archive, premiseCodeValidator)
+	reconciliationExceptionHandler := handler.NewReconciliationExceptionHandler(repos.reconciliationException)
+	lakeUsageHandler := handler.NewLakeUsageHandler(usageLake, repos.ingestionJob, repos.rawPayload, archive)
+	rawPayloadHandler := handler.NewRawPayloadHandler(archive)
+	ingestionJobHandler := handler.NewIngestionJobHandler(repos.ingestionJob)
+	usageQuarantineHandler := handler.NewUsageQuarantineHandler(repos.quarantine, ediMonthlyUsageHandler)
+	meterUsageHandler := handler.NewMeterUsageHandler(repos.meter, repos.powerRegion, repos.meterUsage)
+	intervalExceptionHandler := handler.NewIntervalExceptionHandler(repos.intervalException)
+	powerRegionChannelHandler := handler.NewPowerRegionChannelHandler(repos.powerRegion, repos.powerRegionChannel)
+	veeHandler := handler.NewVEEHandler(repos.meter, repos.powerRegion, repos.meterUsage, repos.ingestionJob, vee.New(vee.DefaultConfig()))
+	pool.Register(dbentity.IngestionJobKindEDIUsage, ediMonthlyUsageHandler)
+	pool.Register(dbentity.IngestionJobKindLakeUsage, lakeUsageHandler)
+	pool.Register(dbentity.IngestionJobKindVEE, veeHandler)
 
-	// Read header
-	header, err := reader.Read()
+	r := chi.NewRouter()
+	r.Post("/accounts", accountHandler.CreateAccount)
+	r.Get("/accounts/{id}", accountHandler.GetAccount)
+	r.Put("/accounts/{id}", accountHandler.UpdateAccount)
+	r.Delete("/accounts/{id}", accountHandler.DeleteAccount)
+	r.Get("/accounts", accountHandler.ListAccounts)
+	r.Post("/premises", premiseHandler.CreatePremise)
+	r.Get("/premises/code-violations", premiseHandler.ListPremiseCodeViolations)
+	r.Get("/premises/{id}", premiseHandler.GetPremise)
+	r.Post("/edi/monthly-usage", ediMonthlyUsageHandler.CreateEDIMonthlyUsage)
+	r.Post("/edi/historic-usage", ediMonthlyUsageHandler.CreateEDIHistoricUsage)
+	r.Post("/edi/monthly-usage/batch", ediMonthlyUsageHandler.CreateEDIMonthlyUsageBatch)
+	r.Post("/edi/historic-usage/batch", ediMonthlyUsageHandler.CreateEDIHistoricUsageBatch)
+	r.Get("/edi/acknowledgements/{id}", ediMonthlyUsageHandler.GetAcknowledgement)
+	r.Get("/meters/{id}/usage", meterUsageHandler.GetMeterUsage)
+	r.Get("/meters/{id}/channels/usage", meterUsageHandler.GetMeterChannelUsage)
+	r.Post("/meters/{id}/vee", veeHandler.RunMeterVEE)
+	r.Get("/power-regions/{power_region}/channels", powerRegionChannelHandler.ListChannels)
+	r.Put("/power-regions/{power_region}/channels/{code}", powerRegionChannelHandler.PutChannel)
+	r.Delete("/power-regions/{power_region}/channels/{code}", powerRegionChannelHandler.DeleteChannel)
+	r.Get("/usage/reconciliation-exceptions", reconciliationExceptionHandler.ListReconciliationExceptions)
+	r.Get("/usage/reconciliation-exceptions/{id}", reconciliationExceptionHandler.GetReconciliationException)
+	r.Get("/usage/incomplete-meters", intervalExceptionHandler.ListIncompleteMeters)
+	r.Get("/usage/interval-exceptions", intervalExceptionHandler.ListIntervalExceptions)
+	r.Get("/usage/quarantine", usageQuarantineHandler.ListQuarantinedTransactions)
+	r.Get("/usage/quarantine/{id}", usageQuarantineHandler.GetQuarantinedTransaction)
+	r.Put("/usage/quarantine/{id}", usageQuarantineHandler.UpdateQuarantinedTransaction)
+	r.Delete("/usage/quarantine/{id}", usageQuarantineHandler.DiscardQuarantinedTransaction)
+	r.Post("/usage/quarantine/{id}/replay", usageQuarantineHandler.ReplayQuarantinedTransaction)
+	r.Post("/lake/accounts/{account_id}/usage", lakeUsageHandler.UploadUsage)
+	r.Get("/jobs/{id}", ingestionJobHandler.GetIngestionJob)
+	r.Get("/payloads/{sha256}", rawPayloadHandler.GetRawPayload)
+	return r
+}
+
+const usageText = `Usage:
+  server - serves the API and runs queued ingestion jobs.
+  server export [table...] - exports usage tables, or only those named, to the lake once.
+`
+
+func main() {
+	flag.Usage = func() {
+		fmt.Fprint(os.Stderr, usageText)
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	userName := os.Getenv("POSTGRES_USER")
+	password := os.Getenv("POSTGRES_PASSWORD")
+	host := os.Getenv("POSTGRES_HOST")
+	dbPort := os.Getenv("POSTGRES_PORT")
+	database := os.Getenv("POSTGRES_DB")
+	dsn := fmt.Sprintf("postgres://%s:%s@%s:%s/%s?sslmode=disable", userName, password, host, dbPort, database)
+	dbpool, err := pgxpool.New(context.Background(), dsn)
 	if err != nil {
-		return nil, err
+		log.Fatal("Failed to connect to DB:", err)
 	}
+	defer dbpool.Close()
 
-	// Validate header
-	if len(header) != 2 || header[0] != "asset_id" || header[1] != "usage_qty" {
-		return nil, errors.New("invalid CSV format. expected columns: asset_id, usage_qty")
+	repos := newRepositories(dbpool)
+	pool := jobs.NewPool(repos.ingestionJob, jobs.Config{Workers: ingestionWorkers()})
+	storeConfig, err := objectStoreConfig()
+	if err != nil {
+		log.Fatal("Failed to configure object store:", err)
+	}
+	archiveStore, err := objectstore.Open(storeConfig)
+	if err != nil {
+		log.Fatal("Failed to open payload archive:", err)
+	}
+	warehouse, err := newLakeWarehouse(storeConfig)
+	if err != nil {
+		log.Fatal("Failed to open lake warehouse:", err)
+	}
+	catalog := newLakeCatalog(repos.icebergTable)
+	exporter, err := newLakeExporter(repos.lakeExport, catalog, warehouse)
+	if err != nil {
+		log.Fatal("Failed to open lake export tables:", err)
+	}
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	if flag.Arg(0) == "export" {
+		runLakeExport(ctx, exporter, flag.Args()[1:])
+		return
 	}
 
-	var data []model.UsageData
-	for {
-		record, err := reader.Read()
-		if err == io.EOF {
-			break
-		}
-		if err != nil {
-			return nil, err
-		}
-
-		usageQty, err := strconv.ParseFloat(record[1], 64)
-		if err != nil {
-			return nil, errors.New("invalid usage_qty format")
-		}
+	usageLake, err := newLakeWriter(catalog, warehouse, lake.IntervalUsage)
+	if err != nil {
+		log.Fatal("Failed to open lake usage table:", err)
+	}
+	// LAKE_EXPORT_INTERVAL, e.g. 15m, schedules the lake export; unset or 0 leaves it unscheduled.
+	// Every API task may schedule it; a source is exported by one task at a time.
+	exportInterval, err := parseLakeExportInterval(os.Getenv("LAKE_EXPORT_INTERVAL"))
+	if err != nil {
+		log.Fatal(err)
+	}
+	r := newRouter(repos, pool, payloadarchive.New(archiveStore, repos.rawPayload), usageLake)
+	var background sync.WaitGroup
+	background.Add(1)
+	go func() {
+		defer background.Done()
+		pool.Run(ctx)
+	}()
+	if exportInterval > 0 {
+		background.Add(1)
+		go func() {
+			defer background.Done()
+			exporter.Schedule(ctx, exportInterval)
+		}()
+	}
 
-		data = append(data, model.UsageData{
-			AssetID:  record[0],
-			UsageQty: usageQty,
-		})
+	port := os.Getenv("PORT")
+	if port == "" {
+		port = "8080"
+	}
+	srv := &http.Server{Addr: ":" + port, Handler: r}
+	serveErr := make(chan error, 1)
+	go func() {
+		log.Printf("Server starting on :%s...", port)
+		serveErr <- srv.ListenAndServe()
+	}()
+	select {
+	case err := <-serveErr:
+		log.Fatal(err)
+	case <-ctx.Done():
 	}
 
-	return data, nil
+	// Stop taking requests, then let in-flight ingestion jobs and exports finish before exiting.
+	log.Println("Shutting down...")
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+	if err := srv.Shutdown(shutdownCtx); err != nil {
+		log.Println("shut down server:", err)
+	}
+	background.Wait()
 }
 
-func helloWorldHandler(w http.ResponseWriter, r *http.Request) {
-	if r.Method != http.MethodPost {
-		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
-		return
+// parseLakeExportInterval parses LAKE_EXPORT_INTERVAL. A value that is not a duration, or is
+// negative, is an error rather than silently leaving the export unscheduled.
+func parseLakeExportInterval(value string) (time.Duration, error) {
+	if value == "" {
+		return 0, nil
 	}
-
-	accountId := chi.URLParam(r, "account_id")
-	if accountId == "" {
-		http.Error(w, "accountId is required in the path", http.StatusBadRequest)
-		return
+	interval, err := time.ParseDuration(value)
+	if err != nil || interval < 0 {
+		return 0, fmt.Errorf("invalid LAKE_EXPORT_INTERVAL %q: want a duration such as 15m", value)
 	}
+	return interval, nil
+}
 
-	format := r.URL.Query().Get("format")
-	var data []model.UsageData
-	var err error
+// shutdownTimeout bounds how long in-flight requests may take to finish after SIGTERM. ECS sends
+// SIGKILL 30 seconds after SIGTERM by default.
+const shutdownTimeout = 25 * time.Second
 
-	if format == "csv" {
-		data, err = parseCSV(r.Body)
-		if err != nil {
-			http.Error(w, err.Error(), http.StatusBadRequest)
-			return
-		}
-	} else {
-		// Default to JSON format
-		var jsonReq JSONRequest
-		if err := json.NewDecoder(r.Body).Decode(&jsonReq); err != nil {
-			http.Error(w, "Invalid JSON format", http.StatusBadRequest)
-			return
-		}
-		data = jsonReq.Data
+// runLakeExport exports tables, or every usage table when none are named, and exits non-zero when any
+// export fails.
+func runLakeExport(ctx context.Context, exporter *lakeexport.Exporter, tables []string) {
+	runs, err := exporter.Run(ctx, tables...)
+	for _, run := range runs {
+		log.Printf("lake export %s %s: %d rows in %d files", run.SourceTable, run.Status, run.RowCount, run.FileCount)
 	}
-	// Upload to S3
-	bucket := os.Getenv("S3_BUCKET")
-	if bucket == "" {
-		http.Error(w, "S3_BUCKET environment variable not set", http.StatusInternalServerError)
-		return
+	if err != nil {
+		log.Fatal(err)
 	}
+}
 
-	key := accountId + "/usage_data_" + time.Now().Format("20060102_150405") + ".parquet"
-	fw, err := s3.NewS3FileWriter(r.Context(), bucket, key, "bucket-owner-full-control", nil)
-	if err != nil {
-		log.Println("Can't open file", err)
-		return
+// ingestionWorkers reads INGESTION_WORKERS, defaulting to 4 workers.
+func ingestionWorkers() int {
+	workers, err := strconv.Atoi(os.Getenv("INGESTION_WORKERS"))
+	if err != nil || workers <= 0 {
+		return 4
 	}
-	pw, err := writer.NewParquetWriter(fw, new(model.UsageData), 4)
-	if err != nil {
-		log.Println("Can't create parquet writer", err)
-		return
+	return workers
+}
+
+// defaultObjectStoreDir, relative to the working directory, holds a filesystem store when
+// OBJECT_STORE_DIR is not set.
+const defaultObjectStoreDir = "object-store"
+
+// objectStoreConfig reads the object store backend from OBJECT_STORE: s3, filesystem or memory. When
+// it is unset, OBJECT_STORE_DIR selects a filesystem store and S3_BUCKET an S3 one; with neither, the
+// store falls back to the filesystem under defaultObjectStoreDir. S3_ENDPOINT and
+// S3_FORCE_PATH_STYLE point the S3 backend at an S3-compatible service such as MinIO.
+func objectStoreConfig() (objectstore.Config, error) {
+	cfg := objectstore.Config{
+		Backend: os.Getenv("OBJECT_STORE"),
+		Bucket:  os.Getenv("S3_BUCKET"),
+		Prefix:  os.Getenv("S3_PREFIX"),
+		S3: objectstore.S3Options{
+			Endpoint: os.Getenv("S3_ENDPOINT"),
+			Region:   os.Getenv("S3_REGION"),
+		},
 	}
-	for _, usage := range data {
-		if err = pw.Write(usage); err != nil {
-			log.Println("Write error", err)
+	cfg.S3.ForcePathStyle, _ = strconv.ParseBool(os.Getenv("S3_FORCE_PATH_STYLE"))
+	dir := os.Getenv("OBJECT_STORE_DIR")
+	if cfg.Backend == "" {
+		switch {
+		case dir != "":
+			cfg.Backend = objectstore.BackendFilesystem
+		case cfg.Bucket != "":
+			cfg.Backend = objectstore.BackendS3
+		default:
+			cfg.Backend = objectstore.BackendFilesystem
 		}
 	}
-	if err = pw.WriteStop(); err != nil {
-		log.Println("WriteStop err", err)
+	if cfg.Backend == objectstore.BackendFilesystem && dir == "" {
+		dir = defaultObjectStoreDir
+		log.Printf("OBJECT_STORE_DIR is not set; storing payloads and lake tables under ./%s", dir)
 	}
-	err = fw.Close()
+	if dir != "" {
+		root, err := filepath.Abs(dir)
+		if err != nil {
+			return cfg, err
+		}
+		cfg.Dir = root
+	}
+	return cfg, nil
+}
+
+// newLakeWarehouse keeps lake tables under warehouse/ in the configured object store.
+func newLakeWarehouse(cfg objectstore.Config) (*iceberg.Warehouse, error) {
+	cfg.Dir = filepath.Join(cfg.Dir, "warehouse")
+	cfg.Prefix = path.Join(cfg.Prefix, "warehouse")
+	store, err := objectstore.Open(cfg)
 	if err != nil {
-		log.Println("Error closing S3 file writer")
+		return nil, err
+	}
+	switch cfg.Backend {
+	case objectstore.BackendS3:
+		return iceberg.NewWarehouse(store, "s3://"+cfg.Bucket+"/"+cfg.Prefix), nil
+	case objectstore.BackendFilesystem:
+		return iceberg.NewWarehouse(store, "file://"+filepath.ToSlash(cfg.Dir)), nil
 	}
+	return iceberg.NewWarehouse(store, "memory://warehouse"), nil
+}
 
-	response := Response{
-		Message:  "Data written to S3 successfully",
-		S3Object: key,
+// newLakeCatalog tracks lake tables in files under ICEBERG_CATALOG_DIR when it is set, and in the
+// iceberg_tables table otherwise, under ICEBERG_CATALOG_NAME (default usage_lakehouse).
+func newLakeCatalog(repo repository.IcebergTableRepository) iceberg.Catalog {
+	if dir := os.Getenv("ICEBERG_CATALOG_DIR"); dir != "" {
+		return iceberg.NewFilesystemCatalog(dir)
 	}
+	name := os.Getenv("ICEBERG_CATALOG_NAME")
+	if name == "" {
+		name = "usage_lakehouse"
+	}
+	return iceberg.NewPostgresCatalog(name, repo)
+}
 
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(response)
+// lakePartitions reads a lake table's partitioning from LAKE_PARTITIONS_<TABLE>, e.g.
+// LAKE_PARTITIONS_INTERVAL_USAGE=power_region,year=year(interval_start), falling back to defaults.
+func lakePartitions(table iceberg.Identifier, defaults []iceberg.PartitionTransform) ([]iceberg.PartitionTransform, error) {
+	spec, ok := os.LookupEnv("LAKE_PARTITIONS_" + strings.ToUpper(table.Name))
+	if !ok {
+		return defaults, nil
+	}
+	return iceberg.ParsePartitionTransforms(spec)
 }
 
-func main() {
-	userName := os.Getenv("POSTGRES_USER")
-	password := os.Getenv("POSTGRES_PASSWORD")
-	host := os.Getenv("POSTGRES_HOST")
-	dbPort := os.Getenv("POSTGRES_PORT")
-	database := os.Getenv("POSTGRES_DB")
-	dsn := fmt.Sprintf("postgres://%s:%s@%s:%s/%s?sslmode=disable", userName, password, host, dbPort, database)
-	dbpool, err := pgxpool.New(context.Background(), dsn)
+// newLakeWriter opens dataset's table with the partitioning lakePartitions configures for it.
+func newLakeWriter[R any](catalog iceberg.Catalog, warehouse *iceberg.Warehouse, dataset lake.Dataset[R]) (*lake.Writer[R], error) {
+	partitions, err := lakePartitions(dataset.Table, dataset.Partitions)
 	if err != nil {
-		log.Fatal("Failed to connect to DB:", err)
+		return nil, err
 	}
-	defer dbpool.Close()
-	accountRepo := repository.NewAccountRepository(dbpool)
-	accountHandler := handler.NewAccountHandler(accountRepo)
+	return lake.NewWriter(catalog, warehouse, dataset, partitions)
+}
 
-	r := chi.NewRouter()
-	r.Post("/accounts", accountHandler.CreateAccount)
-	r.Get("/accounts/{id}", accountHandler.GetAccount)
-	r.Put("/accounts/{id}", accountHandler.UpdateAccount)
-	r.Delete("/accounts/{id}", accountHandler.DeleteAccount)
-	r.Get("/accounts", accountHandler.ListAccounts)
-	r.Post("/edi/monthly-usage", helloWorldHandler)
-	port := os.Getenv("PORT")
-	if port == "" {
-		port = "8080"
+// newLakeExporter exports usage_transaction_detail and meter_usage_15_minute to the lake.
+// LAKE_EXPORT_BATCH_SIZE bounds the rows of one lake commit and LAKE_EXPORT_LAG, a duration, how far
+// exports stay behind the database clock.
+func newLakeExporter(repo repository.LakeExportRepository, catalog iceberg.Catalog, warehouse *iceberg.Warehouse) (*lakeexport.Exporter, error) {
+	details, err := newLakeWriter(catalog, warehouse, lake.UsageTransactionDetail)
+	if err != nil {
+		return nil, err
 	}
-	log.Printf("Server starting on :%s...", port)
-	if err := http.ListenAndServe(":"+port, r); err != nil {
-		log.Fatal(err)
+	meterUsage, err := newLakeWriter(catalog, warehouse, lake.MeterUsage)
+	if err != nil {
+		return nil, err
 	}
+	config := lakeexport.Config{}
+	config.BatchSize, _ = strconv.Atoi(os.Getenv("LAKE_EXPORT_BATCH_SIZE"))
+	config.Lag, _ = time.ParseDuration(os.Getenv("LAKE_EXPORT_LAG"))
+	return lakeexport.New(repo, config,
+		lakeexport.UsageTransactionDetailSource(repo, details),
+		lakeexport.MeterUsageSource(repo, meterUsage),
+	), nil
 }
@@ -0,0 +1,35 @@
+CREATE TABLE IF NOT EXISTS public.ingestion_job (
+	id UUID PRIMARY KEY,
+	kind VARCHAR(32) NOT NULL,
+	status VARCHAR(16) NOT NULL DEFAULT 'QUEUED',
+	params JSONB NOT NULL DEFAULT '{}',
+	payload BYTEA NOT NULL,
+	attempts INTEGER NOT NULL DEFAULT 0,
+	processed_count INTEGER NOT NULL DEFAULT 0,
+	succeeded_count INTEGER NOT NULL DEFAULT 0,
+	failed_count INTEGER NOT NULL DEFAULT 0,
+	errors JSONB NOT NULL DEFAULT '[]',
+	output JSONB,
+	started_dttm timestamp,
+	finished_dttm timestamp,
+	created_dttm timestamp NOT NULL,
+	updated_dttm timestamp NOT NULL,
+    CONSTRAINT check_ingestion_job_kind
+        CHECK (kind IN ('EDI_USAGE', 'LAKE_USAGE')),
+    CONSTRAINT check_ingestion_job_status
+        CHECK (status IN ('QUEUED', 'RUNNING', 'SUCCEEDED', 'FAILED'))
+);
+
+-- Workers poll for the oldest queued job and for running jobs whose worker went away.
+CREATE INDEX IF NOT EXISTS idx_ingestion_job_status
+    ON public.ingestion_job (status, created_dttm);
+
+CREATE TRIGGER set_created_dttm_trigger
+BEFORE INSERT ON public.ingestion_job
+FOR EACH ROW
+EXECUTE FUNCTION public.set_created_dttm();
+
+CREATE TRIGGER set_updated_dttm_trigger
+BEFORE INSERT OR UPDATE  ON public.ingestion_job
+FOR EACH ROW
+EXECUTE FUNCTION public.set_updated_dttm();
@@ -0,0 +1,22 @@
+-- Transactions rejected by a reference lookup are held alongside those quarantined by the
+-- provisioning policy, so the power region itself may be the lookup that failed.
+ALTER TABLE public.usage_transaction_quarantine
+    ALTER COLUMN power_region_id DROP NOT NULL,
+    ADD COLUMN IF NOT EXISTS stage VARCHAR(32),
+    ADD COLUMN IF NOT EXISTS replayed_usage_transaction_id UUID,
+    ADD CONSTRAINT fk_replayed_usage_transaction_id
+        FOREIGN KEY(replayed_usage_transaction_id)
+        REFERENCES public.usage_transaction(id)
+        ON DELETE SET NULL;
+
+UPDATE public.usage_transaction_quarantine
+SET stage = CASE WHEN reason LIKE 'unknown esi_id%' THEN 'PREMISE' ELSE 'METER' END
+WHERE stage IS NULL;
+
+ALTER TABLE public.usage_transaction_quarantine
+    ALTER COLUMN stage SET NOT NULL,
+    ADD CONSTRAINT check_usage_transaction_quarantine_stage
+        CHECK (stage IN ('POWER_REGION', 'TDSP', 'PREMISE', 'METER', 'PURPOSE', 'TRANSACTION_TYPE', 'TRANSACTION_SUB_TYPE'));
+
+CREATE INDEX IF NOT EXISTS idx_usage_transaction_quarantine_stage
+    ON public.usage_transaction_quarantine (stage, status);
@@ -0,0 +1,66 @@
+-- Every inbound request body is archived in the object store under its SHA-256 and never rewritten.
+CREATE TABLE IF NOT EXISTS public.raw_payload (
+	sha256 CHAR(64) PRIMARY KEY,
+	content_type VARCHAR(128) NOT NULL,
+	size_bytes BIGINT NOT NULL,
+	object_key VARCHAR(256) NOT NULL,
+	created_dttm timestamp NOT NULL,
+	updated_dttm timestamp NOT NULL
+);
+
+CREATE TRIGGER set_created_dttm_trigger
+BEFORE INSERT ON public.raw_payload
+FOR EACH ROW
+EXECUTE FUNCTION public.set_created_dttm();
+
+CREATE TRIGGER set_updated_dttm_trigger
+BEFORE INSERT OR UPDATE  ON public.raw_payload
+FOR EACH ROW
+EXECUTE FUNCTION public.set_updated_dttm();
+
+ALTER TABLE public.usage_transaction
+    ADD COLUMN IF NOT EXISTS raw_payload_sha256 CHAR(64),
+    ADD CONSTRAINT fk_raw_payload_sha256
+        FOREIGN KEY(raw_payload_sha256)
+        REFERENCES public.raw_payload(sha256);
+
+CREATE INDEX IF NOT EXISTS idx_usage_transaction_raw_payload_sha256
+    ON public.usage_transaction (raw_payload_sha256);
+
+ALTER TABLE public.usage_transaction_quarantine
+    ADD COLUMN IF NOT EXISTS raw_payload_sha256 CHAR(64),
+    ADD CONSTRAINT fk_raw_payload_sha256
+        FOREIGN KEY(raw_payload_sha256)
+        REFERENCES public.raw_payload(sha256);
+
+ALTER TABLE public.ingestion_job
+    ADD COLUMN IF NOT EXISTS raw_payload_sha256 CHAR(64),
+    ADD CONSTRAINT fk_raw_payload_sha256
+        FOREIGN KEY(raw_payload_sha256)
+        REFERENCES public.raw_payload(sha256);
+
+-- Parquet objects written to the lake and the upload they were built from.
+CREATE TABLE IF NOT EXISTS public.lake_object (
+	object_key VARCHAR(512) PRIMARY KEY,
+	account_id VARCHAR(128) NOT NULL,
+	raw_payload_sha256 CHAR(64) NOT NULL,
+	row_count INTEGER NOT NULL,
+	created_dttm timestamp NOT NULL,
+	updated_dttm timestamp NOT NULL,
+    CONSTRAINT fk_raw_payload_sha256
+        FOREIGN KEY(raw_payload_sha256)
+        REFERENCES public.raw_payload(sha256)
+);
+
+CREATE INDEX IF NOT EXISTS idx_lake_object_raw_payload_sha256
+    ON public.lake_object (raw_payload_sha256);
+
+CREATE TRIGGER set_created_dttm_trigger
+BEFORE INSERT ON public.lake_object
+FOR EACH ROW
+EXECUTE FUNCTION public.set_created_dttm();
+
+CREATE TRIGGER set_updated_dttm_trigger
+BEFORE INSERT OR UPDATE  ON public.lake_object
+FOR EACH ROW
+EXECUTE FUNCTION public.set_updated_dttm();
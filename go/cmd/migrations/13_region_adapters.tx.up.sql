@@ -0,0 +1,48 @@
+-- Purpose codes are only unique within a power region; NYISO reuses the 00/01 BPT01 codes ERCOT sends.
+ALTER TABLE public.power_region_usage_transaction_purpose
+    DROP CONSTRAINT IF EXISTS power_region_usage_transaction_purpose_pkey,
+    DROP CONSTRAINT IF EXISTS power_region_usage_transaction_purpose_name_key,
+    ADD CONSTRAINT pk_power_region_usage_transaction_purpose
+        PRIMARY KEY (code, power_region_id),
+    ADD CONSTRAINT unique_power_region_usage_transaction_purpose_name
+        UNIQUE (name, power_region_id);
+
+-- NYISO reference data for the New York EDI 867 implementation guide. Utilities are loaded as TDSPs
+-- with their DUNS and account number pattern alongside the rest of the region's master data.
+INSERT INTO public.power_region_transaction_type (code, name, power_region_id, transaction_type_code, description)
+SELECT '867', 'Usage', id, 'USAGE', 'Historic or monthly usage transaction'
+FROM public.power_region WHERE name = 'NYISO'
+ON CONFLICT DO NOTHING;
+
+INSERT INTO public.power_region_transaction_sub_type (code, name, power_region_id, transaction_sub_type_code, description)
+SELECT s.code, s.name, pr.id, s.transaction_sub_type_code, s.description
+FROM public.power_region pr
+CROSS JOIN (VALUES
+    ('2', 'Historical Usage', 'UH', 'Historical usage sent by the utility in response to an ESCO request'),
+    ('3', 'Monthly Usage', 'UM', 'Monthly or final usage sent by the utility for each billing period')
+) AS s (code, name, transaction_sub_type_code, description)
+WHERE pr.name = 'NYISO'
+ON CONFLICT DO NOTHING;
+
+INSERT INTO public.power_region_usage_transaction_purpose (code, name, power_region_id, usage_transaction_purpose_code, description)
+SELECT p.code, p.name, pr.id, p.usage_transaction_purpose_code, p.description
+FROM public.power_region pr
+CROSS JOIN (VALUES
+    ('00', 'Original', 'N', 'Conveys original usage for the account being reported.'),
+    ('01', 'Cancellation', 'C', 'Usage previously reported for the account is to be ignored.'),
+    ('52', 'Response to Historical Inquiry', 'N', 'Historical usage requested by the ESCO.')
+) AS p (code, name, usage_transaction_purpose_code, description)
+WHERE pr.name = 'NYISO'
+ON CONFLICT DO NOTHING;
+
+INSERT INTO public.power_region_usage_transaction_product_transfer_detail_type (code, name, power_region_id, is_interval, is_meter, is_summary, description)
+SELECT t.code, t.name, pr.id, t.is_interval, t.is_meter, t.is_summary, t.description
+FROM public.power_region pr
+CROSS JOIN (VALUES
+    ('SU', 'Account Usage Summary', FALSE, FALSE, TRUE, 'Total usage for the account across all meters'),
+    ('PL', 'Non-Interval Meter Detail', FALSE, TRUE, FALSE, 'Usage for a non-interval meter'),
+    ('BO', 'Interval Summary', TRUE, TRUE, TRUE, 'Total usage for an interval meter'),
+    ('PM', 'Interval Detail', TRUE, TRUE, FALSE, 'Interval usage for an interval meter')
+) AS t (code, name, is_interval, is_meter, is_summary, description)
+WHERE pr.name = 'NYISO'
+ON CONFLICT DO NOTHING;
@@ -0,0 +1,47 @@
+-- Each power region carries the IANA zone its utilities report local prevailing time in.
+ALTER TABLE public.power_region
+    ADD COLUMN IF NOT EXISTS time_zone VARCHAR(64) NOT NULL DEFAULT 'UTC';
+
+UPDATE public.power_region SET time_zone = CASE name
+    WHEN 'ERCOT' THEN 'America/Chicago'
+    WHEN 'PJM' THEN 'America/New_York'
+    WHEN 'MISO' THEN 'America/Chicago'
+    WHEN 'CAISO' THEN 'America/Los_Angeles'
+    WHEN 'NYISO' THEN 'America/New_York'
+    WHEN 'ISONE' THEN 'America/New_York'
+    WHEN 'SPP' THEN 'America/Chicago'
+    ELSE time_zone
+END;
+
+-- Interval timestamps were stored as local wall time, so the repeated fall-back hour collided on the
+-- primary key. They are now UTC instants. The primary keys are dropped while existing rows are
+-- shifted so an intermediate state cannot collide with a row not yet converted.
+ALTER TABLE public.usage_transaction_detail DROP CONSTRAINT pk_usage_transaction_detail;
+
+UPDATE public.usage_transaction_detail utd
+SET start_dttm = (utd.start_dttm AT TIME ZONE pr.time_zone) AT TIME ZONE 'UTC',
+    end_dttm = (utd.end_dttm AT TIME ZONE pr.time_zone) AT TIME ZONE 'UTC'
+FROM public.power_region pr
+WHERE pr.id = utd.power_region_id;
+
+ALTER TABLE public.usage_transaction_detail
+    ADD CONSTRAINT pk_usage_transaction_detail
+        PRIMARY KEY (start_dttm, usage_transaction_id, meter_name);
+
+ALTER TABLE public.meter_usage_15_minute DROP CONSTRAINT pk_meter_usage_15_minute;
+
+UPDATE public.meter_usage_15_minute mu
+SET start_dttm = (mu.start_dttm AT TIME ZONE pr.time_zone) AT TIME ZONE 'UTC',
+    end_dttm = (mu.end_dttm AT TIME ZONE pr.time_zone) AT TIME ZONE 'UTC'
+FROM public.meter m
+JOIN public.power_region pr ON pr.id = m.power_region_id
+WHERE m.id = mu.meter_id;
+
+ALTER TABLE public.meter_usage_15_minute
+    ADD CONSTRAINT pk_meter_usage_15_minute
+        PRIMARY KEY (start_dttm, meter_id);
+
+COMMENT ON COLUMN public.usage_transaction_detail.start_dttm IS 'UTC';
+COMMENT ON COLUMN public.usage_transaction_detail.end_dttm IS 'UTC';
+COMMENT ON COLUMN public.meter_usage_15_minute.start_dttm IS 'UTC';
+COMMENT ON COLUMN public.meter_usage_15_minute.end_dttm IS 'UTC';
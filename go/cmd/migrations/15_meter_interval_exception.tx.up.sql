@@ -0,0 +1,62 @@
+ALTER TABLE public.meter
+    ADD COLUMN IF NOT EXISTS interval_minutes SMALLINT NOT NULL DEFAULT 15,
+    ADD CONSTRAINT check_meter_interval_minutes
+        CHECK (interval_minutes IN (5, 15, 30, 60));
+
+-- One row per interval an 867 interval series left missing, reported more than once or reported
+-- outside its service period. start_dttm and end_dttm are UTC.
+CREATE TABLE IF NOT EXISTS public.meter_interval_exception (
+	id UUID PRIMARY KEY,
+	usage_transaction_id UUID NOT NULL,
+	premise_id UUID NOT NULL,
+	power_region_id UUID NOT NULL,
+	meter_id UUID,
+	meter_name VARCHAR(64) NOT NULL,
+	channel VARCHAR(64) NOT NULL,
+	kind VARCHAR(16) NOT NULL,
+	start_dttm TIMESTAMP NOT NULL,
+	end_dttm TIMESTAMP NOT NULL,
+	service_period_start_dt DATE NOT NULL,
+	service_period_end_dt DATE NOT NULL,
+	reported_count INT NOT NULL DEFAULT 0,
+	is_canceled BOOLEAN NOT NULL DEFAULT FALSE,
+	created_dttm timestamp NOT NULL,
+	updated_dttm timestamp NOT NULL,
+    CONSTRAINT fk_usage_transaction_id
+        FOREIGN KEY(usage_transaction_id)
+        REFERENCES public.usage_transaction(id)
+        ON DELETE CASCADE,
+    CONSTRAINT fk_premise_id
+        FOREIGN KEY(premise_id)
+        REFERENCES public.premise(id)
+        ON DELETE CASCADE,
+    CONSTRAINT fk_power_region_id
+        FOREIGN KEY(power_region_id)
+        REFERENCES public.power_region(id)
+        ON DELETE CASCADE,
+    CONSTRAINT fk_meter_id
+        FOREIGN KEY(meter_id)
+        REFERENCES public.meter(id)
+        ON DELETE CASCADE,
+    CONSTRAINT check_meter_interval_exception_kind
+        CHECK (kind IN ('GAP', 'DUPLICATE', 'OUT_OF_RANGE'))
+);
+
+CREATE INDEX IF NOT EXISTS idx_meter_interval_exception_meter_start
+    ON public.meter_interval_exception (meter_id, start_dttm);
+
+CREATE INDEX IF NOT EXISTS idx_meter_interval_exception_start
+    ON public.meter_interval_exception (start_dttm) WHERE NOT is_canceled;
+
+CREATE INDEX IF NOT EXISTS idx_meter_interval_exception_usage_transaction
+    ON public.meter_interval_exception (usage_transaction_id);
+
+CREATE TRIGGER set_created_dttm_trigger
+BEFORE INSERT ON public.meter_interval_exception
+FOR EACH ROW
+EXECUTE FUNCTION public.set_created_dttm();
+
+CREATE TRIGGER set_updated_dttm_trigger
+BEFORE INSERT OR UPDATE  ON public.meter_interval_exception
+FOR EACH ROW
+EXECUTE FUNCTION public.set_updated_dttm();
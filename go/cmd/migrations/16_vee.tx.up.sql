@@ -0,0 +1,48 @@
+-- Validation, estimation and editing (VEE) of normalized interval data. Every reading records whether it
+-- is an actual read, an estimate filling a gap or an edit replacing a read that failed validation,
+-- and the rule that produced it. Actual reads from a later 867 replace estimates and edits.
+ALTER TABLE public.meter_usage_15_minute
+    ADD COLUMN IF NOT EXISTS quality VARCHAR(16) NOT NULL DEFAULT 'ACTUAL',
+    ADD COLUMN IF NOT EXISTS quality_rule VARCHAR(128),
+    ADD COLUMN IF NOT EXISTS original_consumption DECIMAL(10,5),
+    ADD CONSTRAINT check_meter_usage_15_minute_quality
+        CHECK (quality IN ('ACTUAL', 'ESTIMATED', 'EDITED'));
+
+ALTER TABLE public.meter
+    ADD COLUMN IF NOT EXISTS max_demand_kw DECIMAL(12,3);
+
+-- Validation failures of the latest VEE run over each meter and window. start_dttm and end_dttm are UTC.
+CREATE TABLE IF NOT EXISTS public.meter_usage_vee_flag (
+	id UUID PRIMARY KEY,
+	meter_id UUID NOT NULL,
+	rule VARCHAR(64) NOT NULL,
+	start_dttm TIMESTAMP NOT NULL,
+	end_dttm TIMESTAMP NOT NULL,
+	value DECIMAL(14,5),
+	message VARCHAR(1000) NOT NULL,
+	is_replaced BOOLEAN NOT NULL DEFAULT FALSE,
+	created_dttm timestamp NOT NULL,
+	updated_dttm timestamp NOT NULL,
+    CONSTRAINT fk_meter_id
+        FOREIGN KEY(meter_id)
+        REFERENCES public.meter(id)
+        ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_meter_usage_vee_flag_meter_start
+    ON public.meter_usage_vee_flag (meter_id, start_dttm);
+
+CREATE TRIGGER set_created_dttm_trigger
+BEFORE INSERT ON public.meter_usage_vee_flag
+FOR EACH ROW
+EXECUTE FUNCTION public.set_created_dttm();
+
+CREATE TRIGGER set_updated_dttm_trigger
+BEFORE INSERT OR UPDATE  ON public.meter_usage_vee_flag
+FOR EACH ROW
+EXECUTE FUNCTION public.set_updated_dttm();
+
+ALTER TABLE public.ingestion_job
+    DROP CONSTRAINT check_ingestion_job_kind,
+    ADD CONSTRAINT check_ingestion_job_kind
+        CHECK (kind IN ('EDI_USAGE', 'LAKE_USAGE', 'VEE'));
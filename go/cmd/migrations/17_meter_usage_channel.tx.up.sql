@@ -0,0 +1,92 @@
+-- Channel and unit of measure registry. Each power region's interval channels are registered with the
+-- unit their readings are sent in and whether energy flows to (DELIVERED) or from (RECEIVED) the premise.
+CREATE TABLE IF NOT EXISTS public.power_region_channel (
+	power_region_id UUID NOT NULL,
+	code VARCHAR(64) NOT NULL,
+	name VARCHAR(255) NOT NULL,
+	unit_of_measure VARCHAR(16) NOT NULL,
+	direction VARCHAR(16) NOT NULL DEFAULT 'DELIVERED',
+	description VARCHAR(1000),
+	created_dttm timestamp NOT NULL,
+	updated_dttm timestamp NOT NULL,
+    CONSTRAINT pk_power_region_channel
+        PRIMARY KEY (power_region_id, code),
+    CONSTRAINT fk_power_region_id
+        FOREIGN KEY(power_region_id)
+        REFERENCES public.power_region(id)
+        ON DELETE CASCADE,
+    CONSTRAINT check_power_region_channel_unit_of_measure
+        CHECK (unit_of_measure IN ('WH', 'KWH', 'MWH', 'W', 'KW', 'MW', 'VARH', 'KVARH', 'MVARH', 'VAR', 'KVAR', 'MVAR', 'VAH', 'KVAH', 'MVAH', 'VA', 'KVA', 'MVA')),
+    CONSTRAINT check_power_region_channel_direction
+        CHECK (direction IN ('DELIVERED', 'RECEIVED', 'NET'))
+);
+
+CREATE TRIGGER set_created_dttm_trigger
+BEFORE INSERT ON public.power_region_channel
+FOR EACH ROW
+EXECUTE FUNCTION public.set_created_dttm();
+
+CREATE TRIGGER set_updated_dttm_trigger
+BEFORE INSERT OR UPDATE  ON public.power_region_channel
+FOR EACH ROW
+EXECUTE FUNCTION public.set_updated_dttm();
+
+INSERT INTO public.power_region_channel (power_region_id, code, name, unit_of_measure, direction, description)
+SELECT pr.id, c.code, c.name, c.unit_of_measure, c.direction, c.description
+FROM public.power_region pr
+JOIN (VALUES
+    ('ERCOT', '1', 'kWh Delivered', 'KWH', 'DELIVERED', 'Energy delivered to the premise'),
+    ('ERCOT', '4', 'kWh Received', 'KWH', 'RECEIVED', 'Energy received from the premise'),
+    ('NYISO', 'QD', 'kWh Delivered', 'KWH', 'DELIVERED', 'Energy delivered to the premise'),
+    ('NYISO', '87', 'kWh Received', 'KWH', 'RECEIVED', 'Energy received from the premise')
+) AS c (power_region, code, name, unit_of_measure, direction, description) ON c.power_region = pr.name
+ON CONFLICT DO NOTHING;
+
+-- Interval readings of every channel an 867 reports, e.g. demand kW, reactive kVARh and apparent kVAh
+-- as well as the kWh channels meter_usage_15_minute totals into consumption and generation.
+-- start_dttm and end_dttm are UTC. unit_of_measure is NULL for unregistered channels sent without one.
+CREATE TABLE IF NOT EXISTS public.meter_usage_channel (
+	start_dttm TIMESTAMP NOT NULL,
+	end_dttm TIMESTAMP NOT NULL,
+	meter_id UUID NOT NULL,
+	channel VARCHAR(64) NOT NULL,
+	power_region_id UUID NOT NULL,
+	premise_id UUID NOT NULL,
+	usage_transaction_id UUID NOT NULL,
+	unit_of_measure VARCHAR(16),
+	direction VARCHAR(16),
+	quantity DECIMAL(14,5) NOT NULL,
+	is_canceled BOOLEAN NOT NULL DEFAULT FALSE,
+	created_dttm timestamp NOT NULL,
+	updated_dttm timestamp NOT NULL,
+    CONSTRAINT pk_meter_usage_channel
+        PRIMARY KEY (meter_id, channel, start_dttm),
+    CONSTRAINT fk_meter_id
+        FOREIGN KEY(meter_id)
+        REFERENCES public.meter(id)
+        ON DELETE CASCADE,
+    CONSTRAINT fk_power_region_id
+        FOREIGN KEY(power_region_id)
+        REFERENCES public.power_region(id),
+    CONSTRAINT fk_premise_id
+        FOREIGN KEY(premise_id)
+        REFERENCES public.premise(id)
+        ON DELETE CASCADE,
+    CONSTRAINT fk_usage_transaction_id
+        FOREIGN KEY(usage_transaction_id)
+        REFERENCES public.usage_transaction(id)
+        ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_meter_usage_channel_usage_transaction
+    ON public.meter_usage_channel (usage_transaction_id);
+
+CREATE TRIGGER set_created_dttm_trigger
+BEFORE INSERT ON public.meter_usage_channel
+FOR EACH ROW
+EXECUTE FUNCTION public.set_created_dttm();
+
+CREATE TRIGGER set_updated_dttm_trigger
+BEFORE INSERT OR UPDATE  ON public.meter_usage_channel
+FOR EACH ROW
+EXECUTE FUNCTION public.set_updated_dttm();
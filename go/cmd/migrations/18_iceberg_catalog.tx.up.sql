@@ -0,0 +1,26 @@
+-- Iceberg catalog. Each row points a table at its current metadata.json; a commit swaps
+-- metadata_location only while it still holds the metadata the commit was based on. The table and
+-- column names are those of the Iceberg JDBC catalog, so Spark can read the same tables through a
+-- JdbcCatalog configured with this database.
+CREATE TABLE IF NOT EXISTS public.iceberg_tables (
+	catalog_name VARCHAR(255) NOT NULL,
+	table_namespace VARCHAR(255) NOT NULL,
+	table_name VARCHAR(255) NOT NULL,
+	metadata_location VARCHAR(1000),
+	previous_metadata_location VARCHAR(1000),
+	iceberg_type VARCHAR(5),
+	created_dttm timestamp NOT NULL,
+	updated_dttm timestamp NOT NULL,
+    CONSTRAINT pk_iceberg_tables
+        PRIMARY KEY (catalog_name, table_namespace, table_name)
+);
+
+CREATE TRIGGER set_created_dttm_trigger
+BEFORE INSERT ON public.iceberg_tables
+FOR EACH ROW
+EXECUTE FUNCTION public.set_created_dttm();
+
+CREATE TRIGGER set_updated_dttm_trigger
+BEFORE INSERT OR UPDATE  ON public.iceberg_tables
+FOR EACH ROW
+EXECUTE FUNCTION public.set_updated_dttm();
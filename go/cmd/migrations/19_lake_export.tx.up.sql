@@ -0,0 +1,56 @@
+-- Incremental export of Postgres usage tables to the lake. Each source table's high-water mark is the
+-- latest updated_dttm whose rows are committed to its lake table; the next run exports rows updated
+-- after it. Runs record the range and row counts they exported, and why they failed.
+CREATE TABLE IF NOT EXISTS public.lake_export_watermark (
+	source_table VARCHAR(128) PRIMARY KEY,
+	high_water_mark timestamp NOT NULL,
+	created_dttm timestamp NOT NULL,
+	updated_dttm timestamp NOT NULL
+);
+
+CREATE TRIGGER set_created_dttm_trigger
+BEFORE INSERT ON public.lake_export_watermark
+FOR EACH ROW
+EXECUTE FUNCTION public.set_created_dttm();
+
+CREATE TRIGGER set_updated_dttm_trigger
+BEFORE INSERT OR UPDATE  ON public.lake_export_watermark
+FOR EACH ROW
+EXECUTE FUNCTION public.set_updated_dttm();
+
+CREATE TABLE IF NOT EXISTS public.lake_export_run (
+	id UUID PRIMARY KEY,
+	source_table VARCHAR(128) NOT NULL,
+	status VARCHAR(16) NOT NULL,
+	low_water_mark timestamp,
+	high_water_mark timestamp,
+	row_count BIGINT NOT NULL DEFAULT 0,
+	file_count INTEGER NOT NULL DEFAULT 0,
+	snapshot_id BIGINT,
+	error TEXT,
+	started_dttm timestamp NOT NULL,
+	finished_dttm timestamp,
+	created_dttm timestamp NOT NULL,
+	updated_dttm timestamp NOT NULL,
+    CONSTRAINT check_lake_export_run_status
+        CHECK (status IN ('RUNNING', 'SUCCEEDED', 'FAILED'))
+);
+
+CREATE INDEX IF NOT EXISTS idx_lake_export_run_source_started
+    ON public.lake_export_run (source_table, started_dttm);
+
+CREATE TRIGGER set_created_dttm_trigger
+BEFORE INSERT ON public.lake_export_run
+FOR EACH ROW
+EXECUTE FUNCTION public.set_created_dttm();
+
+CREATE TRIGGER set_updated_dttm_trigger
+BEFORE INSERT OR UPDATE  ON public.lake_export_run
+FOR EACH ROW
+EXECUTE FUNCTION public.set_updated_dttm();
+
+CREATE INDEX IF NOT EXISTS idx_usage_transaction_detail_updated
+    ON public.usage_transaction_detail (updated_dttm);
+
+CREATE INDEX IF NOT EXISTS idx_meter_usage_15_minute_updated
+    ON public.meter_usage_15_minute (updated_dttm);
@@ -0,0 +1,6 @@
+-- Workers refresh heartbeat_dttm while they run a job, and a RUNNING job is only reclaimed once its
+-- heartbeat goes stale, so a long job is not picked up by a second worker while the first is alive.
+ALTER TABLE public.ingestion_job
+    ADD COLUMN IF NOT EXISTS heartbeat_dttm timestamp;
+
+UPDATE public.ingestion_job SET heartbeat_dttm = started_dttm WHERE status = 'RUNNING';
@@ -0,0 +1,41 @@
+-- Intervals whose effective reading no account held the premise for on its local date. They cannot be
+-- stored in meter_usage_15_minute, whose account_id is required, so they are kept here until a later
+-- refresh resolves an account or the reading is voided.
+CREATE TABLE IF NOT EXISTS public.meter_usage_15_minute_unassigned (
+	start_dttm TIMESTAMP NOT NULL,
+	end_dttm TIMESTAMP NOT NULL,
+	service_period_start_dt DATE NOT NULL,
+	service_period_end_dt DATE NOT NULL,
+	premise_id UUID NOT NULL,
+	meter_id UUID NOT NULL,
+	usage_transaction_id UUID NOT NULL,
+	created_dttm timestamp NOT NULL,
+	updated_dttm timestamp NOT NULL,
+    CONSTRAINT fk_premise_id
+        FOREIGN KEY(premise_id)
+        REFERENCES public.premise(id)
+        ON DELETE CASCADE,
+    CONSTRAINT fk_meter_id
+        FOREIGN KEY(meter_id)
+        REFERENCES public.meter(id)
+        ON DELETE CASCADE,
+    CONSTRAINT fk_usage_transaction_id
+        FOREIGN KEY(usage_transaction_id)
+        REFERENCES public.usage_transaction(id)
+        ON DELETE CASCADE,
+    CONSTRAINT pk_meter_usage_15_minute_unassigned
+        PRIMARY KEY (start_dttm, meter_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_meter_usage_15_minute_unassigned_premise
+    ON public.meter_usage_15_minute_unassigned (premise_id, start_dttm);
+
+CREATE TRIGGER set_created_dttm_trigger
+BEFORE INSERT ON public.meter_usage_15_minute_unassigned
+FOR EACH ROW
+EXECUTE FUNCTION public.set_created_dttm();
+
+CREATE TRIGGER set_updated_dttm_trigger
+BEFORE INSERT OR UPDATE  ON public.meter_usage_15_minute_unassigned
+FOR EACH ROW
+EXECUTE FUNCTION public.set_updated_dttm();
@@ -0,0 +1,9 @@
+-- Jobs read their payload back from the payload archive through raw_payload_sha256 instead of keeping
+-- a second copy. A queued or running job whose body was never archived cannot be run any more.
+UPDATE public.ingestion_job
+SET status = 'FAILED', finished_dttm = now(),
+    errors = errors || jsonb_build_array(jsonb_build_object('message', 'job has no archived payload'))
+WHERE status IN ('QUEUED', 'RUNNING') AND kind <> 'VEE' AND raw_payload_sha256 IS NULL;
+
+ALTER TABLE public.ingestion_job
+    DROP COLUMN IF EXISTS payload;
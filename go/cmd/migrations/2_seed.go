@@ -166,8 +166,8 @@ func init() {
 		},
 	}
 
-	historicUsageCode := "UH"
-	monthlyUsageCode := "UM"
+	historicUsageCode := dbentity.TransactionSubTypeHistoricUsage
+	monthlyUsageCode := dbentity.TransactionSubTypeMonthlyUsage
 	transactionSubTypes := []dbentity.TransactionSubType{
 		{
 			Code:                historicUsageCode,
@@ -0,0 +1,33 @@
+CREATE SEQUENCE IF NOT EXISTS public.edi_acknowledgement_control_number_seq
+    MINVALUE 1
+    MAXVALUE 999999999
+    CYCLE;
+
+CREATE TABLE IF NOT EXISTS public.edi_acknowledgement (
+	id UUID PRIMARY KEY,
+	acknowledgement_type VARCHAR(3) NOT NULL,
+	control_number BIGINT NOT NULL,
+	interchange_control_number VARCHAR(9) NOT NULL,
+	sender_id VARCHAR(15) NOT NULL,
+	receiver_id VARCHAR(15) NOT NULL,
+	accepted_count INTEGER NOT NULL,
+	rejected_count INTEGER NOT NULL,
+	content TEXT NOT NULL,
+	created_dttm timestamp NOT NULL,
+	updated_dttm timestamp NOT NULL,
+    CONSTRAINT check_acknowledgement_type
+        CHECK (acknowledgement_type IN ('997', '999'))
+);
+
+CREATE INDEX IF NOT EXISTS idx_edi_acknowledgement_interchange
+    ON public.edi_acknowledgement (sender_id, interchange_control_number);
+
+CREATE TRIGGER set_created_dttm_trigger
+BEFORE INSERT ON public.edi_acknowledgement
+FOR EACH ROW
+EXECUTE FUNCTION public.set_created_dttm();
+
+CREATE TRIGGER set_updated_dttm_trigger
+BEFORE INSERT OR UPDATE  ON public.edi_acknowledgement
+FOR EACH ROW
+EXECUTE FUNCTION public.set_updated_dttm();
@@ -0,0 +1,21 @@
+ALTER TABLE public.usage_transaction_purpose
+    ADD COLUMN IF NOT EXISTS is_replace BOOLEAN NOT NULL DEFAULT FALSE;
+
+-- A replacement voids the transaction it corrects but its own readings are effective.
+UPDATE public.usage_transaction_purpose
+SET is_cancel = FALSE, is_replace = TRUE
+WHERE code = 'R';
+
+ALTER TABLE public.usage_transaction
+    ADD COLUMN IF NOT EXISTS original_transaction_id VARCHAR(32),
+    ADD COLUMN IF NOT EXISTS original_usage_transaction_id UUID,
+    ADD CONSTRAINT fk_original_usage_transaction_id
+        FOREIGN KEY(original_usage_transaction_id)
+        REFERENCES public.usage_transaction(id)
+        ON DELETE SET NULL;
+
+CREATE INDEX IF NOT EXISTS idx_usage_transaction_premise_transaction
+    ON public.usage_transaction (premise_id, transaction_id);
+
+CREATE INDEX IF NOT EXISTS idx_usage_transaction_detail_usage_transaction
+    ON public.usage_transaction_detail (usage_transaction_id);
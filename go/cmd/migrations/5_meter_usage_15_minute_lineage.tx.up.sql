@@ -0,0 +1,12 @@
+ALTER TABLE public.usage_transaction_detail
+    ADD COLUMN IF NOT EXISTS is_interval BOOLEAN NOT NULL DEFAULT FALSE;
+
+CREATE INDEX IF NOT EXISTS idx_usage_transaction_detail_meter_start
+    ON public.usage_transaction_detail (meter_id, start_dttm);
+
+ALTER TABLE public.meter_usage_15_minute
+    ADD COLUMN IF NOT EXISTS usage_transaction_id UUID,
+    ADD CONSTRAINT fk_usage_transaction_id
+        FOREIGN KEY(usage_transaction_id)
+        REFERENCES public.usage_transaction(id)
+        ON DELETE SET NULL;
@@ -0,0 +1,85 @@
+CREATE TABLE IF NOT EXISTS public.usage_transaction_summary (
+	id UUID PRIMARY KEY,
+	usage_transaction_id UUID NOT NULL,
+	product_transfer_detail_type_code VARCHAR(64) NOT NULL,
+	premise_id UUID NOT NULL,
+	meter_id UUID,
+	meter_name VARCHAR(64) NOT NULL,
+	channel VARCHAR(64) NOT NULL,
+	service_period_start_dt DATE NOT NULL,
+	service_period_end_dt DATE NOT NULL,
+	quantity DECIMAL(14,5) NOT NULL,
+	interval_quantity DECIMAL(14,5) NOT NULL,
+	is_reconciled BOOLEAN NOT NULL DEFAULT FALSE,
+	is_canceled BOOLEAN NOT NULL DEFAULT FALSE,
+	created_dttm timestamp NOT NULL,
+	updated_dttm timestamp NOT NULL,
+    CONSTRAINT fk_usage_transaction_id
+        FOREIGN KEY(usage_transaction_id)
+        REFERENCES public.usage_transaction(id)
+        ON DELETE CASCADE,
+    CONSTRAINT fk_premise_id
+        FOREIGN KEY(premise_id)
+        REFERENCES public.premise(id)
+        ON DELETE CASCADE,
+    CONSTRAINT fk_meter_id
+        FOREIGN KEY(meter_id)
+        REFERENCES public.meter(id)
+        ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_usage_transaction_summary_usage_transaction
+    ON public.usage_transaction_summary (usage_transaction_id);
+
+CREATE TRIGGER set_created_dttm_trigger
+BEFORE INSERT ON public.usage_transaction_summary
+FOR EACH ROW
+EXECUTE FUNCTION public.set_created_dttm();
+
+CREATE TRIGGER set_updated_dttm_trigger
+BEFORE INSERT OR UPDATE  ON public.usage_transaction_summary
+FOR EACH ROW
+EXECUTE FUNCTION public.set_updated_dttm();
+
+CREATE TABLE IF NOT EXISTS public.usage_reconciliation_exception (
+	id UUID PRIMARY KEY,
+	usage_transaction_id UUID NOT NULL,
+	usage_transaction_summary_id UUID NOT NULL,
+	premise_id UUID NOT NULL,
+	status VARCHAR(16) NOT NULL DEFAULT 'OPEN',
+	summary_quantity DECIMAL(14,5) NOT NULL,
+	interval_quantity DECIMAL(14,5) NOT NULL,
+	difference DECIMAL(14,5) NOT NULL,
+	created_dttm timestamp NOT NULL,
+	updated_dttm timestamp NOT NULL,
+    CONSTRAINT fk_usage_transaction_id
+        FOREIGN KEY(usage_transaction_id)
+        REFERENCES public.usage_transaction(id)
+        ON DELETE CASCADE,
+    CONSTRAINT fk_usage_transaction_summary_id
+        FOREIGN KEY(usage_transaction_summary_id)
+        REFERENCES public.usage_transaction_summary(id)
+        ON DELETE CASCADE,
+    CONSTRAINT fk_premise_id
+        FOREIGN KEY(premise_id)
+        REFERENCES public.premise(id)
+        ON DELETE CASCADE,
+    CONSTRAINT check_usage_reconciliation_exception_status
+        CHECK (status IN ('OPEN', 'SUPERSEDED'))
+);
+
+CREATE INDEX IF NOT EXISTS idx_usage_reconciliation_exception_status
+    ON public.usage_reconciliation_exception (status, created_dttm);
+
+CREATE INDEX IF NOT EXISTS idx_usage_reconciliation_exception_usage_transaction
+    ON public.usage_reconciliation_exception (usage_transaction_id);
+
+CREATE TRIGGER set_created_dttm_trigger
+BEFORE INSERT ON public.usage_reconciliation_exception
+FOR EACH ROW
+EXECUTE FUNCTION public.set_created_dttm();
+
+CREATE TRIGGER set_updated_dttm_trigger
+BEFORE INSERT OR UPDATE  ON public.usage_reconciliation_exception
+FOR EACH ROW
+EXECUTE FUNCTION public.set_updated_dttm();
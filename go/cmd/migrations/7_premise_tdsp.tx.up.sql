@@ -0,0 +1,36 @@
+ALTER TABLE public.premise
+    ADD COLUMN IF NOT EXISTS tdsp_id UUID,
+    ADD CONSTRAINT fk_tdsp_id
+        FOREIGN KEY(tdsp_id)
+        REFERENCES public.tdsp(id)
+        ON DELETE SET NULL;
+
+CREATE INDEX IF NOT EXISTS idx_premise_tdsp
+    ON public.premise (tdsp_id);
+
+-- Premises that have received usage belong to the TDSP that last sent it.
+UPDATE public.premise p
+SET tdsp_id = (
+    SELECT ut.tdsp_id
+    FROM public.usage_transaction ut
+    WHERE ut.premise_id = p.id
+    ORDER BY ut.transaction_dt DESC, ut.created_dttm DESC
+    LIMIT 1
+)
+WHERE p.tdsp_id IS NULL;
+
+-- Otherwise assign the only TDSP of the premise's power region whose pattern matches its code.
+UPDATE public.premise p
+SET tdsp_id = m.tdsp_id
+FROM (
+    SELECT p2.id AS premise_id, MIN(t.id::text)::uuid AS tdsp_id
+    FROM public.premise p2
+    JOIN public.tdsp_power_region_junction tpr ON tpr.power_region_id = p2.power_region_id
+    JOIN public.tdsp t ON t.id = tpr.tdsp_id
+    WHERE p2.tdsp_id IS NULL
+    AND t.premise_code_validation_expression IS NOT NULL
+    AND p2.code ~ t.premise_code_validation_expression
+    GROUP BY p2.id
+    HAVING COUNT(*) = 1
+) m
+WHERE p.id = m.premise_id;
@@ -0,0 +1,3 @@
+ALTER TABLE public.usage_transaction
+    ADD COLUMN IF NOT EXISTS payload JSONB,
+    ADD COLUMN IF NOT EXISTS payload_sha256 CHAR(64);
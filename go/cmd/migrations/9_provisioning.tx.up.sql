@@ -0,0 +1,57 @@
+ALTER TABLE public.power_region
+    ADD COLUMN IF NOT EXISTS provisioning_policy VARCHAR(16) NOT NULL DEFAULT 'REJECT',
+    ADD CONSTRAINT check_power_region_provisioning_policy
+        CHECK (provisioning_policy IN ('REJECT', 'QUARANTINE', 'AUTO_CREATE'));
+
+ALTER TABLE public.premise
+    ADD COLUMN IF NOT EXISTS is_auto_provisioned BOOLEAN NOT NULL DEFAULT FALSE;
+
+ALTER TABLE public.meter
+    ADD COLUMN IF NOT EXISTS is_auto_provisioned BOOLEAN NOT NULL DEFAULT FALSE;
+
+INSERT INTO public.premise_type (code, name, description)
+VALUES ('UNKNOWN', 'UNKNOWN', 'Premise type not yet known, used for auto-provisioned premises')
+ON CONFLICT (code) DO NOTHING;
+
+CREATE TABLE IF NOT EXISTS public.usage_transaction_quarantine (
+	id UUID PRIMARY KEY,
+	transaction_id VARCHAR(32) NOT NULL,
+	transaction_sub_type_code VARCHAR(64) NOT NULL,
+	power_region_id UUID NOT NULL,
+	tdsp_id UUID,
+	esi_id VARCHAR(128) NOT NULL,
+	reason VARCHAR(1000) NOT NULL,
+	status VARCHAR(16) NOT NULL DEFAULT 'QUARANTINED',
+	payload JSONB NOT NULL,
+	payload_sha256 CHAR(64) NOT NULL,
+	created_dttm timestamp NOT NULL,
+	updated_dttm timestamp NOT NULL,
+    CONSTRAINT fk_power_region_id
+        FOREIGN KEY(power_region_id)
+        REFERENCES public.power_region(id)
+        ON DELETE CASCADE,
+    CONSTRAINT fk_tdsp_id
+        FOREIGN KEY(tdsp_id)
+        REFERENCES public.tdsp(id)
+        ON DELETE SET NULL,
+    CONSTRAINT check_usage_transaction_quarantine_status
+        CHECK (status IN ('QUARANTINED', 'REPLAYED', 'DISCARDED'))
+);
+
+-- A redelivery of a transaction that is still held replaces the held payload.
+CREATE UNIQUE INDEX IF NOT EXISTS unique_usage_transaction_quarantine_open_transaction
+    ON public.usage_transaction_quarantine (transaction_id)
+    WHERE status = 'QUARANTINED';
+
+CREATE INDEX IF NOT EXISTS idx_usage_transaction_quarantine_status
+    ON public.usage_transaction_quarantine (status, created_dttm);
+
+CREATE TRIGGER set_created_dttm_trigger
+BEFORE INSERT ON public.usage_transaction_quarantine
+FOR EACH ROW
+EXECUTE FUNCTION public.set_created_dttm();
+
+CREATE TRIGGER set_updated_dttm_trigger
+BEFORE INSERT OR UPDATE  ON public.usage_transaction_quarantine
+FOR EACH ROW
+EXECUTE FUNCTION public.set_updated_dttm();
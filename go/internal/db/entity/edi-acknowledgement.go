@@ -0,0 +1,17 @@
+package dbentity
+
+import "time"
+
+type EDIAcknowledgement struct {
+	ID                       string    `json:"id"`
+	Type                     string    `json:"acknowledgement_type"`
+	ControlNumber            int64     `json:"control_number"`
+	InterchangeControlNumber string    `json:"interchange_control_number"`
+	SenderID                 string    `json:"sender_id"`
+	ReceiverID               string    `json:"receiver_id"`
+	AcceptedCount            int       `json:"accepted_count"`
+	RejectedCount            int       `json:"rejected_count"`
+	Content                  string    `json:"content"`
+	Created                  time.Time `json:"created_dttm"`
+	Updated                  time.Time `json:"updated_dttm"`
+}
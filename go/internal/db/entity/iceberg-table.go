@@ -0,0 +1,14 @@
+package dbentity
+
+import "time"
+
+// IcebergTable is a catalog entry pointing an Iceberg table at its current metadata file.
+type IcebergTable struct {
+	CatalogName              string    `json:"catalog_name"`
+	Namespace                string    `json:"table_namespace"`
+	Name                     string    `json:"table_name"`
+	MetadataLocation         string    `json:"metadata_location"`
+	PreviousMetadataLocation *string   `json:"previous_metadata_location,omitempty"`
+	Created                  time.Time `json:"created_dttm"`
+	Updated                  time.Time `json:"updated_dttm"`
+}
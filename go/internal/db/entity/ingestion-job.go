@@ -0,0 +1,49 @@
+package dbentity
+
+import (
+	"encoding/json"
+	"time"
+)
+
+const (
+	IngestionJobKindEDIUsage  = "EDI_USAGE"
+	IngestionJobKindLakeUsage = "LAKE_USAGE"
+	IngestionJobKindVEE       = "VEE"
+)
+
+const (
+	IngestionJobStatusQueued    = "QUEUED"
+	IngestionJobStatusRunning   = "RUNNING"
+	IngestionJobStatusSucceeded = "SUCCEEDED"
+	IngestionJobStatusFailed    = "FAILED"
+)
+
+type IngestionJob struct {
+	ID               string              `json:"id"`
+	Kind             string              `json:"kind"`
+	Status           string              `json:"status"`
+	Params           json.RawMessage     `json:"params"`
+	RawPayloadSHA256 *string             `json:"raw_payload_sha256,omitempty"`
+	Attempts         int                 `json:"attempts"`
+	ProcessedCount   int                 `json:"processed_count"`
+	SucceededCount   int                 `json:"succeeded_count"`
+	FailedCount      int                 `json:"failed_count"`
+	Errors           []IngestionJobError `json:"errors"`
+	Output           json.RawMessage     `json:"output,omitempty"`
+	Started          *time.Time          `json:"started_dttm,omitempty"`
+	Heartbeat        *time.Time          `json:"heartbeat_dttm,omitempty"`
+	Finished         *time.Time          `json:"finished_dttm,omitempty"`
+	Created          time.Time           `json:"created_dttm"`
+	Updated          time.Time           `json:"updated_dttm"`
+}
+
+// IngestionJobError locates one failure inside a job payload. Item is the 1-based line, transaction set
+// or record the failure belongs to, or 0 when the whole payload failed. Field is the JSON path of the
+// failing field and Code the stable error code the synchronous API reports for it.
+type IngestionJobError struct {
+	Item          int    `json:"item,omitempty"`
+	TransactionID string `json:"transaction_id,omitempty"`
+	Field         string `json:"field,omitempty"`
+	Code          string `json:"code,omitempty"`
+	Message       string `json:"message"`
+}
@@ -0,0 +1,77 @@
+package dbentity
+
+import "time"
+
+// Source tables exported to the lake.
+const (
+	LakeExportSourceUsageTransactionDetail = "usage_transaction_detail"
+	LakeExportSourceMeterUsage15Minute     = "meter_usage_15_minute"
+)
+
+const (
+	LakeExportRunStatusRunning   = "RUNNING"
+	LakeExportRunStatusSucceeded = "SUCCEEDED"
+	LakeExportRunStatusFailed    = "FAILED"
+)
+
+// LakeExportRun is one export of a source table: the rows updated after LowWaterMark up to
+// HighWaterMark, the last watermark it committed. LowWaterMark is nil for the first export.
+type LakeExportRun struct {
+	ID            string     `json:"id"`
+	SourceTable   string     `json:"source_table"`
+	Status        string     `json:"status"`
+	LowWaterMark  *time.Time `json:"low_water_mark,omitempty"`
+	HighWaterMark *time.Time `json:"high_water_mark,omitempty"`
+	RowCount      int64      `json:"row_count"`
+	FileCount     int        `json:"file_count"`
+	SnapshotID    *int64     `json:"snapshot_id,omitempty"`
+	Error         *string    `json:"error,omitempty"`
+	Started       time.Time  `json:"started_dttm"`
+	Finished      *time.Time `json:"finished_dttm,omitempty"`
+	Created       time.Time  `json:"created_dttm"`
+	Updated       time.Time  `json:"updated_dttm"`
+}
+
+// UsageTransactionDetailExport is a usage_transaction_detail row with the codes and names of what it
+// references, as exported to the lake. Start and End are UTC instants.
+type UsageTransactionDetailExport struct {
+	UsageTransactionID string
+	TransactionID      string
+	PowerRegion        string
+	PremiseID          string
+	PremiseCode        string
+	MeterID            *string
+	MeterName          string
+	Start              time.Time
+	End                time.Time
+	ServicePeriodStart time.Time
+	ServicePeriodEnd   time.Time
+	IsCanceled         bool
+	IsInterval         bool
+	Consumption        *float64
+	Generation         *float64
+	Updated            time.Time
+}
+
+// MeterUsageIntervalExport is a meter_usage_15_minute row with the codes and names of what it
+// references, as exported to the lake. Start and End are UTC instants.
+type MeterUsageIntervalExport struct {
+	MeterID             string
+	MeterName           string
+	PremiseID           string
+	PremiseCode         string
+	AccountID           string
+	PowerRegion         string
+	UsageTransactionID  *string
+	Start               time.Time
+	End                 time.Time
+	ServicePeriodStart  time.Time
+	ServicePeriodEnd    time.Time
+	Consumption         *float64
+	Generation          *float64
+	IsCanceled          bool
+	Quality             string
+	QualityRule         *string
+	OriginalConsumption *float64
+	Updated             time.Time
+}
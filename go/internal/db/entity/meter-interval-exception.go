@@ -0,0 +1,44 @@
+package dbentity
+
+import "time"
+
+const (
+	MeterIntervalExceptionGap        = "GAP"
+	MeterIntervalExceptionDuplicate  = "DUPLICATE"
+	MeterIntervalExceptionOutOfRange = "OUT_OF_RANGE"
+)
+
+// MeterIntervalException is one interval an 867 interval series left missing, reported more than once
+// or reported outside its service period. Start and End are UTC instants.
+type MeterIntervalException struct {
+	ID                 string    `json:"id"`
+	UsageTransactionID string    `json:"usage_transaction_id"`
+	TransactionID      string    `json:"transaction_id"`
+	PremiseID          string    `json:"premise_id"`
+	PowerRegionID      string    `json:"power_region_id"`
+	MeterID            *string   `json:"meter_id"`
+	MeterName          string    `json:"meter_name"`
+	Channel            string    `json:"channel"`
+	Kind               string    `json:"kind"`
+	Start              time.Time `json:"start_dttm"`
+	End                time.Time `json:"end_dttm"`
+	ServicePeriodStart time.Time `json:"service_period_start"`
+	ServicePeriodEnd   time.Time `json:"service_period_end"`
+	ReportedCount      int       `json:"reported_count"`
+	IsCanceled         bool      `json:"is_canceled"`
+	Created            time.Time `json:"created_dttm"`
+	Updated            time.Time `json:"updated_dttm"`
+}
+
+// IncompleteMeter totals the open interval exceptions of one meter over a reporting window.
+type IncompleteMeter struct {
+	MeterID             *string    `json:"meter_id"`
+	MeterName           string     `json:"meter_name"`
+	PremiseID           string     `json:"premise_id"`
+	PowerRegionID       string     `json:"power_region_id"`
+	MissingIntervals    int        `json:"missing_intervals"`
+	DuplicateIntervals  int        `json:"duplicate_intervals"`
+	OutOfRangeIntervals int        `json:"out_of_range_intervals"`
+	FirstMissing        *time.Time `json:"first_missing_dttm"`
+	LastMissing         *time.Time `json:"last_missing_dttm"`
+}
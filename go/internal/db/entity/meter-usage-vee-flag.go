@@ -0,0 +1,18 @@
+package dbentity
+
+import "time"
+
+// MeterUsageVEEFlag is one validation failure found by the latest VEE run over a meter's intervals.
+// IsReplaced marks reads that were edited rather than only reported.
+type MeterUsageVEEFlag struct {
+	ID         string    `json:"id"`
+	MeterID    string    `json:"meter_id"`
+	Rule       string    `json:"rule"`
+	Start      time.Time `json:"start_dttm"`
+	End        time.Time `json:"end_dttm"`
+	Value      *float64  `json:"value,omitempty"`
+	Message    string    `json:"message"`
+	IsReplaced bool      `json:"is_replaced"`
+	Created    time.Time `json:"created_dttm"`
+	Updated    time.Time `json:"updated_dttm"`
+}
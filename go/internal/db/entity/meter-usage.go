@@ -0,0 +1,39 @@
+package dbentity
+
+import "time"
+
+// MeterUsageInterval is one normalized 15-minute reading from meter_usage_15_minute. Start and End
+// are UTC instants. Quality is ACTUAL for reads from an 867, or ESTIMATED or EDITED by the VEE rule
+// named in QualityRule; OriginalConsumption keeps the actual read an edit replaced.
+type MeterUsageInterval struct {
+	MeterID             string
+	PremiseID           string
+	AccountID           string
+	UsageTransactionID  *string
+	Start               time.Time
+	End                 time.Time
+	ServicePeriodStart  time.Time
+	ServicePeriodEnd    time.Time
+	Consumption         *float64
+	Generation          *float64
+	IsCanceled          bool
+	Quality             string
+	QualityRule         *string
+	OriginalConsumption *float64
+}
+
+// MeterUsageChannelInterval is one interval reading of any channel from meter_usage_channel. Start and
+// End are UTC instants; UnitOfMeasure and Direction are nil for unregistered channels sent without a unit.
+type MeterUsageChannelInterval struct {
+	MeterID            string
+	Channel            string
+	PowerRegionID      string
+	PremiseID          string
+	UsageTransactionID string
+	Start              time.Time
+	End                time.Time
+	UnitOfMeasure      *string
+	Direction          *string
+	Quantity           float64
+	IsCanceled         bool
+}
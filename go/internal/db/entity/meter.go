@@ -12,6 +12,12 @@ type Meter struct {
 	LoadProfile   string
 	CycleCode     string
 	Active        bool
-	Created       time.Time
-	Updated       time.Time
+	// AutoProvisioned marks meters created by ingestion rather than master data.
+	AutoProvisioned bool
+	// IntervalMinutes is the length of the meter's interval reads, 15 unless configured otherwise.
+	IntervalMinutes int
+	// MaxDemandKW is the highest demand the meter can register; reads implying more fail VEE.
+	MaxDemandKW *float64
+	Created     time.Time
+	Updated     time.Time
 }
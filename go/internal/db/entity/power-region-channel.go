@@ -0,0 +1,22 @@
+package dbentity
+
+import "time"
+
+const (
+	ChannelDirectionDelivered = "DELIVERED"
+	ChannelDirectionReceived  = "RECEIVED"
+	ChannelDirectionNet       = "NET"
+)
+
+// PowerRegionChannel registers an interval channel code of a power region with the unit its readings
+// are sent in.
+type PowerRegionChannel struct {
+	PowerRegionID string    `json:"power_region_id"`
+	Code          string    `json:"code"`
+	Name          string    `json:"name"`
+	UnitOfMeasure string    `json:"unit_of_measure"`
+	Direction     string    `json:"direction"`
+	Description   *string   `json:"description,omitempty"`
+	Created       time.Time `json:"created_dttm"`
+	Updated       time.Time `json:"updated_dttm"`
+}
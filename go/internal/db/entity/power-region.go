@@ -3,8 +3,18 @@ package dbentity
 import "time"
 
 type PowerRegion struct {
-	ID      string    `json:"id"`
-	Name    string    `json:"name"`
-	Created time.Time `json:"created_dttm"`
-	Updated time.Time `json:"updated_dttm"`
+	ID   string `json:"id"`
+	Name string `json:"name"`
+	// ProvisioningPolicy decides what ingestion does with ESI IDs and meters missing from master data.
+	ProvisioningPolicy string `json:"provisioning_policy"`
+	// TimeZone is the IANA zone the region reports local prevailing time in.
+	TimeZone string    `json:"time_zone"`
+	Created  time.Time `json:"created_dttm"`
+	Updated  time.Time `json:"updated_dttm"`
 }
+
+const (
+	ProvisioningPolicyReject     = "REJECT"
+	ProvisioningPolicyQuarantine = "QUARANTINE"
+	ProvisioningPolicyAutoCreate = "AUTO_CREATE"
+)
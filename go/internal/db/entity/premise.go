@@ -57,3 +57,12 @@ type PremiseAccountHistory struct {
 	Created        time.Time
 	Updated        time.Time
 }
+
+// PremiseCodeCheck is a premise's code and the TDSP whose premise_code_validation_expression it is
+// audited against.
+type PremiseCodeCheck struct {
+	PremiseID     string
+	Code          string
+	PowerRegionID string
+	TDSP          TDSP
+}
@@ -0,0 +1,21 @@
+package dbentity
+
+import "time"
+
+type RawPayload struct {
+	SHA256      string    `json:"sha256"`
+	ContentType string    `json:"content_type"`
+	Size        int64     `json:"size_bytes"`
+	ObjectKey   string    `json:"object_key"`
+	Created     time.Time `json:"created_dttm"`
+	Updated     time.Time `json:"updated_dttm"`
+}
+
+type LakeObject struct {
+	ObjectKey        string    `json:"object_key"`
+	AccountID        string    `json:"account_id"`
+	RawPayloadSHA256 string    `json:"raw_payload_sha256"`
+	RowCount         int       `json:"row_count"`
+	Created          time.Time `json:"created_dttm"`
+	Updated          time.Time `json:"updated_dttm"`
+}
@@ -0,0 +1,45 @@
+package dbentity
+
+import "time"
+
+const (
+	ReconciliationExceptionStatusOpen       = "OPEN"
+	ReconciliationExceptionStatusSuperseded = "SUPERSEDED"
+)
+
+type UsageTransactionSummary struct {
+	ID                 string
+	UsageTransactionID string
+	TransferType       string
+	PremiseID          string
+	MeterID            *string
+	MeterName          string
+	Channel            string
+	ServicePeriodStart time.Time
+	ServicePeriodEnd   time.Time
+	Quantity           float64
+	IntervalQuantity   float64
+	IsReconciled       bool
+	IsCanceled         bool
+	Created            time.Time
+	Updated            time.Time
+}
+
+type UsageReconciliationException struct {
+	ID                        string    `json:"id"`
+	UsageTransactionID        string    `json:"usage_transaction_id"`
+	TransactionID             string    `json:"transaction_id"`
+	UsageTransactionSummaryID string    `json:"usage_transaction_summary_id"`
+	PremiseID                 string    `json:"premise_id"`
+	Status                    string    `json:"status"`
+	TransferType              string    `json:"product_transfer_detail_type_code"`
+	MeterName                 string    `json:"meter_name"`
+	Channel                   string    `json:"channel"`
+	ServicePeriodStart        time.Time `json:"service_period_start"`
+	ServicePeriodEnd          time.Time `json:"service_period_end"`
+	SummaryQuantity           float64   `json:"summary_quantity"`
+	IntervalQuantity          float64   `json:"interval_quantity"`
+	Difference                float64   `json:"difference"`
+	Created                   time.Time `json:"created_dttm"`
+	Updated                   time.Time `json:"updated_dttm"`
+}
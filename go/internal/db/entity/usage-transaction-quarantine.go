@@ -0,0 +1,41 @@
+package dbentity
+
+import (
+	"encoding/json"
+	"time"
+)
+
+const (
+	QuarantineStatusQuarantined = "QUARANTINED"
+	QuarantineStatusReplayed    = "REPLAYED"
+	QuarantineStatusDiscarded   = "DISCARDED"
+)
+
+// Quarantine stages name the reference lookup a held transaction failed.
+const (
+	QuarantineStagePowerRegion        = "POWER_REGION"
+	QuarantineStageTDSP               = "TDSP"
+	QuarantineStagePremise            = "PREMISE"
+	QuarantineStageMeter              = "METER"
+	QuarantineStagePurpose            = "PURPOSE"
+	QuarantineStageTransactionType    = "TRANSACTION_TYPE"
+	QuarantineStageTransactionSubType = "TRANSACTION_SUB_TYPE"
+)
+
+type UsageTransactionQuarantine struct {
+	ID                         string          `json:"id"`
+	TransactionID              string          `json:"transaction_id"`
+	TransactionSubType         string          `json:"transaction_sub_type_code"`
+	PowerRegionID              *string         `json:"power_region_id,omitempty"`
+	TDSPID                     *string         `json:"tdsp_id,omitempty"`
+	EsiID                      string          `json:"esi_id"`
+	Stage                      string          `json:"stage"`
+	Reason                     string          `json:"reason"`
+	Status                     string          `json:"status"`
+	ReplayedUsageTransactionID *string         `json:"replayed_usage_transaction_id,omitempty"`
+	Payload                    json.RawMessage `json:"payload,omitempty"`
+	PayloadSHA256              string          `json:"payload_sha256"`
+	RawPayloadSHA256           *string         `json:"raw_payload_sha256,omitempty"`
+	Created                    time.Time       `json:"created_dttm"`
+	Updated                    time.Time       `json:"updated_dttm"`
+}
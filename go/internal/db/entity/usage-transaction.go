@@ -2,6 +2,11 @@ package dbentity
 
 import "time"
 
+const (
+	TransactionSubTypeHistoricUsage = "UH"
+	TransactionSubTypeMonthlyUsage  = "UM"
+)
+
 type TransactionType struct {
 	Code        string
 	Name        string
@@ -33,6 +38,7 @@ type UsageTransactionPurpose struct {
 	Code        string
 	Name        string
 	IsCancel    bool
+	IsReplace   bool
 	Description string
 	Created     time.Time
 	Updated     time.Time
@@ -84,8 +90,16 @@ type UsageTransaction struct {
 	PowerRegionID      string
 	TDSPID             string
 	PremiseID          string
-	Created            time.Time
-	Updated            time.Time
+	// OriginalTransactionID is the partner's reference to the transaction a cancel or replace voids.
+	OriginalTransactionID      *string
+	OriginalUsageTransactionID *string
+	// Payload is the canonical JSON of the ingested transaction; PayloadSHA256 identifies redeliveries.
+	Payload       []byte `json:"-"`
+	PayloadSHA256 string
+	// RawPayloadSHA256 links to the archived request body the transaction was read from.
+	RawPayloadSHA256 *string
+	Created          time.Time
+	Updated          time.Time
 }
 
 type UsageTransactionDetail struct {
@@ -95,6 +109,7 @@ type UsageTransactionDetail struct {
 	ServicePeriodStart time.Time
 	ServicePeriodEnd   time.Time
 	IsCanceled         bool
+	IsInterval         bool
 	MeterID            *string
 	MeterName          string
 	PowerRegionID      string
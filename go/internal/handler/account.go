@@ -3,12 +3,14 @@ package handler
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"net/http"
 	"usage-lakehouse/internal/model"
 	"usage-lakehouse/internal/repository"
 
 	"github.com/go-chi/chi/v5"
 	"github.com/go-playground/validator/v10"
+	"github.com/jackc/pgx/v5"
 )
 
 type AccountHandler struct {
@@ -16,53 +18,84 @@ type AccountHandler struct {
 	validate *validator.Validate
 }
 
-func uniqueAccountNameValidator(repo repository.AccountRepository) validator.Func {
-	return func(fl validator.FieldLevel) bool {
-		ctx := context.Background()
-		name := fl.Parent().FieldByName("Name").String()
-		accountID := fl.Parent().FieldByName("AccountID").Interface().(*string)
-		exists, err := repo.ExistsByName(ctx, name, accountID)
-		return err == nil && !exists
+// accountInput is the body of a create or update. AccountID is the account being updated, so that
+// its own name and legal ID do not count as taken.
+type accountInput struct {
+	AccountID *string `json:"-"`
+	LegalID   *string `json:"legal_id" validate:"required,unique_account_legal_id"`
+	Name      string  `json:"name" validate:"required,unique_account_name"`
+}
+
+func uniqueAccountNameValidator(repo repository.AccountRepository) validator.FuncCtx {
+	return func(ctx context.Context, fl validator.FieldLevel) bool {
+		exists, err := repo.ExistsByName(ctx, fl.Field().String(), validatedAccountID(fl))
+		return unique(ctx, exists, err)
 	}
 }
 
-func uniqueAccountLegalIDValidator(repo repository.AccountRepository) validator.Func {
-	return func(fl validator.FieldLevel) bool {
-		ctx := context.Background()
-		legalID := fl.Parent().FieldByName("LegalID").String()
-		accountID := fl.Parent().FieldByName("AccountID").Interface().(*string)
-		exists, err := repo.ExistsByLegalID(ctx, legalID, accountID)
-		return err == nil && !exists
+func uniqueAccountLegalIDValidator(repo repository.AccountRepository) validator.FuncCtx {
+	return func(ctx context.Context, fl validator.FieldLevel) bool {
+		exists, err := repo.ExistsByLegalID(ctx, fl.Field().String(), validatedAccountID(fl))
+		return unique(ctx, exists, err)
 	}
 }
 
+// uniquenessLookup holds the first database error of a uniqueness validator, which a validator cannot
+// return. validateAccount passes one in the validation context.
+type uniquenessLookup struct {
+	err error
+}
+
+type uniquenessLookupKey struct{}
+
+// unique reports whether a value is not taken. A failed lookup is not a taken value: it passes the
+// field and is recorded for validateAccount to answer as a server error.
+func unique(ctx context.Context, exists bool, err error) bool {
+	if err != nil {
+		if lookup, ok := ctx.Value(uniquenessLookupKey{}).(*uniquenessLookup); ok && lookup.err == nil {
+			lookup.err = err
+		}
+		return true
+	}
+	return !exists
+}
+
+func validatedAccountID(fl validator.FieldLevel) *string {
+	field := fl.Parent().FieldByName("AccountID")
+	if !field.IsValid() {
+		return nil
+	}
+	accountID, _ := field.Interface().(*string)
+	return accountID
+}
+
 func NewAccountHandler(repo repository.AccountRepository) *AccountHandler {
 	validate := validator.New()
-	validate.RegisterValidation("unique_account_name", uniqueAccountNameValidator(repo))
-	validate.RegisterValidation("unique_account_legal_id", uniqueAccountLegalIDValidator(repo))
+	validate.RegisterTagNameFunc(jsonFieldName)
+	validate.RegisterValidationCtx("unique_account_name", uniqueAccountNameValidator(repo))
+	validate.RegisterValidationCtx("unique_account_legal_id", uniqueAccountLegalIDValidator(repo))
 	return &AccountHandler{repo: repo, validate: validate}
 }
 
-func (h *AccountHandler) CreateAccount(w http.ResponseWriter, r *http.Request) {
-	type accountInput struct {
-		LegalID *string `json:"legal_id" validate:"required,unique_account_legal_id"`
-		Name    string  `json:"name" validate:"required,unique_account_name"`
+// validateAccount validates input, checking uniqueness within ctx. A uniqueness lookup that fails is
+// returned instead of the validation errors, so it is answered as a server error, not NOT_UNIQUE.
+func (h *AccountHandler) validateAccount(ctx context.Context, input accountInput) error {
+	lookup := &uniquenessLookup{}
+	err := h.validate.StructCtx(context.WithValue(ctx, uniquenessLookupKey{}, lookup), input)
+	if lookup.err != nil {
+		return lookup.err
 	}
+	return err
+}
+
+func (h *AccountHandler) CreateAccount(w http.ResponseWriter, r *http.Request) {
 	var input accountInput
 	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
-		http.Error(w, "Invalid request", http.StatusBadRequest)
+		writeIngestError(w, decodeError(err))
 		return
 	}
-	if err := h.validate.Struct(input); err != nil {
-		if validationErrors, ok := err.(validator.ValidationErrors); ok {
-			w.Header().Set("Content-Type", "application/json")
-			w.WriteHeader(http.StatusBadRequest)
-			json.NewEncoder(w).Encode(map[string]interface{}{
-				"validationErrors": validationErrors.Error(),
-			})
-			return
-		}
-		http.Error(w, err.Error(), http.StatusBadRequest)
+	if err := h.validateAccount(r.Context(), input); err != nil {
+		writeIngestError(w, err)
 		return
 	}
 	a := model.Account{
@@ -70,9 +103,10 @@ func (h *AccountHandler) CreateAccount(w http.ResponseWriter, r *http.Request) {
 		Name:    input.Name,
 	}
 	if err := h.repo.Create(r.Context(), &a); err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		writeIngestError(w, err)
 		return
 	}
+	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusCreated)
 	json.NewEncoder(w).Encode(a)
 }
@@ -80,32 +114,57 @@ func (h *AccountHandler) CreateAccount(w http.ResponseWriter, r *http.Request) {
 func (h *AccountHandler) GetAccount(w http.ResponseWriter, r *http.Request) {
 	id := chi.URLParam(r, "id")
 	a, err := h.repo.GetByID(r.Context(), id)
+	if errors.Is(err, pgx.ErrNoRows) {
+		writeIngestError(w, notFoundError("id", id))
+		return
+	}
 	if err != nil {
-		http.Error(w, "Not found", http.StatusNotFound)
+		writeIngestError(w, err)
 		return
 	}
+	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(a)
 }
 
 func (h *AccountHandler) UpdateAccount(w http.ResponseWriter, r *http.Request) {
 	id := chi.URLParam(r, "id")
-	var a model.Account
-	if err := json.NewDecoder(r.Body).Decode(&a); err != nil {
-		http.Error(w, "Invalid request", http.StatusBadRequest)
+	var input accountInput
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		writeIngestError(w, decodeError(err))
 		return
 	}
-	a.ID = id
-	if err := h.repo.Update(r.Context(), &a); err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+	input.AccountID = &id
+	if err := h.validateAccount(r.Context(), input); err != nil {
+		writeIngestError(w, err)
 		return
 	}
+	a := model.Account{
+		ID:      id,
+		LegalID: input.LegalID,
+		Name:    input.Name,
+	}
+	err := h.repo.Update(r.Context(), &a)
+	if errors.Is(err, pgx.ErrNoRows) {
+		writeIngestError(w, notFoundError("id", id))
+		return
+	}
+	if err != nil {
+		writeIngestError(w, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(a)
 }
 
 func (h *AccountHandler) DeleteAccount(w http.ResponseWriter, r *http.Request) {
 	id := chi.URLParam(r, "id")
-	if err := h.repo.Delete(r.Context(), id); err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+	err := h.repo.Delete(r.Context(), id)
+	if errors.Is(err, pgx.ErrNoRows) {
+		writeIngestError(w, notFoundError("id", id))
+		return
+	}
+	if err != nil {
+		writeIngestError(w, err)
 		return
 	}
 	w.WriteHeader(http.StatusNoContent)
@@ -114,7 +173,7 @@ func (h *AccountHandler) DeleteAccount(w http.ResponseWriter, r *http.Request) {
 func (h *AccountHandler) ListAccounts(w http.ResponseWriter, r *http.Request) {
 	accounts, err := h.repo.List(r.Context())
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		writeIngestError(w, err)
 		return
 	}
 	json.NewEncoder(w).Encode(accounts)
@@ -1,17 +1,30 @@
 package handler
 
 import (
+	"context"
+	"crypto/sha256"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
+	"log"
 	"net/http"
+	"reflect"
+	"strings"
+	"time"
 	dbentity "usage-lakehouse/internal/db/entity"
 	"usage-lakehouse/internal/model"
+	"usage-lakehouse/internal/payloadarchive"
+	"usage-lakehouse/internal/premisecode"
+	"usage-lakehouse/internal/region"
 	"usage-lakehouse/internal/repository"
+	"usage-lakehouse/internal/uom"
+	"usage-lakehouse/internal/x12"
 
-	"time"
-
+	"github.com/go-chi/chi/v5"
 	"github.com/go-playground/validator/v10"
 	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
 )
 
 type EDIMonthlyUsageHandler struct {
@@ -25,114 +38,554 @@ type EDIMonthlyUsageHandler struct {
 	transactionSubTypeRepo                                   repository.TransactionSubTypeRepository
 	usageTransactionRepo                                     repository.UsageTransactionRepository
 	powerRegionUsageTransactionProductTransferDetailTypeRepo repository.PowerRegionUsageTransactionProductTransferDetailTypeRepository
+	ediAcknowledgementRepo                                   repository.EDIAcknowledgementRepository
+	usageTransactionQuarantineRepo                           repository.UsageTransactionQuarantineRepository
+	ingestionJobRepo                                         repository.IngestionJobRepository
+	powerRegionChannelRepo                                   repository.PowerRegionChannelRepository
+	archive                                                  *payloadarchive.Archive
+	premiseCodeValidator                                     *premisecode.Validator
+	validate                                                 *validator.Validate
 }
 
-func NewEDIMonthlyUsageHandler(repo repository.AccountRepository, powerRegionRepo repository.PowerRegionRepository, tdspRepo repository.TDSPRepository, premiseRepo repository.PremiseRepository, meterRepo repository.MeterRepository, usageTransactionPurposeRepo repository.UsageTransactionPurposeRepository, transactionTypeRepo repository.TransactionTypeRepository, transactionSubTypeRepo repository.TransactionSubTypeRepository, powerRegionUsageTransactionProductTransferDetailTypeRepo repository.PowerRegionUsageTransactionProductTransferDetailTypeRepository, usageTransactionRepo repository.UsageTransactionRepository) *EDIMonthlyUsageHandler {
-	return &EDIMonthlyUsageHandler{repo: repo, powerRegionRepo: powerRegionRepo, tdspRepo: tdspRepo, premiseRepo: premiseRepo, meterRepo: meterRepo, usageTransactionPurposeRepo: usageTransactionPurposeRepo, transactionTypeRepo: transactionTypeRepo, transactionSubTypeRepo: transactionSubTypeRepo, powerRegionUsageTransactionProductTransferDetailTypeRepo: powerRegionUsageTransactionProductTransferDetailTypeRepo, usageTransactionRepo: usageTransactionRepo}
+func NewEDIMonthlyUsageHandler(repo repository.AccountRepository, powerRegionRepo repository.PowerRegionRepository, tdspRepo repository.TDSPRepository, premiseRepo repository.PremiseRepository, meterRepo repository.MeterRepository, usageTransactionPurposeRepo repository.UsageTransactionPurposeRepository, transactionTypeRepo repository.TransactionTypeRepository, transactionSubTypeRepo repository.TransactionSubTypeRepository, powerRegionUsageTransactionProductTransferDetailTypeRepo repository.PowerRegionUsageTransactionProductTransferDetailTypeRepository, usageTransactionRepo repository.UsageTransactionRepository, ediAcknowledgementRepo repository.EDIAcknowledgementRepository, usageTransactionQuarantineRepo repository.UsageTransactionQuarantineRepository, ingestionJobRepo repository.IngestionJobRepository, powerRegionChannelRepo repository.PowerRegionChannelRepository, archive *payloadarchive.Archive, premiseCodeValidator *premisecode.Validator) *EDIMonthlyUsageHandler {
+	validate := validator.New()
+	validate.RegisterTagNameFunc(jsonFieldName)
+	return &EDIMonthlyUsageHandler{repo: repo, powerRegionRepo: powerRegionRepo, tdspRepo: tdspRepo, premiseRepo: premiseRepo, meterRepo: meterRepo, usageTransactionPurposeRepo: usageTransactionPurposeRepo, transactionTypeRepo: transactionTypeRepo, transactionSubTypeRepo: transactionSubTypeRepo, powerRegionUsageTransactionProductTransferDetailTypeRepo: powerRegionUsageTransactionProductTransferDetailTypeRepo, usageTransactionRepo: usageTransactionRepo, ediAcknowledgementRepo: ediAcknowledgementRepo, usageTransactionQuarantineRepo: usageTransactionQuarantineRepo, ingestionJobRepo: ingestionJobRepo, powerRegionChannelRepo: powerRegionChannelRepo, archive: archive, premiseCodeValidator: premiseCodeValidator, validate: validate}
 }
 
-func (h *EDIMonthlyUsageHandler) CreateEDIMonthlyUsage(w http.ResponseWriter, r *http.Request) {
-	powerRegions, err := h.powerRegionRepo.List(r.Context())
-	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+func jsonFieldName(field reflect.StructField) string {
+	name := strings.SplitN(field.Tag.Get("json"), ",", 2)[0]
+	if name == "-" || name == "" {
+		return field.Name
+	}
+	return name
+}
+
+type ingestError struct {
+	status int
+	field  string
+	// code is the stable error code reported for field; path, when set, is the field's full JSON path.
+	code string
+	path string
+	// stage is set when the failure is a missing reference row, which holds the transaction for replay.
+	stage string
+	err   error
+	// body, when set, is written as the JSON response instead of the plain error text.
+	body any
+}
+
+// ingestResult is the outcome of one accepted 867: a new or redelivered usage transaction, or a
+// quarantined one waiting for master data.
+type ingestResult struct {
+	usageTransaction *dbentity.UsageTransaction
+	created          bool
+	quarantine       *dbentity.UsageTransactionQuarantine
+}
+
+type quarantineResponse struct {
+	QuarantineID string `json:"quarantine_id"`
+	Status       string `json:"status"`
+	Reason       string `json:"reason"`
+}
+
+type transactionConflict struct {
+	Error              string              `json:"error"`
+	Errors             []fieldError        `json:"errors"`
+	TransactionID      string              `json:"transaction_id"`
+	UsageTransactionID string              `json:"usage_transaction_id"`
+	DifferenceCount    int                 `json:"difference_count"`
+	Differences        []payloadDifference `json:"differences"`
+}
+
+func (e *ingestError) Error() string {
+	return e.err.Error()
+}
+
+func (e *ingestError) Unwrap() error {
+	return e.err
+}
+
+// lookupStages maps the field a reference lookup is keyed on to the quarantine stage it fails at.
+var lookupStages = map[string]string{
+	"power_region":                 dbentity.QuarantineStagePowerRegion,
+	"tdsp_name":                    dbentity.QuarantineStageTDSP,
+	"transaction_set_purpose_code": dbentity.QuarantineStagePurpose,
+	"transaction_type_code":        dbentity.QuarantineStageTransactionType,
+	"transaction_sub_type_code":    dbentity.QuarantineStageTransactionSubType,
+}
+
+// lookupCodes maps the field a reference lookup is keyed on to the code reported when no row matches.
+var lookupCodes = map[string]string{
+	"power_region":                 codeUnknownPowerRegion,
+	"tdsp_name":                    codeUnknownTDSP,
+	"tdsp_id":                      codeUnknownTDSP,
+	"transaction_set_purpose_code": codeUnknownPurpose,
+	"transaction_type_code":        codeUnknownTransactionType,
+	"transaction_sub_type_code":    codeUnknownTransactionSubType,
+}
+
+// lookupError reports a failed reference lookup of value by field. A missing row is the client's
+// error; anything else is a server error.
+func lookupError(field string, value string, err error) error {
+	if errors.Is(err, pgx.ErrNoRows) {
+		return &ingestError{status: http.StatusUnprocessableEntity, field: field, code: lookupCodes[field], stage: lookupStages[field], err: fmt.Errorf("%s %q does not exist", field, value)}
+	}
+	return &ingestError{status: http.StatusInternalServerError, field: field, err: fmt.Errorf("%s: %w", field, err)}
+}
+
+type heldTransactionError struct {
+	Error        string       `json:"error"`
+	Errors       []fieldError `json:"errors"`
+	Field        string       `json:"field"`
+	Stage        string       `json:"stage"`
+	QuarantineID string       `json:"quarantine_id"`
+}
+
+func writeIngestError(w http.ResponseWriter, err error) {
+	status := errorStatus(err)
+	var ie *ingestError
+	if errors.As(err, &ie) && ie.body != nil {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(status)
+		json.NewEncoder(w).Encode(ie.body)
 		return
 	}
-	powerRegionMap := make(map[string]dbentity.PowerRegion)
-	for _, region := range powerRegions {
-		powerRegionMap[region.Name] = region
+	writeErrorResponse(w, status, err)
+}
+
+// errorStatus is the status err is answered with: an ingestError's own, 400 for failed validation and
+// 500 for anything else.
+func errorStatus(err error) int {
+	var ie *ingestError
+	if errors.As(err, &ie) {
+		return ie.status
 	}
-	validate := validator.New()
-	var input model.ErcotMonthlyUsageTransaction
-	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
-		http.Error(w, "Invalid request", http.StatusBadRequest)
+	var validationErrors validator.ValidationErrors
+	if errors.As(err, &validationErrors) {
+		return http.StatusBadRequest
+	}
+	return http.StatusInternalServerError
+}
+
+type x12IngestResponse struct {
+	AcknowledgementID string                 `json:"acknowledgement_id"`
+	Acknowledgement   string                 `json:"acknowledgement"`
+	Transactions      []x12TransactionResult `json:"transactions"`
+}
+
+type x12TransactionResult struct {
+	GroupControlNumber string                     `json:"group_control_number"`
+	ControlNumber      string                     `json:"control_number"`
+	TransactionID      string                     `json:"transaction_id,omitempty"`
+	UsageTransaction   *dbentity.UsageTransaction `json:"usage_transaction,omitempty"`
+	Duplicate          bool                       `json:"duplicate,omitempty"`
+	QuarantineID       string                     `json:"quarantine_id,omitempty"`
+	Error              string                     `json:"error,omitempty"`
+	Errors             []fieldError               `json:"errors,omitempty"`
+}
+
+func isX12Request(r *http.Request) bool {
+	if r.URL.Query().Get("format") == "x12" {
+		return true
+	}
+	contentType := r.Header.Get("Content-Type")
+	return strings.HasPrefix(contentType, "application/edi-x12")
+}
+
+func (h *EDIMonthlyUsageHandler) CreateEDIMonthlyUsage(w http.ResponseWriter, r *http.Request) {
+	h.createEDIUsage(w, r, model.TransactionSubTypeCodeMonthlyUsage)
+}
+
+// CreateEDIHistoricUsage ingests 867_02 historic usage. Periods already reported by a monthly
+// 867_03 for the same meter are skipped so history never overwrites newer monthly data.
+func (h *EDIMonthlyUsageHandler) CreateEDIHistoricUsage(w http.ResponseWriter, r *http.Request) {
+	h.createEDIUsage(w, r, model.TransactionSubTypeCodeHistoricUsage)
+}
+
+func (h *EDIMonthlyUsageHandler) createEDIUsage(w http.ResponseWriter, r *http.Request, subType model.TransactionSubTypeCode) {
+	format := ediUsageFormatJSON
+	if isX12Request(r) {
+		format = ediUsageFormatX12
+	}
+	body, raw, ok := archiveRequestBody(w, r, h.archive, ediUsageContentTypes[format])
+	if !ok {
 		return
 	}
-	if err := validate.Struct(input); err != nil {
-		if validationErrors, ok := err.(validator.ValidationErrors); ok {
-			w.Header().Set("Content-Type", "application/json")
-			w.WriteHeader(http.StatusBadRequest)
-			json.NewEncoder(w).Encode(map[string]interface{}{
-				"validationErrors": validationErrors.Error(),
-			})
+	defer body.Close()
+	if isAsyncRequest(r) {
+		params := ediUsageJobParams{SubType: subType, Format: format, Ack: ackTypeParam(r)}
+		if format == ediUsageFormatX12 {
+			adapter, err := powerRegionParam(r)
+			if err != nil {
+				writeIngestError(w, err)
+				return
+			}
+			params.PowerRegion = adapter.PowerRegion()
+		}
+		submitIngestionJob(w, r, h.ingestionJobRepo, dbentity.IngestionJobKindEDIUsage, params, raw)
+		return
+	}
+	if format == ediUsageFormatX12 {
+		adapter, err := powerRegionParam(r)
+		if err != nil {
+			writeIngestError(w, err)
 			return
 		}
-		http.Error(w, err.Error(), http.StatusBadRequest)
+		h.createEDIUsageFromX12(w, r, adapter, body, &raw.SHA256, subType)
 		return
 	}
-	powerRegion := powerRegionMap[input.PowerRegion]
-	tdsp, err := h.tdspRepo.GetByName(r.Context(), input.TdspName)
-	if err != nil || tdsp == nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+	var input model.EDIUsageTransaction
+	if err := json.NewDecoder(body).Decode(&input); err != nil {
+		writeIngestError(w, decodeError(err))
 		return
 	}
-	premise, err := h.premiseRepo.GetByCode(r.Context(), input.EsiID)
-	if err != nil || premise == nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+	result, err := h.ingestUsage(r.Context(), h.newReferenceCache(), input, subType, &raw.SHA256)
+	if err != nil {
+		writeIngestError(w, err)
 		return
 	}
-	purpose, err := h.usageTransactionPurposeRepo.GetByPowerRegionAndCode(r.Context(), powerRegion.ID, string(input.Purpose))
-	if err != nil || purpose == nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+	w.Header().Set("Content-Type", "application/json")
+	switch {
+	case result.quarantine != nil:
+		w.WriteHeader(http.StatusAccepted)
+		json.NewEncoder(w).Encode(quarantineResponse{QuarantineID: result.quarantine.ID, Status: result.quarantine.Status, Reason: result.quarantine.Reason})
 		return
+	case result.created:
+		w.WriteHeader(http.StatusCreated)
+	default:
+		w.WriteHeader(http.StatusOK)
 	}
-	transactionType, err := h.transactionTypeRepo.GetByPowerRegionAndCode(r.Context(), powerRegion.ID, string(input.ReportType))
-	if err != nil || transactionType == nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+	json.NewEncoder(w).Encode(result.usageTransaction)
+}
+
+func (h *EDIMonthlyUsageHandler) createEDIUsageFromX12(w http.ResponseWriter, r *http.Request, adapter region.Adapter, body io.Reader, rawPayloadSHA256 *string, subType model.TransactionSubTypeCode) {
+	interchange, err := x12.Parse(body)
+	if err != nil {
+		writeIngestError(w, &ingestError{status: http.StatusBadRequest, code: codeMalformedRequest, err: fmt.Errorf("invalid X12 interchange: %w", err)})
 		return
 	}
-	transactionSubType, err := h.transactionSubTypeRepo.GetByPowerRegionSubTypeCode(r.Context(), powerRegion.ID, string(input.ReportType))
-	if err != nil || transactionSubType == nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+	response, rejected, err := h.ingestInterchange(r.Context(), adapter, interchange, ackTypeParam(r), subType, rawPayloadSHA256)
+	if err != nil {
+		writeIngestError(w, err)
 		return
 	}
-	id := uuid.New().String()
-	isCanceled := purpose.IsCancel
-	usageTransaction := dbentity.UsageTransaction{
-		ID:                 id,
-		TransactionID:      input.TransactionID,
-		TransactionDate:    input.Date,
-		PowerRegionID:      powerRegion.ID,
-		TDSPID:             tdsp.ID,
-		PremiseID:          premise.ID,
-		Purpose:            purpose.Code,
-		IsFinal:            input.Final != nil && *input.Final == "F",
-		IsCanceled:         isCanceled,
-		TransactionType:    transactionType.Code,
-		TransactionSubType: transactionSubType.Code,
-	}
-	meterNameSet := make(map[string]struct{})
-	uniqueMeterNames := make([]string, 0, len(input.ProductTransferDetails))
-	for _, productTransferDetail := range input.ProductTransferDetails {
-		if productTransferDetail.MeterName != nil {
-			name := *productTransferDetail.MeterName
-			if _, exists := meterNameSet[name]; !exists {
-				meterNameSet[name] = struct{}{}
-				uniqueMeterNames = append(uniqueMeterNames, name)
+	w.Header().Set("Content-Type", "application/json")
+	if rejected > 0 {
+		w.WriteHeader(http.StatusOK)
+	} else {
+		w.WriteHeader(http.StatusCreated)
+	}
+	json.NewEncoder(w).Encode(response)
+}
+
+// powerRegionParam picks the adapter an X12 upload is read with from ?power_region=, defaulting to ERCOT.
+func powerRegionParam(r *http.Request) (region.Adapter, error) {
+	name := r.URL.Query().Get("power_region")
+	if name == "" {
+		name = region.DefaultPowerRegion
+	}
+	adapter, ok := region.Lookup(name)
+	if !ok {
+		return nil, &ingestError{status: http.StatusBadRequest, field: "power_region", code: codeUnknownPowerRegion, err: fmt.Errorf("power_region %q has no usage adapter", name)}
+	}
+	return adapter, nil
+}
+
+func ackTypeParam(r *http.Request) x12.AcknowledgementType {
+	if r.URL.Query().Get("ack") == string(x12.Acknowledgement999) {
+		return x12.Acknowledgement999
+	}
+	return x12.Acknowledgement997
+}
+
+// ingestInterchange ingests every transaction set of an interchange, read with adapter, and stores the
+// acknowledgement answering it. It reports how many sets were rejected.
+func (h *EDIMonthlyUsageHandler) ingestInterchange(ctx context.Context, adapter region.Adapter, interchange *x12.Interchange, ackType x12.AcknowledgementType, subType model.TransactionSubTypeCode, rawPayloadSHA256 *string) (x12IngestResponse, int, error) {
+	refs := h.newReferenceCache()
+	var results []x12TransactionResult
+	var setResults []x12.SetResult
+	rejected := 0
+	for _, group := range interchange.FunctionalGroups {
+		for _, set := range group.TransactionSets {
+			result := x12TransactionResult{GroupControlNumber: group.ControlNumber, ControlNumber: set.ControlNumber}
+			setResult := x12.SetResult{GroupControlNumber: group.ControlNumber, ControlNumber: set.ControlNumber}
+			input, err := adapter.ParseX12(set)
+			if err == nil {
+				result.TransactionID = input.TransactionID
+				var ingested ingestResult
+				ingested, err = h.ingestUsage(ctx, refs, input, subType, rawPayloadSHA256)
+				result.UsageTransaction = ingested.usageTransaction
+				result.Duplicate = err == nil && ingested.usageTransaction != nil && !ingested.created
+				if ingested.quarantine != nil {
+					result.QuarantineID = ingested.quarantine.ID
+				}
 			}
+			if err != nil {
+				rejected++
+				result.Error = err.Error()
+				result.Errors = fieldErrors(err)
+				setResult.Errors = segmentErrors(adapter, set, err)
+			} else {
+				setResult.Accepted = true
+			}
+			results = append(results, result)
+			setResults = append(setResults, setResult)
 		}
 	}
-	meterNameIDMap, err := h.meterRepo.GetNameIDMap(r.Context(), uniqueMeterNames)
+	controlNumbers, err := h.ediAcknowledgementRepo.NextControlNumbers(ctx, x12.ControlNumbersNeeded(interchange))
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return x12IngestResponse{}, rejected, err
+	}
+	ack := dbentity.EDIAcknowledgement{
+		ID:                       uuid.New().String(),
+		Type:                     string(ackType),
+		ControlNumber:            controlNumbers[0],
+		InterchangeControlNumber: interchange.ControlNumber,
+		SenderID:                 interchange.SenderID,
+		ReceiverID:               interchange.ReceiverID,
+		AcceptedCount:            len(setResults) - rejected,
+		RejectedCount:            rejected,
+		Content:                  x12.BuildAcknowledgement(interchange, ackType, setResults, controlNumbers, time.Now().UTC()),
+	}
+	if err := h.ediAcknowledgementRepo.Create(ctx, &ack); err != nil {
+		return x12IngestResponse{}, rejected, err
+	}
+	return x12IngestResponse{
+		AcknowledgementID: ack.ID,
+		Acknowledgement:   ack.Content,
+		Transactions:      results,
+	}, rejected, nil
+}
+
+// segmentErrors translates a parse or ingestion failure into the AK3/AK4 detail of a rejected set.
+func segmentErrors(adapter region.Adapter, set x12.TransactionSet, err error) []x12.SegmentError {
+	var segmentErr *x12.SegmentError
+	if errors.As(err, &segmentErr) {
+		return []x12.SegmentError{*segmentErr}
+	}
+	var validationErrors validator.ValidationErrors
+	if errors.As(err, &validationErrors) {
+		var out []x12.SegmentError
+		for _, fe := range validationErrors {
+			out = append(out, adapter.LocateField(set, fe.Field(), x12.ElementMandatoryMissing))
+		}
+		return out
+	}
+	var ie *ingestError
+	if errors.As(err, &ie) && ie.field != "" {
+		return []x12.SegmentError{adapter.LocateField(set, ie.field, x12.ElementInvalidCodeValue)}
+	}
+	return nil
+}
+
+func (h *EDIMonthlyUsageHandler) GetAcknowledgement(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+	ack, err := h.ediAcknowledgementRepo.GetByID(r.Context(), id)
+	if errors.Is(err, pgx.ErrNoRows) {
+		writeIngestError(w, notFoundError("id", id))
 		return
 	}
-	powerRegionUsageTransactionProductTransferDetailTypeMap, err := h.powerRegionUsageTransactionProductTransferDetailTypeRepo.MapByCode(r.Context())
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		writeIngestError(w, err)
 		return
 	}
+	w.Header().Set("Content-Type", "application/edi-x12")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", fmt.Sprintf("%s_%09d.x12", ack.Type, ack.ControlNumber)))
+	w.Write([]byte(ack.Content))
+}
+
+// ingestUsage ingests one 867 read from the archived payload rawPayloadSHA256. A transaction
+// rejected because reference data is missing is held in usage_transaction_quarantine for replay;
+// the error is still returned alongside the hold.
+func (h *EDIMonthlyUsageHandler) ingestUsage(ctx context.Context, refs *referenceCache, input model.EDIUsageTransaction, subType model.TransactionSubTypeCode, rawPayloadSHA256 *string) (ingestResult, error) {
+	if input.TransactionSubType == "" {
+		input.TransactionSubType = subType
+	}
+	result, err := h.ingest(ctx, refs, input, subType, rawPayloadSHA256)
+	var ie *ingestError
+	if err == nil || !errors.As(err, &ie) || ie.stage == "" {
+		return result, err
+	}
+	payload, payloadErr := json.Marshal(input)
+	if payloadErr != nil {
+		return result, err
+	}
+	q, holdErr := h.hold(ctx, refs, input, ie.stage, err.Error(), payload, fmt.Sprintf("%x", sha256.Sum256(payload)), rawPayloadSHA256)
+	if holdErr != nil {
+		log.Printf("quarantine transaction_id %s: %v", input.TransactionID, holdErr)
+		return result, err
+	}
+	held := *ie
+	held.body = heldTransactionError{Error: err.Error(), Errors: fieldErrors(err), Field: ie.field, Stage: ie.stage, QuarantineID: q.ID}
+	return ingestResult{quarantine: q}, &held
+}
+
+// hold quarantines input at stage. The power region and TDSP are recorded when they resolve.
+func (h *EDIMonthlyUsageHandler) hold(ctx context.Context, refs *referenceCache, input model.EDIUsageTransaction, stage string, reason string, payload []byte, payloadSHA256 string, rawPayloadSHA256 *string) (*dbentity.UsageTransactionQuarantine, error) {
+	q := dbentity.UsageTransactionQuarantine{
+		ID:                 uuid.New().String(),
+		TransactionID:      input.TransactionID,
+		TransactionSubType: string(input.TransactionSubType),
+		EsiID:              input.EsiID,
+		Stage:              stage,
+		Reason:             reason,
+		Payload:            payload,
+		PayloadSHA256:      payloadSHA256,
+		RawPayloadSHA256:   rawPayloadSHA256,
+	}
+	if powerRegion, err := refs.powerRegion(ctx, input.PowerRegion); err == nil {
+		q.PowerRegionID = &powerRegion.ID
+	}
+	if tdsp, err := refs.tdsp(ctx, input.TdspName); err == nil {
+		q.TDSPID = &tdsp.ID
+	}
+	if err := h.usageTransactionQuarantineRepo.Create(ctx, &q); err != nil {
+		return nil, err
+	}
+	return &q, nil
+}
+
+func (h *EDIMonthlyUsageHandler) ingest(ctx context.Context, refs *referenceCache, input model.EDIUsageTransaction, subType model.TransactionSubTypeCode, rawPayloadSHA256 *string) (ingestResult, error) {
+	if err := h.validate.Struct(input); err != nil {
+		return ingestResult{}, &ingestError{status: http.StatusBadRequest, err: err}
+	}
+	if input.TransactionSubType == "" {
+		input.TransactionSubType = subType
+	} else if input.TransactionSubType != subType {
+		return ingestResult{}, &ingestError{status: http.StatusUnprocessableEntity, field: "transaction_sub_type_code", code: codeTransactionSubTypeMismatch, err: fmt.Errorf("transaction_sub_type_code %q cannot be ingested as %q", input.TransactionSubType, subType)}
+	}
+	servicePeriodStart, servicePeriodEnd, err := servicePeriod(input.ProductTransferDetails)
+	if err != nil {
+		return ingestResult{}, err
+	}
+	for i, detail := range input.ProductTransferDetails {
+		if detail.UnitOfMeasure != nil && !uom.Valid(*detail.UnitOfMeasure) {
+			return ingestResult{}, &ingestError{status: http.StatusUnprocessableEntity, field: "unit_of_measure", code: codeUnsupportedUnitOfMeasure, path: fmt.Sprintf("product_transfer_details[%d].unit_of_measure", i), err: fmt.Errorf("unit_of_measure %q is not supported", *detail.UnitOfMeasure)}
+		}
+	}
+	payload, err := json.Marshal(input)
+	if err != nil {
+		return ingestResult{}, err
+	}
+	payloadSHA256 := fmt.Sprintf("%x", sha256.Sum256(payload))
+	if existing, err := h.usageTransactionRepo.GetByTransactionID(ctx, input.TransactionID); err == nil {
+		return redelivery(existing, payload, payloadSHA256)
+	} else if !errors.Is(err, pgx.ErrNoRows) {
+		return ingestResult{}, err
+	}
+	powerRegion, err := refs.powerRegion(ctx, input.PowerRegion)
+	if err != nil {
+		return ingestResult{}, lookupError("power_region", input.PowerRegion, err)
+	}
+	adapter, ok := region.Lookup(powerRegion.Name)
+	if !ok {
+		return ingestResult{}, &ingestError{status: http.StatusUnprocessableEntity, field: "power_region", code: codeUnsupportedPowerRegion, err: fmt.Errorf("power_region %s has no usage adapter", powerRegion.Name)}
+	}
+	tdsp, err := refs.tdsp(ctx, input.TdspName)
+	if err != nil {
+		return ingestResult{}, lookupError("tdsp_name", input.TdspName, err)
+	}
+	if err := h.premiseCodeValidator.Validate(tdsp, input.EsiID); err != nil {
+		return ingestResult{}, premiseCodeError("esi_id", err)
+	}
+	md, err := h.loadMasterData(ctx, input, powerRegion)
+	if err != nil {
+		return ingestResult{}, err
+	}
+	if !md.complete() {
+		switch powerRegion.ProvisioningPolicy {
+		case dbentity.ProvisioningPolicyAutoCreate:
+			if md, err = h.provision(ctx, input, powerRegion, tdsp, md); err != nil {
+				return ingestResult{}, err
+			}
+		case dbentity.ProvisioningPolicyQuarantine:
+			stage := dbentity.QuarantineStageMeter
+			if md.missingPremise {
+				stage = dbentity.QuarantineStagePremise
+			}
+			q, err := h.hold(ctx, refs, input, stage, md.reason(input.EsiID), payload, payloadSHA256, rawPayloadSHA256)
+			if err != nil {
+				return ingestResult{}, err
+			}
+			return ingestResult{quarantine: q}, nil
+		default:
+			return ingestResult{}, rejectMissingMasterData(md, input.EsiID)
+		}
+	}
+	premise, meterNameIDMap := md.premise, md.meterNameIDMap
+	intervalMinutes, err := h.meterRepo.GetIntervalMinutesMap(ctx, powerRegion.ID, meterNames(input))
+	if err != nil {
+		return ingestResult{}, err
+	}
+	if premise.TDSPID == nil {
+		if err := h.premiseRepo.AssignTDSP(ctx, premise.ID, tdsp.ID); err != nil {
+			return ingestResult{}, err
+		}
+	}
+	purpose, err := refs.purpose(ctx, powerRegion.ID, string(input.Purpose))
+	if err != nil {
+		return ingestResult{}, lookupError("transaction_set_purpose_code", string(input.Purpose), err)
+	}
+	transactionType, err := refs.transactionType(ctx, powerRegion.ID, string(model.TransactionTypeCodeUsage))
+	if err != nil {
+		return ingestResult{}, lookupError("transaction_type_code", string(model.TransactionTypeCodeUsage), err)
+	}
+	transactionSubType, err := refs.transactionSubType(ctx, powerRegion.ID, string(input.TransactionSubType))
+	if err != nil {
+		return ingestResult{}, lookupError("transaction_sub_type_code", string(input.TransactionSubType), err)
+	}
+	var originalTransactionID *string
+	if purpose.IsCancel || purpose.IsReplace {
+		if input.OriginalTransactionID == nil || *input.OriginalTransactionID == "" {
+			return ingestResult{}, &ingestError{status: http.StatusUnprocessableEntity, field: "original_transaction_id", code: codeRequired, err: fmt.Errorf("original_transaction_id is required for purpose %s", input.Purpose)}
+		}
+		originalTransactionID = input.OriginalTransactionID
+	}
+	id := uuid.New().String()
+	isCanceled := purpose.IsCancel
+	usageTransaction := dbentity.UsageTransaction{
+		ID:                    id,
+		TransactionID:         input.TransactionID,
+		TransactionDate:       input.Date,
+		ServicePeriodStart:    servicePeriodStart,
+		ServicePeriodEnd:      servicePeriodEnd,
+		PowerRegionID:         powerRegion.ID,
+		TDSPID:                tdsp.ID,
+		PremiseID:             premise.ID,
+		Purpose:               purpose.Code,
+		IsFinal:               input.Final != nil && *input.Final == "F",
+		IsCanceled:            isCanceled,
+		TransactionType:       transactionType.Code,
+		TransactionSubType:    transactionSubType.Code,
+		OriginalTransactionID: originalTransactionID,
+		Payload:               payload,
+		PayloadSHA256:         payloadSHA256,
+		RawPayloadSHA256:      rawPayloadSHA256,
+	}
+	powerRegionUsageTransactionProductTransferDetailTypeMap, err := refs.transferDetailTypeMap(ctx, powerRegion.ID)
+	if err != nil {
+		return ingestResult{}, err
+	}
+	channels, err := refs.channelMap(ctx, powerRegion.ID)
+	if err != nil {
+		return ingestResult{}, err
+	}
 	type MeterTransferTypeKey struct {
 		MeterName    string
 		TransferType string
 	}
 	type GroupedProductTransferDetails struct {
-		Details []model.ErcotProductTransferDetail
+		Details []model.ProductTransferDetail
 		Type    dbentity.PowerRegionUsageTransactionProductTransferDetailType
 	}
 	grouped := make(map[MeterTransferTypeKey]GroupedProductTransferDetails)
 	var usageTransactionDetails []dbentity.UsageTransactionDetail
+	var channelIntervals []dbentity.MeterUsageChannelInterval
 	for _, productTransferDetail := range input.ProductTransferDetails {
-		meterName := *productTransferDetail.MeterName
+		var meterName string
+		if productTransferDetail.MeterName != nil {
+			meterName = *productTransferDetail.MeterName
+		}
 		transferType := string(productTransferDetail.TransferType)
 		key := MeterTransferTypeKey{MeterName: meterName, TransferType: transferType}
 		g := grouped[key]
@@ -144,94 +597,251 @@ func (h *EDIMonthlyUsageHandler) CreateEDIMonthlyUsage(w http.ResponseWriter, r
 	}
 	for key, details := range grouped {
 		if details.Type.Interval && details.Type.Meter && !details.Type.Summary {
-			var consumptionDetail *model.ErcotProductTransferDetail
-			var generationDetail *model.ErcotProductTransferDetail
-			for i := range details.Details {
-				detail := &details.Details[i]
-				if detail.Channel != nil && *detail.Channel == "1" {
-					consumptionDetail = detail
-				}
-				if detail.Channel != nil && *detail.Channel == "4" {
-					generationDetail = detail
-				}
+			type intervalReading struct {
+				Consumption        float64
+				Generation         float64
+				ServicePeriodStart time.Time
+				ServicePeriodEnd   time.Time
 			}
-			uniqueTimes := make(map[time.Time]struct {
-				Consumption float64
-				Generation  float64
-			})
-			for _, d := range []*model.ErcotProductTransferDetail{consumptionDetail, generationDetail} {
-				if d == nil {
+			length := intervalLength(intervalMinutes, key.MeterName)
+			// Interval ends are keyed as UTC instants: local wall time repeats on fall-back days and the
+			// same instant decoded from JSON carries a different *time.Location per detail.
+			intervals := make(map[time.Time]intervalReading)
+			for _, detail := range details.Details {
+				if detail.Channel == nil || detail.Quantities == nil {
 					continue
 				}
-				if d.Quantities != nil {
-					for _, q := range *d.Quantities {
-						val := uniqueTimes[q.IntervalEnd]
-						if d == consumptionDetail {
-							val.Consumption += q.Quantity
-						}
-						if d == generationDetail {
-							val.Generation += q.Quantity
-						}
-						uniqueTimes[q.IntervalEnd] = val
+				channel := adapter.Channel(*detail.Channel)
+				if channel == region.ChannelIgnored {
+					continue
+				}
+				// Readings other than active energy are kept only as channel readings.
+				unit, _ := readingUnit(detail, *detail.Channel, channels)
+				for _, q := range *detail.Quantities {
+					kwh, ok := kilowattHours(q.Quantity, unit)
+					if !ok {
+						continue
+					}
+					end := q.IntervalEnd.UTC()
+					val := intervals[end]
+					val.ServicePeriodStart = *detail.ServicePeriodStart
+					val.ServicePeriodEnd = *detail.ServicePeriodEnd
+					if channel == region.ChannelConsumption {
+						val.Consumption += kwh
+					} else {
+						val.Generation += kwh
 					}
+					intervals[end] = val
 				}
 			}
-			for t, v := range uniqueTimes {
-				fmt.Printf("Time: %v, Consumption: %f, Generation: %f\n", t, v.Consumption, v.Generation)
-				meterUUID := meterNameIDMap[key.MeterName]
-				var servicePeriodStart time.Time
-				var servicePeriodEnd time.Time
-				if consumptionDetail != nil {
-					servicePeriodStart = *consumptionDetail.ServicePeriodStart
-					servicePeriodEnd = *consumptionDetail.ServicePeriodEnd
-				}
-				if generationDetail != nil {
-					servicePeriodStart = *generationDetail.ServicePeriodStart
-					servicePeriodEnd = *generationDetail.ServicePeriodEnd
-				}
+			for t, v := range intervals {
 				newDetail := dbentity.UsageTransactionDetail{
 					UsageTransactionID: id,
-					MeterID:            &meterUUID,
+					Start:              t.Add(-length),
+					End:                t,
+					IsInterval:         true,
+					MeterID:            meterIDFor(meterNameIDMap, key.MeterName),
 					MeterName:          key.MeterName,
 					PowerRegionID:      powerRegion.ID,
 					PremiseID:          premise.ID,
 					IsCanceled:         isCanceled,
-					ServicePeriodStart: servicePeriodStart,
-					ServicePeriodEnd:   servicePeriodEnd,
+					ServicePeriodStart: v.ServicePeriodStart,
+					ServicePeriodEnd:   v.ServicePeriodEnd,
 					Consumption:        &v.Consumption,
 					Production:         &v.Generation,
 				}
 				usageTransactionDetails = append(usageTransactionDetails, newDetail)
 			}
 		} else if details.Type.Meter && !details.Type.Summary {
+			// A loop sends a reading per channel and unit. Active energy is totaled into one detail per
+			// service period; anything else, such as the period's peak kW, is kept as a channel reading
+			// over the period.
+			type servicePeriodKey struct {
+				start time.Time
+				end   time.Time
+			}
+			periods := make(map[servicePeriodKey]*dbentity.UsageTransactionDetail)
+			var order []servicePeriodKey
+			meterID, meterKnown := meterNameIDMap[key.MeterName]
+			periodReadings := make(map[channelIntervalKey]int)
 			for _, detail := range details.Details {
-				meterUUID := meterNameIDMap[key.MeterName]
-				if detail.Quantities != nil && len(*detail.Quantities) > 0 {
-					for _, q := range *detail.Quantities {
-						newDetail := dbentity.UsageTransactionDetail{
-							MeterID:            &meterUUID,
+				if detail.Quantities == nil {
+					continue
+				}
+				channel := adapter.DefaultChannel()
+				if detail.Channel != nil {
+					channel = *detail.Channel
+				}
+				unit, direction := readingUnit(detail, channel, channels)
+				start, end := detail.ServicePeriodStart.UTC(), detail.ServicePeriodEnd.UTC()
+				for _, q := range *detail.Quantities {
+					kwh, ok := kilowattHours(q.Quantity, unit)
+					class := adapter.Channel(channel)
+					if !ok || class == region.ChannelIgnored {
+						if !meterKnown || isCanceled {
+							continue
+						}
+						// A channel not sent with the readings is named for their unit, so that a period's
+						// kWh and kW do not share one.
+						readingChannel := channel
+						if detail.Channel == nil && unit != nil {
+							readingChannel = *unit
+						}
+						readingKey := channelIntervalKey{meterID: meterID, channel: readingChannel, end: end}
+						if i, ok := periodReadings[readingKey]; ok {
+							channelIntervals[i].Quantity += q.Quantity
+							continue
+						}
+						periodReadings[readingKey] = len(channelIntervals)
+						channelIntervals = append(channelIntervals, dbentity.MeterUsageChannelInterval{
+							MeterID:            meterID,
+							Channel:            readingChannel,
+							PowerRegionID:      powerRegion.ID,
+							PremiseID:          premise.ID,
+							UsageTransactionID: id,
+							Start:              start,
+							End:                end,
+							UnitOfMeasure:      unit,
+							Direction:          direction,
+							Quantity:           q.Quantity,
+						})
+						continue
+					}
+					period := servicePeriodKey{start: start, end: end}
+					d, ok := periods[period]
+					if !ok {
+						consumption, production := 0.0, 0.0
+						d = &dbentity.UsageTransactionDetail{
+							MeterID:            meterIDFor(meterNameIDMap, key.MeterName),
 							MeterName:          key.MeterName,
 							PowerRegionID:      powerRegion.ID,
 							PremiseID:          premise.ID,
 							IsCanceled:         isCanceled,
 							ServicePeriodStart: *detail.ServicePeriodStart,
 							ServicePeriodEnd:   *detail.ServicePeriodEnd,
-							Start:              *detail.ServicePeriodStart,
-							End:                *detail.ServicePeriodEnd,
-							Consumption:        &q.Quantity,
+							Start:              start,
+							End:                end,
+							Consumption:        &consumption,
+							Production:         &production,
 							UsageTransactionID: id,
 						}
-						usageTransactionDetails = append(usageTransactionDetails, newDetail)
+						periods[period] = d
+						order = append(order, period)
+					}
+					if class == region.ChannelConsumption {
+						*d.Consumption += kwh
+					} else {
+						*d.Production += kwh
 					}
 				}
 			}
+			for _, period := range order {
+				usageTransactionDetails = append(usageTransactionDetails, *periods[period])
+			}
+		}
+	}
+	summaries := reconcileSummaries(adapter, id, premise.ID, isCanceled, input, powerRegionUsageTransactionProductTransferDetailTypeMap, meterNameIDMap)
+	intervalExceptions := checkIntervals(adapter, id, premise.ID, powerRegion.ID, isCanceled, input, powerRegionUsageTransactionProductTransferDetailTypeMap, meterNameIDMap, intervalMinutes)
+	if !isCanceled {
+		channelIntervals = append(channelIntervals, collectChannelIntervals(id, premise.ID, powerRegion.ID, input, powerRegionUsageTransactionProductTransferDetailTypeMap, channels, meterNameIDMap, intervalMinutes)...)
+	}
+	if transactionSubType.Code == dbentity.TransactionSubTypeHistoricUsage {
+		err = h.usageTransactionRepo.SaveHistoricWithDetails(ctx, &usageTransaction, usageTransactionDetails, summaries, intervalExceptions, channelIntervals)
+	} else {
+		err = h.usageTransactionRepo.SaveWithDetails(ctx, &usageTransaction, usageTransactionDetails, summaries, intervalExceptions, channelIntervals)
+	}
+	if errors.Is(err, repository.ErrDuplicateTransactionID) {
+		existing, err := h.usageTransactionRepo.GetByTransactionID(ctx, input.TransactionID)
+		if err != nil {
+			return ingestResult{}, err
 		}
+		return redelivery(existing, payload, payloadSHA256)
+	}
+	if errors.Is(err, repository.ErrOriginalTransactionNotFound) {
+		return ingestResult{}, &ingestError{status: http.StatusUnprocessableEntity, field: "original_transaction_id", code: codeOriginalTransactionNotFound, err: fmt.Errorf("original_transaction_id %s: %w", *originalTransactionID, err)}
 	}
-	err = h.usageTransactionRepo.SaveWithDetails(r.Context(), &usageTransaction, usageTransactionDetails)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return ingestResult{}, err
+	}
+	h.queueVEE(ctx, usageTransactionDetails)
+	return ingestResult{usageTransaction: &usageTransaction, created: true}, nil
+}
+
+// queueVEE queues VEE over the intervals a saved transaction reported or canceled. The transaction is
+// already stored, so a failure is only logged; the meter can be rerun through its VEE endpoint.
+func (h *EDIMonthlyUsageHandler) queueVEE(ctx context.Context, details []dbentity.UsageTransactionDetail) {
+	var meterIDs []string
+	seen := make(map[string]bool)
+	var from, to time.Time
+	for _, d := range details {
+		if !d.IsInterval || d.MeterID == nil {
+			continue
+		}
+		if !seen[*d.MeterID] {
+			seen[*d.MeterID] = true
+			meterIDs = append(meterIDs, *d.MeterID)
+		}
+		if from.IsZero() || d.Start.Before(from) {
+			from = d.Start
+		}
+		if d.End.After(to) {
+			to = d.End
+		}
+	}
+	if len(meterIDs) == 0 {
 		return
 	}
-	w.WriteHeader(http.StatusCreated)
-	json.NewEncoder(w).Encode(usageTransaction)
+	if _, err := queueVEE(ctx, h.ingestionJobRepo, meterIDs, from, to); err != nil {
+		log.Printf("queue VEE for meters %v: %v", meterIDs, err)
+	}
+}
+
+// redelivery resolves an 867 whose transaction_id was already ingested. Identical content returns the
+// stored transaction; anything else is a 409 listing how the payloads differ.
+func redelivery(existing *dbentity.UsageTransaction, payload []byte, payloadSHA256 string) (ingestResult, error) {
+	if existing.PayloadSHA256 == payloadSHA256 {
+		return ingestResult{usageTransaction: existing}, nil
+	}
+	conflict := transactionConflict{
+		Error:              fmt.Sprintf("transaction_id %s was already ingested with different content", existing.TransactionID),
+		TransactionID:      existing.TransactionID,
+		UsageTransactionID: existing.ID,
+		Differences:        []payloadDifference{},
+	}
+	if existing.Payload != nil {
+		diffs, total, err := diffPayloads(existing.Payload, payload)
+		if err != nil {
+			return ingestResult{}, err
+		}
+		conflict.Differences = diffs
+		conflict.DifferenceCount = total
+	}
+	conflictErr := &ingestError{status: http.StatusConflict, field: "transaction_id", code: codeTransactionConflict, err: errors.New(conflict.Error)}
+	conflict.Errors = fieldErrors(conflictErr)
+	conflictErr.body = conflict
+	return ingestResult{}, conflictErr
+}
+
+// servicePeriod spans every product transfer detail, which for historic usage covers many months.
+func servicePeriod(details []model.ProductTransferDetail) (time.Time, time.Time, error) {
+	var start, end time.Time
+	for i, detail := range details {
+		if detail.ServicePeriodStart == nil || detail.ServicePeriodEnd == nil {
+			missing := "service_period_start"
+			if detail.ServicePeriodStart != nil {
+				missing = "service_period_end"
+			}
+			return start, end, &ingestError{status: http.StatusBadRequest, field: missing, code: codeRequired, path: fmt.Sprintf("product_transfer_details[%d].%s", i, missing), err: fmt.Errorf("product transfer detail %s is missing its service period", detail.TransferType)}
+		}
+		if start.IsZero() || detail.ServicePeriodStart.Before(start) {
+			start = *detail.ServicePeriodStart
+		}
+		if detail.ServicePeriodEnd.After(end) {
+			end = *detail.ServicePeriodEnd
+		}
+	}
+	if start.IsZero() {
+		return start, end, &ingestError{status: http.StatusBadRequest, field: "product_transfer_details", code: codeRequired, err: errors.New("product_transfer_details must not be empty")}
+	}
+	return start, end, nil
 }
@@ -0,0 +1,610 @@
+package handler
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"testing"
+	"time"
+	dbentity "usage-lakehouse/internal/db/entity"
+	"usage-lakehouse/internal/model"
+	"usage-lakehouse/internal/premisecode"
+	"usage-lakehouse/internal/repository"
+
+	"github.com/jackc/pgx/v5"
+)
+
+func ptr[T any](v T) *T {
+	return &v
+}
+
+// The fakes below serve ERCOT master data from memory: power region pr-ercot, TDSP ONCOR, premise
+// premise-1 at the ESI ID of testdata/867_monthly.json and its meter M1. Each embeds the repository
+// interface it fakes, so a method the ingestion path is not expected to call panics.
+
+type fakePowerRegionRepository struct {
+	repository.PowerRegionRepository
+	regions map[string]*dbentity.PowerRegion
+	// errs fails the lookup of a name, as a database error would.
+	errs map[string]error
+}
+
+func (r *fakePowerRegionRepository) GetByName(ctx context.Context, name string) (*dbentity.PowerRegion, error) {
+	if err, ok := r.errs[name]; ok {
+		return nil, err
+	}
+	if p, ok := r.regions[name]; ok {
+		return p, nil
+	}
+	return nil, pgx.ErrNoRows
+}
+
+func (r *fakePowerRegionRepository) GetByID(ctx context.Context, id string) (*dbentity.PowerRegion, error) {
+	for _, p := range r.regions {
+		if p.ID == id {
+			return p, nil
+		}
+	}
+	return nil, pgx.ErrNoRows
+}
+
+type fakeTDSPRepository struct {
+	repository.TDSPRepository
+	tdsps map[string]*dbentity.TDSP
+}
+
+func (r *fakeTDSPRepository) GetByName(ctx context.Context, name string) (*dbentity.TDSP, error) {
+	if t, ok := r.tdsps[name]; ok {
+		return t, nil
+	}
+	return nil, pgx.ErrNoRows
+}
+
+type fakePremiseRepository struct {
+	repository.PremiseRepository
+	premises map[string]*model.Premise
+}
+
+func (r *fakePremiseRepository) GetByCode(ctx context.Context, powerRegionID string, code string) (*model.Premise, error) {
+	if p, ok := r.premises[code]; ok && p.PowerRegionID == powerRegionID {
+		return p, nil
+	}
+	return nil, pgx.ErrNoRows
+}
+
+func (r *fakePremiseRepository) AssignTDSP(ctx context.Context, id string, tdspID string) error {
+	for _, p := range r.premises {
+		if p.ID == id {
+			p.TDSPID = &tdspID
+		}
+	}
+	return nil
+}
+
+func (r *fakePremiseRepository) Provision(ctx context.Context, p *model.Premise) error {
+	p.AutoProvisioned = true
+	r.premises[p.Code] = p
+	return nil
+}
+
+type fakeMeterRepository struct {
+	repository.MeterRepository
+	// meters maps meter names to ids.
+	meters      map[string]string
+	provisioned []dbentity.Meter
+}
+
+func (r *fakeMeterRepository) GetByID(ctx context.Context, id string) (*dbentity.Meter, error) {
+	for name, meterID := range r.meters {
+		if meterID == id {
+			return &dbentity.Meter{ID: id, Name: name, PowerRegionID: "pr-ercot"}, nil
+		}
+	}
+	return nil, pgx.ErrNoRows
+}
+
+func (r *fakeMeterRepository) Provision(ctx context.Context, m *dbentity.Meter) error {
+	r.meters[m.Name] = m.ID
+	r.provisioned = append(r.provisioned, *m)
+	return nil
+}
+
+func (r *fakeMeterRepository) GetNameIDMap(ctx context.Context, powerRegionID string, names []string) (map[string]string, error) {
+	ids := make(map[string]string)
+	for _, name := range names {
+		if id, ok := r.meters[name]; ok {
+			ids[name] = id
+		}
+	}
+	return ids, nil
+}
+
+func (r *fakeMeterRepository) GetIntervalMinutesMap(ctx context.Context, powerRegionID string, names []string) (map[string]int, error) {
+	return map[string]int{}, nil
+}
+
+type fakePurposeRepository struct {
+	repository.UsageTransactionPurposeRepository
+}
+
+func (fakePurposeRepository) GetByPowerRegionAndCode(ctx context.Context, powerRegionID string, code string) (*dbentity.UsageTransactionPurpose, error) {
+	switch model.TransactionSetPurposeCode(code) {
+	case model.TransactionSetPurposeCodeOriginal:
+		return &dbentity.UsageTransactionPurpose{Code: code}, nil
+	case model.TransactionSetPurposeCodeCanceled:
+		return &dbentity.UsageTransactionPurpose{Code: code, IsCancel: true}, nil
+	case model.TransactionSetPurposeCodeReplace:
+		return &dbentity.UsageTransactionPurpose{Code: code, IsReplace: true}, nil
+	}
+	return nil, pgx.ErrNoRows
+}
+
+type fakeTransactionTypeRepository struct {
+	repository.TransactionTypeRepository
+}
+
+func (fakeTransactionTypeRepository) GetByPowerRegionAndCode(ctx context.Context, powerRegionID string, code string) (*dbentity.TransactionType, error) {
+	return &dbentity.TransactionType{Code: code}, nil
+}
+
+type fakeTransactionSubTypeRepository struct {
+	repository.TransactionSubTypeRepository
+}
+
+func (fakeTransactionSubTypeRepository) GetByPowerRegionSubTypeCode(ctx context.Context, powerRegionID string, code string) (*dbentity.TransactionSubType, error) {
+	switch model.TransactionSubTypeCode(code) {
+	case model.TransactionSubTypeCodeMonthlyUsage:
+		return &dbentity.TransactionSubType{Code: dbentity.TransactionSubTypeMonthlyUsage}, nil
+	case model.TransactionSubTypeCodeHistoricUsage:
+		return &dbentity.TransactionSubType{Code: dbentity.TransactionSubTypeHistoricUsage}, nil
+	}
+	return nil, pgx.ErrNoRows
+}
+
+// fakeTransferDetailTypeRepository serves the ERCOT product transfer detail types of the seed data.
+type fakeTransferDetailTypeRepository struct {
+	repository.PowerRegionUsageTransactionProductTransferDetailTypeRepository
+}
+
+var testTransferDetailTypes = map[string]dbentity.PowerRegionUsageTransactionProductTransferDetailType{
+	"PL": {Code: "PL", Meter: true},
+	"SU": {Code: "SU", Meter: true, Summary: true},
+	"BD": {Code: "BD"},
+	"BO": {Code: "BO", Interval: true, Meter: true, Summary: true},
+	"IA": {Code: "IA", Interval: true, Meter: true, Summary: true},
+	"PM": {Code: "PM", Interval: true, Meter: true},
+	"PP": {Code: "PP", Interval: true, Meter: true, Summary: true},
+}
+
+func (fakeTransferDetailTypeRepository) MapByCode(ctx context.Context, powerRegionID string) (map[string]dbentity.PowerRegionUsageTransactionProductTransferDetailType, error) {
+	return testTransferDetailTypes, nil
+}
+
+type fakePowerRegionChannelRepository struct {
+	repository.PowerRegionChannelRepository
+}
+
+func (fakePowerRegionChannelRepository) MapByCode(ctx context.Context, powerRegionID string) (map[string]dbentity.PowerRegionChannel, error) {
+	return map[string]dbentity.PowerRegionChannel{}, nil
+}
+
+// savedUsageTransaction is one call of SaveWithDetails or SaveHistoricWithDetails.
+type savedUsageTransaction struct {
+	transaction        dbentity.UsageTransaction
+	historic           bool
+	details            []dbentity.UsageTransactionDetail
+	summaries          []dbentity.UsageTransactionSummary
+	intervalExceptions []dbentity.MeterIntervalException
+	channelIntervals   []dbentity.MeterUsageChannelInterval
+}
+
+// fakeUsageTransactionRepository keeps transactions by transaction_id. Like the SQL repository, saving a
+// cancel or replace voids its original, which must be active and for the same premise.
+type fakeUsageTransactionRepository struct {
+	repository.UsageTransactionRepository
+	byTransactionID map[string]*dbentity.UsageTransaction
+	saved           []savedUsageTransaction
+}
+
+func (r *fakeUsageTransactionRepository) store(t *dbentity.UsageTransaction) {
+	r.byTransactionID[t.TransactionID] = t
+}
+
+func (r *fakeUsageTransactionRepository) GetByTransactionID(ctx context.Context, transactionID string) (*dbentity.UsageTransaction, error) {
+	if t, ok := r.byTransactionID[transactionID]; ok {
+		return t, nil
+	}
+	return nil, pgx.ErrNoRows
+}
+
+func (r *fakeUsageTransactionRepository) SaveWithDetails(ctx context.Context, t *dbentity.UsageTransaction, details []dbentity.UsageTransactionDetail, summaries []dbentity.UsageTransactionSummary, intervalExceptions []dbentity.MeterIntervalException, channelIntervals []dbentity.MeterUsageChannelInterval) error {
+	return r.save(savedUsageTransaction{transaction: *t, details: details, summaries: summaries, intervalExceptions: intervalExceptions, channelIntervals: channelIntervals})
+}
+
+func (r *fakeUsageTransactionRepository) SaveHistoricWithDetails(ctx context.Context, t *dbentity.UsageTransaction, details []dbentity.UsageTransactionDetail, summaries []dbentity.UsageTransactionSummary, intervalExceptions []dbentity.MeterIntervalException, channelIntervals []dbentity.MeterUsageChannelInterval) error {
+	return r.save(savedUsageTransaction{transaction: *t, historic: true, details: details, summaries: summaries, intervalExceptions: intervalExceptions, channelIntervals: channelIntervals})
+}
+
+func (r *fakeUsageTransactionRepository) save(saved savedUsageTransaction) error {
+	t := saved.transaction
+	if _, ok := r.byTransactionID[t.TransactionID]; ok {
+		return repository.ErrDuplicateTransactionID
+	}
+	if t.OriginalTransactionID != nil {
+		original, ok := r.byTransactionID[*t.OriginalTransactionID]
+		if !ok || original.IsCanceled || original.PremiseID != t.PremiseID {
+			return repository.ErrOriginalTransactionNotFound
+		}
+		original.IsCanceled = true
+	}
+	r.store(&t)
+	r.saved = append(r.saved, saved)
+	return nil
+}
+
+type fakeIngestionJobRepository struct {
+	repository.IngestionJobRepository
+	created []dbentity.IngestionJob
+}
+
+func (r *fakeIngestionJobRepository) Create(ctx context.Context, job *dbentity.IngestionJob) error {
+	r.created = append(r.created, *job)
+	return nil
+}
+
+// ingestFakes are the repositories behind a test EDIMonthlyUsageHandler.
+type ingestFakes struct {
+	powerRegions      *fakePowerRegionRepository
+	tdsps             *fakeTDSPRepository
+	premises          *fakePremiseRepository
+	meters            *fakeMeterRepository
+	usageTransactions *fakeUsageTransactionRepository
+	quarantine        *fakeQuarantineRepository
+	jobs              *fakeIngestionJobRepository
+}
+
+// newIngestFakes serves the ERCOT master data with the given provisioning policy.
+func newIngestFakes(provisioningPolicy string) *ingestFakes {
+	return &ingestFakes{
+		powerRegions: &fakePowerRegionRepository{regions: map[string]*dbentity.PowerRegion{
+			"ERCOT": {ID: "pr-ercot", Name: "ERCOT", ProvisioningPolicy: provisioningPolicy, TimeZone: "America/Chicago"},
+		}},
+		tdsps: &fakeTDSPRepository{tdsps: map[string]*dbentity.TDSP{
+			"ONCOR": {ID: "tdsp-oncor", Name: "ONCOR"},
+		}},
+		premises: &fakePremiseRepository{premises: map[string]*model.Premise{
+			"10443720004529147": {ID: "premise-1", Code: "10443720004529147", PowerRegionID: "pr-ercot", TDSPID: ptr("tdsp-oncor")},
+		}},
+		meters:            &fakeMeterRepository{meters: map[string]string{"M1": "meter-1"}},
+		usageTransactions: &fakeUsageTransactionRepository{byTransactionID: map[string]*dbentity.UsageTransaction{}},
+		quarantine:        &fakeQuarantineRepository{held: map[string]*dbentity.UsageTransactionQuarantine{}},
+		jobs:              &fakeIngestionJobRepository{},
+	}
+}
+
+func (f *ingestFakes) handler() *EDIMonthlyUsageHandler {
+	return NewEDIMonthlyUsageHandler(nil, f.powerRegions, f.tdsps, f.premises, f.meters, fakePurposeRepository{}, fakeTransactionTypeRepository{}, fakeTransactionSubTypeRepository{}, fakeTransferDetailTypeRepository{}, f.usageTransactions, nil, f.quarantine, f.jobs, fakePowerRegionChannelRepository{}, nil, premisecode.NewValidator())
+}
+
+// monthlyUsage reads the monthly 867 of testdata/867_monthly.json.
+func monthlyUsage(t *testing.T) model.EDIUsageTransaction {
+	t.Helper()
+	data, err := os.ReadFile("testdata/867_monthly.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var input model.EDIUsageTransaction
+	if err := json.Unmarshal(data, &input); err != nil {
+		t.Fatal(err)
+	}
+	return input
+}
+
+func ingestMonthly(h *EDIMonthlyUsageHandler, input model.EDIUsageTransaction) (ingestResult, error) {
+	return h.ingestUsage(context.Background(), h.newReferenceCache(), input, model.TransactionSubTypeCodeMonthlyUsage, nil)
+}
+
+// periodDetail is a product transfer detail over start to end; a zero time leaves that end unset.
+func periodDetail(transferType model.ProductTransferDetailTypeCode, start time.Time, end time.Time) model.ProductTransferDetail {
+	detail := model.ProductTransferDetail{TransferType: transferType}
+	if !start.IsZero() {
+		detail.ServicePeriodStart = ptr(start)
+	}
+	if !end.IsZero() {
+		detail.ServicePeriodEnd = ptr(end)
+	}
+	return detail
+}
+
+func TestServicePeriod(t *testing.T) {
+	jan := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	feb := time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC)
+	mar := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name      string
+		details   []model.ProductTransferDetail
+		wantStart time.Time
+		wantEnd   time.Time
+		wantPath  string
+		wantCode  string
+	}{
+		{
+			name:      "one period",
+			details:   []model.ProductTransferDetail{periodDetail("PL", jan, feb)},
+			wantStart: jan,
+			wantEnd:   feb,
+		},
+		{
+			name:      "historic months span every detail",
+			details:   []model.ProductTransferDetail{periodDetail("PL", feb, mar), periodDetail("PL", jan, feb), periodDetail("SU", jan, mar)},
+			wantStart: jan,
+			wantEnd:   mar,
+		},
+		{
+			name:     "missing start",
+			details:  []model.ProductTransferDetail{periodDetail("PL", jan, feb), periodDetail("PM", time.Time{}, feb)},
+			wantPath: "product_transfer_details[1].service_period_start",
+			wantCode: codeRequired,
+		},
+		{
+			name:     "missing end",
+			details:  []model.ProductTransferDetail{periodDetail("PL", jan, time.Time{})},
+			wantPath: "product_transfer_details[0].service_period_end",
+			wantCode: codeRequired,
+		},
+		{
+			name:     "no details",
+			wantPath: "product_transfer_details",
+			wantCode: codeRequired,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			start, end, err := servicePeriod(tt.details)
+			if tt.wantCode == "" {
+				if err != nil {
+					t.Fatal(err)
+				}
+				if !start.Equal(tt.wantStart) || !end.Equal(tt.wantEnd) {
+					t.Errorf("service period = %s to %s, want %s to %s", start, end, tt.wantStart, tt.wantEnd)
+				}
+				return
+			}
+			var ie *ingestError
+			if !errors.As(err, &ie) || ie.status != http.StatusBadRequest {
+				t.Fatalf("err = %v, want a 400 ingestError", err)
+			}
+			if fields := fieldErrors(err); len(fields) != 1 || fields[0].Path != tt.wantPath || fields[0].Code != tt.wantCode {
+				t.Errorf("field errors = %+v, want %s at %s", fields, tt.wantCode, tt.wantPath)
+			}
+		})
+	}
+}
+
+func TestIngestCancelAndReplace(t *testing.T) {
+	tests := []struct {
+		name     string
+		purpose  model.TransactionSetPurposeCode
+		original *string
+		canceled bool
+		wantCode string
+	}{
+		{name: "cancel voids the original", purpose: model.TransactionSetPurposeCodeCanceled, original: ptr("867-20240301-0001"), canceled: true},
+		{name: "replace voids the original", purpose: model.TransactionSetPurposeCodeReplace, original: ptr("867-20240301-0001")},
+		{name: "cancel without an original", purpose: model.TransactionSetPurposeCodeCanceled, wantCode: codeRequired},
+		{name: "replace without an original", purpose: model.TransactionSetPurposeCodeReplace, original: ptr(""), wantCode: codeRequired},
+		{name: "cancel of an unknown original", purpose: model.TransactionSetPurposeCodeCanceled, original: ptr("867-unknown"), wantCode: codeOriginalTransactionNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fakes := newIngestFakes(dbentity.ProvisioningPolicyReject)
+			h := fakes.handler()
+			if _, err := ingestMonthly(h, monthlyUsage(t)); err != nil {
+				t.Fatalf("ingest original: %v", err)
+			}
+			input := monthlyUsage(t)
+			input.TransactionID = "867-20240305-0002"
+			input.Purpose = tt.purpose
+			input.OriginalTransactionID = tt.original
+			// The period's peak demand is kept as a channel reading unless the transaction is canceled.
+			demand := input.ProductTransferDetails[0]
+			demand.UnitOfMeasure = ptr("KW")
+			demand.Quantities = &[]model.QuantityDelivered{{Quantity: 4.2, IntervalEnd: *demand.ServicePeriodEnd}}
+			input.ProductTransferDetails = append(input.ProductTransferDetails, demand)
+			result, err := ingestMonthly(h, input)
+			original := fakes.usageTransactions.byTransactionID["867-20240301-0001"]
+			if tt.wantCode != "" {
+				if fields := fieldErrors(err); errorStatus(err) != http.StatusUnprocessableEntity || len(fields) != 1 || fields[0].Path != "original_transaction_id" || fields[0].Code != tt.wantCode {
+					t.Fatalf("err = %v (%+v), want 422 %s on original_transaction_id", err, fields, tt.wantCode)
+				}
+				if original.IsCanceled {
+					t.Error("a rejected cancel or replace voided the original")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !result.created || !original.IsCanceled {
+				t.Fatalf("created = %v, original voided = %v, want a new transaction voiding the original", result.created, original.IsCanceled)
+			}
+			saved := fakes.usageTransactions.saved[len(fakes.usageTransactions.saved)-1]
+			if saved.transaction.IsCanceled != tt.canceled || *saved.transaction.OriginalTransactionID != *tt.original {
+				t.Errorf("saved transaction = %+v, want is_canceled %v against %s", saved.transaction, tt.canceled, *tt.original)
+			}
+			if len(saved.details) == 0 {
+				t.Fatal("saved no details")
+			}
+			for _, d := range saved.details {
+				if d.IsCanceled != tt.canceled {
+					t.Errorf("detail %s to %s is_canceled = %v, want %v", d.Start, d.End, d.IsCanceled, tt.canceled)
+				}
+			}
+			wantReadings := 1
+			if tt.canceled {
+				wantReadings = 0
+			}
+			if len(saved.channelIntervals) != wantReadings {
+				t.Errorf("stored %d channel readings, want %d", len(saved.channelIntervals), wantReadings)
+			}
+		})
+	}
+}
+
+// racingUsageTransactionRepository misses its first transaction_id lookup, as if a concurrent request
+// stored the transaction between ingestion's duplicate check and its save.
+type racingUsageTransactionRepository struct {
+	*fakeUsageTransactionRepository
+	raced bool
+}
+
+func (r *racingUsageTransactionRepository) GetByTransactionID(ctx context.Context, transactionID string) (*dbentity.UsageTransaction, error) {
+	if !r.raced {
+		r.raced = true
+		return nil, pgx.ErrNoRows
+	}
+	return r.fakeUsageTransactionRepository.GetByTransactionID(ctx, transactionID)
+}
+
+func TestIngestRedelivery(t *testing.T) {
+	tests := []struct {
+		name     string
+		quantity float64
+		race     bool
+		wantErr  bool
+	}{
+		{name: "same content", quantity: 812.5},
+		{name: "same content racing the first delivery", quantity: 812.5, race: true},
+		{name: "different content", quantity: 900, wantErr: true},
+		{name: "different content racing the first delivery", quantity: 900, race: true, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fakes := newIngestFakes(dbentity.ProvisioningPolicyReject)
+			h := fakes.handler()
+			first, err := ingestMonthly(h, monthlyUsage(t))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if tt.race {
+				h.usageTransactionRepo = &racingUsageTransactionRepository{fakeUsageTransactionRepository: fakes.usageTransactions}
+			}
+			input := monthlyUsage(t)
+			(*input.ProductTransferDetails[0].Quantities)[0].Quantity = tt.quantity
+			result, err := ingestMonthly(h, input)
+			if len(fakes.usageTransactions.saved) != 1 {
+				t.Errorf("saved %d transactions, want only the first delivery", len(fakes.usageTransactions.saved))
+			}
+			if !tt.wantErr {
+				if err != nil {
+					t.Fatal(err)
+				}
+				if result.created || result.usageTransaction.ID != first.usageTransaction.ID {
+					t.Errorf("redelivery = %+v, want the stored transaction %s", result, first.usageTransaction.ID)
+				}
+				return
+			}
+			var ie *ingestError
+			if !errors.As(err, &ie) || ie.status != http.StatusConflict {
+				t.Fatalf("err = %v, want a 409", err)
+			}
+			conflict, ok := ie.body.(transactionConflict)
+			if !ok {
+				t.Fatalf("conflict body = %T, want transactionConflict", ie.body)
+			}
+			want := payloadDifference{Path: "product_transfer_details[0].quantity_delivered[0].quantity", Existing: 812.5, Incoming: tt.quantity}
+			if conflict.UsageTransactionID != first.usageTransaction.ID || conflict.DifferenceCount != 1 || len(conflict.Differences) != 1 || conflict.Differences[0] != want {
+				t.Errorf("conflict = %+v, want the quantity difference against %s", conflict, first.usageTransaction.ID)
+			}
+			if len(conflict.Errors) != 1 || conflict.Errors[0].Code != codeTransactionConflict || conflict.Errors[0].Path != "transaction_id" {
+				t.Errorf("conflict errors = %+v, want TRANSACTION_CONFLICT on transaction_id", conflict.Errors)
+			}
+		})
+	}
+}
+
+func TestIngestStoresThePayloadAndItsSHA256(t *testing.T) {
+	fakes := newIngestFakes(dbentity.ProvisioningPolicyReject)
+	input := monthlyUsage(t)
+	result, err := ingestMonthly(fakes.handler(), input)
+	if err != nil {
+		t.Fatal(err)
+	}
+	input.TransactionSubType = model.TransactionSubTypeCodeMonthlyUsage
+	payload, err := json.Marshal(input)
+	if err != nil {
+		t.Fatal(err)
+	}
+	stored := result.usageTransaction
+	if string(stored.Payload) != string(payload) || stored.PayloadSHA256 != fmt.Sprintf("%x", sha256.Sum256(payload)) {
+		t.Errorf("stored payload %s with SHA-256 %s, want the normalized input and its SHA-256", stored.Payload, stored.PayloadSHA256)
+	}
+}
+
+func TestIngestLinksTheRawPayload(t *testing.T) {
+	fakes := newIngestFakes(dbentity.ProvisioningPolicyQuarantine)
+	h := fakes.handler()
+	sum := "9f86d081884c7d659a2feaa0c55ad015a3bf4f1b2b0b822cd15d6c15b0f00a08"
+	refs := h.newReferenceCache()
+
+	result, err := h.ingestUsage(context.Background(), refs, monthlyUsage(t), model.TransactionSubTypeCodeMonthlyUsage, &sum)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if linked := result.usageTransaction.RawPayloadSHA256; linked == nil || *linked != sum {
+		t.Errorf("usage transaction raw_payload_sha256 = %v, want %s", linked, sum)
+	}
+
+	unknownMeter := monthlyUsage(t)
+	unknownMeter.TransactionID = "867-2"
+	unknownMeter.ProductTransferDetails[0].MeterName = ptr("M9")
+	result, err = h.ingestUsage(context.Background(), refs, unknownMeter, model.TransactionSubTypeCodeMonthlyUsage, &sum)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if linked := result.quarantine.RawPayloadSHA256; linked == nil || *linked != sum {
+		t.Errorf("quarantined raw_payload_sha256 = %v, want %s", linked, sum)
+	}
+}
+
+// TestIngestKeysIntervalsByInstant ingests the repeated hour of the 2024 fall-back day in Central time:
+// 01:15 CDT and 01:15 CST share a wall clock time but are different intervals.
+func TestIngestKeysIntervalsByInstant(t *testing.T) {
+	fakes := newIngestFakes(dbentity.ProvisioningPolicyReject)
+	central, err := time.LoadLocation("America/Chicago")
+	if err != nil {
+		t.Fatal(err)
+	}
+	cdt := time.Date(2024, 11, 3, 1, 15, 0, 0, central)
+	cst := cdt.Add(time.Hour)
+	start, end := cdt.Add(-15*time.Minute), cst
+	input := monthlyUsage(t)
+	input.ProductTransferDetails = []model.ProductTransferDetail{{
+		TransferType:       "PM",
+		ServicePeriodStart: &start,
+		ServicePeriodEnd:   &end,
+		MeterName:          ptr("M1"),
+		Channel:            ptr("1"),
+		Quantities:         &[]model.QuantityDelivered{{Quantity: 1, IntervalEnd: cdt}, {Quantity: 2, IntervalEnd: cst}},
+	}}
+	if _, err := ingestMonthly(fakes.handler(), input); err != nil {
+		t.Fatal(err)
+	}
+	if cdt.Format("15:04") != cst.Format("15:04") {
+		t.Fatalf("%s and %s do not share a wall clock time", cdt, cst)
+	}
+	consumption := make(map[time.Time]float64)
+	for _, d := range fakes.usageTransactions.saved[0].details {
+		if d.End.Location() != time.UTC || d.End.Sub(d.Start) != 15*time.Minute {
+			t.Errorf("detail %s to %s, want a 15 minute UTC interval", d.Start, d.End)
+		}
+		consumption[d.End] = *d.Consumption
+	}
+	if len(consumption) != 2 || consumption[cdt.UTC()] != 1 || consumption[cst.UTC()] != 2 {
+		t.Errorf("consumption by interval end = %v, want 1 at %s and 2 at %s", consumption, cdt.UTC(), cst.UTC())
+	}
+}
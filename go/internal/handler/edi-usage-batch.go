@@ -0,0 +1,143 @@
+package handler
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"log"
+	"net/http"
+	dbentity "usage-lakehouse/internal/db/entity"
+	"usage-lakehouse/internal/model"
+)
+
+// maxBatchLineBytes bounds one NDJSON line; a month of 15 minute intervals on several channels fits well within it.
+const maxBatchLineBytes = 16 << 20
+
+const (
+	batchStatusCreated     = "created"
+	batchStatusDuplicate   = "duplicate"
+	batchStatusQuarantined = "quarantined"
+	batchStatusRejected    = "rejected"
+)
+
+type batchLineResult struct {
+	Line               int          `json:"line"`
+	TransactionID      string       `json:"transaction_id,omitempty"`
+	Status             string       `json:"status"`
+	UsageTransactionID string       `json:"usage_transaction_id,omitempty"`
+	QuarantineID       string       `json:"quarantine_id,omitempty"`
+	Field              string       `json:"field,omitempty"`
+	Error              string       `json:"error,omitempty"`
+	Errors             []fieldError `json:"errors,omitempty"`
+}
+
+func (h *EDIMonthlyUsageHandler) CreateEDIMonthlyUsageBatch(w http.ResponseWriter, r *http.Request) {
+	h.createEDIUsageBatch(w, r, model.TransactionSubTypeCodeMonthlyUsage)
+}
+
+func (h *EDIMonthlyUsageHandler) CreateEDIHistoricUsageBatch(w http.ResponseWriter, r *http.Request) {
+	h.createEDIUsageBatch(w, r, model.TransactionSubTypeCodeHistoricUsage)
+}
+
+// createEDIUsageBatch ingests newline-delimited EDIUsageTransaction documents one at a time,
+// sharing reference lookups across the batch, and streams back one NDJSON result per input line.
+// A failing line does not stop the batch.
+func (h *EDIMonthlyUsageHandler) createEDIUsageBatch(w http.ResponseWriter, r *http.Request, subType model.TransactionSubTypeCode) {
+	body, raw, ok := archiveRequestBody(w, r, h.archive, ediUsageContentTypes[ediUsageFormatNDJSON])
+	if !ok {
+		return
+	}
+	defer body.Close()
+	if isAsyncRequest(r) {
+		submitIngestionJob(w, r, h.ingestionJobRepo, dbentity.IngestionJobKindEDIUsage, ediUsageJobParams{SubType: subType, Format: ediUsageFormatNDJSON}, raw)
+		return
+	}
+	flusher, _ := w.(http.Flusher)
+	enc := json.NewEncoder(w)
+	w.Header().Set("Content-Type", "application/x-ndjson")
+	w.WriteHeader(http.StatusOK)
+	h.ingestBatch(r.Context(), body, subType, &raw.SHA256, func(result batchLineResult) {
+		enc.Encode(result)
+		if flusher != nil {
+			flusher.Flush()
+		}
+	})
+}
+
+// ingestBatch hands the result of every non-blank line to emit, ending with a rejected result when
+// the body cannot be read to the end.
+func (h *EDIMonthlyUsageHandler) ingestBatch(ctx context.Context, body io.Reader, subType model.TransactionSubTypeCode, rawPayloadSHA256 *string, emit func(batchLineResult)) {
+	refs := h.newReferenceCache()
+	reader := bufio.NewReader(body)
+	for line := 1; ; line++ {
+		raw, err := readBatchLine(reader)
+		if len(bytes.TrimSpace(raw)) > 0 {
+			emit(h.ingestBatchLine(ctx, refs, line, raw, subType, rawPayloadSHA256))
+		}
+		if err == io.EOF {
+			return
+		}
+		if err != nil {
+			emit(batchLineResult{Line: line, Status: batchStatusRejected, Error: err.Error(), Errors: fieldErrors(decodeError(err))})
+			return
+		}
+	}
+}
+
+func readBatchLine(reader *bufio.Reader) ([]byte, error) {
+	var line []byte
+	for {
+		chunk, isPrefix, err := reader.ReadLine()
+		line = append(line, chunk...)
+		if err != nil {
+			return line, err
+		}
+		if len(line) > maxBatchLineBytes {
+			return nil, errors.New("line exceeds the maximum batch line size")
+		}
+		if !isPrefix {
+			return line, nil
+		}
+	}
+}
+
+func (h *EDIMonthlyUsageHandler) ingestBatchLine(ctx context.Context, refs *referenceCache, line int, raw []byte, subType model.TransactionSubTypeCode, rawPayloadSHA256 *string) batchLineResult {
+	result := batchLineResult{Line: line}
+	var input model.EDIUsageTransaction
+	if err := json.Unmarshal(raw, &input); err != nil {
+		result.Status = batchStatusRejected
+		result.Error = err.Error()
+		result.Errors = fieldErrors(decodeError(err))
+		return result
+	}
+	result.TransactionID = input.TransactionID
+	ingested, err := h.ingestUsage(ctx, refs, input, subType, rawPayloadSHA256)
+	switch {
+	case err != nil:
+		result.Status = batchStatusRejected
+		if errorStatus(err) >= http.StatusInternalServerError {
+			log.Printf("batch line %d, transaction %s: %v", line, input.TransactionID, err)
+		}
+		result.Error, result.Errors = clientError(err)
+		var ie *ingestError
+		if errors.As(err, &ie) {
+			result.Field = ie.field
+		}
+		if ingested.quarantine != nil {
+			result.QuarantineID = ingested.quarantine.ID
+		}
+	case ingested.quarantine != nil:
+		result.Status = batchStatusQuarantined
+		result.QuarantineID = ingested.quarantine.ID
+	case ingested.created:
+		result.Status = batchStatusCreated
+		result.UsageTransactionID = ingested.usageTransaction.ID
+	default:
+		result.Status = batchStatusDuplicate
+		result.UsageTransactionID = ingested.usageTransaction.ID
+	}
+	return result
+}
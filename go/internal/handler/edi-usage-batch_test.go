@@ -0,0 +1,155 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"reflect"
+	"strings"
+	"testing"
+	dbentity "usage-lakehouse/internal/db/entity"
+	"usage-lakehouse/internal/model"
+
+	"github.com/jackc/pgx/v5"
+)
+
+func TestClientError(t *testing.T) {
+	internal := []fieldError{{Code: codeInternal, Message: "Internal Server Error"}}
+	tests := []struct {
+		name        string
+		err         error
+		wantMessage string
+		wantErrors  []fieldError
+	}{
+		{
+			name:        "client error",
+			err:         lookupError("tdsp_name", "ONCOR", pgx.ErrNoRows),
+			wantMessage: `tdsp_name "ONCOR" does not exist`,
+			wantErrors:  []fieldError{{Path: "tdsp_name", Code: codeUnknownTDSP, Message: `tdsp_name "ONCOR" does not exist`}},
+		},
+		{
+			name:        "failed lookup",
+			err:         lookupError("tdsp_name", "ONCOR", errors.New("connection refused")),
+			wantMessage: "Internal Server Error",
+			wantErrors:  internal,
+		},
+		{
+			name:        "database error",
+			err:         fmt.Errorf("save: %w", errors.New(`duplicate key value violates unique constraint "pk_usage_transaction"`)),
+			wantMessage: "Internal Server Error",
+			wantErrors:  internal,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			message, errs := clientError(tt.err)
+			if message != tt.wantMessage || !reflect.DeepEqual(errs, tt.wantErrors) {
+				t.Errorf("clientError = %q %+v, want %q %+v", message, errs, tt.wantMessage, tt.wantErrors)
+			}
+		})
+	}
+}
+
+// batchLine is testdata/867_monthly.json on one line, with its transaction_id and power_region replaced.
+func batchLine(t *testing.T, transactionID string, powerRegion string) string {
+	t.Helper()
+	input := monthlyUsage(t)
+	input.TransactionID = transactionID
+	input.PowerRegion = powerRegion
+	line, err := json.Marshal(input)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(line)
+}
+
+func TestIngestBatch(t *testing.T) {
+	fakes := newIngestFakes(dbentity.ProvisioningPolicyReject)
+	fakes.powerRegions.errs = map[string]error{"UNAVAILABLE": errors.New("connection refused")}
+	body := strings.Join([]string{
+		batchLine(t, "867-1", "ERCOT"),
+		"",
+		batchLine(t, "867-1", "ERCOT") + "\r",
+		`{"transaction_id": 7}`,
+		`{"transaction_id": "867-3"`,
+		batchLine(t, "867-4", "PJM"),
+		batchLine(t, "867-5", "UNAVAILABLE"),
+		`{"transaction_id": "867-6"}`,
+		batchLine(t, "867-7", "ERCOT"),
+	}, "\n")
+	var results []batchLineResult
+	fakes.handler().ingestBatch(context.Background(), strings.NewReader(body), model.TransactionSubTypeCodeMonthlyUsage, nil, func(r batchLineResult) {
+		results = append(results, r)
+	})
+
+	want := []struct {
+		line   int
+		status string
+		code   string
+		held   bool
+	}{
+		{1, batchStatusCreated, "", false},
+		{3, batchStatusDuplicate, "", false},
+		{4, batchStatusRejected, codeInvalidType, false},
+		{5, batchStatusRejected, codeMalformedRequest, false},
+		{6, batchStatusRejected, codeUnknownPowerRegion, true},
+		{7, batchStatusRejected, codeInternal, false},
+		{8, batchStatusRejected, codeRequired, false},
+		{9, batchStatusCreated, "", false},
+	}
+	if len(results) != len(want) {
+		t.Fatalf("got %d results, want %d: %+v", len(results), len(want), results)
+	}
+	for i, w := range want {
+		r := results[i]
+		if r.Line != w.line || r.Status != w.status || (r.QuarantineID != "") != w.held {
+			t.Errorf("result %d = %+v, want line %d %s, held %v", i, r, w.line, w.status, w.held)
+		}
+		if w.code == "" {
+			if r.UsageTransactionID == "" || len(r.Errors) != 0 {
+				t.Errorf("line %d = %+v, want a usage transaction", r.Line, r)
+			}
+			continue
+		}
+		if len(r.Errors) == 0 || r.Errors[0].Code != w.code {
+			t.Errorf("line %d errors = %+v, want %s", r.Line, r.Errors, w.code)
+		}
+	}
+	if results[0].UsageTransactionID != results[1].UsageTransactionID {
+		t.Errorf("duplicate line answered %s, want the transaction of line 1 %s", results[1].UsageTransactionID, results[0].UsageTransactionID)
+	}
+	if results[5].Error != http.StatusText(http.StatusInternalServerError) {
+		t.Errorf("server failure reported as %q, want its detail hidden", results[5].Error)
+	}
+	if len(fakes.usageTransactions.saved) != 2 {
+		t.Errorf("saved %d transactions, want 2", len(fakes.usageTransactions.saved))
+	}
+}
+
+func TestIngestBatchReportsAnUnreadableBody(t *testing.T) {
+	fakes := newIngestFakes(dbentity.ProvisioningPolicyReject)
+	body := &failingReader{data: batchLine(t, "867-1", "ERCOT") + "\n" + batchLine(t, "867-2", "ERCOT")[:20]}
+	var results []batchLineResult
+	fakes.handler().ingestBatch(context.Background(), body, model.TransactionSubTypeCodeMonthlyUsage, nil, func(r batchLineResult) {
+		results = append(results, r)
+	})
+	if len(results) != 3 || results[0].Status != batchStatusCreated || results[2].Status != batchStatusRejected || results[2].Error != "connection reset" {
+		t.Errorf("results = %+v, want line 1 created, line 2 rejected and the read failure reported last", results)
+	}
+}
+
+// failingReader reads data and then fails.
+type failingReader struct {
+	data string
+}
+
+func (r *failingReader) Read(p []byte) (int, error) {
+	if r.data == "" {
+		return 0, errors.New("connection reset")
+	}
+	n := copy(p, r.data)
+	r.data = r.data[n:]
+	return n, nil
+}
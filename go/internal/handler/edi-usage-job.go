@@ -0,0 +1,146 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	dbentity "usage-lakehouse/internal/db/entity"
+	"usage-lakehouse/internal/jobs"
+	"usage-lakehouse/internal/model"
+	"usage-lakehouse/internal/region"
+	"usage-lakehouse/internal/x12"
+)
+
+const (
+	ediUsageFormatJSON   = "json"
+	ediUsageFormatX12    = "x12"
+	ediUsageFormatNDJSON = "ndjson"
+)
+
+var ediUsageContentTypes = map[string]string{
+	ediUsageFormatJSON:   "application/json",
+	ediUsageFormatX12:    "application/edi-x12",
+	ediUsageFormatNDJSON: "application/x-ndjson",
+}
+
+type ediUsageJobParams struct {
+	SubType     model.TransactionSubTypeCode `json:"transaction_sub_type_code"`
+	Format      string                       `json:"format"`
+	Ack         x12.AcknowledgementType      `json:"ack,omitempty"`
+	PowerRegion string                       `json:"power_region,omitempty"`
+}
+
+type ediUsageJobOutput struct {
+	UsageTransactionIDs []string `json:"usage_transaction_ids"`
+	QuarantineIDs       []string `json:"quarantine_ids,omitempty"`
+	AcknowledgementID   string   `json:"acknowledgement_id,omitempty"`
+}
+
+func (o *ediUsageJobOutput) add(result ingestResult) {
+	if result.usageTransaction != nil {
+		o.UsageTransactionIDs = append(o.UsageTransactionIDs, result.usageTransaction.ID)
+	}
+	if result.quarantine != nil {
+		o.QuarantineIDs = append(o.QuarantineIDs, result.quarantine.ID)
+	}
+}
+
+// ProcessIngestionJob runs an EDI_USAGE job queued by one of the ?async=true usage endpoints.
+func (h *EDIMonthlyUsageHandler) ProcessIngestionJob(ctx context.Context, job *dbentity.IngestionJob) (jobs.Result, error) {
+	var params ediUsageJobParams
+	if err := json.Unmarshal(job.Params, &params); err != nil {
+		return jobs.Result{}, fmt.Errorf("decode job params: %w", err)
+	}
+	body, err := openJobPayload(ctx, h.archive, job)
+	if err != nil {
+		return jobs.Result{}, err
+	}
+	defer body.Close()
+	output := ediUsageJobOutput{UsageTransactionIDs: []string{}}
+	result := jobs.Result{Output: &output}
+	switch params.Format {
+	case ediUsageFormatJSON:
+		var input model.EDIUsageTransaction
+		if err := json.NewDecoder(body).Decode(&input); err != nil {
+			return result, err
+		}
+		result.Processed = 1
+		ingested, err := h.ingestUsage(ctx, h.newReferenceCache(), input, params.SubType, job.RawPayloadSHA256)
+		if err != nil {
+			result.Failed = 1
+			result.Errors = append(result.Errors, jobErrors(1, input.TransactionID, err.Error(), fieldErrors(err))...)
+			output.add(ingested)
+			return result, nil
+		}
+		result.Succeeded = 1
+		output.add(ingested)
+	case ediUsageFormatX12:
+		interchange, err := x12.Parse(body)
+		if err != nil {
+			return result, err
+		}
+		powerRegion := params.PowerRegion
+		if powerRegion == "" {
+			powerRegion = region.DefaultPowerRegion
+		}
+		adapter, ok := region.Lookup(powerRegion)
+		if !ok {
+			return result, fmt.Errorf("power_region %q has no usage adapter", powerRegion)
+		}
+		ackType := params.Ack
+		if ackType == "" {
+			ackType = x12.Acknowledgement997
+		}
+		response, rejected, err := h.ingestInterchange(ctx, adapter, interchange, ackType, params.SubType, job.RawPayloadSHA256)
+		if err != nil {
+			return result, err
+		}
+		output.AcknowledgementID = response.AcknowledgementID
+		result.Processed = len(response.Transactions)
+		result.Failed = rejected
+		result.Succeeded = result.Processed - rejected
+		for i, t := range response.Transactions {
+			if t.Error != "" {
+				result.Errors = append(result.Errors, jobErrors(i+1, t.TransactionID, t.Error, t.Errors)...)
+			}
+			if t.UsageTransaction != nil {
+				output.UsageTransactionIDs = append(output.UsageTransactionIDs, t.UsageTransaction.ID)
+			}
+			if t.QuarantineID != "" {
+				output.QuarantineIDs = append(output.QuarantineIDs, t.QuarantineID)
+			}
+		}
+	case ediUsageFormatNDJSON:
+		h.ingestBatch(ctx, body, params.SubType, job.RawPayloadSHA256, func(line batchLineResult) {
+			result.Processed++
+			if line.Status == batchStatusRejected {
+				result.Failed++
+				result.Errors = append(result.Errors, jobErrors(line.Line, line.TransactionID, line.Error, line.Errors)...)
+				return
+			}
+			result.Succeeded++
+			if line.UsageTransactionID != "" {
+				output.UsageTransactionIDs = append(output.UsageTransactionIDs, line.UsageTransactionID)
+			}
+			if line.QuarantineID != "" {
+				output.QuarantineIDs = append(output.QuarantineIDs, line.QuarantineID)
+			}
+		})
+	default:
+		return result, fmt.Errorf("unsupported format %q", params.Format)
+	}
+	return result, nil
+}
+
+// jobErrors records one job error per failing field of an item, or a single error carrying message
+// when the failure names no field.
+func jobErrors(item int, transactionID string, message string, errs []fieldError) []dbentity.IngestionJobError {
+	if len(errs) == 0 {
+		return []dbentity.IngestionJobError{{Item: item, TransactionID: transactionID, Message: message}}
+	}
+	out := make([]dbentity.IngestionJobError, 0, len(errs))
+	for _, fe := range errs {
+		out = append(out, dbentity.IngestionJobError{Item: item, TransactionID: transactionID, Field: fe.Path, Code: fe.Code, Message: fe.Message})
+	}
+	return out
+}
@@ -0,0 +1,178 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+
+	"github.com/go-playground/validator/v10"
+)
+
+// Error codes identify why a field was rejected. They are stable so that API clients can map them;
+// messages are for people and may change.
+const (
+	codeMalformedRequest            = "MALFORMED_REQUEST"
+	codeInvalidType                 = "INVALID_TYPE"
+	codeRequired                    = "REQUIRED"
+	codeInvalidValue                = "INVALID_VALUE"
+	codeInvalidFormat               = "INVALID_FORMAT"
+	codeNotUnique                   = "NOT_UNIQUE"
+	codeNotFound                    = "NOT_FOUND"
+	codeInternal                    = "INTERNAL_ERROR"
+	codeUnknownPowerRegion          = "UNKNOWN_POWER_REGION"
+	codeUnsupportedPowerRegion      = "UNSUPPORTED_POWER_REGION"
+	codeUnknownTDSP                 = "UNKNOWN_TDSP"
+	codeUnknownEsiID                = "UNKNOWN_ESI_ID"
+	codeInvalidEsiID                = "INVALID_ESI_ID"
+	codeUnknownMeter                = "UNKNOWN_METER"
+	codeUnknownPurpose              = "UNKNOWN_PURPOSE"
+	codeUnknownTransactionType      = "UNKNOWN_TRANSACTION_TYPE"
+	codeUnknownTransactionSubType   = "UNKNOWN_TRANSACTION_SUB_TYPE"
+	codeTransactionSubTypeMismatch  = "TRANSACTION_SUB_TYPE_MISMATCH"
+	codeUnsupportedUnitOfMeasure    = "UNSUPPORTED_UNIT_OF_MEASURE"
+	codeOriginalTransactionNotFound = "ORIGINAL_TRANSACTION_NOT_FOUND"
+	codeTransactionConflict         = "TRANSACTION_CONFLICT"
+	codeNotQuarantined              = "NOT_QUARANTINED"
+	codeUnitNotConvertible          = "UNIT_NOT_CONVERTIBLE"
+)
+
+// fieldError is one failing field. Path is the JSON path of the field in the request body, e.g.
+// product_transfer_details[2].quantities[0].quantity, and is empty when the whole body failed.
+type fieldError struct {
+	Path    string `json:"path"`
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+// errorResponse is the body of every rejected request.
+type errorResponse struct {
+	Error  string       `json:"error"`
+	Errors []fieldError `json:"errors"`
+}
+
+// validationCodes maps validator tags to error codes. Tags not listed are INVALID_VALUE.
+var validationCodes = map[string]string{
+	"required":                codeRequired,
+	"uuid":                    codeInvalidFormat,
+	"unit_of_measure":         codeUnsupportedUnitOfMeasure,
+	"unique_account_name":     codeNotUnique,
+	"unique_account_legal_id": codeNotUnique,
+}
+
+func validationFieldErrors(validationErrors validator.ValidationErrors) []fieldError {
+	out := make([]fieldError, 0, len(validationErrors))
+	for _, fe := range validationErrors {
+		code, ok := validationCodes[fe.Tag()]
+		if !ok {
+			code = codeInvalidValue
+		}
+		out = append(out, fieldError{Path: validationPath(fe), Code: code, Message: validationMessage(fe, code)})
+	}
+	return out
+}
+
+// validationPath drops the Go type name the validator roots its namespace at. Validators register
+// jsonFieldName, so the rest of the namespace is already the JSON path.
+func validationPath(fe validator.FieldError) string {
+	if _, path, ok := strings.Cut(fe.Namespace(), "."); ok {
+		return path
+	}
+	return fe.Field()
+}
+
+func validationMessage(fe validator.FieldError, code string) string {
+	switch code {
+	case codeRequired:
+		return fmt.Sprintf("%s is required", fe.Field())
+	case codeNotUnique:
+		return fmt.Sprintf("%s is already in use", fe.Field())
+	case codeInvalidFormat:
+		return fmt.Sprintf("%s must be a %s", fe.Field(), strings.ToUpper(fe.Tag()))
+	case codeUnsupportedUnitOfMeasure:
+		return fmt.Sprintf("%s %v is not supported", fe.Field(), fe.Value())
+	}
+	switch fe.Tag() {
+	case "oneof":
+		return fmt.Sprintf("%s must be one of %s", fe.Field(), fe.Param())
+	case "gtfield":
+		return fmt.Sprintf("%s must be later than %s", fe.Field(), fe.Param())
+	case "min":
+		return fmt.Sprintf("%s must have at least %s entries", fe.Field(), fe.Param())
+	}
+	return fmt.Sprintf("%s failed the %s check", fe.Field(), fe.Tag())
+}
+
+// fieldErrors returns the failing fields of a validation or ingestion error, or nil when err does not
+// name any.
+func fieldErrors(err error) []fieldError {
+	var validationErrors validator.ValidationErrors
+	if errors.As(err, &validationErrors) {
+		return validationFieldErrors(validationErrors)
+	}
+	var ie *ingestError
+	if errors.As(err, &ie) && ie.code != "" {
+		path := ie.path
+		if path == "" {
+			path = ie.field
+		}
+		return []fieldError{{Path: path, Code: ie.code, Message: ie.err.Error()}}
+	}
+	return nil
+}
+
+// decodeError rejects a body that is not the JSON the endpoint expects, naming the field when the
+// decoder can.
+func decodeError(err error) error {
+	ie := &ingestError{status: http.StatusBadRequest, code: codeMalformedRequest, err: fmt.Errorf("invalid request: %w", err)}
+	var typeErr *json.UnmarshalTypeError
+	if errors.As(err, &typeErr) && typeErr.Field != "" {
+		ie.path = typeErr.Field
+		ie.code = codeInvalidType
+		ie.err = fmt.Errorf("%s must be a JSON %s", typeErr.Field, typeErr.Type.Kind())
+	}
+	return ie
+}
+
+// notFoundError is the 404 for a path parameter that names no row.
+func notFoundError(field string, id string) error {
+	return &ingestError{status: http.StatusNotFound, field: field, code: codeNotFound, err: fmt.Errorf("%s %s not found", field, id)}
+}
+
+// paramError is the 400 for a query or path parameter the request got wrong.
+func paramError(field string, code string, message string) error {
+	return &ingestError{status: http.StatusBadRequest, field: field, code: code, err: errors.New(message)}
+}
+
+// clientError is the message and failing fields err is reported with where it is one result among
+// others, such as a line of a batch. A server error's detail may be a database message, so it is
+// reported as INTERNAL_ERROR with a generic message; logging it is left to the caller.
+func clientError(err error) (string, []fieldError) {
+	if errorStatus(err) >= http.StatusInternalServerError {
+		message := http.StatusText(http.StatusInternalServerError)
+		return message, []fieldError{{Code: codeInternal, Message: message}}
+	}
+	return err.Error(), fieldErrors(err)
+}
+
+// writeErrorResponse answers with an errorResponse. Server errors are logged and answered without
+// their detail, which may be a database message.
+func writeErrorResponse(w http.ResponseWriter, status int, err error) {
+	response := errorResponse{Error: err.Error(), Errors: fieldErrors(err)}
+	var validationErrors validator.ValidationErrors
+	if errors.As(err, &validationErrors) {
+		response.Error = "request failed validation"
+	}
+	if status >= http.StatusInternalServerError {
+		log.Printf("%d: %v", status, err)
+		response = errorResponse{Error: http.StatusText(status), Errors: []fieldError{{Code: codeInternal, Message: http.StatusText(status)}}}
+	}
+	if response.Errors == nil {
+		response.Errors = []fieldError{}
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(response)
+}
@@ -0,0 +1,140 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/go-playground/validator/v10"
+)
+
+// decodeErrorResponse reads the errorResponse a handler answered with, failing the test when the
+// response is not one.
+func decodeErrorResponse(t *testing.T, rec *httptest.ResponseRecorder) errorResponse {
+	t.Helper()
+	if ct := rec.Header().Get("Content-Type"); ct != "application/json" {
+		t.Fatalf("Content-Type = %q, want application/json; body %s", ct, rec.Body)
+	}
+	var response errorResponse
+	if err := json.NewDecoder(rec.Body).Decode(&response); err != nil {
+		t.Fatalf("decode error response: %v", err)
+	}
+	return response
+}
+
+func TestWriteIngestError(t *testing.T) {
+	var typeErr error
+	var v struct {
+		Quantity float64 `json:"quantity"`
+	}
+	if err := json.NewDecoder(strings.NewReader(`{"quantity":"ten"}`)).Decode(&v); err != nil {
+		typeErr = err
+	}
+	tests := []struct {
+		name   string
+		err    error
+		status int
+		want   errorResponse
+	}{
+		{
+			name:   "not found",
+			err:    notFoundError("id", "42"),
+			status: http.StatusNotFound,
+			want:   errorResponse{Error: "id 42 not found", Errors: []fieldError{{Path: "id", Code: codeNotFound, Message: "id 42 not found"}}},
+		},
+		{
+			name:   "parameter",
+			err:    paramError("limit", codeInvalidValue, "limit must be between 1 and 1000"),
+			status: http.StatusBadRequest,
+			want:   errorResponse{Error: "limit must be between 1 and 1000", Errors: []fieldError{{Path: "limit", Code: codeInvalidValue, Message: "limit must be between 1 and 1000"}}},
+		},
+		{
+			name:   "malformed body",
+			err:    decodeError(errors.New("unexpected EOF")),
+			status: http.StatusBadRequest,
+			want:   errorResponse{Error: "invalid request: unexpected EOF", Errors: []fieldError{{Code: codeMalformedRequest, Message: "invalid request: unexpected EOF"}}},
+		},
+		{
+			name:   "wrong JSON type",
+			err:    decodeError(typeErr),
+			status: http.StatusBadRequest,
+			want:   errorResponse{Error: "quantity must be a JSON float64", Errors: []fieldError{{Path: "quantity", Code: codeInvalidType, Message: "quantity must be a JSON float64"}}},
+		},
+		{
+			name:   "conflict",
+			err:    &ingestError{status: http.StatusConflict, field: "status", code: codeNotQuarantined, err: errors.New("quarantined transaction is REPLAYED")},
+			status: http.StatusConflict,
+			want:   errorResponse{Error: "quarantined transaction is REPLAYED", Errors: []fieldError{{Path: "status", Code: codeNotQuarantined, Message: "quarantined transaction is REPLAYED"}}},
+		},
+		{
+			name:   "server error hides its detail",
+			err:    errors.New(`pq: relation "usage_transaction" does not exist`),
+			status: http.StatusInternalServerError,
+			want:   errorResponse{Error: "Internal Server Error", Errors: []fieldError{{Code: codeInternal, Message: "Internal Server Error"}}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			writeIngestError(rec, tt.err)
+			if rec.Code != tt.status {
+				t.Errorf("status = %d, want %d", rec.Code, tt.status)
+			}
+			if got := decodeErrorResponse(t, rec); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("body = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWriteErrorResponseWithoutFieldsAnswersAnEmptyList(t *testing.T) {
+	rec := httptest.NewRecorder()
+	writeErrorResponse(rec, http.StatusBadRequest, errors.New("bad request"))
+	if body := strings.TrimSpace(rec.Body.String()); body != `{"error":"bad request","errors":[]}` {
+		t.Errorf("body = %s, want an empty errors list", body)
+	}
+}
+
+func TestValidationFieldErrors(t *testing.T) {
+	type reading struct {
+		Quantity *float64 `json:"quantity" validate:"required"`
+		Unit     string   `json:"unit" validate:"oneof=KWH KW"`
+	}
+	type detail struct {
+		Start    int       `json:"service_period_start"`
+		End      int       `json:"service_period_end" validate:"gtfield=Start"`
+		Readings []reading `json:"quantities" validate:"min=1,dive"`
+		Note     string    `validate:"max=2"`
+	}
+	type transaction struct {
+		TransactionID string   `json:"transaction_id" validate:"required"`
+		Details       []detail `json:"product_transfer_details" validate:"dive"`
+	}
+	validate := validator.New()
+	validate.RegisterTagNameFunc(jsonFieldName)
+	err := validate.Struct(transaction{Details: []detail{
+		{Start: 1, End: 2, Readings: []reading{{Quantity: ptr(1.0), Unit: "KWH"}}},
+		{Start: 2, End: 1, Readings: []reading{{Unit: "KWH"}, {Quantity: ptr(1.0), Unit: "KH"}}, Note: "long"},
+		{Start: 1, End: 2},
+	}})
+	var validationErrors validator.ValidationErrors
+	if !errors.As(err, &validationErrors) {
+		t.Fatalf("validate = %v, want validation errors", err)
+	}
+	want := []fieldError{
+		{Path: "transaction_id", Code: codeRequired, Message: "transaction_id is required"},
+		{Path: "product_transfer_details[1].service_period_end", Code: codeInvalidValue, Message: "service_period_end must be later than Start"},
+		{Path: "product_transfer_details[1].quantities[0].quantity", Code: codeRequired, Message: "quantity is required"},
+		{Path: "product_transfer_details[1].quantities[1].unit", Code: codeInvalidValue, Message: "unit must be one of KWH KW"},
+		{Path: "product_transfer_details[1].Note", Code: codeInvalidValue, Message: "Note failed the max check"},
+		{Path: "product_transfer_details[2].quantities", Code: codeInvalidValue, Message: "quantities must have at least 1 entries"},
+	}
+	got := validationFieldErrors(validationErrors)
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("field errors =\n%+v\nwant\n%+v", got, want)
+	}
+}
@@ -0,0 +1,81 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	dbentity "usage-lakehouse/internal/db/entity"
+	"usage-lakehouse/internal/payloadarchive"
+	"usage-lakehouse/internal/repository"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+)
+
+type IngestionJobHandler struct {
+	repo repository.IngestionJobRepository
+}
+
+func NewIngestionJobHandler(repo repository.IngestionJobRepository) *IngestionJobHandler {
+	return &IngestionJobHandler{repo: repo}
+}
+
+func (h *IngestionJobHandler) GetIngestionJob(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+	job, err := h.repo.GetByID(r.Context(), id)
+	if errors.Is(err, pgx.ErrNoRows) {
+		writeIngestError(w, notFoundError("id", id))
+		return
+	}
+	if err != nil {
+		writeIngestError(w, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(job)
+}
+
+type ingestionJobSubmission struct {
+	JobID  string `json:"job_id"`
+	Status string `json:"status"`
+}
+
+// openJobPayload opens the archived request body a job was queued with.
+func openJobPayload(ctx context.Context, archive *payloadarchive.Archive, job *dbentity.IngestionJob) (io.ReadCloser, error) {
+	if job.RawPayloadSHA256 == nil {
+		return nil, errors.New("job has no archived payload")
+	}
+	_, body, err := archive.Open(ctx, *job.RawPayloadSHA256)
+	if err != nil {
+		return nil, fmt.Errorf("open archived payload %s: %w", *job.RawPayloadSHA256, err)
+	}
+	return body, nil
+}
+
+func isAsyncRequest(r *http.Request) bool {
+	return r.URL.Query().Get("async") == "true"
+}
+
+// submitIngestionJob queues a job of kind over the archived request body and answers 202 with the
+// job ID and a Location to poll. The job refers to the body by its SHA-256; the worker reads it back
+// from the archive.
+func submitIngestionJob(w http.ResponseWriter, r *http.Request, repo repository.IngestionJobRepository, kind string, params any, raw *dbentity.RawPayload) {
+	encodedParams, err := json.Marshal(params)
+	if err != nil {
+		writeIngestError(w, err)
+		return
+	}
+	job := dbentity.IngestionJob{ID: uuid.New().String(), Kind: kind, Params: encodedParams, RawPayloadSHA256: &raw.SHA256}
+	if err := repo.Create(r.Context(), &job); err != nil {
+		writeIngestError(w, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Location", "/jobs/"+job.ID)
+	w.WriteHeader(http.StatusAccepted)
+	json.NewEncoder(w).Encode(ingestionJobSubmission{JobID: job.ID, Status: job.Status})
+}
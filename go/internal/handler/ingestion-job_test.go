@@ -0,0 +1,40 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+	dbentity "usage-lakehouse/internal/db/entity"
+	"usage-lakehouse/internal/objectstore"
+	"usage-lakehouse/internal/payloadarchive"
+
+	"github.com/jackc/pgx/v5"
+)
+
+func TestOpenJobPayload(t *testing.T) {
+	ctx := context.Background()
+	archive := payloadarchive.New(objectstore.NewMemory(), &fakeRawPayloadRepository{payloads: map[string]dbentity.RawPayload{}})
+	p, err := archive.Put(ctx, "application/x-ndjson", strings.NewReader("{}\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	body, err := openJobPayload(ctx, archive, &dbentity.IngestionJob{RawPayloadSHA256: &p.SHA256})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer body.Close()
+	if got, _ := io.ReadAll(body); string(got) != "{}\n" {
+		t.Errorf("job payload = %q, want the archived body", got)
+	}
+
+	if _, err := openJobPayload(ctx, archive, &dbentity.IngestionJob{}); err == nil {
+		t.Error("opened the payload of a job without one")
+	}
+	missing := strings.Repeat("0", 64)
+	if _, err := openJobPayload(ctx, archive, &dbentity.IngestionJob{RawPayloadSHA256: &missing}); !errors.Is(err, pgx.ErrNoRows) {
+		t.Errorf("open of an unarchived payload = %v, want pgx.ErrNoRows", err)
+	}
+}
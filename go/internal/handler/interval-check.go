@@ -0,0 +1,109 @@
+package handler
+
+import (
+	"sort"
+	"time"
+	dbentity "usage-lakehouse/internal/db/entity"
+	"usage-lakehouse/internal/model"
+	"usage-lakehouse/internal/region"
+
+	"github.com/google/uuid"
+)
+
+// defaultIntervalLength applies to meters not yet in master data.
+const defaultIntervalLength = 15 * time.Minute
+
+func intervalLength(intervalMinutes map[string]int, meterName string) time.Duration {
+	if minutes, ok := intervalMinutes[meterName]; ok && minutes > 0 {
+		return time.Duration(minutes) * time.Minute
+	}
+	return defaultIntervalLength
+}
+
+type intervalSeriesKey struct {
+	meterName string
+	channel   string
+	start     time.Time
+	end       time.Time
+}
+
+// checkIntervals compares each interval detail series (the meter's interval detail loops for one
+// channel and service period) with the intervals the period should hold at the meter's interval
+// length. The period runs from ServicePeriodStart up to ServicePeriodEnd, so a series is complete with
+// one reading ending at every interval boundary after the start, up to and including the end.
+func checkIntervals(adapter region.Adapter, usageTransactionID string, premiseID string, powerRegionID string, isCanceled bool, input model.EDIUsageTransaction, types map[string]dbentity.PowerRegionUsageTransactionProductTransferDetailType, meterNameIDMap map[string]string, intervalMinutes map[string]int) []dbentity.MeterIntervalException {
+	series := make(map[intervalSeriesKey]map[time.Time]int)
+	var keys []intervalSeriesKey
+	for _, detail := range input.ProductTransferDetails {
+		t := types[string(detail.TransferType)]
+		if !t.Interval || !t.Meter || t.Summary || detail.Channel == nil {
+			continue
+		}
+		if adapter.Channel(*detail.Channel) == region.ChannelIgnored {
+			continue
+		}
+		var meterName string
+		if detail.MeterName != nil {
+			meterName = *detail.MeterName
+		}
+		key := intervalSeriesKey{meterName: meterName, channel: *detail.Channel, start: detail.ServicePeriodStart.UTC(), end: detail.ServicePeriodEnd.UTC()}
+		ends, ok := series[key]
+		if !ok {
+			ends = make(map[time.Time]int)
+			series[key] = ends
+			keys = append(keys, key)
+		}
+		if detail.Quantities != nil {
+			for _, q := range *detail.Quantities {
+				ends[q.IntervalEnd.UTC()]++
+			}
+		}
+	}
+
+	var exceptions []dbentity.MeterIntervalException
+	for _, key := range keys {
+		ends := series[key]
+		length := intervalLength(intervalMinutes, key.meterName)
+		exception := func(kind string, end time.Time, reported int) dbentity.MeterIntervalException {
+			return dbentity.MeterIntervalException{
+				ID:                 uuid.New().String(),
+				UsageTransactionID: usageTransactionID,
+				PremiseID:          premiseID,
+				PowerRegionID:      powerRegionID,
+				MeterID:            meterIDFor(meterNameIDMap, key.meterName),
+				MeterName:          key.meterName,
+				Channel:            key.channel,
+				Kind:               kind,
+				Start:              end.Add(-length),
+				End:                end,
+				ServicePeriodStart: key.start.In(adapter.Location()),
+				ServicePeriodEnd:   key.end.In(adapter.Location()),
+				ReportedCount:      reported,
+				IsCanceled:         isCanceled,
+			}
+		}
+		reported := make([]time.Time, 0, len(ends))
+		for end := range ends {
+			reported = append(reported, end)
+		}
+		sort.Slice(reported, func(i, j int) bool { return reported[i].Before(reported[j]) })
+		for _, end := range reported {
+			count := ends[end]
+			switch {
+			case !end.After(key.start) || end.After(key.end) || end.Sub(key.start)%length != 0:
+				exceptions = append(exceptions, exception(dbentity.MeterIntervalExceptionOutOfRange, end, count))
+			case count > 1:
+				exceptions = append(exceptions, exception(dbentity.MeterIntervalExceptionDuplicate, end, count))
+			}
+		}
+		if !key.end.After(key.start) {
+			continue
+		}
+		for end := key.start.Add(length); !end.After(key.end); end = end.Add(length) {
+			if ends[end] == 0 {
+				exceptions = append(exceptions, exception(dbentity.MeterIntervalExceptionGap, end, 0))
+			}
+		}
+	}
+	return exceptions
+}
@@ -0,0 +1,147 @@
+package handler
+
+import (
+	"testing"
+	"time"
+	dbentity "usage-lakehouse/internal/db/entity"
+	"usage-lakehouse/internal/model"
+	"usage-lakehouse/internal/region"
+)
+
+// intervalDetail is an interval detail over start to end with a reading ending at each of ends.
+func intervalDetail(meter string, channel string, start time.Time, end time.Time, ends ...time.Time) model.ProductTransferDetail {
+	detail := readingDetail("PM", meter, channel, start, end)
+	readings := make([]model.QuantityDelivered, 0, len(ends))
+	for _, e := range ends {
+		readings = append(readings, model.QuantityDelivered{Quantity: 1, IntervalEnd: e})
+	}
+	detail.Quantities = &readings
+	return detail
+}
+
+// everyInterval answers the interval ends from start up to and including end, step apart.
+func everyInterval(start time.Time, end time.Time, step time.Duration) []time.Time {
+	var ends []time.Time
+	for e := start.Add(step); !e.After(end); e = e.Add(step) {
+		ends = append(ends, e)
+	}
+	return ends
+}
+
+func TestCheckIntervals(t *testing.T) {
+	ercot, _ := region.Lookup("ERCOT")
+	start := time.Date(2024, 2, 1, 6, 0, 0, 0, time.UTC)
+	end := start.Add(time.Hour)
+	at := func(minutes int) time.Time {
+		return start.Add(time.Duration(minutes) * time.Minute)
+	}
+	// The 2024 fall-back day in Central time runs 25 hours, 100 intervals of 15 minutes.
+	fallBackStart := time.Date(2024, 11, 3, 5, 0, 0, 0, time.UTC)
+	fallBackEnd := time.Date(2024, 11, 4, 6, 0, 0, 0, time.UTC)
+	fallBack := everyInterval(fallBackStart, fallBackEnd, 15*time.Minute)
+	repeatedHour := time.Date(2024, 11, 3, 7, 15, 0, 0, time.UTC) // 01:15 CST, the second 01:15 of the day.
+	var fallBackMissingOne []time.Time
+	for _, e := range fallBack {
+		if !e.Equal(repeatedHour) {
+			fallBackMissingOne = append(fallBackMissingOne, e)
+		}
+	}
+
+	type want struct {
+		kind     string
+		meter    string
+		channel  string
+		end      time.Time
+		reported int
+	}
+	tests := []struct {
+		name    string
+		details []model.ProductTransferDetail
+		minutes map[string]int
+		want    []want
+	}{
+		{
+			name:    "complete series",
+			details: []model.ProductTransferDetail{intervalDetail("M1", "1", start, end, at(15), at(30), at(45), at(60))},
+		},
+		{
+			name:    "gap",
+			details: []model.ProductTransferDetail{intervalDetail("M1", "1", start, end, at(15), at(30), at(60))},
+			want:    []want{{dbentity.MeterIntervalExceptionGap, "M1", "1", at(45), 0}},
+		},
+		{
+			name:    "duplicate",
+			details: []model.ProductTransferDetail{intervalDetail("M1", "1", start, end, at(15), at(15), at(15), at(30), at(45), at(60))},
+			want:    []want{{dbentity.MeterIntervalExceptionDuplicate, "M1", "1", at(15), 3}},
+		},
+		{
+			name:    "readings outside the period or off the interval boundaries",
+			details: []model.ProductTransferDetail{intervalDetail("M1", "1", start, end, at(0), at(15), at(20), at(30), at(45), at(60), at(75))},
+			want: []want{
+				{dbentity.MeterIntervalExceptionOutOfRange, "M1", "1", at(0), 1},
+				{dbentity.MeterIntervalExceptionOutOfRange, "M1", "1", at(20), 1},
+				{dbentity.MeterIntervalExceptionOutOfRange, "M1", "1", at(75), 1},
+			},
+		},
+		{
+			name:    "series split across detail loops",
+			details: []model.ProductTransferDetail{intervalDetail("M1", "1", start, end, at(15), at(30)), intervalDetail("M1", "1", start, end, at(45), at(60))},
+		},
+		{
+			name:    "channels are separate series",
+			details: []model.ProductTransferDetail{intervalDetail("M1", "1", start, end, at(15), at(30), at(45), at(60)), intervalDetail("M1", "4", start, end, at(15), at(30), at(45))},
+			want:    []want{{dbentity.MeterIntervalExceptionGap, "M1", "4", at(60), 0}},
+		},
+		{
+			name:    "meter interval length from master data",
+			details: []model.ProductTransferDetail{intervalDetail("M1", "1", start, end, at(30), at(60))},
+			minutes: map[string]int{"M1": 30},
+		},
+		{
+			name:    "unknown meter at the default interval length",
+			details: []model.ProductTransferDetail{intervalDetail("M9", "1", start, end, at(30), at(60))},
+			minutes: map[string]int{"M1": 30},
+			want:    []want{{dbentity.MeterIntervalExceptionGap, "M9", "1", at(15), 0}, {dbentity.MeterIntervalExceptionGap, "M9", "1", at(45), 0}},
+		},
+		{
+			name:    "ignored channels and summaries are not checked",
+			details: []model.ProductTransferDetail{intervalDetail("M1", "KH", start, end, at(15)), readingDetail("BO", "M1", "1", start, end, 10)},
+		},
+		{
+			name:    "empty period",
+			details: []model.ProductTransferDetail{intervalDetail("M1", "1", start, start)},
+		},
+		{
+			name:    "complete fall-back day",
+			details: []model.ProductTransferDetail{intervalDetail("M1", "1", fallBackStart, fallBackEnd, fallBack...)},
+		},
+		{
+			name:    "gap in the repeated hour",
+			details: []model.ProductTransferDetail{intervalDetail("M1", "1", fallBackStart, fallBackEnd, fallBackMissingOne...)},
+			want:    []want{{dbentity.MeterIntervalExceptionGap, "M1", "1", repeatedHour, 0}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			exceptions := checkIntervals(ercot, "ut-1", "premise-1", "pr-ercot", false, model.EDIUsageTransaction{ProductTransferDetails: tt.details}, testTransferDetailTypes, map[string]string{"M1": "meter-1"}, tt.minutes)
+			if len(exceptions) != len(tt.want) {
+				t.Fatalf("got %d exceptions, want %d: %+v", len(exceptions), len(tt.want), exceptions)
+			}
+			for i, w := range tt.want {
+				e := exceptions[i]
+				if e.Kind != w.kind || e.MeterName != w.meter || e.Channel != w.channel || !e.End.Equal(w.end) || e.ReportedCount != w.reported {
+					t.Errorf("exception %d = %s %s/%s ending %s reported %d, want %s %s/%s ending %s reported %d", i, e.Kind, e.MeterName, e.Channel, e.End, e.ReportedCount, w.kind, w.meter, w.channel, w.end, w.reported)
+				}
+				if length := e.End.Sub(e.Start); length != intervalLength(tt.minutes, w.meter) {
+					t.Errorf("exception %d spans %s, want the meter's interval length", i, length)
+				}
+				if wantID := w.meter == "M1"; (e.MeterID != nil) != wantID || (wantID && *e.MeterID != "meter-1") {
+					t.Errorf("exception %d meter_id = %v", i, e.MeterID)
+				}
+				if e.UsageTransactionID != "ut-1" || e.PremiseID != "premise-1" || e.PowerRegionID != "pr-ercot" || e.ServicePeriodStart.Location() != ercot.Location() {
+					t.Errorf("exception %d = %+v, want it for ut-1 at premise-1 with its service period in %s", i, e, ercot.Location())
+				}
+			}
+		})
+	}
+}
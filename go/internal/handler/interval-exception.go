@@ -0,0 +1,85 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+	dbentity "usage-lakehouse/internal/db/entity"
+	"usage-lakehouse/internal/repository"
+)
+
+var intervalExceptionKinds = map[string]bool{
+	dbentity.MeterIntervalExceptionGap:        true,
+	dbentity.MeterIntervalExceptionDuplicate:  true,
+	dbentity.MeterIntervalExceptionOutOfRange: true,
+}
+
+type IntervalExceptionHandler struct {
+	repo repository.MeterIntervalExceptionRepository
+}
+
+func NewIntervalExceptionHandler(repo repository.MeterIntervalExceptionRepository) *IntervalExceptionHandler {
+	return &IntervalExceptionHandler{repo: repo}
+}
+
+// ListIncompleteMeters reports the meters with open gaps, duplicates or out-of-range intervals starting
+// within a from/to (YYYY-MM-DD, local to the meter's power region) window, filtered by premise_id.
+func (h *IntervalExceptionHandler) ListIncompleteMeters(w http.ResponseWriter, r *http.Request) {
+	filter, ok := intervalExceptionFilter(w, r)
+	if !ok {
+		return
+	}
+	meters, err := h.repo.ListIncompleteMeters(r.Context(), filter)
+	if err != nil {
+		writeIngestError(w, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(meters)
+}
+
+// ListIntervalExceptions lists the open exceptions themselves, additionally filtered by meter_id and kind.
+func (h *IntervalExceptionHandler) ListIntervalExceptions(w http.ResponseWriter, r *http.Request) {
+	filter, ok := intervalExceptionFilter(w, r)
+	if !ok {
+		return
+	}
+	exceptions, err := h.repo.List(r.Context(), filter)
+	if err != nil {
+		writeIngestError(w, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(exceptions)
+}
+
+func intervalExceptionFilter(w http.ResponseWriter, r *http.Request) (repository.MeterIntervalExceptionFilter, bool) {
+	query := r.URL.Query()
+	filter := repository.MeterIntervalExceptionFilter{Limit: defaultListLimit}
+	if premiseID := query.Get("premise_id"); premiseID != "" {
+		filter.PremiseID = &premiseID
+	}
+	if meterID := query.Get("meter_id"); meterID != "" {
+		filter.MeterID = &meterID
+	}
+	if kind := query.Get("kind"); kind != "" {
+		if !intervalExceptionKinds[kind] {
+			writeIngestError(w, paramError("kind", codeInvalidValue, "kind must be GAP, DUPLICATE or OUT_OF_RANGE"))
+			return filter, false
+		}
+		filter.Kind = &kind
+	}
+	var err error
+	if filter.From, err = parseDateParam("from", query.Get("from")); err != nil {
+		writeIngestError(w, err)
+		return filter, false
+	}
+	if filter.To, err = parseDateParam("to", query.Get("to")); err != nil {
+		writeIngestError(w, err)
+		return filter, false
+	}
+	if filter.Limit, err = parseLimitParam(query.Get("limit")); err != nil {
+		writeIngestError(w, err)
+		return filter, false
+	}
+	return filter, true
+}
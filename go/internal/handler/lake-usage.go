@@ -0,0 +1,258 @@
+package handler
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+	dbentity "usage-lakehouse/internal/db/entity"
+	"usage-lakehouse/internal/iceberg"
+	"usage-lakehouse/internal/jobs"
+	"usage-lakehouse/internal/lake"
+	"usage-lakehouse/internal/model"
+	"usage-lakehouse/internal/payloadarchive"
+	"usage-lakehouse/internal/repository"
+	"usage-lakehouse/internal/uom"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-playground/validator/v10"
+)
+
+type LakeUsageHandler struct {
+	usage            *lake.Writer[model.UsageRow]
+	ingestionJobRepo repository.IngestionJobRepository
+	rawPayloadRepo   repository.RawPayloadRepository
+	archive          *payloadarchive.Archive
+	validate         *validator.Validate
+}
+
+type usageUploadRequest struct {
+	Data []model.UsageData `json:"data" validate:"required,min=1,dive"`
+}
+
+// usageUploadResponse lists the keys of the parquet files written, one per partition.
+type usageUploadResponse struct {
+	Message    string   `json:"message"`
+	Objects    []string `json:"objects"`
+	SnapshotID int64    `json:"snapshot_id,omitempty"`
+}
+
+type lakeUsageJobParams struct {
+	AccountID string `json:"account_id"`
+	Format    string `json:"format"`
+}
+
+type lakeUsageJobOutput struct {
+	Objects    []string `json:"objects"`
+	SnapshotID int64    `json:"snapshot_id"`
+}
+
+// NewLakeUsageHandler appends uploads to the lake's interval usage table through usage.
+func NewLakeUsageHandler(usage *lake.Writer[model.UsageRow], ingestionJobRepo repository.IngestionJobRepository, rawPayloadRepo repository.RawPayloadRepository, archive *payloadarchive.Archive) *LakeUsageHandler {
+	validate := validator.New()
+	validate.RegisterTagNameFunc(jsonFieldName)
+	validate.RegisterValidation("unit_of_measure", func(fl validator.FieldLevel) bool {
+		return uom.Valid(fl.Field().String())
+	})
+	return &LakeUsageHandler{usage: usage, ingestionJobRepo: ingestionJobRepo, rawPayloadRepo: rawPayloadRepo, archive: archive, validate: validate}
+}
+
+// usageCSVColumns are the columns a CSV upload may have, in any order, named as in the JSON upload.
+var usageCSVColumns = map[string]bool{
+	"meter_code":            true,
+	"premise_code":          false,
+	"power_region":          false,
+	"channel":               false,
+	"interval_start":        true,
+	"interval_end":          true,
+	"unit_of_measure":       true,
+	"quantity":              true,
+	"quality_flag":          false,
+	"source_transaction_id": false,
+}
+
+// parseUsageCSV reads a CSV upload with a header row. Errors name the row as data[i], counting from
+// the first row after the header, so they line up with the JSON upload's paths.
+func parseUsageCSV(r io.Reader) ([]model.UsageData, error) {
+	reader := csv.NewReader(r)
+	header, err := reader.Read()
+	if err != nil {
+		return nil, &ingestError{status: http.StatusBadRequest, code: codeMalformedRequest, err: fmt.Errorf("read CSV header: %w", err)}
+	}
+	columns := map[string]int{}
+	for i, name := range header {
+		name = strings.TrimSpace(name)
+		if _, ok := usageCSVColumns[name]; !ok {
+			return nil, &ingestError{status: http.StatusBadRequest, code: codeInvalidValue, path: name, err: fmt.Errorf("unknown CSV column %s", name)}
+		}
+		columns[name] = i
+	}
+	for name, required := range usageCSVColumns {
+		if _, ok := columns[name]; required && !ok {
+			return nil, &ingestError{status: http.StatusBadRequest, code: codeRequired, path: name, err: fmt.Errorf("CSV column %s is required", name)}
+		}
+	}
+
+	var data []model.UsageData
+	for row := 0; ; row++ {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, &ingestError{status: http.StatusBadRequest, code: codeMalformedRequest, path: fmt.Sprintf("data[%d]", row), err: err}
+		}
+		value := func(name string) string {
+			if i, ok := columns[name]; ok {
+				return strings.TrimSpace(record[i])
+			}
+			return ""
+		}
+		optional := func(name string) *string {
+			if v := value(name); v != "" {
+				return &v
+			}
+			return nil
+		}
+		invalid := func(name string, format string) error {
+			return &ingestError{status: http.StatusBadRequest, code: codeInvalidFormat, path: fmt.Sprintf("data[%d].%s", row, name), err: fmt.Errorf("%s %q is not %s", name, value(name), format)}
+		}
+		usage := model.UsageData{
+			MeterCode:           value("meter_code"),
+			PremiseCode:         optional("premise_code"),
+			PowerRegion:         optional("power_region"),
+			Channel:             optional("channel"),
+			UnitOfMeasure:       value("unit_of_measure"),
+			QualityFlag:         optional("quality_flag"),
+			SourceTransactionID: optional("source_transaction_id"),
+		}
+		if usage.IntervalStart, err = time.Parse(time.RFC3339, value("interval_start")); err != nil {
+			return nil, invalid("interval_start", "an RFC 3339 timestamp")
+		}
+		if usage.IntervalEnd, err = time.Parse(time.RFC3339, value("interval_end")); err != nil {
+			return nil, invalid("interval_end", "an RFC 3339 timestamp")
+		}
+		if v := value("quantity"); v != "" {
+			quantity, err := strconv.ParseFloat(v, 64)
+			if err != nil {
+				return nil, invalid("quantity", "a number")
+			}
+			usage.Quantity = &quantity
+		}
+		data = append(data, usage)
+	}
+	return data, nil
+}
+
+func (h *LakeUsageHandler) UploadUsage(w http.ResponseWriter, r *http.Request) {
+	accountId := chi.URLParam(r, "account_id")
+	if accountId == "" {
+		writeIngestError(w, paramError("account_id", codeRequired, "account_id is required in the path"))
+		return
+	}
+
+	format := r.URL.Query().Get("format")
+	contentType := "application/json"
+	if format == "csv" {
+		contentType = "text/csv"
+	}
+	body, raw, ok := archiveRequestBody(w, r, h.archive, contentType)
+	if !ok {
+		return
+	}
+	defer body.Close()
+	if isAsyncRequest(r) {
+		submitIngestionJob(w, r, h.ingestionJobRepo, dbentity.IngestionJobKindLakeUsage, lakeUsageJobParams{AccountID: accountId, Format: format}, raw)
+		return
+	}
+	data, err := h.parseUsageUpload(format, body)
+	if err != nil {
+		writeErrorResponse(w, http.StatusBadRequest, err)
+		return
+	}
+	keys, snapshot, err := h.writeUsage(r.Context(), accountId, data, raw.SHA256)
+	if err != nil {
+		writeIngestError(w, err)
+		return
+	}
+
+	response := usageUploadResponse{
+		Message:    "Data appended to the lake usage table",
+		Objects:    keys,
+		SnapshotID: snapshot.SnapshotID,
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// ProcessIngestionJob runs a LAKE_USAGE job queued by UploadUsage with ?async=true.
+func (h *LakeUsageHandler) ProcessIngestionJob(ctx context.Context, job *dbentity.IngestionJob) (jobs.Result, error) {
+	var params lakeUsageJobParams
+	if err := json.Unmarshal(job.Params, &params); err != nil {
+		return jobs.Result{}, fmt.Errorf("decode job params: %w", err)
+	}
+	body, err := openJobPayload(ctx, h.archive, job)
+	if err != nil {
+		return jobs.Result{}, err
+	}
+	defer body.Close()
+	data, err := h.parseUsageUpload(params.Format, body)
+	if err != nil {
+		if errs := fieldErrors(err); errs != nil {
+			return jobs.Result{Errors: jobErrors(0, "", err.Error(), errs)}, errors.New("upload failed validation")
+		}
+		return jobs.Result{}, err
+	}
+	keys, snapshot, err := h.writeUsage(ctx, params.AccountID, data, *job.RawPayloadSHA256)
+	if err != nil {
+		return jobs.Result{Processed: len(data), Failed: len(data)}, err
+	}
+	return jobs.Result{Processed: len(data), Succeeded: len(data), Output: lakeUsageJobOutput{Objects: keys, SnapshotID: snapshot.SnapshotID}}, nil
+}
+
+// parseUsageUpload reads and validates a CSV or JSON upload.
+func (h *LakeUsageHandler) parseUsageUpload(format string, r io.Reader) ([]model.UsageData, error) {
+	var upload usageUploadRequest
+	if format == "csv" {
+		data, err := parseUsageCSV(r)
+		if err != nil {
+			return nil, err
+		}
+		upload.Data = data
+	} else if err := json.NewDecoder(r).Decode(&upload); err != nil {
+		return nil, decodeError(err)
+	}
+	if err := h.validate.Struct(upload); err != nil {
+		return nil, err
+	}
+	return upload.Data, nil
+}
+
+// writeUsage appends data to the lake, one parquet file per partition, and records which archived
+// upload each file came from.
+func (h *LakeUsageHandler) writeUsage(ctx context.Context, accountId string, data []model.UsageData, rawPayloadSHA256 string) ([]string, *iceberg.Snapshot, error) {
+	rows := make([]model.UsageRow, 0, len(data))
+	for _, usage := range data {
+		rows = append(rows, model.NewUsageRow(accountId, usage))
+	}
+	files, snapshot, err := h.usage.Write(ctx, rows, nil)
+	if err != nil {
+		return nil, nil, err
+	}
+	keys := make([]string, 0, len(files))
+	for _, f := range files {
+		lakeObject := dbentity.LakeObject{ObjectKey: f.Key, AccountID: accountId, RawPayloadSHA256: rawPayloadSHA256, RowCount: f.RowCount}
+		if err := h.rawPayloadRepo.CreateLakeObject(ctx, &lakeObject); err != nil {
+			return nil, nil, err
+		}
+		keys = append(keys, f.Key)
+	}
+	return keys, snapshot, nil
+}
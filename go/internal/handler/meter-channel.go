@@ -0,0 +1,98 @@
+package handler
+
+import (
+	"sort"
+	"time"
+	dbentity "usage-lakehouse/internal/db/entity"
+	"usage-lakehouse/internal/model"
+	"usage-lakehouse/internal/uom"
+)
+
+type channelIntervalKey struct {
+	meterID string
+	channel string
+	end     time.Time
+}
+
+// collectChannelIntervals keeps the reading of every channel in the transaction's interval detail
+// loops, registered or not, so that demand, reactive and apparent channels are stored alongside kWh.
+// A unit sent with the readings takes precedence over the channel's registered unit. Readings of
+// meters not in master data are skipped, as they are for meter_usage_15_minute.
+func collectChannelIntervals(usageTransactionID string, premiseID string, powerRegionID string, input model.EDIUsageTransaction, types map[string]dbentity.PowerRegionUsageTransactionProductTransferDetailType, channels map[string]dbentity.PowerRegionChannel, meterNameIDMap map[string]string, intervalMinutes map[string]int) []dbentity.MeterUsageChannelInterval {
+	readings := make(map[channelIntervalKey]*dbentity.MeterUsageChannelInterval)
+	for _, detail := range input.ProductTransferDetails {
+		t := types[string(detail.TransferType)]
+		if !t.Interval || !t.Meter || t.Summary || detail.Channel == nil || detail.Quantities == nil || detail.MeterName == nil {
+			continue
+		}
+		meterID, ok := meterNameIDMap[*detail.MeterName]
+		if !ok {
+			continue
+		}
+		unit, direction := readingUnit(detail, *detail.Channel, channels)
+		length := intervalLength(intervalMinutes, *detail.MeterName)
+		for _, q := range *detail.Quantities {
+			end := q.IntervalEnd.UTC()
+			key := channelIntervalKey{meterID: meterID, channel: *detail.Channel, end: end}
+			if reading, ok := readings[key]; ok {
+				reading.Quantity += q.Quantity
+				continue
+			}
+			readings[key] = &dbentity.MeterUsageChannelInterval{
+				MeterID:            meterID,
+				Channel:            *detail.Channel,
+				PowerRegionID:      powerRegionID,
+				PremiseID:          premiseID,
+				UsageTransactionID: usageTransactionID,
+				Start:              end.Add(-length),
+				End:                end,
+				UnitOfMeasure:      unit,
+				Direction:          direction,
+				Quantity:           q.Quantity,
+			}
+		}
+	}
+	intervals := make([]dbentity.MeterUsageChannelInterval, 0, len(readings))
+	for _, reading := range readings {
+		intervals = append(intervals, *reading)
+	}
+	sort.Slice(intervals, func(i, j int) bool {
+		a, b := intervals[i], intervals[j]
+		if a.MeterID != b.MeterID {
+			return a.MeterID < b.MeterID
+		}
+		if a.Channel != b.Channel {
+			return a.Channel < b.Channel
+		}
+		return a.Start.Before(b.Start)
+	})
+	return intervals
+}
+
+// readingUnit is the unit and direction of a detail's readings on channel. A unit sent with the
+// readings takes precedence over the channel's registered unit; either is nil when not known.
+func readingUnit(detail model.ProductTransferDetail, channel string, channels map[string]dbentity.PowerRegionChannel) (unit *string, direction *string) {
+	if registered, ok := channels[channel]; ok {
+		unit, direction = &registered.UnitOfMeasure, &registered.Direction
+	}
+	if detail.UnitOfMeasure != nil {
+		unit = detail.UnitOfMeasure
+	}
+	return unit, direction
+}
+
+// kilowattHours converts a reading to kWh. It is false for readings that are not active energy, such
+// as demand or reactive energy. A reading without a unit is taken to be kWh.
+func kilowattHours(quantity float64, unit *string) (float64, bool) {
+	if unit == nil {
+		return quantity, true
+	}
+	if !uom.IsEnergy(*unit) {
+		return 0, false
+	}
+	kwh, err := uom.Convert(quantity, *unit, "KWH", 0)
+	if err != nil {
+		return 0, false
+	}
+	return kwh, true
+}
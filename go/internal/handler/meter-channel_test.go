@@ -0,0 +1,121 @@
+package handler
+
+import (
+	"testing"
+	"time"
+	dbentity "usage-lakehouse/internal/db/entity"
+	"usage-lakehouse/internal/model"
+)
+
+func TestCollectChannelIntervals(t *testing.T) {
+	start := time.Date(2024, 2, 1, 6, 0, 0, 0, time.UTC)
+	end := start.Add(30 * time.Minute)
+	channels := map[string]dbentity.PowerRegionChannel{
+		"1": {Code: "1", UnitOfMeasure: "KWH", Direction: "CONSUMPTION"},
+		"2": {Code: "2", UnitOfMeasure: "KW", Direction: "CONSUMPTION"},
+	}
+	inWh := readingDetail("PM", "M1", "1", start, end, 500, 600)
+	inWh.UnitOfMeasure = ptr("WH")
+	type want struct {
+		meterID   string
+		channel   string
+		end       time.Time
+		unit      string
+		direction string
+		quantity  float64
+	}
+	tests := []struct {
+		name    string
+		details []model.ProductTransferDetail
+		minutes map[string]int
+		want    []want
+	}{
+		{
+			name:    "registered channels keep their unit and direction",
+			details: []model.ProductTransferDetail{readingDetail("PM", "M1", "2", start, end, 4), readingDetail("PM", "M1", "1", start, end, 1, 2)},
+			want: []want{
+				{"meter-1", "1", start.Add(15 * time.Minute), "KWH", "CONSUMPTION", 1},
+				{"meter-1", "1", end, "KWH", "CONSUMPTION", 2},
+				{"meter-1", "2", start.Add(15 * time.Minute), "KW", "CONSUMPTION", 4},
+			},
+		},
+		{
+			name:    "unregistered channels are kept without a unit",
+			details: []model.ProductTransferDetail{readingDetail("PM", "M1", "K3", start, end, 7)},
+			want:    []want{{"meter-1", "K3", start.Add(15 * time.Minute), "", "", 7}},
+		},
+		{
+			name:    "a unit sent with the readings takes precedence",
+			details: []model.ProductTransferDetail{inWh},
+			want: []want{
+				{"meter-1", "1", start.Add(15 * time.Minute), "WH", "CONSUMPTION", 500},
+				{"meter-1", "1", end, "WH", "CONSUMPTION", 600},
+			},
+		},
+		{
+			name:    "readings of the same interval are summed",
+			details: []model.ProductTransferDetail{readingDetail("PM", "M1", "1", start, end, 1), readingDetail("PM", "M1", "1", start, end, 2.5)},
+			want:    []want{{"meter-1", "1", start.Add(15 * time.Minute), "KWH", "CONSUMPTION", 3.5}},
+		},
+		{
+			name:    "meter interval length from master data",
+			details: []model.ProductTransferDetail{readingDetail("PM", "M1", "1", start, end, 1)},
+			minutes: map[string]int{"M1": 5},
+			want:    []want{{"meter-1", "1", start.Add(15 * time.Minute), "KWH", "CONSUMPTION", 1}},
+		},
+		{
+			name: "unknown meters, summaries and details without a channel are skipped",
+			details: []model.ProductTransferDetail{
+				readingDetail("PM", "M9", "1", start, end, 1),
+				readingDetail("BO", "M1", "1", start, end, 10),
+				readingDetail("PM", "M1", "", start, end, 1),
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			intervals := collectChannelIntervals("ut-1", "premise-1", "pr-ercot", model.EDIUsageTransaction{ProductTransferDetails: tt.details}, testTransferDetailTypes, channels, map[string]string{"M1": "meter-1"}, tt.minutes)
+			if len(intervals) != len(tt.want) {
+				t.Fatalf("got %d intervals, want %d: %+v", len(intervals), len(tt.want), intervals)
+			}
+			for i, w := range tt.want {
+				got := intervals[i]
+				var unit, direction string
+				if got.UnitOfMeasure != nil {
+					unit = *got.UnitOfMeasure
+				}
+				if got.Direction != nil {
+					direction = *got.Direction
+				}
+				if got.MeterID != w.meterID || got.Channel != w.channel || !got.End.Equal(w.end) || unit != w.unit || direction != w.direction || got.Quantity != w.quantity {
+					t.Errorf("interval %d = %s/%s ending %s %v %s %s, want %s/%s ending %s %v %s %s", i, got.MeterID, got.Channel, got.End, got.Quantity, unit, direction, w.meterID, w.channel, w.end, w.quantity, w.unit, w.direction)
+				}
+				if got.End.Sub(got.Start) != intervalLength(tt.minutes, "M1") || got.UsageTransactionID != "ut-1" || got.PremiseID != "premise-1" || got.PowerRegionID != "pr-ercot" {
+					t.Errorf("interval %d = %+v", i, got)
+				}
+			}
+		})
+	}
+}
+
+func TestKilowattHours(t *testing.T) {
+	tests := []struct {
+		quantity float64
+		unit     *string
+		want     float64
+		ok       bool
+	}{
+		{2, nil, 2, true},
+		{2, ptr("KWH"), 2, true},
+		{2500, ptr("WH"), 2.5, true},
+		{2, ptr("KW"), 0, false},
+		{2, ptr("KVARH"), 0, false},
+		{2, ptr("KH"), 0, false},
+	}
+	for _, tt := range tests {
+		got, ok := kilowattHours(tt.quantity, tt.unit)
+		if got != tt.want || ok != tt.ok {
+			t.Errorf("kilowattHours(%v, %v) = %v, %v, want %v, %v", tt.quantity, tt.unit, got, ok, tt.want, tt.ok)
+		}
+	}
+}
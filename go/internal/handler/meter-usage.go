@@ -0,0 +1,281 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+	dbentity "usage-lakehouse/internal/db/entity"
+	"usage-lakehouse/internal/repository"
+	"usage-lakehouse/internal/uom"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/jackc/pgx/v5"
+)
+
+// maxMeterUsageDays bounds one interval query to a year of 15-minute reads.
+const maxMeterUsageDays = 366
+
+type MeterUsageHandler struct {
+	meterRepo       repository.MeterRepository
+	powerRegionRepo repository.PowerRegionRepository
+	meterUsageRepo  repository.MeterUsageRepository
+}
+
+func NewMeterUsageHandler(meterRepo repository.MeterRepository, powerRegionRepo repository.PowerRegionRepository, meterUsageRepo repository.MeterUsageRepository) *MeterUsageHandler {
+	return &MeterUsageHandler{meterRepo: meterRepo, powerRegionRepo: powerRegionRepo, meterUsageRepo: meterUsageRepo}
+}
+
+type meterUsageResponse struct {
+	MeterID   string                       `json:"meter_id"`
+	TimeZone  string                       `json:"time_zone"`
+	Days      []meterUsageDay              `json:"days"`
+	Intervals []meterUsageInterval         `json:"intervals"`
+	VEEFlags  []dbentity.MeterUsageVEEFlag `json:"vee_flags"`
+}
+
+// meterUsageDay is one local calendar day: 23, 24 or 25 hours long depending on DST.
+type meterUsageDay struct {
+	Date              string `json:"date"`
+	Hours             int    `json:"hours"`
+	ExpectedIntervals int    `json:"expected_intervals"`
+	Intervals         int    `json:"intervals"`
+}
+
+type meterUsageInterval struct {
+	StartUTC            time.Time `json:"start_utc"`
+	EndUTC              time.Time `json:"end_utc"`
+	StartLocal          time.Time `json:"start_local"`
+	EndLocal            time.Time `json:"end_local"`
+	LocalDate           string    `json:"local_date"`
+	Consumption         *float64  `json:"consumption"`
+	Generation          *float64  `json:"generation"`
+	IsCanceled          bool      `json:"is_canceled"`
+	AccountID           string    `json:"account_id"`
+	UsageTransactionID  *string   `json:"usage_transaction_id,omitempty"`
+	Quality             string    `json:"quality"`
+	QualityRule         *string   `json:"quality_rule,omitempty"`
+	OriginalConsumption *float64  `json:"original_consumption,omitempty"`
+}
+
+// GetMeterUsage returns the meter's 15-minute intervals for the local days from..to (YYYY-MM-DD,
+// inclusive) in its power region's time zone, with each interval in both UTC and local prevailing time
+// and its VEE quality, and the VEE flags raised over those days.
+func (h *MeterUsageHandler) GetMeterUsage(w http.ResponseWriter, r *http.Request) {
+	meter, loc, start, end, ok := loadMeterDays(w, r, h.meterRepo, h.powerRegionRepo)
+	if !ok {
+		return
+	}
+	intervals, err := h.meterUsageRepo.ListIntervals(r.Context(), meter.ID, start, end)
+	if err != nil {
+		writeIngestError(w, err)
+		return
+	}
+
+	flags, err := h.meterUsageRepo.ListVEEFlags(r.Context(), meter.ID, start, end)
+	if err != nil {
+		writeIngestError(w, err)
+		return
+	}
+
+	response := meterUsageResponse{MeterID: meter.ID, TimeZone: loc.String(), Days: []meterUsageDay{}, Intervals: []meterUsageInterval{}, VEEFlags: []dbentity.MeterUsageVEEFlag{}}
+	response.VEEFlags = append(response.VEEFlags, flags...)
+	counts := make(map[string]int)
+	for _, i := range intervals {
+		startLocal := i.Start.In(loc)
+		localDate := startLocal.Format(time.DateOnly)
+		counts[localDate]++
+		response.Intervals = append(response.Intervals, meterUsageInterval{
+			StartUTC:            i.Start.UTC(),
+			EndUTC:              i.End.UTC(),
+			StartLocal:          startLocal,
+			EndLocal:            i.End.In(loc),
+			LocalDate:           localDate,
+			Consumption:         i.Consumption,
+			Generation:          i.Generation,
+			IsCanceled:          i.IsCanceled,
+			AccountID:           i.AccountID,
+			UsageTransactionID:  i.UsageTransactionID,
+			Quality:             i.Quality,
+			QualityRule:         i.QualityRule,
+			OriginalConsumption: i.OriginalConsumption,
+		})
+	}
+	for day := start; day.Before(end); {
+		next := time.Date(day.Year(), day.Month(), day.Day()+1, 0, 0, 0, 0, loc)
+		hours := int(next.Sub(day) / time.Hour)
+		date := day.Format(time.DateOnly)
+		response.Days = append(response.Days, meterUsageDay{Date: date, Hours: hours, ExpectedIntervals: hours * 4, Intervals: counts[date]})
+		day = next
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+type meterChannelUsageResponse struct {
+	MeterID  string              `json:"meter_id"`
+	TimeZone string              `json:"time_zone"`
+	Channels []meterChannelUsage `json:"channels"`
+	// PowerFactor compares delivered active and reactive energy over the days, when both are metered.
+	PowerFactor *float64 `json:"power_factor"`
+}
+
+type meterChannelUsage struct {
+	Channel       string  `json:"channel"`
+	UnitOfMeasure *string `json:"unit_of_measure"`
+	Direction     *string `json:"direction"`
+	// Total is only reported for energy units; demand readings do not add up.
+	Total     *float64                    `json:"total,omitempty"`
+	Peak      *meterChannelUsageInterval  `json:"peak,omitempty"`
+	Intervals []meterChannelUsageInterval `json:"intervals"`
+}
+
+type meterChannelUsageInterval struct {
+	StartUTC           time.Time `json:"start_utc"`
+	EndUTC             time.Time `json:"end_utc"`
+	StartLocal         time.Time `json:"start_local"`
+	Quantity           float64   `json:"quantity"`
+	IsCanceled         bool      `json:"is_canceled"`
+	UsageTransactionID string    `json:"usage_transaction_id"`
+}
+
+// GetMeterChannelUsage returns the meter's readings of every channel, or of the one named by channel,
+// for the local days from..to. With unit, the channel's readings are converted to that unit; kWh
+// converts to kW as the average demand over each interval.
+func (h *MeterUsageHandler) GetMeterChannelUsage(w http.ResponseWriter, r *http.Request) {
+	channel, unit := r.URL.Query().Get("channel"), strings.ToUpper(r.URL.Query().Get("unit"))
+	if unit != "" && !uom.Valid(unit) {
+		writeIngestError(w, paramError("unit", codeUnsupportedUnitOfMeasure, fmt.Sprintf("unit %s is not a supported unit of measure", unit)))
+		return
+	}
+	if unit != "" && channel == "" {
+		writeIngestError(w, paramError("channel", codeRequired, "channel is required to convert to a unit"))
+		return
+	}
+	meter, loc, start, end, ok := loadMeterDays(w, r, h.meterRepo, h.powerRegionRepo)
+	if !ok {
+		return
+	}
+	intervals, err := h.meterUsageRepo.ListChannelIntervals(r.Context(), meter.ID, channel, start, end)
+	if err != nil {
+		writeIngestError(w, err)
+		return
+	}
+
+	response := meterChannelUsageResponse{MeterID: meter.ID, TimeZone: loc.String(), Channels: []meterChannelUsage{}}
+	var kwh, kvarh float64
+	var hasActive, hasReactive bool
+	var current *meterChannelUsage
+	for _, i := range intervals {
+		if current == nil || current.Channel != i.Channel {
+			response.Channels = append(response.Channels, meterChannelUsage{Channel: i.Channel, UnitOfMeasure: i.UnitOfMeasure, Direction: i.Direction, Intervals: []meterChannelUsageInterval{}})
+			current = &response.Channels[len(response.Channels)-1]
+		}
+		length := i.End.Sub(i.Start)
+		quantity := i.Quantity
+		if unit != "" && i.UnitOfMeasure != nil {
+			quantity, err = uom.Convert(i.Quantity, *i.UnitOfMeasure, unit, length)
+			if err != nil {
+				writeIngestError(w, &ingestError{status: http.StatusUnprocessableEntity, field: "unit", code: codeUnitNotConvertible, err: fmt.Errorf("channel %s: %w", i.Channel, err)})
+				return
+			}
+			current.UnitOfMeasure = &unit
+		} else if unit != "" {
+			writeIngestError(w, &ingestError{status: http.StatusUnprocessableEntity, field: "unit", code: codeUnitNotConvertible, err: fmt.Errorf("channel %s has no unit of measure to convert from", i.Channel)})
+			return
+		}
+		reading := meterChannelUsageInterval{
+			StartUTC:           i.Start.UTC(),
+			EndUTC:             i.End.UTC(),
+			StartLocal:         i.Start.In(loc),
+			Quantity:           quantity,
+			IsCanceled:         i.IsCanceled,
+			UsageTransactionID: i.UsageTransactionID,
+		}
+		current.Intervals = append(current.Intervals, reading)
+		if i.IsCanceled {
+			continue
+		}
+		if current.Peak == nil || reading.Quantity > current.Peak.Quantity {
+			peak := reading
+			current.Peak = &peak
+		}
+		if current.UnitOfMeasure != nil && uom.IsEnergy(*current.UnitOfMeasure) {
+			total := quantity
+			if current.Total != nil {
+				total += *current.Total
+			}
+			current.Total = &total
+		}
+		if i.UnitOfMeasure == nil || i.Direction == nil || *i.Direction != dbentity.ChannelDirectionDelivered {
+			continue
+		}
+		if v, err := uom.Convert(i.Quantity, *i.UnitOfMeasure, "KWH", length); err == nil {
+			kwh += v
+			hasActive = true
+		} else if v, err := uom.Convert(i.Quantity, *i.UnitOfMeasure, "KVARH", length); err == nil {
+			kvarh += v
+			hasReactive = true
+		}
+	}
+	if hasActive && hasReactive {
+		if pf, ok := uom.PowerFactor(kwh, kvarh); ok {
+			response.PowerFactor = &pf
+		}
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// loadMeterDays reads the {id} meter and the local days from..to (YYYY-MM-DD, inclusive) of a meter
+// request, answering the request itself when either is invalid. start and end bound the days in the
+// meter's power region time zone.
+func loadMeterDays(w http.ResponseWriter, r *http.Request, meterRepo repository.MeterRepository, powerRegionRepo repository.PowerRegionRepository) (meter *dbentity.Meter, loc *time.Location, start time.Time, end time.Time, ok bool) {
+	query := r.URL.Query()
+	from, err := parseDateParam("from", query.Get("from"))
+	if err != nil {
+		writeIngestError(w, err)
+		return
+	}
+	if from == nil {
+		writeIngestError(w, paramError("from", codeRequired, "from is required"))
+		return
+	}
+	to, err := parseDateParam("to", query.Get("to"))
+	if err != nil {
+		writeIngestError(w, err)
+		return
+	}
+	if to == nil {
+		writeIngestError(w, paramError("to", codeRequired, "to is required"))
+		return
+	}
+	if to.Before(*from) || to.Sub(*from) >= maxMeterUsageDays*24*time.Hour {
+		writeIngestError(w, paramError("to", codeInvalidValue, "to must be on or after from and within a year of it"))
+		return
+	}
+	id := chi.URLParam(r, "id")
+	meter, err = meterRepo.GetByID(r.Context(), id)
+	if errors.Is(err, pgx.ErrNoRows) {
+		writeIngestError(w, notFoundError("id", id))
+		return
+	} else if err != nil {
+		writeIngestError(w, err)
+		return
+	}
+	powerRegion, err := powerRegionRepo.GetByID(r.Context(), meter.PowerRegionID)
+	if err != nil {
+		writeIngestError(w, err)
+		return
+	}
+	loc, err = time.LoadLocation(powerRegion.TimeZone)
+	if err != nil {
+		writeIngestError(w, err)
+		return
+	}
+	start = time.Date(from.Year(), from.Month(), from.Day(), 0, 0, 0, 0, loc)
+	end = time.Date(to.Year(), to.Month(), to.Day()+1, 0, 0, 0, 0, loc)
+	return meter, loc, start, end, true
+}
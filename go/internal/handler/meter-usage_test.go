@@ -0,0 +1,127 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+	dbentity "usage-lakehouse/internal/db/entity"
+	"usage-lakehouse/internal/repository"
+)
+
+// fakeMeterUsageRepository serves intervals starting in the queried range and records the range.
+type fakeMeterUsageRepository struct {
+	repository.MeterUsageRepository
+	intervals []dbentity.MeterUsageInterval
+	from, to  time.Time
+}
+
+func (r *fakeMeterUsageRepository) ListIntervals(ctx context.Context, meterID string, from time.Time, to time.Time) ([]dbentity.MeterUsageInterval, error) {
+	r.from, r.to = from, to
+	var intervals []dbentity.MeterUsageInterval
+	for _, i := range r.intervals {
+		if !i.Start.Before(from) && i.Start.Before(to) {
+			intervals = append(intervals, i)
+		}
+	}
+	return intervals, nil
+}
+
+func (r *fakeMeterUsageRepository) ListVEEFlags(ctx context.Context, meterID string, from time.Time, to time.Time) ([]dbentity.MeterUsageVEEFlag, error) {
+	return nil, nil
+}
+
+func getMeterUsage(t *testing.T, usage *fakeMeterUsageRepository, query string) *httptest.ResponseRecorder {
+	t.Helper()
+	fakes := newIngestFakes(dbentity.ProvisioningPolicyReject)
+	h := NewMeterUsageHandler(fakes.meters, fakes.powerRegions, usage)
+	rec := httptest.NewRecorder()
+	h.GetMeterUsage(rec, withURLParam(httptest.NewRequest(http.MethodGet, "/meters/meter-1/usage?"+query, nil), "id", "meter-1"))
+	return rec
+}
+
+func TestGetMeterUsageServesLocalDays(t *testing.T) {
+	utc := func(hour int, minute int) time.Time {
+		return time.Date(2024, 11, 3, hour, minute, 0, 0, time.UTC)
+	}
+	interval := func(start time.Time) dbentity.MeterUsageInterval {
+		return dbentity.MeterUsageInterval{MeterID: "meter-1", Start: start, End: start.Add(15 * time.Minute), Consumption: ptr(1.0), Quality: "ACTUAL"}
+	}
+	// 01:00 CDT, 01:00 CST, and the last interval of the local day, 23:45 CST.
+	usage := &fakeMeterUsageRepository{intervals: []dbentity.MeterUsageInterval{interval(utc(6, 0)), interval(utc(7, 0)), interval(time.Date(2024, 11, 4, 5, 45, 0, 0, time.UTC))}}
+	rec := getMeterUsage(t, usage, "from=2024-11-02&to=2024-11-03")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d; body %s", rec.Code, rec.Body)
+	}
+	var response meterUsageResponse
+	if err := json.NewDecoder(rec.Body).Decode(&response); err != nil {
+		t.Fatal(err)
+	}
+
+	if want := time.Date(2024, 11, 2, 5, 0, 0, 0, time.UTC); !usage.from.Equal(want) {
+		t.Errorf("queried from %s, want local midnight %s", usage.from.UTC(), want)
+	}
+	if want := time.Date(2024, 11, 4, 6, 0, 0, 0, time.UTC); !usage.to.Equal(want) {
+		t.Errorf("queried to %s, want the local midnight after to, %s", usage.to.UTC(), want)
+	}
+	wantDays := []meterUsageDay{{Date: "2024-11-02", Hours: 24, ExpectedIntervals: 96}, {Date: "2024-11-03", Hours: 25, ExpectedIntervals: 100, Intervals: 3}}
+	if response.TimeZone != "America/Chicago" || len(response.Days) != 2 || response.Days[0] != wantDays[0] || response.Days[1] != wantDays[1] {
+		t.Errorf("days = %+v in %s, want %+v in America/Chicago", response.Days, response.TimeZone, wantDays)
+	}
+	wantLocal := []string{"2024-11-03T01:00:00-05:00", "2024-11-03T01:00:00-06:00", "2024-11-03T23:45:00-06:00"}
+	if len(response.Intervals) != len(wantLocal) {
+		t.Fatalf("got %d intervals, want %d", len(response.Intervals), len(wantLocal))
+	}
+	for i, want := range wantLocal {
+		got := response.Intervals[i]
+		if got.StartLocal.Format(time.RFC3339) != want || got.LocalDate != "2024-11-03" || got.StartUTC.Location() != time.UTC || !got.StartUTC.Equal(got.StartLocal) {
+			t.Errorf("interval %d starts %s local, %s UTC on %s, want %s", i, got.StartLocal.Format(time.RFC3339), got.StartUTC, got.LocalDate, want)
+		}
+	}
+}
+
+func TestGetMeterUsageSpringForwardDay(t *testing.T) {
+	rec := getMeterUsage(t, &fakeMeterUsageRepository{}, "from=2024-03-10&to=2024-03-10")
+	var response meterUsageResponse
+	if err := json.NewDecoder(rec.Body).Decode(&response); err != nil {
+		t.Fatal(err)
+	}
+	if len(response.Days) != 1 || response.Days[0].Hours != 23 || response.Days[0].ExpectedIntervals != 92 {
+		t.Errorf("days = %+v, want one 23 hour day", response.Days)
+	}
+}
+
+func TestGetMeterUsageErrors(t *testing.T) {
+	tests := []struct {
+		query  string
+		status int
+		path   string
+		code   string
+	}{
+		{"to=2024-03-10", http.StatusBadRequest, "from", codeRequired},
+		{"from=2024-03-10", http.StatusBadRequest, "to", codeRequired},
+		{"from=03/10/2024&to=2024-03-10", http.StatusBadRequest, "from", codeInvalidFormat},
+		{"from=2024-03-10&to=2024-03-09", http.StatusBadRequest, "to", codeInvalidValue},
+		{"from=2024-01-01&to=2025-01-01", http.StatusBadRequest, "to", codeInvalidValue},
+	}
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			rec := getMeterUsage(t, &fakeMeterUsageRepository{}, tt.query)
+			if rec.Code != tt.status {
+				t.Fatalf("status = %d, want %d", rec.Code, tt.status)
+			}
+			if response := decodeErrorResponse(t, rec); len(response.Errors) != 1 || response.Errors[0].Path != tt.path || response.Errors[0].Code != tt.code {
+				t.Errorf("errors = %+v, want %s on %s", response.Errors, tt.code, tt.path)
+			}
+		})
+	}
+
+	fakes := newIngestFakes(dbentity.ProvisioningPolicyReject)
+	rec := httptest.NewRecorder()
+	NewMeterUsageHandler(fakes.meters, fakes.powerRegions, &fakeMeterUsageRepository{}).GetMeterUsage(rec, withURLParam(httptest.NewRequest(http.MethodGet, "/meters/meter-9/usage?from=2024-03-10&to=2024-03-10", nil), "id", "meter-9"))
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("usage of an unknown meter = %d, want 404", rec.Code)
+	}
+}
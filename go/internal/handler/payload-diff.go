@@ -0,0 +1,87 @@
+package handler
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+)
+
+// maxPayloadDifferences bounds a conflict response; a changed interval file can differ in thousands of reads.
+const maxPayloadDifferences = 100
+
+type payloadDifference struct {
+	Path     string `json:"path"`
+	Existing any    `json:"existing"`
+	Incoming any    `json:"incoming"`
+}
+
+// diffPayloads compares two JSON documents and returns the leaf values that differ, addressed by path
+// (for example product_transfer_details[0].quantity_delivered[3].quantity), and the total count.
+func diffPayloads(existing []byte, incoming []byte) ([]payloadDifference, int, error) {
+	var a, b any
+	if err := json.Unmarshal(existing, &a); err != nil {
+		return nil, 0, err
+	}
+	if err := json.Unmarshal(incoming, &b); err != nil {
+		return nil, 0, err
+	}
+	var diffs []payloadDifference
+	total := 0
+	var walk func(path string, a any, b any)
+	walk = func(path string, a any, b any) {
+		switch av := a.(type) {
+		case map[string]any:
+			if bv, ok := b.(map[string]any); ok {
+				for _, key := range unionKeys(av, bv) {
+					walk(joinPath(path, key), av[key], bv[key])
+				}
+				return
+			}
+		case []any:
+			if bv, ok := b.([]any); ok {
+				for i := 0; i < max(len(av), len(bv)); i++ {
+					var ai, bi any
+					if i < len(av) {
+						ai = av[i]
+					}
+					if i < len(bv) {
+						bi = bv[i]
+					}
+					walk(fmt.Sprintf("%s[%d]", path, i), ai, bi)
+				}
+				return
+			}
+		}
+		if reflect.DeepEqual(a, b) {
+			return
+		}
+		total++
+		if len(diffs) < maxPayloadDifferences {
+			diffs = append(diffs, payloadDifference{Path: path, Existing: a, Incoming: b})
+		}
+	}
+	walk("", a, b)
+	return diffs, total, nil
+}
+
+func unionKeys(a map[string]any, b map[string]any) []string {
+	keys := make([]string, 0, len(a)+len(b))
+	for k := range a {
+		keys = append(keys, k)
+	}
+	for k := range b {
+		if _, ok := a[k]; !ok {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func joinPath(path string, key string) string {
+	if path == "" {
+		return key
+	}
+	return path + "." + key
+}
@@ -0,0 +1,83 @@
+package handler
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestDiffPayloads(t *testing.T) {
+	tests := []struct {
+		name      string
+		existing  string
+		incoming  string
+		want      []payloadDifference
+		wantTotal int
+	}{
+		{
+			name:     "identical",
+			existing: `{"transaction_id":"1","product_transfer_details":[{"quantity_delivered":[{"quantity":1}]}]}`,
+			incoming: `{"product_transfer_details":[{"quantity_delivered":[{"quantity":1}]}],"transaction_id":"1"}`,
+		},
+		{
+			name:      "changed reading",
+			existing:  `{"product_transfer_details":[{"quantity_delivered":[{"quantity":1},{"quantity":2}]}]}`,
+			incoming:  `{"product_transfer_details":[{"quantity_delivered":[{"quantity":1},{"quantity":3}]}]}`,
+			want:      []payloadDifference{{Path: "product_transfer_details[0].quantity_delivered[1].quantity", Existing: 2.0, Incoming: 3.0}},
+			wantTotal: 1,
+		},
+		{
+			name:      "added and removed fields",
+			existing:  `{"action_code":"F","esi_id":"1"}`,
+			incoming:  `{"esi_id":"1","original_transaction_id":"9"}`,
+			want:      []payloadDifference{{Path: "action_code", Existing: "F"}, {Path: "original_transaction_id", Incoming: "9"}},
+			wantTotal: 2,
+		},
+		{
+			name:      "longer array",
+			existing:  `{"q":[1]}`,
+			incoming:  `{"q":[1,{"quantity":2}]}`,
+			want:      []payloadDifference{{Path: "q[1]", Incoming: map[string]any{"quantity": 2.0}}},
+			wantTotal: 1,
+		},
+		{
+			name:      "changed type",
+			existing:  `{"quantity_delivered":[1]}`,
+			incoming:  `{"quantity_delivered":"1"}`,
+			want:      []payloadDifference{{Path: "quantity_delivered", Existing: []any{1.0}, Incoming: "1"}},
+			wantTotal: 1,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			diffs, total, err := diffPayloads([]byte(tt.existing), []byte(tt.incoming))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if total != tt.wantTotal || !reflect.DeepEqual(diffs, tt.want) {
+				t.Errorf("diffPayloads = %+v (%d), want %+v (%d)", diffs, total, tt.want, tt.wantTotal)
+			}
+		})
+	}
+}
+
+func TestDiffPayloadsBoundsTheDifferencesReported(t *testing.T) {
+	existing, incoming := make([]string, 250), make([]string, 250)
+	for i := range existing {
+		existing[i], incoming[i] = fmt.Sprint(i), fmt.Sprint(i+1)
+	}
+	diffs, total, err := diffPayloads([]byte("["+strings.Join(existing, ",")+"]"), []byte("["+strings.Join(incoming, ",")+"]"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(diffs) != maxPayloadDifferences || total != 250 || diffs[0].Path != "[0]" {
+		t.Errorf("got %d differences of %d starting at %q, want %d of 250 starting at [0]", len(diffs), total, diffs[0].Path, maxPayloadDifferences)
+	}
+}
+
+func TestDiffPayloadsRejectsInvalidJSON(t *testing.T) {
+	if _, _, err := diffPayloads([]byte(`{}`), []byte(`{`)); err == nil {
+		t.Error("diffPayloads of invalid JSON succeeded")
+	}
+}
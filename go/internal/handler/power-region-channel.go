@@ -0,0 +1,116 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+	dbentity "usage-lakehouse/internal/db/entity"
+	"usage-lakehouse/internal/repository"
+	"usage-lakehouse/internal/uom"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-playground/validator/v10"
+	"github.com/jackc/pgx/v5"
+)
+
+// PowerRegionChannelHandler maintains the channel and unit of measure registry of each power region.
+type PowerRegionChannelHandler struct {
+	powerRegionRepo repository.PowerRegionRepository
+	repo            repository.PowerRegionChannelRepository
+	validate        *validator.Validate
+}
+
+func NewPowerRegionChannelHandler(powerRegionRepo repository.PowerRegionRepository, repo repository.PowerRegionChannelRepository) *PowerRegionChannelHandler {
+	validate := validator.New()
+	validate.RegisterTagNameFunc(jsonFieldName)
+	validate.RegisterValidation("unit_of_measure", func(fl validator.FieldLevel) bool {
+		return uom.Valid(fl.Field().String())
+	})
+	return &PowerRegionChannelHandler{powerRegionRepo: powerRegionRepo, repo: repo, validate: validate}
+}
+
+// powerRegion loads the {power_region} named by the request, answering 404 when there is none.
+func (h *PowerRegionChannelHandler) powerRegion(w http.ResponseWriter, r *http.Request) (*dbentity.PowerRegion, bool) {
+	name := chi.URLParam(r, "power_region")
+	powerRegion, err := h.powerRegionRepo.GetByName(r.Context(), name)
+	if errors.Is(err, pgx.ErrNoRows) {
+		writeIngestError(w, notFoundError("power_region", name))
+		return nil, false
+	} else if err != nil {
+		writeIngestError(w, err)
+		return nil, false
+	}
+	return powerRegion, true
+}
+
+func (h *PowerRegionChannelHandler) ListChannels(w http.ResponseWriter, r *http.Request) {
+	powerRegion, ok := h.powerRegion(w, r)
+	if !ok {
+		return
+	}
+	channels, err := h.repo.List(r.Context(), powerRegion.ID)
+	if err != nil {
+		writeIngestError(w, err)
+		return
+	}
+	if channels == nil {
+		channels = []dbentity.PowerRegionChannel{}
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(channels)
+}
+
+// PutChannel registers the {code} channel of the power region or replaces its registration. Readings
+// already stored keep the unit they were ingested with.
+func (h *PowerRegionChannelHandler) PutChannel(w http.ResponseWriter, r *http.Request) {
+	type channelInput struct {
+		Name          string  `json:"name" validate:"required"`
+		UnitOfMeasure string  `json:"unit_of_measure" validate:"required,unit_of_measure"`
+		Direction     string  `json:"direction" validate:"omitempty,oneof=DELIVERED RECEIVED NET"`
+		Description   *string `json:"description"`
+	}
+	var input channelInput
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		writeIngestError(w, decodeError(err))
+		return
+	}
+	input.UnitOfMeasure = strings.ToUpper(input.UnitOfMeasure)
+	if err := h.validate.Struct(input); err != nil {
+		writeIngestError(w, err)
+		return
+	}
+	powerRegion, ok := h.powerRegion(w, r)
+	if !ok {
+		return
+	}
+	c := dbentity.PowerRegionChannel{
+		PowerRegionID: powerRegion.ID,
+		Code:          chi.URLParam(r, "code"),
+		Name:          input.Name,
+		UnitOfMeasure: input.UnitOfMeasure,
+		Direction:     input.Direction,
+		Description:   input.Description,
+	}
+	if c.Direction == "" {
+		c.Direction = dbentity.ChannelDirectionDelivered
+	}
+	if err := h.repo.Upsert(r.Context(), &c); err != nil {
+		writeIngestError(w, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(c)
+}
+
+func (h *PowerRegionChannelHandler) DeleteChannel(w http.ResponseWriter, r *http.Request) {
+	powerRegion, ok := h.powerRegion(w, r)
+	if !ok {
+		return
+	}
+	if err := h.repo.Delete(r.Context(), powerRegion.ID, chi.URLParam(r, "code")); err != nil {
+		writeIngestError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
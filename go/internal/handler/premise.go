@@ -0,0 +1,147 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	dbentity "usage-lakehouse/internal/db/entity"
+	"usage-lakehouse/internal/model"
+	"usage-lakehouse/internal/premisecode"
+	"usage-lakehouse/internal/repository"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-playground/validator/v10"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+)
+
+type PremiseHandler struct {
+	repo          repository.PremiseRepository
+	tdspRepo      repository.TDSPRepository
+	codeValidator *premisecode.Validator
+	validate      *validator.Validate
+}
+
+func NewPremiseHandler(repo repository.PremiseRepository, tdspRepo repository.TDSPRepository, codeValidator *premisecode.Validator) *PremiseHandler {
+	validate := validator.New()
+	validate.RegisterTagNameFunc(jsonFieldName)
+	return &PremiseHandler{repo: repo, tdspRepo: tdspRepo, codeValidator: codeValidator, validate: validate}
+}
+
+func (h *PremiseHandler) CreatePremise(w http.ResponseWriter, r *http.Request) {
+	type premiseInput struct {
+		Code          string        `json:"code" validate:"required"`
+		Name          string        `json:"name"`
+		CustomerName  string        `json:"customer_name" validate:"required"`
+		Address       model.Address `json:"address"`
+		PremiseTypeID *string       `json:"premise_type_id" validate:"required"`
+		PowerRegionID string        `json:"power_region_id" validate:"required,uuid"`
+		TDSPID        string        `json:"tdsp_id" validate:"required,uuid"`
+	}
+	var input premiseInput
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		writeIngestError(w, decodeError(err))
+		return
+	}
+	if err := h.validate.Struct(input); err != nil {
+		writeIngestError(w, err)
+		return
+	}
+	tdsp, err := h.tdspRepo.GetByID(r.Context(), input.TDSPID)
+	if err != nil {
+		writeIngestError(w, lookupError("tdsp_id", input.TDSPID, err))
+		return
+	}
+	if err := h.codeValidator.Validate(tdsp, input.Code); err != nil {
+		writeIngestError(w, premiseCodeError("code", err))
+		return
+	}
+	p := model.Premise{
+		ID:            uuid.New().String(),
+		Code:          input.Code,
+		Name:          input.Name,
+		CustomerName:  input.CustomerName,
+		Address:       input.Address,
+		PremiseTypeID: input.PremiseTypeID,
+		PowerRegionID: input.PowerRegionID,
+		TDSPID:        &tdsp.ID,
+	}
+	if err := h.repo.Create(r.Context(), &p); err != nil {
+		writeIngestError(w, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(p)
+}
+
+func (h *PremiseHandler) GetPremise(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+	p, err := h.repo.GetByID(r.Context(), id)
+	if errors.Is(err, pgx.ErrNoRows) {
+		writeIngestError(w, notFoundError("id", id))
+		return
+	}
+	if err != nil {
+		writeIngestError(w, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(p)
+}
+
+// ListPremiseCodeViolations audits existing premises against the pattern of the TDSP they belong to,
+// with the same validator ingestion uses. A TDSP whose expression does not compile is reported once
+// instead of its premises.
+func (h *PremiseHandler) ListPremiseCodeViolations(w http.ResponseWriter, r *http.Request) {
+	checks, err := h.repo.ListCodeChecks(r.Context())
+	if err != nil {
+		writeIngestError(w, err)
+		return
+	}
+	audit := auditPremiseCodes(h.codeValidator, checks)
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(audit)
+}
+
+func auditPremiseCodes(codeValidator *premisecode.Validator, checks []dbentity.PremiseCodeCheck) model.PremiseCodeAudit {
+	audit := model.PremiseCodeAudit{Violations: []model.PremiseCodeViolation{}, InvalidExpressions: []model.InvalidPremiseCodeExpression{}}
+	invalid := make(map[string]bool)
+	for _, c := range checks {
+		if invalid[c.TDSP.ID] {
+			continue
+		}
+		err := codeValidator.Validate(&c.TDSP, c.Code)
+		var mismatch *premisecode.MismatchError
+		switch {
+		case err == nil:
+		case errors.As(err, &mismatch):
+			audit.Violations = append(audit.Violations, model.PremiseCodeViolation{
+				PremiseID:       c.PremiseID,
+				Code:            c.Code,
+				PowerRegionID:   c.PowerRegionID,
+				TDSPID:          c.TDSP.ID,
+				TDSPName:        c.TDSP.Name,
+				ExpectedPattern: c.TDSP.PremiseCodeValidationExpression,
+			})
+		default:
+			invalid[c.TDSP.ID] = true
+			audit.InvalidExpressions = append(audit.InvalidExpressions, model.InvalidPremiseCodeExpression{
+				TDSPID:     c.TDSP.ID,
+				TDSPName:   c.TDSP.Name,
+				Expression: c.TDSP.PremiseCodeValidationExpression,
+				Error:      err.Error(),
+			})
+		}
+	}
+	return audit
+}
+
+// premiseCodeError rejects a mismatched code with 422; an unusable TDSP expression is a server error.
+func premiseCodeError(field string, err error) error {
+	var mismatch *premisecode.MismatchError
+	if errors.As(err, &mismatch) {
+		return &ingestError{status: http.StatusUnprocessableEntity, field: field, code: codeInvalidEsiID, err: err}
+	}
+	return err
+}
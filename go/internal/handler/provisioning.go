@@ -0,0 +1,143 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	dbentity "usage-lakehouse/internal/db/entity"
+	"usage-lakehouse/internal/model"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+)
+
+// unknownPremiseType is the premise_type given to premises created from usage alone.
+const unknownPremiseType = "UNKNOWN"
+
+type masterData struct {
+	premise        *model.Premise
+	meterNameIDMap map[string]string
+	// missingPremise and missingMeterNames describe what the 867 refers to that master data lacks.
+	missingPremise    bool
+	missingMeterNames []string
+}
+
+func (m masterData) complete() bool {
+	return !m.missingPremise && len(m.missingMeterNames) == 0
+}
+
+func (m masterData) reason(esiID string) string {
+	var reasons []string
+	if m.missingPremise {
+		reasons = append(reasons, fmt.Sprintf("unknown esi_id %s", esiID))
+	}
+	if len(m.missingMeterNames) > 0 {
+		reasons = append(reasons, fmt.Sprintf("unknown meter_name %s", strings.Join(m.missingMeterNames, ", ")))
+	}
+	return strings.Join(reasons, "; ")
+}
+
+func meterNames(input model.EDIUsageTransaction) []string {
+	seen := make(map[string]struct{})
+	names := make([]string, 0, len(input.ProductTransferDetails))
+	for _, detail := range input.ProductTransferDetails {
+		if detail.MeterName == nil || *detail.MeterName == "" {
+			continue
+		}
+		if _, ok := seen[*detail.MeterName]; !ok {
+			seen[*detail.MeterName] = struct{}{}
+			names = append(names, *detail.MeterName)
+		}
+	}
+	return names
+}
+
+// meterTypes takes each meter's type from the first loop that reports one.
+func meterTypes(input model.EDIUsageTransaction) map[string]string {
+	types := make(map[string]string)
+	for _, detail := range input.ProductTransferDetails {
+		if detail.MeterName == nil || detail.MeterType == nil {
+			continue
+		}
+		if _, ok := types[*detail.MeterName]; !ok {
+			types[*detail.MeterName] = *detail.MeterType
+		}
+	}
+	return types
+}
+
+func (h *EDIMonthlyUsageHandler) loadMasterData(ctx context.Context, input model.EDIUsageTransaction, powerRegion *dbentity.PowerRegion) (masterData, error) {
+	var md masterData
+	premise, err := h.premiseRepo.GetByCode(ctx, powerRegion.ID, input.EsiID)
+	if errors.Is(err, pgx.ErrNoRows) {
+		md.missingPremise = true
+	} else if err != nil {
+		return md, err
+	}
+	md.premise = premise
+	names := meterNames(input)
+	md.meterNameIDMap, err = h.meterRepo.GetNameIDMap(ctx, powerRegion.ID, names)
+	if err != nil {
+		return md, err
+	}
+	for _, name := range names {
+		if _, ok := md.meterNameIDMap[name]; !ok {
+			md.missingMeterNames = append(md.missingMeterNames, name)
+		}
+	}
+	return md, nil
+}
+
+// provision creates the premise and meters the 867 refers to but master data lacks, flagged as
+// auto-provisioned and linked to the sending TDSP.
+func (h *EDIMonthlyUsageHandler) provision(ctx context.Context, input model.EDIUsageTransaction, powerRegion *dbentity.PowerRegion, tdsp *dbentity.TDSP, md masterData) (masterData, error) {
+	if md.missingPremise {
+		premiseType := unknownPremiseType
+		premise := model.Premise{
+			ID:            uuid.New().String(),
+			Code:          input.EsiID,
+			PremiseTypeID: &premiseType,
+			PowerRegionID: powerRegion.ID,
+			TDSPID:        &tdsp.ID,
+		}
+		if err := h.premiseRepo.Provision(ctx, &premise); err != nil {
+			return md, err
+		}
+		md.premise = &premise
+		md.missingPremise = false
+	}
+	types := meterTypes(input)
+	for _, name := range md.missingMeterNames {
+		meter := dbentity.Meter{
+			ID:            uuid.New().String(),
+			PremiseID:     md.premise.ID,
+			PowerRegionID: powerRegion.ID,
+			Name:          name,
+			Type:          types[name],
+		}
+		if err := h.meterRepo.Provision(ctx, &meter); err != nil {
+			return md, err
+		}
+		md.meterNameIDMap[name] = meter.ID
+	}
+	md.missingMeterNames = nil
+	return md, nil
+}
+
+// rejectMissingMasterData reports the first missing reference against the field it came from.
+func rejectMissingMasterData(md masterData, esiID string) error {
+	field, code, stage := "meter_name", codeUnknownMeter, dbentity.QuarantineStageMeter
+	if md.missingPremise {
+		field, code, stage = "esi_id", codeUnknownEsiID, dbentity.QuarantineStagePremise
+	}
+	return &ingestError{status: http.StatusUnprocessableEntity, field: field, code: code, stage: stage, err: errors.New(md.reason(esiID))}
+}
+
+func meterIDFor(meterNameIDMap map[string]string, name string) *string {
+	if id, ok := meterNameIDMap[name]; ok {
+		return &id
+	}
+	return nil
+}
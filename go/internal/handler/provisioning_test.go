@@ -0,0 +1,124 @@
+package handler
+
+import (
+	"net/http"
+	"reflect"
+	"testing"
+	dbentity "usage-lakehouse/internal/db/entity"
+	"usage-lakehouse/internal/model"
+)
+
+func TestMeterNamesAndTypes(t *testing.T) {
+	input := model.EDIUsageTransaction{ProductTransferDetails: []model.ProductTransferDetail{
+		{MeterName: ptr("M2")},
+		{MeterName: ptr("M1"), MeterType: ptr("K1015")},
+		{},
+		{MeterName: ptr("")},
+		{MeterName: ptr("M1"), MeterType: ptr("KHMON")},
+		{MeterName: ptr("M2"), MeterType: ptr("KHMON")},
+	}}
+	if names := meterNames(input); !reflect.DeepEqual(names, []string{"M2", "M1"}) {
+		t.Errorf("meterNames = %v, want [M2 M1]", names)
+	}
+	if types := meterTypes(input); !reflect.DeepEqual(types, map[string]string{"M1": "K1015", "M2": "KHMON"}) {
+		t.Errorf("meterTypes = %v, want each meter's first reported type", types)
+	}
+}
+
+func TestRejectMissingMasterData(t *testing.T) {
+	tests := []struct {
+		name   string
+		md     masterData
+		field  string
+		code   string
+		stage  string
+		reason string
+	}{
+		{"premise", masterData{missingPremise: true}, "esi_id", codeUnknownEsiID, dbentity.QuarantineStagePremise, "unknown esi_id 1044"},
+		{"meters", masterData{missingMeterNames: []string{"M1", "M2"}}, "meter_name", codeUnknownMeter, dbentity.QuarantineStageMeter, "unknown meter_name M1, M2"},
+		{"both", masterData{missingPremise: true, missingMeterNames: []string{"M1"}}, "esi_id", codeUnknownEsiID, dbentity.QuarantineStagePremise, "unknown esi_id 1044; unknown meter_name M1"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.md.complete() {
+				t.Fatal("masterData with missing rows is complete")
+			}
+			err := rejectMissingMasterData(tt.md, "1044")
+			ie := err.(*ingestError)
+			if ie.status != http.StatusUnprocessableEntity || ie.field != tt.field || ie.code != tt.code || ie.stage != tt.stage || err.Error() != tt.reason {
+				t.Errorf("err = %+v (%v), want 422 %s on %s at stage %s: %s", ie, err, tt.code, tt.field, tt.stage, tt.reason)
+			}
+		})
+	}
+}
+
+// TestIngestMissingMasterData ingests testdata/867_monthly.json, whose meter M1 is unknown, at a new
+// ESI ID under each provisioning policy.
+func TestIngestMissingMasterData(t *testing.T) {
+	tests := []struct {
+		policy   string
+		knownESI bool
+		code     string
+		stage    string
+	}{
+		{policy: dbentity.ProvisioningPolicyReject, code: codeUnknownEsiID},
+		{policy: dbentity.ProvisioningPolicyReject, knownESI: true, code: codeUnknownMeter},
+		{policy: dbentity.ProvisioningPolicyQuarantine, stage: dbentity.QuarantineStagePremise},
+		{policy: dbentity.ProvisioningPolicyQuarantine, knownESI: true, stage: dbentity.QuarantineStageMeter},
+		{policy: dbentity.ProvisioningPolicyAutoCreate},
+		{policy: dbentity.ProvisioningPolicyAutoCreate, knownESI: true},
+	}
+	for _, tt := range tests {
+		name := tt.policy + " new premise"
+		if tt.knownESI {
+			name = tt.policy + " known premise"
+		}
+		t.Run(name, func(t *testing.T) {
+			fakes := newIngestFakes(tt.policy)
+			delete(fakes.meters.meters, "M1")
+			input := monthlyUsage(t)
+			input.ProductTransferDetails[0].MeterType = ptr("KHMON")
+			if !tt.knownESI {
+				input.EsiID = "10443720009999999"
+			}
+			result, err := ingestMonthly(fakes.handler(), input)
+
+			switch tt.policy {
+			case dbentity.ProvisioningPolicyReject:
+				var held heldTransactionError
+				if ie, ok := err.(*ingestError); ok {
+					held, _ = ie.body.(heldTransactionError)
+				}
+				if errorStatus(err) != http.StatusUnprocessableEntity || len(held.Errors) != 1 || held.Errors[0].Code != tt.code || held.QuarantineID == "" {
+					t.Errorf("err = %v, want a 422 %s held for replay", err, tt.code)
+				}
+			case dbentity.ProvisioningPolicyQuarantine:
+				if err != nil {
+					t.Fatal(err)
+				}
+				if result.quarantine == nil || result.quarantine.Stage != tt.stage || len(fakes.usageTransactions.saved) != 0 {
+					t.Errorf("result = %+v, want the transaction held at stage %s", result, tt.stage)
+				}
+			case dbentity.ProvisioningPolicyAutoCreate:
+				if err != nil {
+					t.Fatal(err)
+				}
+				premise := fakes.premises.premises[input.EsiID]
+				if premise.AutoProvisioned == tt.knownESI || *premise.TDSPID != "tdsp-oncor" || premise.PowerRegionID != "pr-ercot" {
+					t.Errorf("premise = %+v, want it auto-provisioned only when new, under ONCOR in ERCOT", premise)
+				}
+				if len(fakes.meters.provisioned) != 1 {
+					t.Fatalf("provisioned %d meters, want M1", len(fakes.meters.provisioned))
+				}
+				meter := fakes.meters.provisioned[0]
+				if meter.Name != "M1" || meter.Type != "KHMON" || meter.PremiseID != premise.ID {
+					t.Errorf("meter = %+v, want M1 of type KHMON at %s", meter, premise.ID)
+				}
+				details := fakes.usageTransactions.saved[0].details
+				if result.usageTransaction.PremiseID != premise.ID || len(details) != 1 || *details[0].MeterID != meter.ID {
+					t.Errorf("saved %+v with details %+v, want them at the provisioned premise and meter", result.usageTransaction, details)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,73 @@
+package handler
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"regexp"
+	"strconv"
+	dbentity "usage-lakehouse/internal/db/entity"
+	"usage-lakehouse/internal/objectstore"
+	"usage-lakehouse/internal/payloadarchive"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/jackc/pgx/v5"
+)
+
+var sha256Pattern = regexp.MustCompile(`^[0-9a-f]{64}$`)
+
+type RawPayloadHandler struct {
+	archive *payloadarchive.Archive
+}
+
+func NewRawPayloadHandler(archive *payloadarchive.Archive) *RawPayloadHandler {
+	return &RawPayloadHandler{archive: archive}
+}
+
+// GetRawPayload returns an archived request body exactly as it was received.
+func (h *RawPayloadHandler) GetRawPayload(w http.ResponseWriter, r *http.Request) {
+	sum := chi.URLParam(r, "sha256")
+	if !sha256Pattern.MatchString(sum) {
+		writeIngestError(w, paramError("sha256", codeInvalidFormat, "sha256 must be 64 lowercase hex characters"))
+		return
+	}
+	p, body, err := h.archive.Open(r.Context(), sum)
+	if errors.Is(err, pgx.ErrNoRows) || errors.Is(err, objectstore.ErrNotFound) {
+		writeIngestError(w, notFoundError("sha256", sum))
+		return
+	}
+	if err != nil {
+		writeIngestError(w, err)
+		return
+	}
+	defer body.Close()
+	w.Header().Set("Content-Type", p.ContentType)
+	w.Header().Set("Content-Length", strconv.FormatInt(p.Size, 10))
+	w.Header().Set("ETag", `"`+p.SHA256+`"`)
+	if _, err := io.Copy(w, body); err != nil {
+		log.Printf("stream raw payload %s: %v", p.SHA256, err)
+	}
+}
+
+// archiveRequestBody spools and archives the request body before anything else reads it. On failure
+// it writes the error response and returns false; otherwise the caller must close the body.
+func archiveRequestBody(w http.ResponseWriter, r *http.Request, archive *payloadarchive.Archive, defaultContentType string) (*payloadarchive.SpooledBody, *dbentity.RawPayload, bool) {
+	body, err := payloadarchive.Spool(r.Body)
+	if err != nil {
+		writeIngestError(w, &ingestError{status: http.StatusBadRequest, code: codeMalformedRequest, err: fmt.Errorf("read request body: %w", err)})
+		return nil, nil, false
+	}
+	contentType := r.Header.Get("Content-Type")
+	if contentType == "" {
+		contentType = defaultContentType
+	}
+	raw, err := archive.Put(r.Context(), contentType, body)
+	if err != nil {
+		body.Close()
+		writeIngestError(w, fmt.Errorf("archive payload: %w", err))
+		return nil, nil, false
+	}
+	return body, raw, true
+}
@@ -0,0 +1,78 @@
+package handler
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	dbentity "usage-lakehouse/internal/db/entity"
+	"usage-lakehouse/internal/objectstore"
+	"usage-lakehouse/internal/payloadarchive"
+	"usage-lakehouse/internal/repository"
+
+	"github.com/jackc/pgx/v5"
+)
+
+type fakeRawPayloadRepository struct {
+	repository.RawPayloadRepository
+	payloads map[string]dbentity.RawPayload
+}
+
+func (r *fakeRawPayloadRepository) Create(ctx context.Context, p *dbentity.RawPayload) error {
+	if _, ok := r.payloads[p.SHA256]; !ok {
+		r.payloads[p.SHA256] = *p
+	}
+	return nil
+}
+
+func (r *fakeRawPayloadRepository) GetBySHA256(ctx context.Context, sum string) (*dbentity.RawPayload, error) {
+	p, ok := r.payloads[sum]
+	if !ok {
+		return nil, pgx.ErrNoRows
+	}
+	return &p, nil
+}
+
+func getRawPayload(h *RawPayloadHandler, sum string) *httptest.ResponseRecorder {
+	rec := httptest.NewRecorder()
+	h.GetRawPayload(rec, withURLParam(httptest.NewRequest(http.MethodGet, "/raw-payloads/"+sum, nil), "sha256", sum))
+	return rec
+}
+
+func TestGetRawPayload(t *testing.T) {
+	archive := payloadarchive.New(objectstore.NewMemory(), &fakeRawPayloadRepository{payloads: map[string]dbentity.RawPayload{}})
+	const content = "{\"transaction_id\":\"867-1\"}\n"
+	p, err := archive.Put(context.Background(), "application/x-ndjson", strings.NewReader(content))
+	if err != nil {
+		t.Fatal(err)
+	}
+	h := NewRawPayloadHandler(archive)
+
+	rec := getRawPayload(h, p.SHA256)
+	if rec.Code != http.StatusOK || rec.Body.String() != content {
+		t.Fatalf("GET = %d %q, want 200 with the archived body", rec.Code, rec.Body)
+	}
+	if rec.Header().Get("Content-Type") != "application/x-ndjson" || rec.Header().Get("ETag") != `"`+p.SHA256+`"` {
+		t.Errorf("headers = %v, want the recorded content type and the SHA-256 as ETag", rec.Header())
+	}
+
+	rec = getRawPayload(h, strings.Repeat("a", 64))
+	if rec.Code != http.StatusNotFound {
+		t.Fatalf("GET of an unknown payload = %d, want 404", rec.Code)
+	}
+	if response := decodeErrorResponse(t, rec); len(response.Errors) != 1 || response.Errors[0].Path != "sha256" || response.Errors[0].Code != codeNotFound {
+		t.Errorf("errors = %+v, want NOT_FOUND on sha256", response.Errors)
+	}
+}
+
+func TestGetRawPayloadRejectsMalformedSHA256(t *testing.T) {
+	rec := getRawPayload(NewRawPayloadHandler(nil), "ABC")
+	if rec.Code != http.StatusBadRequest {
+		t.Fatalf("status = %d, want 400", rec.Code)
+	}
+	response := decodeErrorResponse(t, rec)
+	if len(response.Errors) != 1 || response.Errors[0].Path != "sha256" || response.Errors[0].Code != codeInvalidFormat {
+		t.Errorf("errors = %+v, want INVALID_FORMAT on sha256", response.Errors)
+	}
+}
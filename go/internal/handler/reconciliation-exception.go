@@ -0,0 +1,98 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+	"usage-lakehouse/internal/repository"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/jackc/pgx/v5"
+)
+
+const (
+	defaultListLimit = 100
+	maxListLimit     = 1000
+)
+
+type ReconciliationExceptionHandler struct {
+	repo repository.UsageReconciliationExceptionRepository
+}
+
+func NewReconciliationExceptionHandler(repo repository.UsageReconciliationExceptionRepository) *ReconciliationExceptionHandler {
+	return &ReconciliationExceptionHandler{repo: repo}
+}
+
+func (h *ReconciliationExceptionHandler) GetReconciliationException(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+	e, err := h.repo.GetByID(r.Context(), id)
+	if errors.Is(err, pgx.ErrNoRows) {
+		writeIngestError(w, notFoundError("id", id))
+		return
+	}
+	if err != nil {
+		writeIngestError(w, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(e)
+}
+
+// ListReconciliationExceptions filters by status, premise_id and a from/to (YYYY-MM-DD) window that
+// overlaps the summary's service period.
+func (h *ReconciliationExceptionHandler) ListReconciliationExceptions(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	filter := repository.UsageReconciliationExceptionFilter{Limit: defaultListLimit}
+	if status := query.Get("status"); status != "" {
+		filter.Status = &status
+	}
+	if premiseID := query.Get("premise_id"); premiseID != "" {
+		filter.PremiseID = &premiseID
+	}
+	var err error
+	if filter.From, err = parseDateParam("from", query.Get("from")); err != nil {
+		writeIngestError(w, err)
+		return
+	}
+	if filter.To, err = parseDateParam("to", query.Get("to")); err != nil {
+		writeIngestError(w, err)
+		return
+	}
+	if filter.Limit, err = parseLimitParam(query.Get("limit")); err != nil {
+		writeIngestError(w, err)
+		return
+	}
+	exceptions, err := h.repo.List(r.Context(), filter)
+	if err != nil {
+		writeIngestError(w, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(exceptions)
+}
+
+// parseDateParam reads the optional YYYY-MM-DD query parameter name.
+func parseDateParam(name string, value string) (*time.Time, error) {
+	if value == "" {
+		return nil, nil
+	}
+	t, err := time.Parse(time.DateOnly, value)
+	if err != nil {
+		return nil, paramError(name, codeInvalidFormat, name+" must be a YYYY-MM-DD date")
+	}
+	return &t, nil
+}
+
+func parseLimitParam(value string) (int, error) {
+	if value == "" {
+		return defaultListLimit, nil
+	}
+	limit, err := strconv.Atoi(value)
+	if err != nil || limit < 1 || limit > maxListLimit {
+		return 0, paramError("limit", codeInvalidValue, fmt.Sprintf("limit must be between 1 and %d", maxListLimit))
+	}
+	return limit, nil
+}
@@ -0,0 +1,73 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	dbentity "usage-lakehouse/internal/db/entity"
+	"usage-lakehouse/internal/repository"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/jackc/pgx/v5"
+)
+
+type fakeReconciliationExceptionRepository struct {
+	err error
+}
+
+func (r fakeReconciliationExceptionRepository) GetByID(ctx context.Context, id string) (*dbentity.UsageReconciliationException, error) {
+	return nil, r.err
+}
+
+func (r fakeReconciliationExceptionRepository) List(ctx context.Context, filter repository.UsageReconciliationExceptionFilter) ([]dbentity.UsageReconciliationException, error) {
+	return nil, r.err
+}
+
+// withURLParam sets a chi path parameter on r, as the router would.
+func withURLParam(r *http.Request, key string, value string) *http.Request {
+	rctx := chi.NewRouteContext()
+	rctx.URLParams.Add(key, value)
+	return r.WithContext(context.WithValue(r.Context(), chi.RouteCtxKey, rctx))
+}
+
+func TestReconciliationExceptionErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		repoErr error
+		request *http.Request
+		get     bool
+		status  int
+		path    string
+		code    string
+	}{
+		{"missing exception", pgx.ErrNoRows, withURLParam(httptest.NewRequest(http.MethodGet, "/reconciliation-exceptions/e1", nil), "id", "e1"), true, http.StatusNotFound, "id", codeNotFound},
+		{"lookup failure", errors.New("connection refused"), withURLParam(httptest.NewRequest(http.MethodGet, "/reconciliation-exceptions/e1", nil), "id", "e1"), true, http.StatusInternalServerError, "", codeInternal},
+		{"bad from", nil, httptest.NewRequest(http.MethodGet, "/reconciliation-exceptions?from=2024-13-01", nil), false, http.StatusBadRequest, "from", codeInvalidFormat},
+		{"bad to", nil, httptest.NewRequest(http.MethodGet, "/reconciliation-exceptions?to=yesterday", nil), false, http.StatusBadRequest, "to", codeInvalidFormat},
+		{"bad limit", nil, httptest.NewRequest(http.MethodGet, "/reconciliation-exceptions?limit=5000", nil), false, http.StatusBadRequest, "limit", codeInvalidValue},
+		{"list failure", errors.New("connection refused"), httptest.NewRequest(http.MethodGet, "/reconciliation-exceptions", nil), false, http.StatusInternalServerError, "", codeInternal},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := NewReconciliationExceptionHandler(fakeReconciliationExceptionRepository{err: tt.repoErr})
+			rec := httptest.NewRecorder()
+			if tt.get {
+				h.GetReconciliationException(rec, tt.request)
+			} else {
+				h.ListReconciliationExceptions(rec, tt.request)
+			}
+			if rec.Code != tt.status {
+				t.Fatalf("status = %d, want %d; body %s", rec.Code, tt.status, rec.Body)
+			}
+			response := decodeErrorResponse(t, rec)
+			if len(response.Errors) != 1 || response.Errors[0].Path != tt.path || response.Errors[0].Code != tt.code {
+				t.Errorf("errors = %+v, want one %s on %q", response.Errors, tt.code, tt.path)
+			}
+			if tt.status == http.StatusInternalServerError && response.Error != http.StatusText(tt.status) {
+				t.Errorf("error = %q, want the server error's detail hidden", response.Error)
+			}
+		})
+	}
+}
@@ -0,0 +1,125 @@
+package handler
+
+import (
+	"context"
+	dbentity "usage-lakehouse/internal/db/entity"
+)
+
+type regionCodeKey struct {
+	powerRegionID string
+	code          string
+}
+
+// referenceCache memoizes the reference lookups an 867 needs for the lifetime of one request, so a
+// batch or a multi-set interchange loads each power region, TDSP and code table row once.
+// It is not safe for concurrent use.
+type referenceCache struct {
+	h                   *EDIMonthlyUsageHandler
+	powerRegions        map[string]*dbentity.PowerRegion
+	tdsps               map[string]*dbentity.TDSP
+	purposes            map[regionCodeKey]*dbentity.UsageTransactionPurpose
+	transactionTypes    map[regionCodeKey]*dbentity.TransactionType
+	transactionSubTypes map[regionCodeKey]*dbentity.TransactionSubType
+	transferDetailTypes map[string]map[string]dbentity.PowerRegionUsageTransactionProductTransferDetailType
+	channels            map[string]map[string]dbentity.PowerRegionChannel
+}
+
+func (h *EDIMonthlyUsageHandler) newReferenceCache() *referenceCache {
+	return &referenceCache{
+		h:                   h,
+		powerRegions:        make(map[string]*dbentity.PowerRegion),
+		tdsps:               make(map[string]*dbentity.TDSP),
+		purposes:            make(map[regionCodeKey]*dbentity.UsageTransactionPurpose),
+		transactionTypes:    make(map[regionCodeKey]*dbentity.TransactionType),
+		transactionSubTypes: make(map[regionCodeKey]*dbentity.TransactionSubType),
+		transferDetailTypes: make(map[string]map[string]dbentity.PowerRegionUsageTransactionProductTransferDetailType),
+		channels:            make(map[string]map[string]dbentity.PowerRegionChannel),
+	}
+}
+
+func (c *referenceCache) powerRegion(ctx context.Context, name string) (*dbentity.PowerRegion, error) {
+	if p, ok := c.powerRegions[name]; ok {
+		return p, nil
+	}
+	p, err := c.h.powerRegionRepo.GetByName(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+	c.powerRegions[name] = p
+	return p, nil
+}
+
+func (c *referenceCache) tdsp(ctx context.Context, name string) (*dbentity.TDSP, error) {
+	if t, ok := c.tdsps[name]; ok {
+		return t, nil
+	}
+	t, err := c.h.tdspRepo.GetByName(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+	c.tdsps[name] = t
+	return t, nil
+}
+
+func (c *referenceCache) purpose(ctx context.Context, powerRegionID string, code string) (*dbentity.UsageTransactionPurpose, error) {
+	key := regionCodeKey{powerRegionID: powerRegionID, code: code}
+	if p, ok := c.purposes[key]; ok {
+		return p, nil
+	}
+	p, err := c.h.usageTransactionPurposeRepo.GetByPowerRegionAndCode(ctx, powerRegionID, code)
+	if err != nil {
+		return nil, err
+	}
+	c.purposes[key] = p
+	return p, nil
+}
+
+func (c *referenceCache) transactionType(ctx context.Context, powerRegionID string, code string) (*dbentity.TransactionType, error) {
+	key := regionCodeKey{powerRegionID: powerRegionID, code: code}
+	if t, ok := c.transactionTypes[key]; ok {
+		return t, nil
+	}
+	t, err := c.h.transactionTypeRepo.GetByPowerRegionAndCode(ctx, powerRegionID, code)
+	if err != nil {
+		return nil, err
+	}
+	c.transactionTypes[key] = t
+	return t, nil
+}
+
+func (c *referenceCache) transactionSubType(ctx context.Context, powerRegionID string, code string) (*dbentity.TransactionSubType, error) {
+	key := regionCodeKey{powerRegionID: powerRegionID, code: code}
+	if t, ok := c.transactionSubTypes[key]; ok {
+		return t, nil
+	}
+	t, err := c.h.transactionSubTypeRepo.GetByPowerRegionSubTypeCode(ctx, powerRegionID, code)
+	if err != nil {
+		return nil, err
+	}
+	c.transactionSubTypes[key] = t
+	return t, nil
+}
+
+func (c *referenceCache) transferDetailTypeMap(ctx context.Context, powerRegionID string) (map[string]dbentity.PowerRegionUsageTransactionProductTransferDetailType, error) {
+	if m, ok := c.transferDetailTypes[powerRegionID]; ok {
+		return m, nil
+	}
+	m, err := c.h.powerRegionUsageTransactionProductTransferDetailTypeRepo.MapByCode(ctx, powerRegionID)
+	if err != nil {
+		return nil, err
+	}
+	c.transferDetailTypes[powerRegionID] = m
+	return m, nil
+}
+
+func (c *referenceCache) channelMap(ctx context.Context, powerRegionID string) (map[string]dbentity.PowerRegionChannel, error) {
+	if m, ok := c.channels[powerRegionID]; ok {
+		return m, nil
+	}
+	m, err := c.h.powerRegionChannelRepo.MapByCode(ctx, powerRegionID)
+	if err != nil {
+		return nil, err
+	}
+	c.channels[powerRegionID] = m
+	return m, nil
+}
@@ -0,0 +1,182 @@
+package handler
+
+import (
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	dbentity "usage-lakehouse/internal/db/entity"
+	"usage-lakehouse/internal/model"
+	"usage-lakehouse/internal/repository"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/jackc/pgx/v5"
+)
+
+type UsageQuarantineHandler struct {
+	repo   repository.UsageTransactionQuarantineRepository
+	ingest *EDIMonthlyUsageHandler
+}
+
+func NewUsageQuarantineHandler(repo repository.UsageTransactionQuarantineRepository, ingest *EDIMonthlyUsageHandler) *UsageQuarantineHandler {
+	return &UsageQuarantineHandler{repo: repo, ingest: ingest}
+}
+
+// ListQuarantinedTransactions filters by status, stage, power_region_id and transaction_id.
+func (h *UsageQuarantineHandler) ListQuarantinedTransactions(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	filter := repository.UsageTransactionQuarantineFilter{}
+	if status := query.Get("status"); status != "" {
+		filter.Status = &status
+	}
+	if stage := query.Get("stage"); stage != "" {
+		filter.Stage = &stage
+	}
+	if powerRegionID := query.Get("power_region_id"); powerRegionID != "" {
+		filter.PowerRegionID = &powerRegionID
+	}
+	if transactionID := query.Get("transaction_id"); transactionID != "" {
+		filter.TransactionID = &transactionID
+	}
+	var err error
+	if filter.Limit, err = parseLimitParam(query.Get("limit")); err != nil {
+		writeIngestError(w, err)
+		return
+	}
+	held, err := h.repo.List(r.Context(), filter)
+	if err != nil {
+		writeIngestError(w, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(held)
+}
+
+func (h *UsageQuarantineHandler) GetQuarantinedTransaction(w http.ResponseWriter, r *http.Request) {
+	q, ok := h.getHeld(w, r)
+	if !ok {
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(q)
+}
+
+// UpdateQuarantinedTransaction replaces the held payload with a corrected EDIUsageTransaction.
+func (h *UsageQuarantineHandler) UpdateQuarantinedTransaction(w http.ResponseWriter, r *http.Request) {
+	q, ok := h.getOpen(w, r)
+	if !ok {
+		return
+	}
+	var input model.EDIUsageTransaction
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		writeIngestError(w, decodeError(err))
+		return
+	}
+	if err := h.ingest.validate.Struct(input); err != nil {
+		writeIngestError(w, err)
+		return
+	}
+	if input.TransactionSubType == "" {
+		input.TransactionSubType = model.TransactionSubTypeCode(q.TransactionSubType)
+	} else if string(input.TransactionSubType) != q.TransactionSubType {
+		writeIngestError(w, &ingestError{status: http.StatusUnprocessableEntity, field: "transaction_sub_type_code", code: codeTransactionSubTypeMismatch, err: fmt.Errorf("transaction_sub_type_code must remain %q", q.TransactionSubType)})
+		return
+	}
+	payload, err := json.Marshal(input)
+	if err != nil {
+		writeIngestError(w, err)
+		return
+	}
+	q.TransactionID = input.TransactionID
+	q.EsiID = input.EsiID
+	q.Payload = payload
+	q.PayloadSHA256 = fmt.Sprintf("%x", sha256.Sum256(payload))
+	err = h.repo.UpdatePayload(r.Context(), q)
+	if errors.Is(err, repository.ErrQuarantineTransactionIDHeld) {
+		writeIngestError(w, &ingestError{status: http.StatusConflict, field: "transaction_id", code: codeTransactionConflict, err: err})
+		return
+	}
+	if errors.Is(err, pgx.ErrNoRows) {
+		writeIngestError(w, &ingestError{status: http.StatusConflict, field: "status", code: codeNotQuarantined, err: errors.New("quarantined transaction is no longer held")})
+		return
+	}
+	if err != nil {
+		writeIngestError(w, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(q)
+}
+
+// ReplayQuarantinedTransaction ingests the held payload as if it had just arrived, including the
+// transaction_id duplicate checks. A replay that fails again stays held under the same id.
+func (h *UsageQuarantineHandler) ReplayQuarantinedTransaction(w http.ResponseWriter, r *http.Request) {
+	q, ok := h.getOpen(w, r)
+	if !ok {
+		return
+	}
+	var input model.EDIUsageTransaction
+	if err := json.Unmarshal(q.Payload, &input); err != nil {
+		writeIngestError(w, err)
+		return
+	}
+	result, err := h.ingest.ingestUsage(r.Context(), h.ingest.newReferenceCache(), input, model.TransactionSubTypeCode(q.TransactionSubType), q.RawPayloadSHA256)
+	if err != nil {
+		writeIngestError(w, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	if result.quarantine != nil {
+		w.WriteHeader(http.StatusAccepted)
+		json.NewEncoder(w).Encode(quarantineResponse{QuarantineID: result.quarantine.ID, Status: result.quarantine.Status, Reason: result.quarantine.Reason})
+		return
+	}
+	if err := h.repo.MarkReplayed(r.Context(), q.ID, result.usageTransaction.ID); err != nil {
+		writeIngestError(w, err)
+		return
+	}
+	if result.created {
+		w.WriteHeader(http.StatusCreated)
+	}
+	json.NewEncoder(w).Encode(result.usageTransaction)
+}
+
+func (h *UsageQuarantineHandler) DiscardQuarantinedTransaction(w http.ResponseWriter, r *http.Request) {
+	q, ok := h.getOpen(w, r)
+	if !ok {
+		return
+	}
+	if err := h.repo.Discard(r.Context(), q.ID); err != nil {
+		writeIngestError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *UsageQuarantineHandler) getHeld(w http.ResponseWriter, r *http.Request) (*dbentity.UsageTransactionQuarantine, bool) {
+	id := chi.URLParam(r, "id")
+	q, err := h.repo.GetByID(r.Context(), id)
+	if errors.Is(err, pgx.ErrNoRows) {
+		writeIngestError(w, notFoundError("id", id))
+		return nil, false
+	}
+	if err != nil {
+		writeIngestError(w, err)
+		return nil, false
+	}
+	return q, true
+}
+
+// getOpen loads a transaction that can still be edited, replayed or discarded.
+func (h *UsageQuarantineHandler) getOpen(w http.ResponseWriter, r *http.Request) (*dbentity.UsageTransactionQuarantine, bool) {
+	q, ok := h.getHeld(w, r)
+	if !ok {
+		return nil, false
+	}
+	if q.Status != dbentity.QuarantineStatusQuarantined {
+		writeIngestError(w, &ingestError{status: http.StatusConflict, field: "status", code: codeNotQuarantined, err: fmt.Errorf("quarantined transaction is %s", q.Status)})
+		return nil, false
+	}
+	return q, true
+}
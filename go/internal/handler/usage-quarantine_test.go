@@ -0,0 +1,194 @@
+package handler
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	dbentity "usage-lakehouse/internal/db/entity"
+	"usage-lakehouse/internal/model"
+	"usage-lakehouse/internal/repository"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// fakeQuarantineRepository holds transactions in memory. Like the SQL repository, Create of a
+// transaction_id that is already held updates the held row and keeps its id.
+type fakeQuarantineRepository struct {
+	repository.UsageTransactionQuarantineRepository
+	held    map[string]*dbentity.UsageTransactionQuarantine
+	err     error
+	creates int
+}
+
+func (r *fakeQuarantineRepository) Create(ctx context.Context, q *dbentity.UsageTransactionQuarantine) error {
+	r.creates++
+	for _, held := range r.held {
+		if held.TransactionID == q.TransactionID && held.Status == dbentity.QuarantineStatusQuarantined {
+			q.ID = held.ID
+			break
+		}
+	}
+	q.Status = dbentity.QuarantineStatusQuarantined
+	stored := *q
+	r.held[q.ID] = &stored
+	return nil
+}
+
+func (r *fakeQuarantineRepository) GetByID(ctx context.Context, id string) (*dbentity.UsageTransactionQuarantine, error) {
+	if r.err != nil {
+		return nil, r.err
+	}
+	q, ok := r.held[id]
+	if !ok {
+		return nil, pgx.ErrNoRows
+	}
+	stored := *q
+	return &stored, nil
+}
+
+func (r *fakeQuarantineRepository) MarkReplayed(ctx context.Context, id string, usageTransactionID string) error {
+	r.held[id].Status = dbentity.QuarantineStatusReplayed
+	r.held[id].ReplayedUsageTransactionID = &usageTransactionID
+	return nil
+}
+
+// heldMonthlyUsage is testdata/867_monthly.json as it is held in quarantine: with its sub type set and
+// the payload SHA-256 ingestion computes.
+func heldMonthlyUsage(t *testing.T, id string) (*dbentity.UsageTransactionQuarantine, model.EDIUsageTransaction) {
+	t.Helper()
+	input := monthlyUsage(t)
+	input.TransactionSubType = model.TransactionSubTypeCodeMonthlyUsage
+	payload, err := json.Marshal(input)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &dbentity.UsageTransactionQuarantine{
+		ID:                 id,
+		TransactionID:      input.TransactionID,
+		TransactionSubType: string(input.TransactionSubType),
+		EsiID:              input.EsiID,
+		Stage:              dbentity.QuarantineStagePowerRegion,
+		Reason:             `power_region "ERCOT" does not exist`,
+		Status:             dbentity.QuarantineStatusQuarantined,
+		Payload:            payload,
+		PayloadSHA256:      fmt.Sprintf("%x", sha256.Sum256(payload)),
+	}, input
+}
+
+// newTestQuarantineHandler replays into a deployment without master data, where every replay fails
+// its power region lookup unless its transaction_id was already ingested.
+func newTestQuarantineHandler(quarantineRepo *fakeQuarantineRepository, usageTransactions map[string]*dbentity.UsageTransaction) *UsageQuarantineHandler {
+	fakes := newIngestFakes("")
+	fakes.powerRegions.regions = nil
+	fakes.quarantine = quarantineRepo
+	for _, t := range usageTransactions {
+		fakes.usageTransactions.store(t)
+	}
+	return NewUsageQuarantineHandler(quarantineRepo, fakes.handler())
+}
+
+func replay(h *UsageQuarantineHandler, id string) *httptest.ResponseRecorder {
+	rec := httptest.NewRecorder()
+	h.ReplayQuarantinedTransaction(rec, withURLParam(httptest.NewRequest(http.MethodPost, "/usage-quarantine/"+id+"/replay", nil), "id", id))
+	return rec
+}
+
+// A replay is held to the transaction_id rules of fresh traffic: the same content resolves to the
+// stored transaction and different content is a conflict that leaves the transaction held.
+func TestReplayQuarantinedTransactionOfAnIngestedTransactionID(t *testing.T) {
+	tests := []struct {
+		name       string
+		sameSHA256 bool
+		status     int
+		held       string
+	}{
+		{"same content", true, http.StatusOK, dbentity.QuarantineStatusReplayed},
+		{"different content", false, http.StatusConflict, dbentity.QuarantineStatusQuarantined},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			q, input := heldMonthlyUsage(t, "q1")
+			existing := &dbentity.UsageTransaction{ID: "u1", TransactionID: input.TransactionID, PayloadSHA256: q.PayloadSHA256}
+			if !tt.sameSHA256 {
+				existing.PayloadSHA256 = "0000000000000000000000000000000000000000000000000000000000000000"
+			}
+			quarantineRepo := &fakeQuarantineRepository{held: map[string]*dbentity.UsageTransactionQuarantine{q.ID: q}}
+			h := newTestQuarantineHandler(quarantineRepo, map[string]*dbentity.UsageTransaction{input.TransactionID: existing})
+
+			rec := replay(h, q.ID)
+			if rec.Code != tt.status {
+				t.Fatalf("status = %d, want %d; body %s", rec.Code, tt.status, rec.Body)
+			}
+			if got := quarantineRepo.held[q.ID]; got.Status != tt.held {
+				t.Errorf("quarantined transaction is %s, want %s", got.Status, tt.held)
+			}
+			if tt.sameSHA256 {
+				var stored dbentity.UsageTransaction
+				if err := json.NewDecoder(rec.Body).Decode(&stored); err != nil {
+					t.Fatal(err)
+				}
+				if stored.ID != existing.ID || *quarantineRepo.held[q.ID].ReplayedUsageTransactionID != existing.ID {
+					t.Errorf("replay answered %s, want the stored transaction %s", stored.ID, existing.ID)
+				}
+				return
+			}
+			var conflict transactionConflict
+			if err := json.NewDecoder(rec.Body).Decode(&conflict); err != nil {
+				t.Fatal(err)
+			}
+			if conflict.UsageTransactionID != existing.ID || len(conflict.Errors) != 1 || conflict.Errors[0].Code != codeTransactionConflict {
+				t.Errorf("conflict = %+v, want TRANSACTION_CONFLICT against %s", conflict, existing.ID)
+			}
+		})
+	}
+}
+
+func TestReplayQuarantinedTransactionThatFailsAgainStaysHeldUnderItsID(t *testing.T) {
+	q, _ := heldMonthlyUsage(t, "q1")
+	quarantineRepo := &fakeQuarantineRepository{held: map[string]*dbentity.UsageTransactionQuarantine{q.ID: q}}
+	h := newTestQuarantineHandler(quarantineRepo, nil)
+
+	rec := replay(h, q.ID)
+	if rec.Code != http.StatusUnprocessableEntity {
+		t.Fatalf("status = %d, want 422; body %s", rec.Code, rec.Body)
+	}
+	var held heldTransactionError
+	if err := json.NewDecoder(rec.Body).Decode(&held); err != nil {
+		t.Fatal(err)
+	}
+	if held.QuarantineID != q.ID || held.Stage != dbentity.QuarantineStagePowerRegion {
+		t.Errorf("held = %+v, want quarantine_id %s at stage %s", held, q.ID, dbentity.QuarantineStagePowerRegion)
+	}
+	if quarantineRepo.creates != 1 || len(quarantineRepo.held) != 1 || quarantineRepo.held[q.ID].Status != dbentity.QuarantineStatusQuarantined {
+		t.Errorf("quarantine = %+v after %d holds, want the one transaction still held", quarantineRepo.held, quarantineRepo.creates)
+	}
+}
+
+func TestGetQuarantinedTransactionErrors(t *testing.T) {
+	tests := []struct {
+		name   string
+		err    error
+		status int
+		code   string
+	}{
+		{"missing", nil, http.StatusNotFound, codeNotFound},
+		{"lookup failure", errors.New("connection refused"), http.StatusInternalServerError, codeInternal},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			quarantineRepo := &fakeQuarantineRepository{held: map[string]*dbentity.UsageTransactionQuarantine{}, err: tt.err}
+			rec := replay(newTestQuarantineHandler(quarantineRepo, nil), "q1")
+			if rec.Code != tt.status {
+				t.Fatalf("status = %d, want %d", rec.Code, tt.status)
+			}
+			if response := decodeErrorResponse(t, rec); len(response.Errors) != 1 || response.Errors[0].Code != tt.code {
+				t.Errorf("errors = %+v, want %s", response.Errors, tt.code)
+			}
+		})
+	}
+}
@@ -0,0 +1,100 @@
+package handler
+
+import (
+	"math"
+	"time"
+	dbentity "usage-lakehouse/internal/db/entity"
+	"usage-lakehouse/internal/model"
+	"usage-lakehouse/internal/region"
+
+	"github.com/google/uuid"
+)
+
+// reconciliationTolerance absorbs rounding between TDSP summary totals and summed interval reads.
+const reconciliationTolerance = 0.001
+
+type reconciliationKey struct {
+	meterName string
+	channel   string
+	start     time.Time
+	end       time.Time
+}
+
+func newReconciliationKey(meterName string, channel string, detail model.ProductTransferDetail) reconciliationKey {
+	return reconciliationKey{meterName: meterName, channel: channel, start: detail.ServicePeriodStart.UTC(), end: detail.ServicePeriodEnd.UTC()}
+}
+
+func sumQuantities(detail model.ProductTransferDetail) float64 {
+	var total float64
+	if detail.Quantities != nil {
+		for _, q := range *detail.Quantities {
+			total += q.Quantity
+		}
+	}
+	return total
+}
+
+// reconcileSummaries compares every interval summary loop (BO, IA, PP) with the interval detail of the
+// same transaction for the meter, channel and service period. PP summarises across meters, so it is
+// compared with the interval detail of all meters. Summaries without a channel are totals of the
+// adapter's default channel.
+func reconcileSummaries(adapter region.Adapter, usageTransactionID string, premiseID string, isCanceled bool, input model.EDIUsageTransaction, types map[string]dbentity.PowerRegionUsageTransactionProductTransferDetailType, meterNameIDMap map[string]string) []dbentity.UsageTransactionSummary {
+	perMeter := make(map[reconciliationKey]float64)
+	acrossMeters := make(map[reconciliationKey]float64)
+	for _, detail := range input.ProductTransferDetails {
+		t := types[string(detail.TransferType)]
+		if !t.Interval || !t.Meter || t.Summary || detail.Channel == nil {
+			continue
+		}
+		var meterName string
+		if detail.MeterName != nil {
+			meterName = *detail.MeterName
+		}
+		total := sumQuantities(detail)
+		perMeter[newReconciliationKey(meterName, *detail.Channel, detail)] += total
+		acrossMeters[newReconciliationKey("", *detail.Channel, detail)] += total
+	}
+
+	var summaries []dbentity.UsageTransactionSummary
+	for _, detail := range input.ProductTransferDetails {
+		t := types[string(detail.TransferType)]
+		if !t.Interval || !t.Summary {
+			continue
+		}
+		var meterName string
+		var meterID *string
+		if detail.MeterName != nil {
+			meterName = *detail.MeterName
+			if id, ok := meterNameIDMap[meterName]; ok {
+				meterID = &id
+			}
+		}
+		channel := adapter.DefaultChannel()
+		if detail.Channel != nil && *detail.Channel != "" {
+			channel = *detail.Channel
+		}
+		var intervalQuantity float64
+		if detail.TransferType == model.ProductTransferDetailTypeCodeNetIntervalUsageSummaryAcrossMeters {
+			intervalQuantity = acrossMeters[newReconciliationKey("", channel, detail)]
+		} else {
+			intervalQuantity = perMeter[newReconciliationKey(meterName, channel, detail)]
+		}
+		quantity := sumQuantities(detail)
+		summaries = append(summaries, dbentity.UsageTransactionSummary{
+			ID:                 uuid.New().String(),
+			UsageTransactionID: usageTransactionID,
+			TransferType:       string(detail.TransferType),
+			PremiseID:          premiseID,
+			MeterID:            meterID,
+			MeterName:          meterName,
+			Channel:            channel,
+			ServicePeriodStart: *detail.ServicePeriodStart,
+			ServicePeriodEnd:   *detail.ServicePeriodEnd,
+			Quantity:           quantity,
+			IntervalQuantity:   intervalQuantity,
+			IsReconciled:       math.Abs(quantity-intervalQuantity) <= reconciliationTolerance,
+			IsCanceled:         isCanceled,
+		})
+	}
+	return summaries
+}
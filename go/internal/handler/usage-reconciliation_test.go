@@ -0,0 +1,112 @@
+package handler
+
+import (
+	"testing"
+	"time"
+	"usage-lakehouse/internal/model"
+	"usage-lakehouse/internal/region"
+)
+
+// readingDetail is a product transfer detail over start to end with one reading per quantity, ending
+// 15 minutes apart after start. An empty meter or channel is left unset.
+func readingDetail(transferType model.ProductTransferDetailTypeCode, meter string, channel string, start time.Time, end time.Time, quantities ...float64) model.ProductTransferDetail {
+	detail := periodDetail(transferType, start, end)
+	if meter != "" {
+		detail.MeterName = ptr(meter)
+	}
+	if channel != "" {
+		detail.Channel = ptr(channel)
+	}
+	readings := make([]model.QuantityDelivered, 0, len(quantities))
+	for i, q := range quantities {
+		readings = append(readings, model.QuantityDelivered{Quantity: q, IntervalEnd: start.Add(time.Duration(i+1) * 15 * time.Minute)})
+	}
+	detail.Quantities = &readings
+	return detail
+}
+
+func TestReconcileSummaries(t *testing.T) {
+	ercot, _ := region.Lookup("ERCOT")
+	start := time.Date(2024, 2, 1, 6, 0, 0, 0, time.UTC)
+	end := start.Add(time.Hour)
+	nextStart, nextEnd := end, end.Add(time.Hour)
+	m1 := []model.ProductTransferDetail{
+		readingDetail("PM", "M1", "1", start, end, 1, 2, 3, 4),
+		readingDetail("PM", "M1", "4", start, end, 0.5, 0.5),
+		readingDetail("PM", "M1", "1", nextStart, nextEnd, 100),
+	}
+	m2 := readingDetail("PM", "M2", "1", start, end, 5, 5)
+	type want struct {
+		meterName        string
+		meterID          *string
+		channel          string
+		quantity         float64
+		intervalQuantity float64
+		reconciled       bool
+	}
+	tests := []struct {
+		name    string
+		details []model.ProductTransferDetail
+		want    []want
+	}{
+		{
+			name:    "summary matches its interval detail",
+			details: append(m1, readingDetail("BO", "M1", "1", start, end, 10)),
+			want:    []want{{"M1", ptr("meter-1"), "1", 10, 10, true}},
+		},
+		{
+			name:    "summary differs from its interval detail",
+			details: append(m1, readingDetail("BO", "M1", "1", start, end, 12)),
+			want:    []want{{"M1", ptr("meter-1"), "1", 12, 10, false}},
+		},
+		{
+			name:    "difference within tolerance",
+			details: append(m1, readingDetail("IA", "M1", "1", start, end, 10.0005)),
+			want:    []want{{"M1", ptr("meter-1"), "1", 10.0005, 10, true}},
+		},
+		{
+			name:    "summary without a channel totals the default channel",
+			details: append(m1, readingDetail("BO", "M1", "", start, end, 10)),
+			want:    []want{{"M1", ptr("meter-1"), "1", 10, 10, true}},
+		},
+		{
+			name:    "generation channel",
+			details: append(m1, readingDetail("BO", "M1", "4", start, end, 1)),
+			want:    []want{{"M1", ptr("meter-1"), "4", 1, 1, true}},
+		},
+		{
+			name:    "across meters",
+			details: append(append(m1, m2), readingDetail("PP", "", "1", start, end, 20), readingDetail("BO", "M2", "1", start, end, 10)),
+			want:    []want{{"", nil, "1", 20, 20, true}, {"M2", nil, "1", 10, 10, true}},
+		},
+		{
+			name:    "summary without interval detail",
+			details: []model.ProductTransferDetail{readingDetail("BO", "M1", "1", start, end, 10)},
+			want:    []want{{"M1", ptr("meter-1"), "1", 10, 0, false}},
+		},
+		{
+			name:    "non-interval summaries are not reconciled",
+			details: append(m1, readingDetail("SU", "M1", "1", start, end, 10)),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			summaries := reconcileSummaries(ercot, "ut-1", "premise-1", false, model.EDIUsageTransaction{ProductTransferDetails: tt.details}, testTransferDetailTypes, map[string]string{"M1": "meter-1"})
+			if len(summaries) != len(tt.want) {
+				t.Fatalf("got %d summaries, want %d: %+v", len(summaries), len(tt.want), summaries)
+			}
+			for i, w := range tt.want {
+				s := summaries[i]
+				if s.MeterName != w.meterName || s.Channel != w.channel || s.Quantity != w.quantity || s.IntervalQuantity != w.intervalQuantity || s.IsReconciled != w.reconciled {
+					t.Errorf("summary %d = %s/%s %v against %v reconciled %v, want %s/%s %v against %v reconciled %v", i, s.MeterName, s.Channel, s.Quantity, s.IntervalQuantity, s.IsReconciled, w.meterName, w.channel, w.quantity, w.intervalQuantity, w.reconciled)
+				}
+				if (s.MeterID == nil) != (w.meterID == nil) || (s.MeterID != nil && *s.MeterID != *w.meterID) {
+					t.Errorf("summary %d meter_id = %v, want %v", i, s.MeterID, w.meterID)
+				}
+				if s.UsageTransactionID != "ut-1" || s.PremiseID != "premise-1" || !s.ServicePeriodStart.Equal(start) || !s.ServicePeriodEnd.Equal(end) {
+					t.Errorf("summary %d = %+v, want it for ut-1 at premise-1 over %s to %s", i, s, start, end)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,224 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+	dbentity "usage-lakehouse/internal/db/entity"
+	"usage-lakehouse/internal/jobs"
+	"usage-lakehouse/internal/region"
+	"usage-lakehouse/internal/repository"
+	"usage-lakehouse/internal/vee"
+
+	"github.com/google/uuid"
+)
+
+// VEEHandler runs validation, estimation and editing over meters' normalized intervals. Ingestion
+// queues a VEE job for every meter an 867 reported intervals for; a run can also be requested for one
+// meter and date range.
+type VEEHandler struct {
+	meterRepo        repository.MeterRepository
+	powerRegionRepo  repository.PowerRegionRepository
+	meterUsageRepo   repository.MeterUsageRepository
+	ingestionJobRepo repository.IngestionJobRepository
+	engine           *vee.Engine
+}
+
+func NewVEEHandler(meterRepo repository.MeterRepository, powerRegionRepo repository.PowerRegionRepository, meterUsageRepo repository.MeterUsageRepository, ingestionJobRepo repository.IngestionJobRepository, engine *vee.Engine) *VEEHandler {
+	return &VEEHandler{meterRepo: meterRepo, powerRegionRepo: powerRegionRepo, meterUsageRepo: meterUsageRepo, ingestionJobRepo: ingestionJobRepo, engine: engine}
+}
+
+// veeJobParams selects the meters and the UTC window [From, To) a VEE job runs over.
+type veeJobParams struct {
+	MeterIDs []string  `json:"meter_ids"`
+	From     time.Time `json:"from"`
+	To       time.Time `json:"to"`
+}
+
+type veeJobOutput struct {
+	Flags     int `json:"flags"`
+	Estimated int `json:"estimated"`
+	Edited    int `json:"edited"`
+}
+
+// queueVEE queues a VEE job over the meters' intervals in [from, to). VEE jobs carry no payload.
+func queueVEE(ctx context.Context, repo repository.IngestionJobRepository, meterIDs []string, from time.Time, to time.Time) (*dbentity.IngestionJob, error) {
+	params, err := json.Marshal(veeJobParams{MeterIDs: meterIDs, From: from.UTC(), To: to.UTC()})
+	if err != nil {
+		return nil, err
+	}
+	job := dbentity.IngestionJob{ID: uuid.New().String(), Kind: dbentity.IngestionJobKindVEE, Params: params}
+	if err := repo.Create(ctx, &job); err != nil {
+		return nil, err
+	}
+	return &job, nil
+}
+
+// RunMeterVEE queues a VEE run over the meter's intervals for the local days from..to (YYYY-MM-DD,
+// inclusive) and answers 202 with the job to poll.
+func (h *VEEHandler) RunMeterVEE(w http.ResponseWriter, r *http.Request) {
+	meter, _, start, end, ok := loadMeterDays(w, r, h.meterRepo, h.powerRegionRepo)
+	if !ok {
+		return
+	}
+	job, err := queueVEE(r.Context(), h.ingestionJobRepo, []string{meter.ID}, start, end)
+	if err != nil {
+		writeIngestError(w, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Location", "/jobs/"+job.ID)
+	w.WriteHeader(http.StatusAccepted)
+	json.NewEncoder(w).Encode(ingestionJobSubmission{JobID: job.ID, Status: job.Status})
+}
+
+// ProcessIngestionJob runs VEE over each meter of a queued job. A meter that fails is reported by its
+// position in meter_ids and does not stop the others.
+func (h *VEEHandler) ProcessIngestionJob(ctx context.Context, job *dbentity.IngestionJob) (jobs.Result, error) {
+	var params veeJobParams
+	if err := json.Unmarshal(job.Params, &params); err != nil {
+		return jobs.Result{}, err
+	}
+	var output veeJobOutput
+	result := jobs.Result{Errors: []dbentity.IngestionJobError{}, Output: &output}
+	for i, meterID := range params.MeterIDs {
+		result.Processed++
+		if err := h.run(ctx, meterID, params.From, params.To, &output); err != nil {
+			result.Failed++
+			result.Errors = append(result.Errors, dbentity.IngestionJobError{Item: i + 1, Field: "meter_ids", Message: fmt.Sprintf("meter %s: %v", meterID, err)})
+			continue
+		}
+		result.Succeeded++
+	}
+	return result, nil
+}
+
+func (h *VEEHandler) run(ctx context.Context, meterID string, from time.Time, to time.Time, output *veeJobOutput) error {
+	meter, err := h.meterRepo.GetByID(ctx, meterID)
+	if err != nil {
+		return err
+	}
+	powerRegion, err := h.powerRegionRepo.GetByID(ctx, meter.PowerRegionID)
+	if err != nil {
+		return err
+	}
+	loc, err := time.LoadLocation(powerRegion.TimeZone)
+	if err != nil {
+		return err
+	}
+	adapter, ok := region.Lookup(powerRegion.Name)
+	if !ok {
+		return fmt.Errorf("no adapter for power region %s", powerRegion.Name)
+	}
+	from, to = from.UTC(), to.UTC()
+	series := vee.Series{
+		MeterID:     meter.ID,
+		Length:      defaultIntervalLength,
+		Location:    loc,
+		MaxDemandKW: meter.MaxDemandKW,
+		From:        from,
+		To:          to,
+	}
+	if meter.IntervalMinutes > 0 {
+		series.Length = time.Duration(meter.IntervalMinutes) * time.Minute
+	}
+
+	rows, err := h.meterUsageRepo.ListIntervals(ctx, meter.ID, from.Add(-h.engine.Lookback()), to)
+	if err != nil {
+		return err
+	}
+	for _, row := range rows {
+		interval := vee.Interval{Start: row.Start.UTC(), End: row.End.UTC(), Consumption: row.Consumption, Quality: row.Quality}
+		if row.QualityRule != nil {
+			interval.QualityRule = *row.QualityRule
+		}
+		if row.IsCanceled {
+			interval.Consumption = nil
+		}
+		if interval.Start.Before(from) {
+			series.History = append(series.History, interval)
+		} else {
+			series.Intervals = append(series.Intervals, interval)
+		}
+	}
+	summaries, err := h.meterUsageRepo.ListSummaries(ctx, meter.ID, from.In(loc), to.In(loc))
+	if err != nil {
+		return err
+	}
+	for _, s := range summaries {
+		if adapter.Channel(s.Channel) != region.ChannelConsumption {
+			continue
+		}
+		series.Summaries = append(series.Summaries, vee.Summary{
+			Start:    localMidnight(s.ServicePeriodStart, loc).UTC(),
+			End:      localMidnight(s.ServicePeriodEnd, loc).UTC(),
+			Quantity: s.Quantity,
+		})
+	}
+
+	result := h.engine.Run(&series)
+	flags := make([]dbentity.MeterUsageVEEFlag, 0, len(result.Flags))
+	for _, f := range result.Flags {
+		flags = append(flags, dbentity.MeterUsageVEEFlag{
+			ID:         uuid.New().String(),
+			MeterID:    meter.ID,
+			Rule:       f.Rule,
+			Start:      f.Start,
+			End:        f.End,
+			Value:      f.Value,
+			Message:    f.Message,
+			IsReplaced: f.Replace,
+		})
+	}
+	var intervals []dbentity.MeterUsageInterval
+	for _, v := range result.Values {
+		// Estimates take the premise, account and service period of the nearest stored interval.
+		neighbour, ok := nearestInterval(rows, v.Start)
+		if !ok {
+			break
+		}
+		consumption, rule := v.Consumption, v.Rule
+		intervals = append(intervals, dbentity.MeterUsageInterval{
+			MeterID:            meter.ID,
+			PremiseID:          neighbour.PremiseID,
+			AccountID:          neighbour.AccountID,
+			Start:              v.Start,
+			End:                v.End,
+			ServicePeriodStart: neighbour.ServicePeriodStart,
+			ServicePeriodEnd:   neighbour.ServicePeriodEnd,
+			Consumption:        &consumption,
+			Quality:            v.Quality,
+			QualityRule:        &rule,
+		})
+		if v.Quality == vee.QualityEdited {
+			output.Edited++
+		} else {
+			output.Estimated++
+		}
+	}
+	output.Flags += len(flags)
+	return h.meterUsageRepo.ApplyVEE(ctx, meter.ID, from, to, flags, intervals)
+}
+
+// nearestInterval returns the last stored interval starting at or before t, or the first one after
+// it. intervals are in start order.
+func nearestInterval(intervals []dbentity.MeterUsageInterval, t time.Time) (dbentity.MeterUsageInterval, bool) {
+	if len(intervals) == 0 {
+		return dbentity.MeterUsageInterval{}, false
+	}
+	nearest := intervals[0]
+	for _, i := range intervals {
+		if i.Start.After(t) {
+			break
+		}
+		nearest = i
+	}
+	return nearest, true
+}
+
+// localMidnight is the start of date in loc. DATE columns scan as midnight UTC.
+func localMidnight(date time.Time, loc *time.Location) time.Time {
+	return time.Date(date.Year(), date.Month(), date.Day(), 0, 0, 0, 0, loc)
+}
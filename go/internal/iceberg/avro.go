@@ -0,0 +1,469 @@
+package iceberg
+
+import (
+	"bufio"
+	"bytes"
+	"compress/flate"
+	"crypto/rand"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+)
+
+// Manifests and manifest lists are Avro object container files. This is the subset of Avro they use:
+// records, unions, arrays, maps, fixed and the primitive types, with the null and deflate codecs.
+// Values are decoded generically, records as map[string]any, so manifests written by other engines
+// can be read whatever optional fields they carry.
+
+var avroMagic = []byte{'O', 'b', 'j', 1}
+
+type avroType struct {
+	kind     string
+	name     string
+	fields   []avroField
+	items    *avroType
+	branches []*avroType
+	symbols  []string
+	size     int
+}
+
+type avroField struct {
+	name string
+	typ  *avroType
+}
+
+func parseAvroSchema(schema []byte) (*avroType, error) {
+	var raw any
+	if err := json.Unmarshal(schema, &raw); err != nil {
+		return nil, err
+	}
+	return parseAvroType(raw, make(map[string]*avroType))
+}
+
+func mustParseAvroSchema(schema string) *avroType {
+	t, err := parseAvroSchema([]byte(schema))
+	if err != nil {
+		panic(err)
+	}
+	return t
+}
+
+func parseAvroType(raw any, named map[string]*avroType) (*avroType, error) {
+	switch v := raw.(type) {
+	case string:
+		switch v {
+		case "null", "boolean", "int", "long", "float", "double", "bytes", "string":
+			return &avroType{kind: v}, nil
+		}
+		if t, ok := named[v]; ok {
+			return t, nil
+		}
+		return nil, fmt.Errorf("avro: unknown type %q", v)
+	case []any:
+		t := &avroType{kind: "union"}
+		for _, b := range v {
+			branch, err := parseAvroType(b, named)
+			if err != nil {
+				return nil, err
+			}
+			t.branches = append(t.branches, branch)
+		}
+		return t, nil
+	case map[string]any:
+		kind, _ := v["type"].(string)
+		name, _ := v["name"].(string)
+		switch kind {
+		case "record", "error":
+			t := &avroType{kind: "record", name: name}
+			named[name] = t
+			fields, _ := v["fields"].([]any)
+			for _, f := range fields {
+				field, _ := f.(map[string]any)
+				fieldName, _ := field["name"].(string)
+				ft, err := parseAvroType(field["type"], named)
+				if err != nil {
+					return nil, err
+				}
+				t.fields = append(t.fields, avroField{name: fieldName, typ: ft})
+			}
+			return t, nil
+		case "array", "map":
+			key := "items"
+			if kind == "map" {
+				key = "values"
+			}
+			items, err := parseAvroType(v[key], named)
+			if err != nil {
+				return nil, err
+			}
+			return &avroType{kind: kind, items: items}, nil
+		case "enum":
+			t := &avroType{kind: "enum", name: name}
+			symbols, _ := v["symbols"].([]any)
+			for _, s := range symbols {
+				symbol, _ := s.(string)
+				t.symbols = append(t.symbols, symbol)
+			}
+			named[name] = t
+			return t, nil
+		case "fixed":
+			size, _ := v["size"].(float64)
+			t := &avroType{kind: "fixed", name: name, size: int(size)}
+			named[name] = t
+			return t, nil
+		}
+		// A primitive with attributes, such as a logical type.
+		return parseAvroType(v["type"], named)
+	}
+	return nil, fmt.Errorf("avro: invalid schema %v", raw)
+}
+
+type avroEncoder struct {
+	buf bytes.Buffer
+}
+
+func (e *avroEncoder) long(v int64) {
+	var b [binary.MaxVarintLen64]byte
+	n := binary.PutVarint(b[:], v)
+	e.buf.Write(b[:n])
+}
+
+func (e *avroEncoder) bytes(b []byte) {
+	e.long(int64(len(b)))
+	e.buf.Write(b)
+}
+
+func (e *avroEncoder) encode(t *avroType, v any) error {
+	switch t.kind {
+	case "null":
+		return nil
+	case "boolean":
+		b, _ := v.(bool)
+		if b {
+			e.buf.WriteByte(1)
+		} else {
+			e.buf.WriteByte(0)
+		}
+	case "int", "long":
+		n, ok := avroInt(v)
+		if !ok {
+			return fmt.Errorf("avro: %T is not a %s", v, t.kind)
+		}
+		e.long(n)
+	case "float":
+		f, _ := v.(float32)
+		binary.Write(&e.buf, binary.LittleEndian, math.Float32bits(f))
+	case "double":
+		f, _ := v.(float64)
+		binary.Write(&e.buf, binary.LittleEndian, math.Float64bits(f))
+	case "bytes":
+		b, _ := v.([]byte)
+		e.bytes(b)
+	case "string":
+		s, _ := v.(string)
+		e.bytes([]byte(s))
+	case "fixed":
+		b, _ := v.([]byte)
+		if len(b) != t.size {
+			return fmt.Errorf("avro: fixed %s needs %d bytes", t.name, t.size)
+		}
+		e.buf.Write(b)
+	case "enum":
+		s, _ := v.(string)
+		for i, symbol := range t.symbols {
+			if symbol == s {
+				e.long(int64(i))
+				return nil
+			}
+		}
+		return fmt.Errorf("avro: %q is not a symbol of %s", s, t.name)
+	case "record":
+		m, _ := v.(map[string]any)
+		for _, f := range t.fields {
+			if err := e.encode(f.typ, m[f.name]); err != nil {
+				return fmt.Errorf("%s.%s: %w", t.name, f.name, err)
+			}
+		}
+	case "array":
+		items, _ := v.([]any)
+		if len(items) > 0 {
+			e.long(int64(len(items)))
+			for _, item := range items {
+				if err := e.encode(t.items, item); err != nil {
+					return err
+				}
+			}
+		}
+		e.long(0)
+	case "map":
+		m, _ := v.(map[string]any)
+		if len(m) > 0 {
+			e.long(int64(len(m)))
+			for k, item := range m {
+				e.bytes([]byte(k))
+				if err := e.encode(t.items, item); err != nil {
+					return err
+				}
+			}
+		}
+		e.long(0)
+	case "union":
+		// Unions here are optional values: nil takes the null branch, anything else the first other.
+		for i, b := range t.branches {
+			if (v == nil) == (b.kind == "null") {
+				e.long(int64(i))
+				return e.encode(b, v)
+			}
+		}
+		return errors.New("avro: no union branch for value")
+	default:
+		return fmt.Errorf("avro: cannot encode %s", t.kind)
+	}
+	return nil
+}
+
+func avroInt(v any) (int64, bool) {
+	switch n := v.(type) {
+	case int:
+		return int64(n), true
+	case int32:
+		return int64(n), true
+	case int64:
+		return n, true
+	}
+	return 0, false
+}
+
+type avroDecoder struct {
+	r *bufio.Reader
+}
+
+func (d *avroDecoder) long() (int64, error) {
+	return binary.ReadVarint(d.r)
+}
+
+func (d *avroDecoder) bytes() ([]byte, error) {
+	n, err := d.long()
+	if err != nil {
+		return nil, err
+	}
+	if n < 0 {
+		return nil, errors.New("avro: negative length")
+	}
+	b := make([]byte, n)
+	_, err = io.ReadFull(d.r, b)
+	return b, err
+}
+
+// decode reads a value of type t. int and long both decode as int64.
+func (d *avroDecoder) decode(t *avroType) (any, error) {
+	switch t.kind {
+	case "null":
+		return nil, nil
+	case "boolean":
+		b, err := d.r.ReadByte()
+		return b == 1, err
+	case "int", "long":
+		return d.long()
+	case "float":
+		var bits uint32
+		err := binary.Read(d.r, binary.LittleEndian, &bits)
+		return math.Float32frombits(bits), err
+	case "double":
+		var bits uint64
+		err := binary.Read(d.r, binary.LittleEndian, &bits)
+		return math.Float64frombits(bits), err
+	case "bytes":
+		return d.bytes()
+	case "string":
+		b, err := d.bytes()
+		return string(b), err
+	case "fixed":
+		b := make([]byte, t.size)
+		_, err := io.ReadFull(d.r, b)
+		return b, err
+	case "enum":
+		i, err := d.long()
+		if err != nil {
+			return nil, err
+		}
+		if i < 0 || int(i) >= len(t.symbols) {
+			return nil, fmt.Errorf("avro: enum index %d out of range", i)
+		}
+		return t.symbols[i], nil
+	case "record":
+		m := make(map[string]any, len(t.fields))
+		for _, f := range t.fields {
+			v, err := d.decode(f.typ)
+			if err != nil {
+				return nil, err
+			}
+			m[f.name] = v
+		}
+		return m, nil
+	case "array", "map":
+		var items []any
+		m := make(map[string]any)
+		for {
+			n, err := d.long()
+			if err != nil {
+				return nil, err
+			}
+			if n == 0 {
+				break
+			}
+			if n < 0 {
+				// A negative count is followed by the block's size in bytes.
+				n = -n
+				if _, err := d.long(); err != nil {
+					return nil, err
+				}
+			}
+			for ; n > 0; n-- {
+				var key []byte
+				if t.kind == "map" {
+					if key, err = d.bytes(); err != nil {
+						return nil, err
+					}
+				}
+				v, err := d.decode(t.items)
+				if err != nil {
+					return nil, err
+				}
+				if t.kind == "map" {
+					m[string(key)] = v
+				} else {
+					items = append(items, v)
+				}
+			}
+		}
+		if t.kind == "map" {
+			return m, nil
+		}
+		return items, nil
+	case "union":
+		i, err := d.long()
+		if err != nil {
+			return nil, err
+		}
+		if i < 0 || int(i) >= len(t.branches) {
+			return nil, fmt.Errorf("avro: union index %d out of range", i)
+		}
+		return d.decode(t.branches[i])
+	}
+	return nil, fmt.Errorf("avro: cannot decode %s", t.kind)
+}
+
+// writeAvroFile writes records as one deflate-compressed block of an object container file.
+// metadata is stored in the file header alongside the schema.
+func writeAvroFile(w io.Writer, schema string, t *avroType, metadata map[string]string, records []map[string]any) error {
+	var header avroEncoder
+	header.buf.Write(avroMagic)
+	meta := map[string]any{"avro.schema": []byte(schema), "avro.codec": []byte("deflate")}
+	for k, v := range metadata {
+		meta[k] = []byte(v)
+	}
+	if err := header.encode(&avroType{kind: "map", items: &avroType{kind: "bytes"}}, meta); err != nil {
+		return err
+	}
+	sync := make([]byte, 16)
+	if _, err := rand.Read(sync); err != nil {
+		return err
+	}
+	header.buf.Write(sync)
+
+	var block avroEncoder
+	for _, r := range records {
+		if err := block.encode(t, r); err != nil {
+			return err
+		}
+	}
+	var compressed bytes.Buffer
+	fw, err := flate.NewWriter(&compressed, flate.DefaultCompression)
+	if err != nil {
+		return err
+	}
+	if _, err := fw.Write(block.buf.Bytes()); err != nil {
+		return err
+	}
+	if err := fw.Close(); err != nil {
+		return err
+	}
+	header.long(int64(len(records)))
+	header.bytes(compressed.Bytes())
+	header.buf.Write(sync)
+	_, err = w.Write(header.buf.Bytes())
+	return err
+}
+
+// readAvroFile reads every record of an object container file, decoded with the schema in its header.
+func readAvroFile(r io.Reader) (map[string]string, []map[string]any, error) {
+	d := &avroDecoder{r: bufio.NewReader(r)}
+	magic := make([]byte, len(avroMagic))
+	if _, err := io.ReadFull(d.r, magic); err != nil {
+		return nil, nil, err
+	}
+	if !bytes.Equal(magic, avroMagic) {
+		return nil, nil, errors.New("avro: not an object container file")
+	}
+	rawMeta, err := d.decode(&avroType{kind: "map", items: &avroType{kind: "bytes"}})
+	if err != nil {
+		return nil, nil, err
+	}
+	metadata := make(map[string]string)
+	for k, v := range rawMeta.(map[string]any) {
+		metadata[k] = string(v.([]byte))
+	}
+	t, err := parseAvroSchema([]byte(metadata["avro.schema"]))
+	if err != nil {
+		return nil, nil, err
+	}
+	codec := metadata["avro.codec"]
+	if codec != "" && codec != "null" && codec != "deflate" {
+		return nil, nil, fmt.Errorf("avro: unsupported codec %q", codec)
+	}
+	sync := make([]byte, 16)
+	if _, err := io.ReadFull(d.r, sync); err != nil {
+		return nil, nil, err
+	}
+	var records []map[string]any
+	for {
+		count, err := d.long()
+		if err == io.EOF {
+			return metadata, records, nil
+		}
+		if err != nil {
+			return nil, nil, err
+		}
+		data, err := d.bytes()
+		if err != nil {
+			return nil, nil, err
+		}
+		var block io.Reader = bytes.NewReader(data)
+		if codec == "deflate" {
+			block = flate.NewReader(block)
+		}
+		bd := &avroDecoder{r: bufio.NewReader(block)}
+		for ; count > 0; count-- {
+			v, err := bd.decode(t)
+			if err != nil {
+				return nil, nil, err
+			}
+			record, ok := v.(map[string]any)
+			if !ok {
+				return nil, nil, errors.New("avro: file does not hold records")
+			}
+			records = append(records, record)
+		}
+		marker := make([]byte, 16)
+		if _, err := io.ReadFull(d.r, marker); err != nil {
+			return nil, nil, err
+		}
+		if !bytes.Equal(marker, sync) {
+			return nil, nil, errors.New("avro: sync marker mismatch")
+		}
+	}
+}
@@ -0,0 +1,179 @@
+package iceberg
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/hex"
+	"reflect"
+	"testing"
+)
+
+func TestAvroEncode(t *testing.T) {
+	tests := []struct {
+		name   string
+		schema string
+		value  any
+		want   string
+	}{
+		{"long zero", `"long"`, int64(0), "00"},
+		{"long minus one", `"long"`, int64(-1), "01"},
+		{"long one", `"long"`, int64(1), "02"},
+		{"long minus 64", `"long"`, int64(-64), "7f"},
+		{"long 64", `"long"`, int64(64), "8001"},
+		{"long 8192", `"long"`, int64(8192), "808001"},
+		{"int from int32", `"int"`, int32(-3), "05"},
+		{"boolean true", `"boolean"`, true, "01"},
+		{"boolean false", `"boolean"`, false, "00"},
+		{"double", `"double"`, 1.0, "000000000000f03f"},
+		{"string", `"string"`, "foo", "06666f6f"},
+		{"bytes", `"bytes"`, []byte{0xde, 0xad}, "04dead"},
+		{"empty bytes", `"bytes"`, []byte{}, "00"},
+		{"union null", `["null", "string"]`, nil, "00"},
+		{"union value", `["null", "string"]`, "a", "020261"},
+		{"union null second", `["long", "null"]`, nil, "02"},
+		{"array", `{"type": "array", "items": "long"}`, []any{int64(1), int64(2)}, "04020400"},
+		{"empty array", `{"type": "array", "items": "long"}`, []any{}, "00"},
+		{"map", `{"type": "map", "values": "long"}`, map[string]any{"a": int64(1)}, "0202610200"},
+		{"enum", `{"type": "enum", "name": "e", "symbols": ["A", "B"]}`, "B", "02"},
+		{"fixed", `{"type": "fixed", "name": "f", "size": 2}`, []byte{1, 2}, "0102"},
+		{"logical type", `{"type": "int", "logicalType": "date"}`, int32(19724), "98b402"},
+		{
+			"record",
+			`{"type": "record", "name": "r", "fields": [{"name": "id", "type": "long"}, {"name": "tag", "type": ["null", "string"]}]}`,
+			map[string]any{"id": int64(2), "tag": "x"},
+			"04020278",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			typ, err := parseAvroSchema([]byte(tt.schema))
+			if err != nil {
+				t.Fatal(err)
+			}
+			var e avroEncoder
+			if err := e.encode(typ, tt.value); err != nil {
+				t.Fatal(err)
+			}
+			if got := hex.EncodeToString(e.buf.Bytes()); got != tt.want {
+				t.Errorf("encode(%v) = %s, want %s", tt.value, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAvroDecode(t *testing.T) {
+	tests := []struct {
+		name   string
+		schema string
+		data   string
+		want   any
+	}{
+		{"long", `"long"`, "7f", int64(-64)},
+		{"int decodes as int64", `"int"`, "8001", int64(64)},
+		{"string", `"string"`, "06666f6f", "foo"},
+		{"union null", `["null", "string"]`, "00", nil},
+		{"union value", `["null", "string"]`, "020261", "a"},
+		{"array", `{"type": "array", "items": "long"}`, "04020400", []any{int64(1), int64(2)}},
+		// A negative block count is followed by the block's size in bytes.
+		{"array with block size", `{"type": "array", "items": "long"}`, "0304020400", []any{int64(1), int64(2)}},
+		{"map", `{"type": "map", "values": "long"}`, "0202610200", map[string]any{"a": int64(1)}},
+		{"enum", `{"type": "enum", "name": "e", "symbols": ["A", "B"]}`, "02", "B"},
+		{
+			"record",
+			`{"type": "record", "name": "r", "fields": [{"name": "id", "type": "long"}, {"name": "tag", "type": ["null", "string"]}]}`,
+			"0400",
+			map[string]any{"id": int64(2), "tag": nil},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			typ, err := parseAvroSchema([]byte(tt.schema))
+			if err != nil {
+				t.Fatal(err)
+			}
+			data, err := hex.DecodeString(tt.data)
+			if err != nil {
+				t.Fatal(err)
+			}
+			d := &avroDecoder{r: bufio.NewReader(bytes.NewReader(data))}
+			got, err := d.decode(typ)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("decode(%s) = %#v, want %#v", tt.data, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAvroEncodeErrors(t *testing.T) {
+	tests := []struct {
+		name   string
+		schema string
+		value  any
+	}{
+		{"long from string", `"long"`, "1"},
+		{"fixed of wrong size", `{"type": "fixed", "name": "f", "size": 2}`, []byte{1}},
+		{"unknown enum symbol", `{"type": "enum", "name": "e", "symbols": ["A"]}`, "B"},
+		{"null into non-null union", `["long", "string"]`, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var e avroEncoder
+			if err := e.encode(mustParseAvroSchema(tt.schema), tt.value); err == nil {
+				t.Errorf("encode(%v) succeeded, want an error", tt.value)
+			}
+		})
+	}
+}
+
+func TestAvroFileRoundTrip(t *testing.T) {
+	schema := `{"type": "record", "name": "r", "fields": [{"name": "id", "type": "long"}, {"name": "name", "type": ["null", "string"]}]}`
+	records := []map[string]any{
+		{"id": int64(1), "name": "one"},
+		{"id": int64(-2), "name": nil},
+	}
+	var buf bytes.Buffer
+	if err := writeAvroFile(&buf, schema, mustParseAvroSchema(schema), map[string]string{"format-version": "2"}, records); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.HasPrefix(buf.Bytes(), avroMagic) {
+		t.Fatalf("file starts with %x, want the Avro magic", buf.Bytes()[:4])
+	}
+	metadata, got, err := readAvroFile(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if metadata["format-version"] != "2" || metadata["avro.codec"] != "deflate" || metadata["avro.schema"] != schema {
+		t.Errorf("metadata = %v", metadata)
+	}
+	if !reflect.DeepEqual(got, records) {
+		t.Errorf("records = %v, want %v", got, records)
+	}
+}
+
+func TestReadAvroFileRejectsBadInput(t *testing.T) {
+	schema := `{"type": "record", "name": "r", "fields": [{"name": "id", "type": "long"}]}`
+	var buf bytes.Buffer
+	if err := writeAvroFile(&buf, schema, mustParseAvroSchema(schema), nil, []map[string]any{{"id": int64(1)}}); err != nil {
+		t.Fatal(err)
+	}
+	corruptSync := append([]byte{}, buf.Bytes()...)
+	corruptSync[len(corruptSync)-1] ^= 0xff
+
+	tests := []struct {
+		name string
+		data []byte
+	}{
+		{"not avro", []byte("PAR1")},
+		{"sync marker mismatch", corruptSync},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, _, err := readAvroFile(bytes.NewReader(tt.data)); err == nil {
+				t.Error("readAvroFile succeeded, want an error")
+			}
+		})
+	}
+}
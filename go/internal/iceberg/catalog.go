@@ -0,0 +1,38 @@
+package iceberg
+
+import (
+	"context"
+	"errors"
+)
+
+var (
+	// ErrNoSuchTable is returned when the catalog has no entry for a table.
+	ErrNoSuchTable = errors.New("iceberg: no such table")
+	// ErrTableExists is returned when creating a table the catalog already has.
+	ErrTableExists = errors.New("iceberg: table already exists")
+	// ErrCommitConflict is returned when a table's metadata changed after the commit read it.
+	ErrCommitConflict = errors.New("iceberg: table metadata changed during commit")
+)
+
+// Identifier names a table within a catalog.
+type Identifier struct {
+	Namespace string
+	Name      string
+}
+
+func (id Identifier) String() string {
+	return id.Namespace + "." + id.Name
+}
+
+// Catalog tracks the current metadata file of each table. Commits are atomic: a table only moves to
+// new metadata if it still points at the metadata the new file was built from.
+type Catalog interface {
+	// MetadataLocation returns the location of the table's current metadata file, or ErrNoSuchTable.
+	MetadataLocation(ctx context.Context, id Identifier) (string, error)
+	// Create registers a table whose first metadata file is at metadataLocation, or returns
+	// ErrTableExists.
+	Create(ctx context.Context, id Identifier, metadataLocation string) error
+	// Commit points the table at metadataLocation if its current metadata is base, and returns
+	// ErrCommitConflict otherwise.
+	Commit(ctx context.Context, id Identifier, base string, metadataLocation string) error
+}
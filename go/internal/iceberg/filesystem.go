@@ -0,0 +1,97 @@
+package iceberg
+
+import (
+	"context"
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+type filesystemCatalog struct {
+	root string
+}
+
+// NewFilesystemCatalog keeps each table's current metadata location in the file
+// root/<namespace>/<name>. It is meant for tests and local development: commits are serialized with a
+// lock file, which a process that dies mid-commit leaves behind.
+func NewFilesystemCatalog(root string) Catalog {
+	return &filesystemCatalog{root: root}
+}
+
+func (c *filesystemCatalog) path(id Identifier) string {
+	return filepath.Join(c.root, id.Namespace, id.Name)
+}
+
+func (c *filesystemCatalog) MetadataLocation(ctx context.Context, id Identifier) (string, error) {
+	b, err := os.ReadFile(c.path(id))
+	if errors.Is(err, fs.ErrNotExist) {
+		return "", ErrNoSuchTable
+	}
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(string(b)), nil
+}
+
+func (c *filesystemCatalog) Create(ctx context.Context, id Identifier, metadataLocation string) error {
+	return c.swap(id, func(current string, exists bool) error {
+		if exists {
+			return ErrTableExists
+		}
+		return nil
+	}, metadataLocation)
+}
+
+func (c *filesystemCatalog) Commit(ctx context.Context, id Identifier, base string, metadataLocation string) error {
+	return c.swap(id, func(current string, exists bool) error {
+		if !exists {
+			return ErrNoSuchTable
+		}
+		if current != base {
+			return ErrCommitConflict
+		}
+		return nil
+	}, metadataLocation)
+}
+
+// swap replaces the table's pointer with metadataLocation while holding its lock, if check accepts
+// the current pointer. A commit that finds the lock held conflicts rather than waiting.
+func (c *filesystemCatalog) swap(id Identifier, check func(current string, exists bool) error, metadataLocation string) error {
+	path := c.path(id)
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	lock, err := os.OpenFile(path+".lock", os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0o644)
+	if errors.Is(err, fs.ErrExist) {
+		return ErrCommitConflict
+	}
+	if err != nil {
+		return err
+	}
+	lock.Close()
+	defer os.Remove(path + ".lock")
+
+	b, err := os.ReadFile(path)
+	exists := err == nil
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+	if err := check(strings.TrimSpace(string(b)), exists); err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".commit-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.WriteString(metadataLocation + "\n"); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
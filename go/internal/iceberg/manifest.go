@@ -0,0 +1,259 @@
+package iceberg
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+)
+
+// DataFile is a parquet file to add to a table. Path is its full location and Partition holds its
+// value for each partition field of the table's spec, by partition field name: int32 for int, date
+// and the year, month, day and hour transforms, int64 for long and timestamps, and string, bool,
+// float32 or float64 for the other types.
+type DataFile struct {
+	Path            string
+	RecordCount     int64
+	FileSizeInBytes int64
+	Partition       map[string]any
+	// EqualityIDs makes the file an equality delete file: each of its rows deletes the rows of earlier
+	// snapshots whose columns with these field IDs hold the same values.
+	EqualityIDs []int
+}
+
+const (
+	manifestEntryStatusAdded       = 1
+	manifestContentData            = 0
+	manifestContentDeletes         = 1
+	dataFileContentData            = 0
+	dataFileContentEqualityDeletes = 2
+)
+
+// isDeletes reports whether files, which a manifest holds and so are all of one content, are delete
+// files.
+func isDeletes(files []DataFile) bool {
+	return len(files) > 0 && files[0].EqualityIDs != nil
+}
+
+// avroTypes maps Iceberg primitive types to the Avro types manifests store their values as.
+var avroTypes = map[string]any{
+	"boolean":     "boolean",
+	"int":         "int",
+	"long":        "long",
+	"float":       "float",
+	"double":      "double",
+	"string":      "string",
+	"binary":      "bytes",
+	"date":        map[string]any{"type": "int", "logicalType": "date"},
+	"timestamp":   map[string]any{"type": "long", "logicalType": "timestamp-micros", "adjust-to-utc": false},
+	"timestamptz": map[string]any{"type": "long", "logicalType": "timestamp-micros", "adjust-to-utc": true},
+}
+
+// partitionType is the Iceberg type of a partition field's values.
+func partitionType(schema Schema, f PartitionField) (string, error) {
+	source, ok := schema.field(f.SourceID)
+	if !ok {
+		return "", fmt.Errorf("partition field %s: source column %d is not in the schema", f.Name, f.SourceID)
+	}
+	switch f.Transform {
+	case "identity":
+		return source.Type, nil
+	case "year", "month", "hour":
+		return "int", nil
+	case "day":
+		return "date", nil
+	}
+	return "", fmt.Errorf("partition field %s: unsupported transform %s", f.Name, f.Transform)
+}
+
+// manifestSchema is the Avro schema of a v2 data or delete manifest for tables with the given spec.
+func manifestSchema(schema Schema, spec PartitionSpec) (string, error) {
+	partitionFields := []any{}
+	for _, f := range spec.Fields {
+		t, err := partitionType(schema, f)
+		if err != nil {
+			return "", err
+		}
+		avroType, ok := avroTypes[t]
+		if !ok {
+			return "", fmt.Errorf("partition field %s: unsupported type %s", f.Name, t)
+		}
+		partitionFields = append(partitionFields, map[string]any{"name": f.Name, "type": []any{"null", avroType}, "default": nil, "field-id": f.FieldID})
+	}
+	optionalLong := []any{"null", "long"}
+	s := map[string]any{
+		"type": "record",
+		"name": "manifest_entry",
+		"fields": []any{
+			map[string]any{"name": "status", "type": "int", "field-id": 0},
+			map[string]any{"name": "snapshot_id", "type": optionalLong, "default": nil, "field-id": 1},
+			map[string]any{"name": "sequence_number", "type": optionalLong, "default": nil, "field-id": 3},
+			map[string]any{"name": "file_sequence_number", "type": optionalLong, "default": nil, "field-id": 4},
+			map[string]any{"name": "data_file", "field-id": 2, "type": map[string]any{
+				"type": "record",
+				"name": "r2",
+				"fields": []any{
+					map[string]any{"name": "content", "type": "int", "field-id": 134},
+					map[string]any{"name": "file_path", "type": "string", "field-id": 100},
+					map[string]any{"name": "file_format", "type": "string", "field-id": 101},
+					map[string]any{"name": "partition", "field-id": 102, "type": map[string]any{"type": "record", "name": "r102", "fields": partitionFields}},
+					map[string]any{"name": "record_count", "type": "long", "field-id": 103},
+					map[string]any{"name": "file_size_in_bytes", "type": "long", "field-id": 104},
+					map[string]any{"name": "equality_ids", "type": []any{"null", map[string]any{"type": "array", "items": "int", "element-id": 136}}, "default": nil, "field-id": 135},
+				},
+			}},
+		},
+	}
+	b, err := json.Marshal(s)
+	return string(b), err
+}
+
+// writeManifest writes a manifest adding files in snapshotID, either data files or equality delete
+// files. Their sequence numbers are left null so that they inherit the one the manifest list assigns
+// to the manifest.
+func writeManifest(w io.Writer, schema Schema, spec PartitionSpec, snapshotID int64, files []DataFile) error {
+	avroSchema, err := manifestSchema(schema, spec)
+	if err != nil {
+		return err
+	}
+	t, err := parseAvroSchema([]byte(avroSchema))
+	if err != nil {
+		return err
+	}
+	schemaJSON, err := json.Marshal(schema)
+	if err != nil {
+		return err
+	}
+	specJSON, err := json.Marshal(spec.Fields)
+	if err != nil {
+		return err
+	}
+	metadata := map[string]string{
+		"schema":            string(schemaJSON),
+		"schema-id":         strconv.Itoa(schema.SchemaID),
+		"partition-spec":    string(specJSON),
+		"partition-spec-id": strconv.Itoa(spec.SpecID),
+		"format-version":    strconv.Itoa(formatVersion),
+		"content":           "data",
+	}
+	if isDeletes(files) {
+		metadata["content"] = "deletes"
+	}
+	records := make([]map[string]any, 0, len(files))
+	for _, f := range files {
+		partition := make(map[string]any, len(spec.Fields))
+		for _, pf := range spec.Fields {
+			partition[pf.Name] = f.Partition[pf.Name]
+		}
+		dataFile := map[string]any{
+			"content":            int64(dataFileContentData),
+			"file_path":          f.Path,
+			"file_format":        "PARQUET",
+			"partition":          partition,
+			"record_count":       f.RecordCount,
+			"file_size_in_bytes": f.FileSizeInBytes,
+		}
+		if f.EqualityIDs != nil {
+			ids := make([]any, len(f.EqualityIDs))
+			for i, id := range f.EqualityIDs {
+				ids[i] = id
+			}
+			dataFile["content"] = int64(dataFileContentEqualityDeletes)
+			dataFile["equality_ids"] = ids
+		}
+		records = append(records, map[string]any{
+			"status":      int64(manifestEntryStatusAdded),
+			"snapshot_id": snapshotID,
+			"data_file":   dataFile,
+		})
+	}
+	return writeAvroFile(w, avroSchema, t, metadata, records)
+}
+
+const manifestListSchemaJSON = `{
+	"type": "record",
+	"name": "manifest_file",
+	"fields": [
+		{"name": "manifest_path", "type": "string", "field-id": 500},
+		{"name": "manifest_length", "type": "long", "field-id": 501},
+		{"name": "partition_spec_id", "type": "int", "field-id": 502},
+		{"name": "content", "type": "int", "field-id": 517},
+		{"name": "sequence_number", "type": "long", "field-id": 515},
+		{"name": "min_sequence_number", "type": "long", "field-id": 516},
+		{"name": "added_snapshot_id", "type": "long", "field-id": 503},
+		{"name": "added_files_count", "type": "int", "field-id": 504},
+		{"name": "existing_files_count", "type": "int", "field-id": 505},
+		{"name": "deleted_files_count", "type": "int", "field-id": 506},
+		{"name": "added_rows_count", "type": "long", "field-id": 512},
+		{"name": "existing_rows_count", "type": "long", "field-id": 513},
+		{"name": "deleted_rows_count", "type": "long", "field-id": 514},
+		{"name": "partitions", "type": ["null", {"type": "array", "element-id": 508, "items": {
+			"type": "record",
+			"name": "r508",
+			"fields": [
+				{"name": "contains_null", "type": "boolean", "field-id": 509},
+				{"name": "contains_nan", "type": ["null", "boolean"], "default": null, "field-id": 518},
+				{"name": "lower_bound", "type": ["null", "bytes"], "default": null, "field-id": 510},
+				{"name": "upper_bound", "type": ["null", "bytes"], "default": null, "field-id": 511}
+			]
+		}}], "default": null, "field-id": 507},
+		{"name": "key_metadata", "type": ["null", "bytes"], "default": null, "field-id": 519}
+	]
+}`
+
+var manifestListSchema = mustParseAvroSchema(manifestListSchemaJSON)
+
+// manifestFile is the manifest list entry for a manifest of added files.
+func manifestFile(location string, length int64, specID int, snapshotID int64, sequenceNumber int64, files []DataFile) map[string]any {
+	var rows int64
+	for _, f := range files {
+		rows += f.RecordCount
+	}
+	content := manifestContentData
+	if isDeletes(files) {
+		content = manifestContentDeletes
+	}
+	return map[string]any{
+		"manifest_path":        location,
+		"manifest_length":      length,
+		"partition_spec_id":    int64(specID),
+		"content":              int64(content),
+		"sequence_number":      sequenceNumber,
+		"min_sequence_number":  sequenceNumber,
+		"added_snapshot_id":    snapshotID,
+		"added_files_count":    int64(len(files)),
+		"existing_files_count": int64(0),
+		"deleted_files_count":  int64(0),
+		"added_rows_count":     rows,
+		"existing_rows_count":  int64(0),
+		"deleted_rows_count":   int64(0),
+	}
+}
+
+// writeManifestList writes the manifest list of a snapshot. manifests are manifest_file records,
+// either new or carried over from the parent snapshot's list.
+func writeManifestList(w io.Writer, snapshotID int64, parentSnapshotID *int64, sequenceNumber int64, manifests []map[string]any) error {
+	parent := "null"
+	if parentSnapshotID != nil {
+		parent = strconv.FormatInt(*parentSnapshotID, 10)
+	}
+	metadata := map[string]string{
+		"snapshot-id":        strconv.FormatInt(snapshotID, 10),
+		"parent-snapshot-id": parent,
+		"sequence-number":    strconv.FormatInt(sequenceNumber, 10),
+		"format-version":     strconv.Itoa(formatVersion),
+	}
+	return writeAvroFile(w, manifestListSchemaJSON, manifestListSchema, metadata, manifests)
+}
+
+// readManifestList reads the manifest_file records of a snapshot's manifest list.
+func readManifestList(r io.Reader) ([]map[string]any, error) {
+	metadata, manifests, err := readAvroFile(r)
+	if err != nil {
+		return nil, err
+	}
+	if v := metadata["format-version"]; v != strconv.Itoa(formatVersion) {
+		return nil, fmt.Errorf("manifest list has format version %q, want %d", v, formatVersion)
+	}
+	return manifests, nil
+}
@@ -0,0 +1,131 @@
+package iceberg
+
+import (
+	"bytes"
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func testSchema() Schema {
+	return NewSchema(
+		Field{Name: "account_id", Required: true, Type: "string"},
+		Field{Name: "interval_start", Required: true, Type: "timestamptz"},
+		Field{Name: "quantity", Required: true, Type: "double"},
+	)
+}
+
+func TestWriteManifest(t *testing.T) {
+	schema := testSchema()
+	spec, err := NewPartitionSpec(schema,
+		PartitionTransform{Column: "account_id", Transform: "identity"},
+		PartitionTransform{Name: "month", Column: "interval_start", Transform: "month"},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	files := []DataFile{
+		{Path: "s3://bucket/t/data/a.parquet", RecordCount: 10, FileSizeInBytes: 1024, Partition: map[string]any{"account_id": "a1", "month": int32(650)}},
+		{Path: "s3://bucket/t/data/b.parquet", RecordCount: 5, FileSizeInBytes: 512, Partition: map[string]any{"account_id": nil, "month": int32(651)}},
+	}
+	var buf bytes.Buffer
+	if err := writeManifest(&buf, schema, spec, 42, files); err != nil {
+		t.Fatal(err)
+	}
+	metadata, records, err := readAvroFile(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if metadata["format-version"] != "2" || metadata["content"] != "data" || metadata["partition-spec-id"] != "0" {
+		t.Errorf("metadata = %v", metadata)
+	}
+	var specFields []PartitionField
+	if err := json.Unmarshal([]byte(metadata["partition-spec"]), &specFields); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(specFields, spec.Fields) {
+		t.Errorf("partition-spec = %v, want %v", specFields, spec.Fields)
+	}
+	if len(records) != len(files) {
+		t.Fatalf("got %d manifest entries, want %d", len(records), len(files))
+	}
+	for i, r := range records {
+		if r["status"] != int64(manifestEntryStatusAdded) || r["snapshot_id"] != int64(42) {
+			t.Errorf("entry %d: status %v snapshot %v", i, r["status"], r["snapshot_id"])
+		}
+		// Sequence numbers are inherited from the manifest list.
+		if r["sequence_number"] != nil || r["file_sequence_number"] != nil {
+			t.Errorf("entry %d: sequence numbers %v, %v, want null", i, r["sequence_number"], r["file_sequence_number"])
+		}
+		dataFile := r["data_file"].(map[string]any)
+		want := files[i]
+		if dataFile["file_path"] != want.Path || dataFile["file_format"] != "PARQUET" ||
+			dataFile["record_count"] != want.RecordCount || dataFile["file_size_in_bytes"] != want.FileSizeInBytes {
+			t.Errorf("entry %d: data_file %v, want %+v", i, dataFile, want)
+		}
+		partition := dataFile["partition"].(map[string]any)
+		wantPartition := map[string]any{"account_id": want.Partition["account_id"], "month": int64(want.Partition["month"].(int32))}
+		if !reflect.DeepEqual(partition, wantPartition) {
+			t.Errorf("entry %d: partition %v, want %v", i, partition, wantPartition)
+		}
+	}
+}
+
+func TestManifestListRoundTrip(t *testing.T) {
+	files := []DataFile{{RecordCount: 3}, {RecordCount: 4}}
+	parent := int64(7)
+	manifests := []map[string]any{
+		manifestFile("s3://bucket/t/metadata/new-m0.avro", 2048, 1, 8, 2, files),
+		manifestFile("s3://bucket/t/metadata/old-m0.avro", 1024, 0, 7, 1, files[:1]),
+	}
+	var buf bytes.Buffer
+	if err := writeManifestList(&buf, 8, &parent, 2, manifests); err != nil {
+		t.Fatal(err)
+	}
+	got, err := readManifestList(bytes.NewReader(buf.Bytes()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []map[string]any{
+		{
+			"manifest_path": "s3://bucket/t/metadata/new-m0.avro", "manifest_length": int64(2048), "partition_spec_id": int64(1),
+			"content": int64(0), "sequence_number": int64(2), "min_sequence_number": int64(2), "added_snapshot_id": int64(8),
+			"added_files_count": int64(2), "existing_files_count": int64(0), "deleted_files_count": int64(0),
+			"added_rows_count": int64(7), "existing_rows_count": int64(0), "deleted_rows_count": int64(0),
+			"partitions": nil, "key_metadata": nil,
+		},
+		{
+			"manifest_path": "s3://bucket/t/metadata/old-m0.avro", "manifest_length": int64(1024), "partition_spec_id": int64(0),
+			"content": int64(0), "sequence_number": int64(1), "min_sequence_number": int64(1), "added_snapshot_id": int64(7),
+			"added_files_count": int64(1), "existing_files_count": int64(0), "deleted_files_count": int64(0),
+			"added_rows_count": int64(3), "existing_rows_count": int64(0), "deleted_rows_count": int64(0),
+			"partitions": nil, "key_metadata": nil,
+		},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("manifest list = %v\nwant %v", got, want)
+	}
+
+	// Entries read back are written unchanged into the next snapshot's list.
+	var next bytes.Buffer
+	if err := writeManifestList(&next, 9, nil, 3, got); err != nil {
+		t.Fatal(err)
+	}
+	again, err := readManifestList(&next)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(again, want) {
+		t.Errorf("rewritten manifest list = %v\nwant %v", again, want)
+	}
+}
+
+func TestReadManifestListRejectsFormatVersion(t *testing.T) {
+	var buf bytes.Buffer
+	if err := writeAvroFile(&buf, manifestListSchemaJSON, manifestListSchema, map[string]string{"format-version": "1"}, nil); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := readManifestList(&buf); err == nil {
+		t.Error("readManifestList accepted a format version 1 list")
+	}
+}
@@ -0,0 +1,308 @@
+package iceberg
+
+import (
+	"encoding/json"
+	"fmt"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const formatVersion = 2
+
+// Field is a column of a table schema. Type is an Iceberg primitive type name, e.g. string, long,
+// double, date or timestamptz; nested types are not supported.
+type Field struct {
+	ID       int    `json:"id"`
+	Name     string `json:"name"`
+	Required bool   `json:"required"`
+	Type     string `json:"type"`
+	Doc      string `json:"doc,omitempty"`
+}
+
+type Schema struct {
+	Type     string  `json:"type"`
+	SchemaID int     `json:"schema-id"`
+	Fields   []Field `json:"fields"`
+}
+
+// NewSchema numbers fields from 1 in the order given.
+func NewSchema(fields ...Field) Schema {
+	for i := range fields {
+		fields[i].ID = i + 1
+	}
+	return Schema{Type: "struct", Fields: fields}
+}
+
+func (s Schema) field(id int) (Field, bool) {
+	for _, f := range s.Fields {
+		if f.ID == id {
+			return f, true
+		}
+	}
+	return Field{}, false
+}
+
+func (s Schema) fieldByName(name string) (Field, bool) {
+	for _, f := range s.Fields {
+		if f.Name == name {
+			return f, true
+		}
+	}
+	return Field{}, false
+}
+
+// PartitionField derives a partition value from a source column. Transform is identity, year, month,
+// day or hour.
+type PartitionField struct {
+	Name      string `json:"name"`
+	Transform string `json:"transform"`
+	SourceID  int    `json:"source-id"`
+	FieldID   int    `json:"field-id"`
+}
+
+type PartitionSpec struct {
+	SpecID int              `json:"spec-id"`
+	Fields []PartitionField `json:"fields"`
+}
+
+// firstPartitionFieldID is where partition field IDs start, per the Iceberg spec.
+const firstPartitionFieldID = 1000
+
+// NewPartitionSpec partitions by the given transforms of the schema's columns. Partition fields not
+// given a name are named after their column, or column_transform for transforms other than identity.
+func NewPartitionSpec(schema Schema, transforms ...PartitionTransform) (PartitionSpec, error) {
+	spec := PartitionSpec{Fields: []PartitionField{}}
+	for i, t := range transforms {
+		source, ok := schema.fieldByName(t.Column)
+		if !ok {
+			return spec, fmt.Errorf("partition column %s is not in the schema", t.Column)
+		}
+		name := t.Name
+		if name == "" {
+			name = source.Name
+			if t.Transform != "identity" {
+				name += "_" + t.Transform
+			}
+		}
+		spec.Fields = append(spec.Fields, PartitionField{Name: name, Transform: t.Transform, SourceID: source.ID, FieldID: firstPartitionFieldID + i})
+	}
+	return spec, nil
+}
+
+// PartitionTransform names a column and the transform a table is partitioned by.
+type PartitionTransform struct {
+	Name      string
+	Column    string
+	Transform string
+}
+
+type SortOrder struct {
+	OrderID int   `json:"order-id"`
+	Fields  []any `json:"fields"`
+}
+
+type Snapshot struct {
+	SnapshotID       int64             `json:"snapshot-id"`
+	ParentSnapshotID *int64            `json:"parent-snapshot-id,omitempty"`
+	SequenceNumber   int64             `json:"sequence-number"`
+	TimestampMS      int64             `json:"timestamp-ms"`
+	ManifestList     string            `json:"manifest-list"`
+	Summary          map[string]string `json:"summary"`
+	SchemaID         int               `json:"schema-id"`
+}
+
+type SnapshotLogEntry struct {
+	TimestampMS int64 `json:"timestamp-ms"`
+	SnapshotID  int64 `json:"snapshot-id"`
+}
+
+type MetadataLogEntry struct {
+	TimestampMS  int64  `json:"timestamp-ms"`
+	MetadataFile string `json:"metadata-file"`
+}
+
+type SnapshotRef struct {
+	SnapshotID int64  `json:"snapshot-id"`
+	Type       string `json:"type"`
+}
+
+// Metadata is a table's metadata.json in format version 2.
+type Metadata struct {
+	FormatVersion      int               `json:"format-version"`
+	TableUUID          string            `json:"table-uuid"`
+	Location           string            `json:"location"`
+	LastSequenceNumber int64             `json:"last-sequence-number"`
+	LastUpdatedMS      int64             `json:"last-updated-ms"`
+	LastColumnID       int               `json:"last-column-id"`
+	Schemas            []Schema          `json:"schemas"`
+	CurrentSchemaID    int               `json:"current-schema-id"`
+	PartitionSpecs     []PartitionSpec   `json:"partition-specs"`
+	DefaultSpecID      int               `json:"default-spec-id"`
+	LastPartitionID    int               `json:"last-partition-id"`
+	Properties         map[string]string `json:"properties"`
+	// CurrentSnapshotID is -1 until the first snapshot; older readers require the field.
+	CurrentSnapshotID  int64                  `json:"current-snapshot-id"`
+	Snapshots          []Snapshot             `json:"snapshots"`
+	SnapshotLog        []SnapshotLogEntry     `json:"snapshot-log"`
+	MetadataLog        []MetadataLogEntry     `json:"metadata-log"`
+	SortOrders         []SortOrder            `json:"sort-orders"`
+	DefaultSortOrderID int                    `json:"default-sort-order-id"`
+	Refs               map[string]SnapshotRef `json:"refs"`
+}
+
+// newMetadata describes an empty table at location. The default name mapping is written so that
+// engines can read data files whose parquet schema carries no field IDs.
+func newMetadata(location string, schema Schema, spec PartitionSpec, properties map[string]string) (*Metadata, error) {
+	props := map[string]string{}
+	for k, v := range properties {
+		props[k] = v
+	}
+	mapping, err := nameMapping(schema)
+	if err != nil {
+		return nil, err
+	}
+	props["schema.name-mapping.default"] = mapping
+	lastColumnID := 0
+	for _, f := range schema.Fields {
+		lastColumnID = max(lastColumnID, f.ID)
+	}
+	lastPartitionID := firstPartitionFieldID - 1
+	for _, f := range spec.Fields {
+		lastPartitionID = max(lastPartitionID, f.FieldID)
+	}
+	return &Metadata{
+		FormatVersion:      formatVersion,
+		TableUUID:          uuid.New().String(),
+		Location:           location,
+		LastUpdatedMS:      time.Now().UnixMilli(),
+		LastColumnID:       lastColumnID,
+		Schemas:            []Schema{schema},
+		CurrentSchemaID:    schema.SchemaID,
+		PartitionSpecs:     []PartitionSpec{spec},
+		DefaultSpecID:      spec.SpecID,
+		LastPartitionID:    lastPartitionID,
+		Properties:         props,
+		CurrentSnapshotID:  -1,
+		Snapshots:          []Snapshot{},
+		SnapshotLog:        []SnapshotLogEntry{},
+		MetadataLog:        []MetadataLogEntry{},
+		SortOrders:         []SortOrder{{OrderID: 0, Fields: []any{}}},
+		DefaultSortOrderID: 0,
+		Refs:               map[string]SnapshotRef{},
+	}, nil
+}
+
+func nameMapping(schema Schema) (string, error) {
+	type mappedField struct {
+		FieldID int      `json:"field-id"`
+		Names   []string `json:"names"`
+	}
+	mapping := make([]mappedField, 0, len(schema.Fields))
+	for _, f := range schema.Fields {
+		mapping = append(mapping, mappedField{FieldID: f.ID, Names: []string{f.Name}})
+	}
+	b, err := json.Marshal(mapping)
+	return string(b), err
+}
+
+func (m *Metadata) schema() (Schema, error) {
+	for _, s := range m.Schemas {
+		if s.SchemaID == m.CurrentSchemaID {
+			return s, nil
+		}
+	}
+	return Schema{}, fmt.Errorf("current schema %d is missing", m.CurrentSchemaID)
+}
+
+// useSpec makes the spec with spec's fields the default, adding it to the table when no earlier spec
+// has the same fields.
+func (m *Metadata) useSpec(spec PartitionSpec) PartitionSpec {
+	s := m.addSpec(spec)
+	m.DefaultSpecID = s.SpecID
+	return s
+}
+
+// addSpec returns the table's spec with spec's fields, adding it when no earlier spec has the same
+// fields. Fields an earlier spec already had, by source column and transform, keep their field IDs,
+// so data files written under either spec agree on the partition values.
+func (m *Metadata) addSpec(spec PartitionSpec) PartitionSpec {
+	for _, s := range m.PartitionSpecs {
+		if samePartitionFields(s.Fields, spec.Fields) {
+			return s
+		}
+	}
+	next := PartitionSpec{Fields: make([]PartitionField, 0, len(spec.Fields))}
+	for _, s := range m.PartitionSpecs {
+		next.SpecID = max(next.SpecID, s.SpecID+1)
+	}
+	for _, f := range spec.Fields {
+		f.FieldID = m.partitionFieldID(f)
+		next.Fields = append(next.Fields, f)
+	}
+	m.PartitionSpecs = append(append([]PartitionSpec{}, m.PartitionSpecs...), next)
+	return next
+}
+
+func (m *Metadata) partitionFieldID(f PartitionField) int {
+	for _, s := range m.PartitionSpecs {
+		for _, existing := range s.Fields {
+			if existing.SourceID == f.SourceID && existing.Transform == f.Transform {
+				return existing.FieldID
+			}
+		}
+	}
+	m.LastPartitionID++
+	return m.LastPartitionID
+}
+
+func samePartitionFields(a []PartitionField, b []PartitionField) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i].Name != b[i].Name || a[i].Transform != b[i].Transform || a[i].SourceID != b[i].SourceID {
+			return false
+		}
+	}
+	return true
+}
+
+// sameColumns reports whether two schemas have the same columns, ignoring their docs.
+func sameColumns(a Schema, b Schema) bool {
+	if len(a.Fields) != len(b.Fields) {
+		return false
+	}
+	for i := range a.Fields {
+		x, y := a.Fields[i], b.Fields[i]
+		if x.ID != y.ID || x.Name != y.Name || x.Type != y.Type || x.Required != y.Required {
+			return false
+		}
+	}
+	return true
+}
+
+func (m *Metadata) currentSnapshot() *Snapshot {
+	for i := range m.Snapshots {
+		if m.Snapshots[i].SnapshotID == m.CurrentSnapshotID {
+			return &m.Snapshots[i]
+		}
+	}
+	return nil
+}
+
+// metadataFileName names the metadata file following the one at base, as Java engines do:
+// 00000-<uuid>.metadata.json, 00001-<uuid>.metadata.json and so on.
+func metadataFileName(base string) string {
+	version := 0
+	if base != "" {
+		prefix, _, _ := strings.Cut(path.Base(base), "-")
+		if n, err := strconv.Atoi(prefix); err == nil {
+			version = n + 1
+		}
+	}
+	return fmt.Sprintf("%05d-%s.metadata.json", version, uuid.New().String())
+}
@@ -0,0 +1,104 @@
+package iceberg
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// ParsePartitionTransforms reads a partitioning written as comma-separated fields, each a column or
+// transform(column) and optionally preceded by name=, e.g.
+// power_region,account_id,year=year(interval_start),month=month(interval_start).
+func ParsePartitionTransforms(s string) ([]PartitionTransform, error) {
+	var transforms []PartitionTransform
+	for _, field := range strings.Split(s, ",") {
+		field = strings.TrimSpace(field)
+		if field == "" {
+			continue
+		}
+		t := PartitionTransform{Transform: "identity"}
+		if name, rest, ok := strings.Cut(field, "="); ok {
+			t.Name = strings.TrimSpace(name)
+			field = strings.TrimSpace(rest)
+		}
+		t.Column = field
+		if transform, rest, ok := strings.Cut(field, "("); ok {
+			column, ok := strings.CutSuffix(rest, ")")
+			if !ok {
+				return nil, fmt.Errorf("partition field %s: missing )", field)
+			}
+			t.Transform = strings.TrimSpace(transform)
+			t.Column = strings.TrimSpace(column)
+		}
+		switch t.Transform {
+		case "identity", "year", "month", "day", "hour":
+		default:
+			return nil, fmt.Errorf("partition field %s: unsupported transform %s", field, t.Transform)
+		}
+		transforms = append(transforms, t)
+	}
+	return transforms, nil
+}
+
+// Partition computes a row's partition values under the spec, by partition field name. value returns
+// the row's value of a column as written to parquet: timestamps as int64 microseconds since the
+// epoch, dates as int32 days, and nil for null.
+func (s PartitionSpec) Partition(schema Schema, value func(column string) any) (map[string]any, error) {
+	partition := make(map[string]any, len(s.Fields))
+	for _, f := range s.Fields {
+		source, ok := schema.field(f.SourceID)
+		if !ok {
+			return nil, fmt.Errorf("partition field %s: source column %d is not in the schema", f.Name, f.SourceID)
+		}
+		v, err := transform(f.Transform, source.Type, value(source.Name))
+		if err != nil {
+			return nil, fmt.Errorf("partition field %s: %w", f.Name, err)
+		}
+		partition[f.Name] = v
+	}
+	return partition, nil
+}
+
+// transform applies a partition transform to a value of sourceType. Time transforms give int32
+// years, months, days or hours since 1970-01-01 as the Iceberg spec defines them.
+func transform(name string, sourceType string, v any) (any, error) {
+	if v == nil || name == "identity" {
+		return v, nil
+	}
+	n, ok := avroInt(v)
+	if !ok {
+		return nil, fmt.Errorf("%s of %T", name, v)
+	}
+	var t time.Time
+	switch sourceType {
+	case "timestamp", "timestamptz":
+		t = time.UnixMicro(n).UTC()
+	case "date":
+		t = time.Unix(0, 0).UTC().AddDate(0, 0, int(n))
+	default:
+		return nil, fmt.Errorf("%s of a %s column", name, sourceType)
+	}
+	switch name {
+	case "year":
+		return int32(t.Year() - 1970), nil
+	case "month":
+		return int32((t.Year()-1970)*12 + int(t.Month()) - 1), nil
+	case "day":
+		return int32(floorDiv(t.Unix(), 86400)), nil
+	case "hour":
+		if sourceType == "date" {
+			return nil, fmt.Errorf("hour of a date column")
+		}
+		return int32(floorDiv(t.Unix(), 3600)), nil
+	}
+	return nil, fmt.Errorf("unsupported transform %s", name)
+}
+
+// floorDiv rounds down, so instants before 1970 fall in the day or hour they belong to.
+func floorDiv(a int64, b int64) int64 {
+	q := a / b
+	if a%b < 0 {
+		q--
+	}
+	return q
+}
@@ -0,0 +1,207 @@
+package iceberg
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestTransform(t *testing.T) {
+	micros := func(year int, month time.Month, day, hour, minute int) int64 {
+		return time.Date(year, month, day, hour, minute, 0, 0, time.UTC).UnixMicro()
+	}
+	tests := []struct {
+		name       string
+		transform  string
+		sourceType string
+		value      any
+		want       any
+	}{
+		{"identity", "identity", "string", "ERCOT", "ERCOT"},
+		{"null", "month", "timestamptz", nil, nil},
+		{"year", "year", "timestamptz", micros(2024, 3, 10, 8, 0), int32(54)},
+		{"month", "month", "timestamptz", micros(2024, 3, 10, 8, 0), int32(650)},
+		{"day", "day", "timestamptz", micros(2024, 3, 10, 8, 0), int32(19792)},
+		{"hour", "hour", "timestamptz", micros(2024, 3, 10, 8, 59), int32(19792*24 + 8)},
+		{"timestamp without zone", "day", "timestamp", micros(1970, 1, 2, 0, 0), int32(1)},
+		{"epoch", "hour", "timestamptz", int64(0), int32(0)},
+		// Instants before 1970 fall in the hour, day and month they belong to rather than rounding
+		// towards the epoch.
+		{"hour before 1970", "hour", "timestamptz", micros(1969, 12, 31, 23, 30), int32(-1)},
+		{"day before 1970", "day", "timestamptz", micros(1969, 12, 31, 23, 30), int32(-1)},
+		{"month before 1970", "month", "timestamptz", micros(1969, 12, 31, 23, 30), int32(-1)},
+		{"year before 1970", "year", "timestamptz", micros(1969, 12, 31, 23, 30), int32(-1)},
+		{"year of a date", "year", "date", int32(19792), int32(54)},
+		{"month of a date", "month", "date", int32(19792), int32(650)},
+		{"day of a date", "day", "date", int32(19792), int32(19792)},
+		{"day of a date before 1970", "day", "date", int32(-1), int32(-1)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := transform(tt.transform, tt.sourceType, tt.value)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want {
+				t.Errorf("%s(%v) = %#v, want %#v", tt.transform, tt.value, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTransformErrors(t *testing.T) {
+	tests := []struct {
+		name       string
+		transform  string
+		sourceType string
+		value      any
+	}{
+		{"hour of a date", "hour", "date", int32(1)},
+		{"year of a string", "year", "string", "2024"},
+		{"month of a double", "month", "double", 1.5},
+		{"unknown transform", "bucket", "timestamptz", int64(0)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got, err := transform(tt.transform, tt.sourceType, tt.value); err == nil {
+				t.Errorf("%s(%v) = %v, want an error", tt.transform, tt.value, got)
+			}
+		})
+	}
+}
+
+func TestParsePartitionTransforms(t *testing.T) {
+	got, err := ParsePartitionTransforms(" power_region, account_id ,year=year(interval_start), month( interval_start ),")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []PartitionTransform{
+		{Column: "power_region", Transform: "identity"},
+		{Column: "account_id", Transform: "identity"},
+		{Name: "year", Column: "interval_start", Transform: "year"},
+		{Column: "interval_start", Transform: "month"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("transforms = %+v, want %+v", got, want)
+	}
+	for _, bad := range []string{"bucket(account_id)", "day(interval_start", "truncate[4](account_id)"} {
+		if _, err := ParsePartitionTransforms(bad); err == nil {
+			t.Errorf("ParsePartitionTransforms(%q) succeeded, want an error", bad)
+		}
+	}
+}
+
+func TestPartitionPathValue(t *testing.T) {
+	tests := []struct {
+		transform string
+		value     any
+		want      string
+	}{
+		{"year", int32(54), "2024"},
+		{"year", int32(-1), "1969"},
+		{"month", int32(650), "2024-03"},
+		{"month", int32(-1), "1969-12"},
+		{"day", int32(19792), "2024-03-10"},
+		{"day", int32(-1), "1969-12-31"},
+		{"hour", int32(19792*24 + 8), "2024-03-10-08"},
+		{"hour", int32(-1), "1969-12-31-23"},
+		{"identity", "ERCOT", "ERCOT"},
+		{"identity", "a/b c&d=e", "a%2Fb+c%26d%3De"},
+		{"identity", int64(42), "42"},
+		{"identity", true, "true"},
+		{"month", nil, "null"},
+	}
+	for _, tt := range tests {
+		if got := partitionPathValue(tt.transform, tt.value); got != tt.want {
+			t.Errorf("partitionPathValue(%s, %#v) = %q, want %q", tt.transform, tt.value, got, tt.want)
+		}
+	}
+}
+
+func TestPartitionPath(t *testing.T) {
+	schema := testSchema()
+	tests := []struct {
+		name       string
+		transforms []PartitionTransform
+		partition  map[string]any
+		want       string
+	}{
+		{
+			name: "identity and time transforms",
+			transforms: []PartitionTransform{
+				{Column: "account_id", Transform: "identity"},
+				{Name: "year", Column: "interval_start", Transform: "year"},
+				{Name: "month", Column: "interval_start", Transform: "month"},
+			},
+			partition: map[string]any{"account_id": "a1", "year": int32(54), "month": int32(650)},
+			want:      "account_id=a1/year=2024/month=2024-03",
+		},
+		{
+			name: "default names",
+			transforms: []PartitionTransform{
+				{Column: "interval_start", Transform: "day"},
+				{Column: "interval_start", Transform: "hour"},
+			},
+			partition: map[string]any{"interval_start_day": int32(19792), "interval_start_hour": int32(19792*24 + 8)},
+			want:      "interval_start_day=2024-03-10/interval_start_hour=2024-03-10-08",
+		},
+		{
+			name:       "null and escaped values",
+			transforms: []PartitionTransform{{Column: "account_id", Transform: "identity"}, {Column: "interval_start", Transform: "month"}},
+			partition:  map[string]any{"account_id": "a/1"},
+			want:       "account_id=a%2F1/interval_start_month=null",
+		},
+		{
+			name:      "unpartitioned",
+			partition: map[string]any{},
+			want:      "",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			spec, err := NewPartitionSpec(schema, tt.transforms...)
+			if err != nil {
+				t.Fatal(err)
+			}
+			table := NewTable(nil, NewWarehouse(nil, "memory://warehouse"), testTableID, schema, spec, nil)
+			if got := table.PartitionPath(tt.partition); got != tt.want {
+				t.Errorf("PartitionPath = %q, want %q", got, tt.want)
+			}
+			key := table.DataFileKey(tt.partition)
+			dir := "usage/events/data/" + tt.want
+			if tt.want == "" {
+				dir = "usage/events/data"
+			}
+			if got := key[:len(key)-len("00000000-0000-0000-0000-000000000000.parquet")-1]; got != dir {
+				t.Errorf("DataFileKey %s is not in %s", key, dir)
+			}
+		})
+	}
+}
+
+func TestPartitionSpecPartition(t *testing.T) {
+	schema := testSchema()
+	spec, err := NewPartitionSpec(schema,
+		PartitionTransform{Column: "account_id", Transform: "identity"},
+		PartitionTransform{Name: "day", Column: "interval_start", Transform: "day"},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	row := map[string]any{"account_id": "a1", "interval_start": time.Date(2024, 3, 10, 23, 45, 0, 0, time.UTC).UnixMicro()}
+	got, err := spec.Partition(schema, func(column string) any { return row[column] })
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := map[string]any{"account_id": "a1", "day": int32(19792)}; !reflect.DeepEqual(got, want) {
+		t.Errorf("partition = %v, want %v", got, want)
+	}
+
+	row["interval_start"] = "2024-03-10"
+	if _, err := spec.Partition(schema, func(column string) any { return row[column] }); err == nil {
+		t.Error("Partition of a string timestamp succeeded, want an error")
+	}
+	if _, err := NewPartitionSpec(schema, PartitionTransform{Column: "meter_code", Transform: "identity"}); err == nil {
+		t.Error("NewPartitionSpec of a column not in the schema succeeded, want an error")
+	}
+}
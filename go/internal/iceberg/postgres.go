@@ -0,0 +1,48 @@
+package iceberg
+
+import (
+	"context"
+	"errors"
+	dbentity "usage-lakehouse/internal/db/entity"
+	"usage-lakehouse/internal/repository"
+
+	"github.com/jackc/pgx/v5"
+)
+
+type postgresCatalog struct {
+	name string
+	repo repository.IcebergTableRepository
+}
+
+// NewPostgresCatalog keeps tables in the iceberg_tables table under the catalog name, laid out as the
+// Iceberg JDBC catalog lays them out.
+func NewPostgresCatalog(name string, repo repository.IcebergTableRepository) Catalog {
+	return &postgresCatalog{name: name, repo: repo}
+}
+
+func (c *postgresCatalog) MetadataLocation(ctx context.Context, id Identifier) (string, error) {
+	t, err := c.repo.Get(ctx, c.name, id.Namespace, id.Name)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return "", ErrNoSuchTable
+	}
+	if err != nil {
+		return "", err
+	}
+	return t.MetadataLocation, nil
+}
+
+func (c *postgresCatalog) Create(ctx context.Context, id Identifier, metadataLocation string) error {
+	err := c.repo.Create(ctx, &dbentity.IcebergTable{CatalogName: c.name, Namespace: id.Namespace, Name: id.Name, MetadataLocation: metadataLocation})
+	if errors.Is(err, repository.ErrIcebergTableExists) {
+		return ErrTableExists
+	}
+	return err
+}
+
+func (c *postgresCatalog) Commit(ctx context.Context, id Identifier, base string, metadataLocation string) error {
+	err := c.repo.Swap(ctx, c.name, id.Namespace, id.Name, base, metadataLocation)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return ErrCommitConflict
+	}
+	return err
+}
@@ -0,0 +1,434 @@
+// Package iceberg appends parquet data files, and the equality delete files of rows they replace, to
+// Apache Iceberg format version 2 tables. Each append writes its manifests, a manifest list and a new
+// metadata.json to the table's location in a warehouse, then commits the new metadata through a
+// catalog so that readers such as Spark and Athena see either the whole append or none of it.
+package iceberg
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+	"usage-lakehouse/internal/objectstore"
+
+	"github.com/google/uuid"
+)
+
+// commitAttempts bounds how many times an append is rebuilt on newer metadata after losing a commit race.
+const commitAttempts = 4
+
+// Warehouse is the object store tables keep their files in, and the URI query engines know the store's
+// root by, e.g. s3://bucket/warehouse or file:///var/lib/lake.
+type Warehouse struct {
+	store objectstore.Store
+	uri   string
+}
+
+func NewWarehouse(store objectstore.Store, uri string) *Warehouse {
+	return &Warehouse{store: store, uri: strings.TrimSuffix(uri, "/")}
+}
+
+func (w *Warehouse) Store() objectstore.Store {
+	return w.store
+}
+
+// Location is the URI of the object at key.
+func (w *Warehouse) Location(key string) string {
+	return w.uri + "/" + key
+}
+
+func (w *Warehouse) key(location string) (string, error) {
+	key, ok := strings.CutPrefix(location, w.uri+"/")
+	if !ok {
+		return "", fmt.Errorf("%s is outside the warehouse %s", location, w.uri)
+	}
+	return key, nil
+}
+
+// Table appends to one table, creating it with its schema and partition spec on the first append.
+// A table that already exists must have the same columns. When its default partition spec differs,
+// the append evolves the table to the Table's spec; data files written earlier keep theirs.
+type Table struct {
+	catalog    Catalog
+	warehouse  *Warehouse
+	id         Identifier
+	schema     Schema
+	spec       PartitionSpec
+	properties map[string]string
+}
+
+func NewTable(catalog Catalog, warehouse *Warehouse, id Identifier, schema Schema, spec PartitionSpec, properties map[string]string) *Table {
+	return &Table{catalog: catalog, warehouse: warehouse, id: id, schema: schema, spec: spec, properties: properties}
+}
+
+func (t *Table) Warehouse() *Warehouse {
+	return t.warehouse
+}
+
+// key is the warehouse key of path within the table's location.
+func (t *Table) key(elem ...string) string {
+	return path.Join(append([]string{t.id.Namespace, t.id.Name}, elem...)...)
+}
+
+// DataFileKey is the warehouse key to write a new data file to: a unique name under the table's data
+// directory, in the directory of its partition values.
+func (t *Table) DataFileKey(partition map[string]any) string {
+	return t.key("data", t.PartitionPath(partition), uuid.New().String()+".parquet")
+}
+
+// PartitionPath is the Hive-style directory of partition values, e.g. power_region=ERCOT/year=2024,
+// or empty for an unpartitioned table.
+func (t *Table) PartitionPath(partition map[string]any) string {
+	dirs := make([]string, 0, len(t.spec.Fields))
+	for _, f := range t.spec.Fields {
+		dirs = append(dirs, f.Name+"="+partitionPathValue(f.Transform, partition[f.Name]))
+	}
+	return path.Join(dirs...)
+}
+
+// DeleteFileKey is the warehouse key to write a new equality delete file to. Delete files are written
+// under the unpartitioned spec, so they are not in a partition's directory.
+func (t *Table) DeleteFileKey() string {
+	return t.key("data", uuid.New().String()+"-deletes.parquet")
+}
+
+// DataFileWriter streams a new data file into the table's warehouse, counting its size as it goes.
+type DataFileWriter struct {
+	w    objectstore.Writer
+	key  string
+	file DataFile
+}
+
+// CreateDataFile starts a data file at DataFileKey(partition).
+func (t *Table) CreateDataFile(ctx context.Context, partition map[string]any) (*DataFileWriter, error) {
+	key := t.DataFileKey(partition)
+	w, err := t.warehouse.store.Create(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+	return &DataFileWriter{w: w, key: key, file: DataFile{Path: t.warehouse.Location(key), Partition: partition}}, nil
+}
+
+func (w *DataFileWriter) Write(p []byte) (int, error) {
+	n, err := w.w.Write(p)
+	w.file.FileSizeInBytes += int64(n)
+	return n, err
+}
+
+// Key is the file's warehouse key.
+func (w *DataFileWriter) Key() string {
+	return w.key
+}
+
+// CreateDeleteFile starts an equality delete file at DeleteFileKey whose rows delete the earlier rows
+// with the same values of columns. Its rows may hold other columns too; only these are compared.
+func (t *Table) CreateDeleteFile(ctx context.Context, columns ...string) (*DataFileWriter, error) {
+	if len(columns) == 0 {
+		return nil, errors.New("iceberg: an equality delete file needs columns to compare")
+	}
+	ids := make([]int, 0, len(columns))
+	for _, column := range columns {
+		f, ok := t.schema.fieldByName(column)
+		if !ok {
+			return nil, fmt.Errorf("equality delete column %s is not in the schema", column)
+		}
+		ids = append(ids, f.ID)
+	}
+	key := t.DeleteFileKey()
+	w, err := t.warehouse.store.Create(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+	return &DataFileWriter{w: w, key: key, file: DataFile{Path: t.warehouse.Location(key), Partition: map[string]any{}, EqualityIDs: ids}}, nil
+}
+
+// Close completes the file and returns it, holding recordCount rows, ready to Append.
+func (w *DataFileWriter) Close(recordCount int64) (DataFile, error) {
+	if err := w.w.Close(); err != nil {
+		return DataFile{}, err
+	}
+	w.file.RecordCount = recordCount
+	return w.file, nil
+}
+
+func (w *DataFileWriter) Abort() error {
+	return w.w.Abort()
+}
+
+// partitionPathValue formats a partition value as Iceberg does in data file paths, URL-escaped.
+func partitionPathValue(transform string, v any) string {
+	if v == nil {
+		return "null"
+	}
+	n, isInt := avroInt(v)
+	if !isInt {
+		return url.QueryEscape(fmt.Sprint(v))
+	}
+	epoch := time.Unix(0, 0).UTC()
+	switch transform {
+	case "year":
+		return strconv.Itoa(1970 + int(n))
+	case "month":
+		return epoch.AddDate(0, int(n), 0).Format("2006-01")
+	case "day":
+		return epoch.AddDate(0, 0, int(n)).Format("2006-01-02")
+	case "hour":
+		return epoch.Add(time.Duration(n) * time.Hour).Format("2006-01-02-15")
+	}
+	return strconv.FormatInt(n, 10)
+}
+
+// Append adds files, already written to the warehouse, to the table in one snapshot. properties are
+// added to the snapshot's summary, where they record what the append wrote; they are not carried on
+// to later snapshots.
+func (t *Table) Append(ctx context.Context, files []DataFile, properties map[string]string) (*Snapshot, error) {
+	return t.Replace(ctx, files, nil, properties)
+}
+
+// Replace appends files as Append does, along with equality delete files deleting the rows of earlier
+// snapshots that files replace. The rows of files themselves are not deleted: an equality delete only
+// applies to data files of lower sequence numbers.
+func (t *Table) Replace(ctx context.Context, files []DataFile, deletes []DataFile, properties map[string]string) (*Snapshot, error) {
+	if len(files) == 0 {
+		return nil, errors.New("iceberg: nothing to append")
+	}
+	for _, f := range deletes {
+		if f.EqualityIDs == nil {
+			return nil, fmt.Errorf("iceberg: %s is not an equality delete file", f.Path)
+		}
+	}
+	for attempt := 0; attempt < commitAttempts; attempt++ {
+		base, metadata, err := t.load(ctx)
+		if err != nil {
+			return nil, err
+		}
+		snapshot, metadataLocation, err := t.writeSnapshot(ctx, base, metadata, files, deletes, properties)
+		if err != nil {
+			return nil, err
+		}
+		if base == "" {
+			err = t.catalog.Create(ctx, t.id, metadataLocation)
+		} else {
+			err = t.catalog.Commit(ctx, t.id, base, metadataLocation)
+		}
+		if errors.Is(err, ErrTableExists) || errors.Is(err, ErrCommitConflict) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		return snapshot, nil
+	}
+	return nil, fmt.Errorf("append to %s: %w", t.id, ErrCommitConflict)
+}
+
+// CurrentSnapshot returns the table's current snapshot, or nil when it has none or does not exist yet.
+func (t *Table) CurrentSnapshot(ctx context.Context) (*Snapshot, error) {
+	_, metadata, err := t.load(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return metadata.currentSnapshot(), nil
+}
+
+// load reads the table's current metadata. A table the catalog does not have yet loads as new
+// metadata with an empty base.
+func (t *Table) load(ctx context.Context) (string, *Metadata, error) {
+	base, err := t.catalog.MetadataLocation(ctx, t.id)
+	if errors.Is(err, ErrNoSuchTable) {
+		metadata, err := newMetadata(t.warehouse.Location(t.key()), t.schema, t.spec, t.properties)
+		return "", metadata, err
+	}
+	if err != nil {
+		return "", nil, err
+	}
+	var metadata Metadata
+	if err := t.readJSON(ctx, base, &metadata); err != nil {
+		return "", nil, fmt.Errorf("read %s: %w", base, err)
+	}
+	if metadata.FormatVersion != formatVersion {
+		return "", nil, fmt.Errorf("%s has format version %d, want %d", t.id, metadata.FormatVersion, formatVersion)
+	}
+	return base, &metadata, nil
+}
+
+func (t *Table) readJSON(ctx context.Context, location string, v any) error {
+	key, err := t.warehouse.key(location)
+	if err != nil {
+		return err
+	}
+	r, err := t.warehouse.store.Get(ctx, key)
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+	return json.NewDecoder(r).Decode(v)
+}
+
+// put writes b to the table's metadata directory and returns its location.
+func (t *Table) put(ctx context.Context, name string, b []byte) (string, error) {
+	key := t.key("metadata", name)
+	if err := t.warehouse.store.Put(ctx, key, bytes.NewReader(b)); err != nil {
+		return "", err
+	}
+	return t.warehouse.Location(key), nil
+}
+
+// writeSnapshot writes the manifests, manifest list and metadata file of a snapshot appending files
+// and deletes to metadata, and returns the snapshot and the new metadata file's location. Delete
+// files are listed under the unpartitioned spec, which makes their deletes apply in every partition.
+func (t *Table) writeSnapshot(ctx context.Context, base string, metadata *Metadata, files []DataFile, deletes []DataFile, properties map[string]string) (*Snapshot, string, error) {
+	schema, err := metadata.schema()
+	if err != nil {
+		return nil, "", err
+	}
+	if !sameColumns(schema, t.schema) {
+		return nil, "", fmt.Errorf("%s has different columns than this writer; a new schema needs a new table", t.id)
+	}
+	next := *metadata
+	spec := next.useSpec(t.spec)
+	snapshotID := newSnapshotID()
+	sequenceNumber := metadata.LastSequenceNumber + 1
+
+	var manifest bytes.Buffer
+	if err := writeManifest(&manifest, schema, spec, snapshotID, files); err != nil {
+		return nil, "", err
+	}
+	manifestLocation, err := t.put(ctx, uuid.New().String()+"-m0.avro", manifest.Bytes())
+	if err != nil {
+		return nil, "", err
+	}
+	manifests := []map[string]any{manifestFile(manifestLocation, int64(manifest.Len()), spec.SpecID, snapshotID, sequenceNumber, files)}
+	if len(deletes) > 0 {
+		unpartitioned := next.addSpec(PartitionSpec{Fields: []PartitionField{}})
+		var deleteManifest bytes.Buffer
+		if err := writeManifest(&deleteManifest, schema, unpartitioned, snapshotID, deletes); err != nil {
+			return nil, "", err
+		}
+		location, err := t.put(ctx, uuid.New().String()+"-m1.avro", deleteManifest.Bytes())
+		if err != nil {
+			return nil, "", err
+		}
+		manifests = append(manifests, manifestFile(location, int64(deleteManifest.Len()), unpartitioned.SpecID, snapshotID, sequenceNumber, deletes))
+	}
+
+	parent := metadata.currentSnapshot()
+	var parentSnapshotID *int64
+	parentSummary := map[string]string{}
+	if parent != nil {
+		parentSnapshotID = &parent.SnapshotID
+		parentSummary = parent.Summary
+		key, err := t.warehouse.key(parent.ManifestList)
+		if err != nil {
+			return nil, "", err
+		}
+		r, err := t.warehouse.store.Get(ctx, key)
+		if err != nil {
+			return nil, "", err
+		}
+		inherited, err := readManifestList(r)
+		r.Close()
+		if err != nil {
+			return nil, "", fmt.Errorf("read %s: %w", parent.ManifestList, err)
+		}
+		manifests = append(manifests, inherited...)
+	}
+	var manifestList bytes.Buffer
+	if err := writeManifestList(&manifestList, snapshotID, parentSnapshotID, sequenceNumber, manifests); err != nil {
+		return nil, "", err
+	}
+	manifestListLocation, err := t.put(ctx, fmt.Sprintf("snap-%d-1-%s.avro", snapshotID, uuid.New().String()), manifestList.Bytes())
+	if err != nil {
+		return nil, "", err
+	}
+
+	summary := appendSummary(parentSummary, files, deletes)
+	for k, v := range properties {
+		if _, ok := summary[k]; !ok {
+			summary[k] = v
+		}
+	}
+	now := time.Now().UnixMilli()
+	snapshot := Snapshot{
+		SnapshotID:       snapshotID,
+		ParentSnapshotID: parentSnapshotID,
+		SequenceNumber:   sequenceNumber,
+		TimestampMS:      now,
+		ManifestList:     manifestListLocation,
+		Summary:          summary,
+		SchemaID:         schema.SchemaID,
+	}
+	if base != "" {
+		next.MetadataLog = append(append([]MetadataLogEntry{}, metadata.MetadataLog...), MetadataLogEntry{TimestampMS: metadata.LastUpdatedMS, MetadataFile: base})
+	}
+	next.LastSequenceNumber = sequenceNumber
+	next.LastUpdatedMS = now
+	next.CurrentSnapshotID = snapshotID
+	next.Snapshots = append(append([]Snapshot{}, metadata.Snapshots...), snapshot)
+	next.SnapshotLog = append(append([]SnapshotLogEntry{}, metadata.SnapshotLog...), SnapshotLogEntry{TimestampMS: now, SnapshotID: snapshotID})
+	next.Refs = map[string]SnapshotRef{}
+	for name, ref := range metadata.Refs {
+		next.Refs[name] = ref
+	}
+	next.Refs["main"] = SnapshotRef{SnapshotID: snapshotID, Type: "branch"}
+
+	b, err := json.Marshal(next)
+	if err != nil {
+		return nil, "", err
+	}
+	metadataLocation, err := t.put(ctx, metadataFileName(base), b)
+	if err != nil {
+		return nil, "", err
+	}
+	return &snapshot, metadataLocation, nil
+}
+
+// newSnapshotID returns a random positive snapshot ID.
+func newSnapshotID() int64 {
+	id := uuid.New()
+	return int64(binary.BigEndian.Uint64(id[:8]) >> 1)
+}
+
+// appendSummary is the summary of a snapshot adding files and deletes, with totals carried on from
+// the parent's. A snapshot with deletes is an overwrite.
+func appendSummary(parent map[string]string, files []DataFile, deletes []DataFile) map[string]string {
+	var records, size, deleted, deletesSize int64
+	for _, f := range files {
+		records += f.RecordCount
+		size += f.FileSizeInBytes
+	}
+	for _, f := range deletes {
+		deleted += f.RecordCount
+		deletesSize += f.FileSizeInBytes
+	}
+	total := func(key string, added int64) string {
+		n, _ := strconv.ParseInt(parent[key], 10, 64)
+		return strconv.FormatInt(n+added, 10)
+	}
+	summary := map[string]string{
+		"operation":              "append",
+		"added-data-files":       strconv.Itoa(len(files)),
+		"added-records":          strconv.FormatInt(records, 10),
+		"added-files-size":       strconv.FormatInt(size+deletesSize, 10),
+		"total-data-files":       total("total-data-files", int64(len(files))),
+		"total-records":          total("total-records", records),
+		"total-files-size":       total("total-files-size", size+deletesSize),
+		"total-delete-files":     total("total-delete-files", int64(len(deletes))),
+		"total-position-deletes": total("total-position-deletes", 0),
+		"total-equality-deletes": total("total-equality-deletes", deleted),
+	}
+	if len(deletes) > 0 {
+		summary["operation"] = "overwrite"
+		summary["added-delete-files"] = strconv.Itoa(len(deletes))
+		summary["added-equality-delete-files"] = strconv.Itoa(len(deletes))
+		summary["added-equality-deletes"] = strconv.FormatInt(deleted, 10)
+	}
+	return summary
+}
@@ -0,0 +1,318 @@
+package iceberg
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+	"usage-lakehouse/internal/objectstore"
+)
+
+var testTableID = Identifier{Namespace: "usage", Name: "events"}
+
+func newTestTable(t *testing.T, catalog Catalog, warehouse *Warehouse) *Table {
+	t.Helper()
+	schema := testSchema()
+	spec, err := NewPartitionSpec(schema, PartitionTransform{Column: "account_id", Transform: "identity"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return NewTable(catalog, warehouse, testTableID, schema, spec, nil)
+}
+
+func testDataFile(table *Table, account string, records int64) DataFile {
+	partition := map[string]any{"account_id": account}
+	return DataFile{
+		Path:            table.Warehouse().Location(table.DataFileKey(partition)),
+		RecordCount:     records,
+		FileSizeInBytes: records * 100,
+		Partition:       partition,
+	}
+}
+
+// manifestCount reads the number of manifests in snapshot's manifest list.
+func manifestCount(t *testing.T, warehouse *Warehouse, snapshot *Snapshot) int {
+	t.Helper()
+	return len(snapshotManifests(t, warehouse, snapshot))
+}
+
+// snapshotManifests reads the manifest_file records of snapshot's manifest list.
+func snapshotManifests(t *testing.T, warehouse *Warehouse, snapshot *Snapshot) []map[string]any {
+	t.Helper()
+	key, err := warehouse.key(snapshot.ManifestList)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r, err := warehouse.Store().Get(context.Background(), key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+	manifests, err := readManifestList(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return manifests
+}
+
+// racingCatalog runs race before the first Create or Commit it is asked for, as another writer would.
+type racingCatalog struct {
+	Catalog
+	race    func()
+	commits int
+}
+
+func (c *racingCatalog) Create(ctx context.Context, id Identifier, metadataLocation string) error {
+	c.runRace()
+	return c.Catalog.Create(ctx, id, metadataLocation)
+}
+
+func (c *racingCatalog) Commit(ctx context.Context, id Identifier, base string, metadataLocation string) error {
+	c.runRace()
+	c.commits++
+	return c.Catalog.Commit(ctx, id, base, metadataLocation)
+}
+
+func (c *racingCatalog) runRace() {
+	if c.race != nil {
+		race := c.race
+		c.race = nil
+		race()
+	}
+}
+
+// conflictingCatalog conflicts on every commit.
+type conflictingCatalog struct {
+	Catalog
+}
+
+func (c conflictingCatalog) Commit(ctx context.Context, id Identifier, base string, metadataLocation string) error {
+	return ErrCommitConflict
+}
+
+func TestTableAppend(t *testing.T) {
+	ctx := context.Background()
+	warehouse := NewWarehouse(objectstore.NewMemory(), "memory://warehouse")
+	table := newTestTable(t, NewFilesystemCatalog(t.TempDir()), warehouse)
+
+	if snapshot, err := table.CurrentSnapshot(ctx); err != nil || snapshot != nil {
+		t.Fatalf("CurrentSnapshot before the first append = %v, %v, want nil", snapshot, err)
+	}
+	first, err := table.Append(ctx, []DataFile{testDataFile(table, "a1", 10), testDataFile(table, "a2", 5)}, map[string]string{"batch": "1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if first.ParentSnapshotID != nil || first.SequenceNumber != 1 {
+		t.Errorf("first snapshot parent %v sequence %d, want nil and 1", first.ParentSnapshotID, first.SequenceNumber)
+	}
+	second, err := table.Append(ctx, []DataFile{testDataFile(table, "a1", 3)}, map[string]string{"batch": "2", "operation": "overwrite"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if second.ParentSnapshotID == nil || *second.ParentSnapshotID != first.SnapshotID || second.SequenceNumber != 2 {
+		t.Errorf("second snapshot parent %v sequence %d, want %d and 2", second.ParentSnapshotID, second.SequenceNumber, first.SnapshotID)
+	}
+	wantSummary := map[string]string{
+		// Properties do not replace the summary's own keys.
+		"operation":        "append",
+		"batch":            "2",
+		"added-data-files": "1",
+		"added-records":    "3",
+		"total-data-files": "3",
+		"total-records":    "18",
+	}
+	for k, v := range wantSummary {
+		if second.Summary[k] != v {
+			t.Errorf("summary[%q] = %q, want %q", k, second.Summary[k], v)
+		}
+	}
+	if n := manifestCount(t, warehouse, second); n != 2 {
+		t.Errorf("second snapshot lists %d manifests, want 2", n)
+	}
+
+	current, err := table.CurrentSnapshot(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if current == nil || current.SnapshotID != second.SnapshotID {
+		t.Errorf("CurrentSnapshot = %v, want snapshot %d", current, second.SnapshotID)
+	}
+}
+
+func TestTableAppendRetriesCommitConflict(t *testing.T) {
+	ctx := context.Background()
+	warehouse := NewWarehouse(objectstore.NewMemory(), "memory://warehouse")
+	catalog := NewFilesystemCatalog(t.TempDir())
+	other := newTestTable(t, catalog, warehouse)
+	if _, err := other.Append(ctx, []DataFile{testDataFile(other, "a1", 1)}, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	racing := &racingCatalog{Catalog: catalog}
+	var concurrent *Snapshot
+	racing.race = func() {
+		var err error
+		if concurrent, err = other.Append(ctx, []DataFile{testDataFile(other, "a2", 2)}, nil); err != nil {
+			t.Fatal(err)
+		}
+	}
+	table := newTestTable(t, racing, warehouse)
+	snapshot, err := table.Append(ctx, []DataFile{testDataFile(table, "a3", 4)}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if racing.commits != 2 {
+		t.Errorf("committed %d times, want 2", racing.commits)
+	}
+	// The retry builds on the concurrent append rather than dropping it.
+	if snapshot.ParentSnapshotID == nil || *snapshot.ParentSnapshotID != concurrent.SnapshotID || snapshot.SequenceNumber != 3 {
+		t.Errorf("snapshot parent %v sequence %d, want %d and 3", snapshot.ParentSnapshotID, snapshot.SequenceNumber, concurrent.SnapshotID)
+	}
+	if snapshot.Summary["total-records"] != "7" {
+		t.Errorf("total-records = %s, want 7", snapshot.Summary["total-records"])
+	}
+	if n := manifestCount(t, warehouse, snapshot); n != 3 {
+		t.Errorf("snapshot lists %d manifests, want 3", n)
+	}
+}
+
+func TestTableAppendRetriesCreateRace(t *testing.T) {
+	ctx := context.Background()
+	warehouse := NewWarehouse(objectstore.NewMemory(), "memory://warehouse")
+	catalog := NewFilesystemCatalog(t.TempDir())
+	other := newTestTable(t, catalog, warehouse)
+
+	racing := &racingCatalog{Catalog: catalog}
+	var concurrent *Snapshot
+	racing.race = func() {
+		var err error
+		if concurrent, err = other.Append(ctx, []DataFile{testDataFile(other, "a1", 2)}, nil); err != nil {
+			t.Fatal(err)
+		}
+	}
+	table := newTestTable(t, racing, warehouse)
+	snapshot, err := table.Append(ctx, []DataFile{testDataFile(table, "a2", 4)}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The losing create retries as a commit on top of the table the other writer created.
+	if racing.commits != 1 {
+		t.Errorf("committed %d times, want 1", racing.commits)
+	}
+	if snapshot.ParentSnapshotID == nil || *snapshot.ParentSnapshotID != concurrent.SnapshotID || snapshot.SequenceNumber != 2 {
+		t.Errorf("snapshot parent %v sequence %d, want %d and 2", snapshot.ParentSnapshotID, snapshot.SequenceNumber, concurrent.SnapshotID)
+	}
+	if snapshot.Summary["total-records"] != "6" {
+		t.Errorf("total-records = %s, want 6", snapshot.Summary["total-records"])
+	}
+}
+
+func TestTableAppendGivesUpOnConflicts(t *testing.T) {
+	ctx := context.Background()
+	warehouse := NewWarehouse(objectstore.NewMemory(), "memory://warehouse")
+	catalog := NewFilesystemCatalog(t.TempDir())
+	table := newTestTable(t, catalog, warehouse)
+	if _, err := table.Append(ctx, []DataFile{testDataFile(table, "a1", 1)}, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	conflicting := newTestTable(t, conflictingCatalog{catalog}, warehouse)
+	if _, err := conflicting.Append(ctx, []DataFile{testDataFile(table, "a2", 1)}, nil); !errors.Is(err, ErrCommitConflict) {
+		t.Errorf("Append error = %v, want ErrCommitConflict", err)
+	}
+	if _, err := table.Append(ctx, nil, nil); err == nil {
+		t.Error("Append of no files succeeded, want an error")
+	}
+}
+
+func TestTableReplace(t *testing.T) {
+	ctx := context.Background()
+	warehouse := NewWarehouse(objectstore.NewMemory(), "memory://warehouse")
+	table := newTestTable(t, NewFilesystemCatalog(t.TempDir()), warehouse)
+	if _, err := table.Append(ctx, []DataFile{testDataFile(table, "a1", 10)}, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	w, err := table.CreateDeleteFile(ctx, "account_id", "interval_start")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := w.Write([]byte("deletes")); err != nil {
+		t.Fatal(err)
+	}
+	deletes, err := w.Close(2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []int{1, 2}; len(deletes.EqualityIDs) != 2 || deletes.EqualityIDs[0] != want[0] || deletes.EqualityIDs[1] != want[1] {
+		t.Errorf("equality IDs = %v, want %v", deletes.EqualityIDs, want)
+	}
+	snapshot, err := table.Replace(ctx, []DataFile{testDataFile(table, "a1", 2)}, []DataFile{deletes}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	wantSummary := map[string]string{
+		"operation":                   "overwrite",
+		"added-data-files":            "1",
+		"added-records":               "2",
+		"added-delete-files":          "1",
+		"added-equality-delete-files": "1",
+		"added-equality-deletes":      "2",
+		"total-data-files":            "2",
+		"total-records":               "12",
+		"total-delete-files":          "1",
+		"total-equality-deletes":      "2",
+	}
+	for k, v := range wantSummary {
+		if snapshot.Summary[k] != v {
+			t.Errorf("summary[%q] = %q, want %q", k, snapshot.Summary[k], v)
+		}
+	}
+
+	// The deletes are listed in their own manifest under an unpartitioned spec, which the table gains
+	// without it becoming the default.
+	_, metadata, err := table.load(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if metadata.DefaultSpecID != 0 || len(metadata.PartitionSpecs) != 2 || len(metadata.PartitionSpecs[1].Fields) != 0 {
+		t.Errorf("default spec %d of %+v, want 0 and an unpartitioned spec 1", metadata.DefaultSpecID, metadata.PartitionSpecs)
+	}
+	manifests := snapshotManifests(t, warehouse, snapshot)
+	if len(manifests) != 3 {
+		t.Fatalf("snapshot lists %d manifests, want 3", len(manifests))
+	}
+	deleteManifest := manifests[1]
+	if deleteManifest["content"] != int64(manifestContentDeletes) || deleteManifest["partition_spec_id"] != int64(1) ||
+		deleteManifest["sequence_number"] != snapshot.SequenceNumber || deleteManifest["added_rows_count"] != int64(2) {
+		t.Errorf("delete manifest = %v", deleteManifest)
+	}
+	key, err := warehouse.key(deleteManifest["manifest_path"].(string))
+	if err != nil {
+		t.Fatal(err)
+	}
+	r, err := warehouse.Store().Get(ctx, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+	meta, entries, err := readAvroFile(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if meta["content"] != "deletes" || len(entries) != 1 {
+		t.Fatalf("delete manifest has content %q and %d entries", meta["content"], len(entries))
+	}
+	dataFile := entries[0]["data_file"].(map[string]any)
+	if dataFile["content"] != int64(dataFileContentEqualityDeletes) || dataFile["file_path"] != deletes.Path ||
+		!reflect.DeepEqual(dataFile["equality_ids"], []any{int64(1), int64(2)}) {
+		t.Errorf("delete file entry = %v", dataFile)
+	}
+
+	if _, err := table.CreateDeleteFile(ctx, "meter_code"); err == nil {
+		t.Error("CreateDeleteFile on a column not in the schema succeeded, want an error")
+	}
+	if _, err := table.Replace(ctx, []DataFile{testDataFile(table, "a1", 1)}, []DataFile{testDataFile(table, "a1", 1)}, nil); err == nil {
+		t.Error("Replace with a data file as a delete file succeeded, want an error")
+	}
+}
@@ -0,0 +1,191 @@
+// Package jobs runs ingestion jobs queued in the ingestion_job table on a pool of worker goroutines.
+package jobs
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+	dbentity "usage-lakehouse/internal/db/entity"
+	"usage-lakehouse/internal/repository"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// Result is what a Processor reports for one job. Output is stored as JSON and should carry
+// references to what the job produced, such as object keys or usage_transaction IDs.
+type Result struct {
+	Processed int
+	Succeeded int
+	Failed    int
+	Errors    []dbentity.IngestionJobError
+	Output    any
+}
+
+// Processor handles the payload of one job kind. A returned error fails the whole job.
+type Processor interface {
+	ProcessIngestionJob(ctx context.Context, job *dbentity.IngestionJob) (Result, error)
+}
+
+type Config struct {
+	Workers      int
+	PollInterval time.Duration
+	// StaleAfter is how long a RUNNING job may go without a heartbeat before another worker reclaims it.
+	StaleAfter time.Duration
+	// HeartbeatInterval is how often a worker records that it is still running its job.
+	HeartbeatInterval time.Duration
+	// MaxAttempts is how many times a job is claimed before one whose worker went away is failed
+	// instead of reclaimed.
+	MaxAttempts int
+}
+
+type Pool struct {
+	repo       repository.IngestionJobRepository
+	processors map[string]Processor
+	config     Config
+}
+
+func NewPool(repo repository.IngestionJobRepository, config Config) *Pool {
+	if config.Workers <= 0 {
+		config.Workers = 1
+	}
+	if config.PollInterval <= 0 {
+		config.PollInterval = time.Second
+	}
+	if config.StaleAfter <= 0 {
+		config.StaleAfter = 5 * time.Minute
+	}
+	if config.HeartbeatInterval <= 0 {
+		config.HeartbeatInterval = config.StaleAfter / 5
+	}
+	if config.MaxAttempts <= 0 {
+		config.MaxAttempts = 3
+	}
+	return &Pool{repo: repo, processors: make(map[string]Processor), config: config}
+}
+
+// Register routes jobs of kind to p. It must be called before Run.
+func (p *Pool) Register(kind string, processor Processor) {
+	p.processors[kind] = processor
+}
+
+// Run starts the workers and blocks until ctx is canceled and every in-flight job has finished.
+func (p *Pool) Run(ctx context.Context) {
+	var wg sync.WaitGroup
+	for i := 0; i < p.config.Workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			p.work(ctx)
+		}()
+	}
+	wg.Wait()
+}
+
+func (p *Pool) work(ctx context.Context) {
+	ticker := time.NewTicker(p.config.PollInterval)
+	defer ticker.Stop()
+	for {
+		if failed, err := p.repo.FailAbandoned(ctx, p.config.StaleAfter, p.config.MaxAttempts); err != nil {
+			log.Println("fail abandoned ingestion jobs:", err)
+		} else if failed > 0 {
+			log.Printf("failed %d ingestion jobs abandoned after %d attempts", failed, p.config.MaxAttempts)
+		}
+		// Drain the queue before waiting for the next tick.
+		for ctx.Err() == nil {
+			job, err := p.repo.ClaimNext(ctx, p.config.StaleAfter, p.config.MaxAttempts)
+			if errors.Is(err, pgx.ErrNoRows) {
+				break
+			}
+			if err != nil {
+				log.Println("claim ingestion job:", err)
+				break
+			}
+			p.run(job)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// run processes job to completion. It is detached from the pool context so that shutdown lets the
+// job finish instead of leaving it RUNNING until it goes stale. The job's heartbeat is refreshed while
+// it runs; if another worker has reclaimed it meanwhile, the job's context is canceled.
+func (p *Pool) run(job *dbentity.IngestionJob) {
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		p.heartbeat(ctx, cancel, job)
+	}()
+	result, err := p.process(ctx, job)
+	cancel()
+	<-done
+	job.ProcessedCount = result.Processed
+	job.SucceededCount = result.Succeeded
+	job.FailedCount = result.Failed
+	job.Errors = result.Errors
+	job.Status = dbentity.IngestionJobStatusSucceeded
+	if err != nil {
+		job.Status = dbentity.IngestionJobStatusFailed
+		job.Errors = append(job.Errors, dbentity.IngestionJobError{Message: err.Error()})
+	}
+	if result.Output != nil {
+		output, err := json.Marshal(result.Output)
+		if err != nil {
+			log.Printf("encode output of ingestion job %s: %v", job.ID, err)
+		} else {
+			job.Output = output
+		}
+	}
+	// ctx may have been canceled by a lost claim; Finish then finds the job reclaimed and discards
+	// the result.
+	err = p.repo.Finish(context.Background(), job)
+	if errors.Is(err, pgx.ErrNoRows) {
+		log.Printf("ingestion job %s was reclaimed by another worker; result discarded", job.ID)
+	} else if err != nil {
+		log.Printf("finish ingestion job %s: %v", job.ID, err)
+	}
+}
+
+// heartbeat refreshes the job's heartbeat every HeartbeatInterval until ctx is done, and cancels the
+// job once it has lost its claim.
+func (p *Pool) heartbeat(ctx context.Context, cancel context.CancelFunc, job *dbentity.IngestionJob) {
+	ticker := time.NewTicker(p.config.HeartbeatInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		err := p.repo.Heartbeat(ctx, job)
+		if errors.Is(err, pgx.ErrNoRows) {
+			log.Printf("ingestion job %s was reclaimed by another worker; stopping", job.ID)
+			cancel()
+			return
+		}
+		if err != nil && ctx.Err() == nil {
+			log.Printf("heartbeat of ingestion job %s: %v", job.ID, err)
+		}
+	}
+}
+
+func (p *Pool) process(ctx context.Context, job *dbentity.IngestionJob) (result Result, err error) {
+	processor, ok := p.processors[job.Kind]
+	if !ok {
+		return result, fmt.Errorf("no processor registered for job kind %s", job.Kind)
+	}
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("ingestion job panicked: %v", r)
+		}
+	}()
+	return processor.ProcessIngestionJob(ctx, job)
+}
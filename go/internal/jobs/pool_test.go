@@ -0,0 +1,166 @@
+package jobs
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+	dbentity "usage-lakehouse/internal/db/entity"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// fakeJobRepository queues jobs in memory. Once the queue is empty, ClaimNext calls idle, and
+// Heartbeat and Finish answer pgx.ErrNoRows for a job in lost, as if another worker had reclaimed it.
+type fakeJobRepository struct {
+	mu       sync.Mutex
+	queue    []*dbentity.IngestionJob
+	lost     map[string]bool
+	finished []dbentity.IngestionJob
+	idle     func()
+}
+
+func (r *fakeJobRepository) Create(ctx context.Context, job *dbentity.IngestionJob) error {
+	return errors.New("not implemented")
+}
+
+func (r *fakeJobRepository) GetByID(ctx context.Context, id string) (*dbentity.IngestionJob, error) {
+	return nil, pgx.ErrNoRows
+}
+
+func (r *fakeJobRepository) ClaimNext(ctx context.Context, staleAfter time.Duration, maxAttempts int) (*dbentity.IngestionJob, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if len(r.queue) == 0 {
+		r.idle()
+		return nil, pgx.ErrNoRows
+	}
+	job := r.queue[0]
+	r.queue = r.queue[1:]
+	job.Status = dbentity.IngestionJobStatusRunning
+	job.Attempts++
+	return job, nil
+}
+
+func (r *fakeJobRepository) FailAbandoned(ctx context.Context, staleAfter time.Duration, maxAttempts int) (int64, error) {
+	return 0, nil
+}
+
+func (r *fakeJobRepository) Heartbeat(ctx context.Context, job *dbentity.IngestionJob) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.lost[job.ID] {
+		return pgx.ErrNoRows
+	}
+	return nil
+}
+
+func (r *fakeJobRepository) Finish(ctx context.Context, job *dbentity.IngestionJob) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.lost[job.ID] {
+		return pgx.ErrNoRows
+	}
+	r.finished = append(r.finished, *job)
+	return nil
+}
+
+type processorFunc func(ctx context.Context, job *dbentity.IngestionJob) (Result, error)
+
+func (f processorFunc) ProcessIngestionJob(ctx context.Context, job *dbentity.IngestionJob) (Result, error) {
+	return f(ctx, job)
+}
+
+// runPool runs a single worker over repo's queue until it is drained.
+func runPool(repo *fakeJobRepository, config Config, processors map[string]Processor) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	repo.idle = cancel
+	config.Workers = 1
+	pool := NewPool(repo, config)
+	for kind, p := range processors {
+		pool.Register(kind, p)
+	}
+	pool.Run(ctx)
+}
+
+func TestPoolRun(t *testing.T) {
+	repo := &fakeJobRepository{queue: []*dbentity.IngestionJob{
+		{ID: "ok", Kind: "TEST"},
+		{ID: "error", Kind: "TEST"},
+		{ID: "panic", Kind: "TEST"},
+		{ID: "unknown", Kind: "OTHER"},
+	}}
+	runPool(repo, Config{}, map[string]Processor{
+		"TEST": processorFunc(func(ctx context.Context, job *dbentity.IngestionJob) (Result, error) {
+			switch job.ID {
+			case "error":
+				return Result{Processed: 2, Succeeded: 1, Failed: 1, Errors: []dbentity.IngestionJobError{{Item: 2, Message: "line 2"}}}, errors.New("upload failed")
+			case "panic":
+				panic("boom")
+			}
+			return Result{Processed: 1, Succeeded: 1, Output: map[string]string{"id": job.ID}}, nil
+		}),
+	})
+
+	if len(repo.finished) != 4 {
+		t.Fatalf("finished %d jobs, want 4", len(repo.finished))
+	}
+	tests := []struct {
+		status   string
+		messages []string
+	}{
+		{dbentity.IngestionJobStatusSucceeded, nil},
+		{dbentity.IngestionJobStatusFailed, []string{"line 2", "upload failed"}},
+		{dbentity.IngestionJobStatusFailed, []string{"ingestion job panicked: boom"}},
+		{dbentity.IngestionJobStatusFailed, []string{"no processor registered for job kind OTHER"}},
+	}
+	for i, tt := range tests {
+		job := repo.finished[i]
+		var messages []string
+		for _, e := range job.Errors {
+			messages = append(messages, e.Message)
+		}
+		if job.Status != tt.status || len(messages) != len(tt.messages) {
+			t.Errorf("job %s finished %s with errors %v, want %s with %v", job.ID, job.Status, messages, tt.status, tt.messages)
+			continue
+		}
+		for j := range messages {
+			if messages[j] != tt.messages[j] {
+				t.Errorf("job %s error %d = %q, want %q", job.ID, j, messages[j], tt.messages[j])
+			}
+		}
+	}
+	if ok := repo.finished[0]; ok.SucceededCount != 1 || string(ok.Output) != `{"id":"ok"}` {
+		t.Errorf("succeeded job = %+v", ok)
+	}
+	if failed := repo.finished[1]; failed.ProcessedCount != 2 || failed.FailedCount != 1 {
+		t.Errorf("failed job counts = %+v, want the processor's partial result", failed)
+	}
+}
+
+func TestPoolRunCancelsJobThatLostItsClaim(t *testing.T) {
+	repo := &fakeJobRepository{
+		queue: []*dbentity.IngestionJob{{ID: "reclaimed", Kind: "TEST"}},
+		lost:  map[string]bool{"reclaimed": true},
+	}
+	var processErr error
+	runPool(repo, Config{HeartbeatInterval: time.Millisecond}, map[string]Processor{
+		"TEST": processorFunc(func(ctx context.Context, job *dbentity.IngestionJob) (Result, error) {
+			select {
+			case <-ctx.Done():
+				processErr = ctx.Err()
+			case <-time.After(5 * time.Second):
+				processErr = errors.New("job was not canceled")
+			}
+			return Result{}, processErr
+		}),
+	})
+	if !errors.Is(processErr, context.Canceled) {
+		t.Errorf("processor finished with %v, want its context canceled", processErr)
+	}
+	if len(repo.finished) != 0 {
+		t.Errorf("finished %+v, want the reclaimed job's result discarded", repo.finished)
+	}
+}
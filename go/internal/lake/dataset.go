@@ -0,0 +1,57 @@
+// Package lake writes datasets to their Iceberg tables in a Hive-style layout: rows are split by
+// partition and each partition's rows of a write go to one parquet file under
+// <table>/data/<field>=<value>/..., so query engines can prune by the partition columns.
+package lake
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"usage-lakehouse/internal/iceberg"
+)
+
+// Dataset is a lake table whose rows are R, a struct whose parquet tags name the table's columns.
+type Dataset[R any] struct {
+	Table  iceberg.Identifier
+	Schema iceberg.Schema
+	// Partitions is the table's default partitioning.
+	Partitions []iceberg.PartitionTransform
+	// Key names the columns identifying a row, for tables whose rows are written again when they
+	// change. A write then replaces the rows of earlier writes with the same key. Tables without a
+	// key only append.
+	Key []string
+	// Version is the schema version, recorded in the table's properties and every file's footer.
+	Version int
+}
+
+// schemaVersionKey is the table property and parquet footer key of Dataset.Version.
+const schemaVersionKey = "usage-lakehouse.schema-version"
+
+// columnIndexes maps the parquet column names of R to its struct field indexes.
+func columnIndexes[R any]() (map[string]int, error) {
+	t := reflect.TypeOf((*R)(nil)).Elem()
+	if t.Kind() != reflect.Struct {
+		return nil, fmt.Errorf("lake row type %s is not a struct", t)
+	}
+	columns := map[string]int{}
+	for i := 0; i < t.NumField(); i++ {
+		for _, option := range strings.Split(t.Field(i).Tag.Get("parquet"), ",") {
+			if name, ok := strings.CutPrefix(strings.TrimSpace(option), "name="); ok {
+				columns[name] = i
+			}
+		}
+	}
+	return columns, nil
+}
+
+// columnValue is a row's value of the column at field index i, with nil pointers as nil.
+func columnValue(row reflect.Value, i int) any {
+	v := row.Field(i)
+	if v.Kind() == reflect.Pointer {
+		if v.IsNil() {
+			return nil
+		}
+		v = v.Elem()
+	}
+	return v.Interface()
+}
@@ -0,0 +1,101 @@
+package lake
+
+import (
+	"usage-lakehouse/internal/iceberg"
+	"usage-lakehouse/internal/model"
+)
+
+// IntervalUsage is the lake's interval usage table. Its columns are those of model.UsageRow, with the
+// same field IDs; a new schema version is a new table.
+var IntervalUsage = Dataset[model.UsageRow]{
+	Table: iceberg.Identifier{Namespace: "usage", Name: "interval_usage"},
+	Schema: iceberg.NewSchema(
+		iceberg.Field{Name: "account_id", Required: true, Type: "string"},
+		iceberg.Field{Name: "premise_code", Type: "string"},
+		iceberg.Field{Name: "meter_code", Required: true, Type: "string"},
+		iceberg.Field{Name: "power_region", Type: "string"},
+		iceberg.Field{Name: "channel", Type: "string"},
+		iceberg.Field{Name: "interval_start", Required: true, Type: "timestamptz"},
+		iceberg.Field{Name: "interval_end", Required: true, Type: "timestamptz"},
+		iceberg.Field{Name: "unit_of_measure", Required: true, Type: "string"},
+		iceberg.Field{Name: "quantity", Required: true, Type: "double"},
+		iceberg.Field{Name: "quality_flag", Type: "string", Doc: "ACTUAL, ESTIMATED or EDITED"},
+		iceberg.Field{Name: "source_transaction_id", Type: "string"},
+	),
+	Partitions: []iceberg.PartitionTransform{
+		{Column: "power_region", Transform: "identity"},
+		{Column: "account_id", Transform: "identity"},
+		{Name: "year", Column: "interval_start", Transform: "year"},
+		{Name: "month", Column: "interval_start", Transform: "month"},
+	},
+	Version: model.UsageSchemaVersion,
+}
+
+// UsageTransactionDetail is the lake's copy of the usage_transaction_detail table, exported from
+// Postgres. Its columns are those of model.UsageTransactionDetailRow, with the same field IDs. A row
+// exported again after an update replaces the version exported before.
+var UsageTransactionDetail = Dataset[model.UsageTransactionDetailRow]{
+	Table: iceberg.Identifier{Namespace: "usage", Name: "usage_transaction_detail"},
+	Schema: iceberg.NewSchema(
+		iceberg.Field{Name: "usage_transaction_id", Required: true, Type: "string"},
+		iceberg.Field{Name: "transaction_id", Required: true, Type: "string"},
+		iceberg.Field{Name: "power_region", Required: true, Type: "string"},
+		iceberg.Field{Name: "premise_id", Required: true, Type: "string"},
+		iceberg.Field{Name: "premise_code", Required: true, Type: "string"},
+		iceberg.Field{Name: "meter_id", Type: "string"},
+		iceberg.Field{Name: "meter_name", Required: true, Type: "string"},
+		iceberg.Field{Name: "start_dttm", Required: true, Type: "timestamptz"},
+		iceberg.Field{Name: "end_dttm", Required: true, Type: "timestamptz"},
+		iceberg.Field{Name: "service_period_start_dt", Required: true, Type: "date"},
+		iceberg.Field{Name: "service_period_end_dt", Required: true, Type: "date"},
+		iceberg.Field{Name: "is_canceled", Required: true, Type: "boolean"},
+		iceberg.Field{Name: "is_interval", Required: true, Type: "boolean"},
+		iceberg.Field{Name: "consumption", Type: "double"},
+		iceberg.Field{Name: "generation", Type: "double"},
+		iceberg.Field{Name: "updated_dttm", Required: true, Type: "timestamp", Doc: "Postgres updated_dttm of the exported version"},
+	),
+	Partitions: []iceberg.PartitionTransform{
+		{Column: "power_region", Transform: "identity"},
+		{Name: "year", Column: "start_dttm", Transform: "year"},
+		{Name: "month", Column: "start_dttm", Transform: "month"},
+	},
+	// The primary key of usage_transaction_detail.
+	Key:     []string{"start_dttm", "usage_transaction_id", "meter_name"},
+	Version: model.UsageTransactionDetailSchemaVersion,
+}
+
+// MeterUsage is the lake's copy of the meter_usage_15_minute table, exported from Postgres. Its
+// columns are those of model.MeterUsageRow, with the same field IDs. Like UsageTransactionDetail, a
+// row exported again replaces the version exported before.
+var MeterUsage = Dataset[model.MeterUsageRow]{
+	Table: iceberg.Identifier{Namespace: "usage", Name: "meter_usage_15_minute"},
+	Schema: iceberg.NewSchema(
+		iceberg.Field{Name: "meter_id", Required: true, Type: "string"},
+		iceberg.Field{Name: "meter_name", Required: true, Type: "string"},
+		iceberg.Field{Name: "premise_id", Required: true, Type: "string"},
+		iceberg.Field{Name: "premise_code", Required: true, Type: "string"},
+		iceberg.Field{Name: "account_id", Required: true, Type: "string"},
+		iceberg.Field{Name: "power_region", Required: true, Type: "string"},
+		iceberg.Field{Name: "usage_transaction_id", Type: "string"},
+		iceberg.Field{Name: "start_dttm", Required: true, Type: "timestamptz"},
+		iceberg.Field{Name: "end_dttm", Required: true, Type: "timestamptz"},
+		iceberg.Field{Name: "service_period_start_dt", Required: true, Type: "date"},
+		iceberg.Field{Name: "service_period_end_dt", Required: true, Type: "date"},
+		iceberg.Field{Name: "consumption", Type: "double"},
+		iceberg.Field{Name: "generation", Type: "double"},
+		iceberg.Field{Name: "is_canceled", Required: true, Type: "boolean"},
+		iceberg.Field{Name: "quality", Required: true, Type: "string", Doc: "ACTUAL, ESTIMATED or EDITED"},
+		iceberg.Field{Name: "quality_rule", Type: "string"},
+		iceberg.Field{Name: "original_consumption", Type: "double"},
+		iceberg.Field{Name: "updated_dttm", Required: true, Type: "timestamp", Doc: "Postgres updated_dttm of the exported version"},
+	),
+	Partitions: []iceberg.PartitionTransform{
+		{Column: "power_region", Transform: "identity"},
+		{Column: "account_id", Transform: "identity"},
+		{Name: "year", Column: "start_dttm", Transform: "year"},
+		{Name: "month", Column: "start_dttm", Transform: "month"},
+	},
+	// The primary key of meter_usage_15_minute.
+	Key:     []string{"start_dttm", "meter_id"},
+	Version: model.MeterUsageSchemaVersion,
+}
@@ -0,0 +1,195 @@
+package lake
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"reflect"
+	"sort"
+	"strconv"
+	"usage-lakehouse/internal/iceberg"
+
+	"github.com/xitongsys/parquet-go-source/writerfile"
+	"github.com/xitongsys/parquet-go/parquet"
+	"github.com/xitongsys/parquet-go/writer"
+)
+
+// Writer appends rows of a dataset to its table.
+type Writer[R any] struct {
+	dataset Dataset[R]
+	table   *iceberg.Table
+	spec    iceberg.PartitionSpec
+	columns map[string]int
+}
+
+// NewWriter writes dataset to its table in warehouse, committed through catalog and partitioned by
+// partitions. A table created under other partitions is evolved to these on the next write.
+func NewWriter[R any](catalog iceberg.Catalog, warehouse *iceberg.Warehouse, dataset Dataset[R], partitions []iceberg.PartitionTransform) (*Writer[R], error) {
+	columns, err := columnIndexes[R]()
+	if err != nil {
+		return nil, err
+	}
+	for _, f := range dataset.Schema.Fields {
+		if _, ok := columns[f.Name]; !ok {
+			return nil, fmt.Errorf("%s: column %s has no parquet field", dataset.Table, f.Name)
+		}
+	}
+	for _, column := range dataset.Key {
+		if _, ok := columns[column]; !ok {
+			return nil, fmt.Errorf("%s: key column %s has no parquet field", dataset.Table, column)
+		}
+	}
+	spec, err := iceberg.NewPartitionSpec(dataset.Schema, partitions...)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", dataset.Table, err)
+	}
+	properties := map[string]string{schemaVersionKey: strconv.Itoa(dataset.Version)}
+	table := iceberg.NewTable(catalog, warehouse, dataset.Table, dataset.Schema, spec, properties)
+	return &Writer[R]{dataset: dataset, table: table, spec: spec, columns: columns}, nil
+}
+
+// CurrentSnapshot returns the table's current snapshot, or nil before its first write.
+func (w *Writer[R]) CurrentSnapshot(ctx context.Context) (*iceberg.Snapshot, error) {
+	return w.table.CurrentSnapshot(ctx)
+}
+
+// File is a data file a write added to the table.
+type File struct {
+	Key       string
+	Partition map[string]any
+	RowCount  int
+}
+
+// partitionRows are the rows of one partition.
+type partitionRows[R any] struct {
+	partition map[string]any
+	path      string
+	rows      []R
+}
+
+// Write splits rows by partition, writes each partition's rows to one parquet file and appends the
+// files to the table in a single snapshot, so readers see all of the write or none of it. properties
+// are recorded in the snapshot's summary. Files already written are deleted when the write fails.
+//
+// For a dataset with a key, the rows are also written to an equality delete file on the key columns,
+// committed in the same snapshot, so that they replace the rows earlier writes stored for their keys.
+func (w *Writer[R]) Write(ctx context.Context, rows []R, properties map[string]string) ([]File, *iceberg.Snapshot, error) {
+	partitions, err := w.split(rows)
+	if err != nil {
+		return nil, nil, err
+	}
+	var files []File
+	var dataFiles []iceberg.DataFile
+	for _, p := range partitions {
+		dataFile, key, err := w.writeFile(ctx, p.partition, p.rows)
+		if err != nil {
+			w.remove(files)
+			return nil, nil, err
+		}
+		files = append(files, File{Key: key, Partition: p.partition, RowCount: len(p.rows)})
+		dataFiles = append(dataFiles, dataFile)
+	}
+	var deletes []iceberg.DataFile
+	var deleteKeys []File
+	if len(w.dataset.Key) > 0 && len(rows) > 0 {
+		deleteFile, key, err := w.writeDeleteFile(ctx, rows)
+		if err != nil {
+			w.remove(files)
+			return nil, nil, err
+		}
+		deletes = append(deletes, deleteFile)
+		deleteKeys = append(deleteKeys, File{Key: key, Partition: deleteFile.Partition, RowCount: len(rows)})
+	}
+	snapshot, err := w.table.Replace(ctx, dataFiles, deletes, properties)
+	if err != nil {
+		w.remove(append(files, deleteKeys...))
+		return nil, nil, err
+	}
+	return files, snapshot, nil
+}
+
+// split groups rows by partition, in the order of their partition paths.
+func (w *Writer[R]) split(rows []R) ([]*partitionRows[R], error) {
+	byPath := map[string]*partitionRows[R]{}
+	var partitions []*partitionRows[R]
+	for _, row := range rows {
+		v := reflect.ValueOf(row)
+		partition, err := w.spec.Partition(w.dataset.Schema, func(column string) any {
+			return columnValue(v, w.columns[column])
+		})
+		if err != nil {
+			return nil, err
+		}
+		path := w.table.PartitionPath(partition)
+		p, ok := byPath[path]
+		if !ok {
+			p = &partitionRows[R]{partition: partition, path: path}
+			byPath[path] = p
+			partitions = append(partitions, p)
+		}
+		p.rows = append(p.rows, row)
+	}
+	sort.Slice(partitions, func(i, j int) bool { return partitions[i].path < partitions[j].path })
+	return partitions, nil
+}
+
+func (w *Writer[R]) writeFile(ctx context.Context, partition map[string]any, rows []R) (iceberg.DataFile, string, error) {
+	f, err := w.table.CreateDataFile(ctx, partition)
+	if err != nil {
+		return iceberg.DataFile{}, "", err
+	}
+	if err := w.writeParquet(f, rows); err != nil {
+		f.Abort()
+		return iceberg.DataFile{}, "", err
+	}
+	dataFile, err := f.Close(int64(len(rows)))
+	if err != nil {
+		return iceberg.DataFile{}, "", err
+	}
+	return dataFile, f.Key(), nil
+}
+
+// writeDeleteFile writes rows to an equality delete file on the dataset's key. The whole rows are
+// written, in the data files' layout; readers compare only the key columns.
+func (w *Writer[R]) writeDeleteFile(ctx context.Context, rows []R) (iceberg.DataFile, string, error) {
+	f, err := w.table.CreateDeleteFile(ctx, w.dataset.Key...)
+	if err != nil {
+		return iceberg.DataFile{}, "", err
+	}
+	if err := w.writeParquet(f, rows); err != nil {
+		f.Abort()
+		return iceberg.DataFile{}, "", err
+	}
+	deleteFile, err := f.Close(int64(len(rows)))
+	if err != nil {
+		return iceberg.DataFile{}, "", err
+	}
+	return deleteFile, f.Key(), nil
+}
+
+func (w *Writer[R]) writeParquet(out io.Writer, rows []R) error {
+	pw, err := writer.NewParquetWriter(writerfile.NewWriterFile(out), new(R), 4)
+	if err != nil {
+		return fmt.Errorf("create parquet writer: %w", err)
+	}
+	for _, row := range rows {
+		if err := pw.Write(row); err != nil {
+			return fmt.Errorf("write parquet row: %w", err)
+		}
+	}
+	version := strconv.Itoa(w.dataset.Version)
+	pw.Footer.KeyValueMetadata = append(pw.Footer.KeyValueMetadata, &parquet.KeyValue{Key: schemaVersionKey, Value: &version})
+	if err := pw.WriteStop(); err != nil {
+		return fmt.Errorf("finish parquet file: %w", err)
+	}
+	return nil
+}
+
+// remove deletes files no snapshot references. A file left behind is only wasted space, so failures
+// are ignored.
+func (w *Writer[R]) remove(files []File) {
+	store := w.table.Warehouse().Store()
+	for _, f := range files {
+		store.Delete(context.Background(), f.Key)
+	}
+}
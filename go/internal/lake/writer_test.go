@@ -0,0 +1,193 @@
+package lake
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+	"usage-lakehouse/internal/iceberg"
+	"usage-lakehouse/internal/model"
+	"usage-lakehouse/internal/objectstore"
+)
+
+func usageRow(account string, region *string, start time.Time, quantity float64) model.UsageRow {
+	return model.UsageRow{
+		AccountID:     account,
+		MeterCode:     "m1",
+		PowerRegion:   region,
+		IntervalStart: start.UnixMicro(),
+		IntervalEnd:   start.Add(15 * time.Minute).UnixMicro(),
+		UnitOfMeasure: "KWH",
+		Quantity:      quantity,
+	}
+}
+
+func newTestWriter(t *testing.T, partitions []iceberg.PartitionTransform) (*Writer[model.UsageRow], objectstore.Store) {
+	t.Helper()
+	store := objectstore.NewMemory()
+	w, err := NewWriter(iceberg.NewFilesystemCatalog(t.TempDir()), iceberg.NewWarehouse(store, "memory://warehouse"), IntervalUsage, partitions)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return w, store
+}
+
+// splitPartition is the path of a partition split returns and the quantities of its rows.
+type splitPartition struct {
+	path       string
+	quantities []float64
+}
+
+func TestWriterSplit(t *testing.T) {
+	ercot, nyiso := "ERCOT", "NYISO"
+	jan := time.Date(2024, 1, 31, 23, 45, 0, 0, time.UTC)
+	feb := time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC)
+	dec := time.Date(2023, 12, 31, 23, 45, 0, 0, time.UTC)
+	rows := []model.UsageRow{
+		usageRow("a2", &ercot, feb, 1),
+		usageRow("a1", &ercot, jan, 2),
+		usageRow("a1", &nyiso, jan, 3),
+		usageRow("a1", &ercot, feb, 4),
+		usageRow("a1", &ercot, jan.Add(-15*time.Minute), 5),
+		usageRow("a1", nil, dec, 6),
+	}
+	tests := []struct {
+		name       string
+		partitions []iceberg.PartitionTransform
+		want       []splitPartition
+	}{
+		{
+			name:       "default partitioning",
+			partitions: IntervalUsage.Partitions,
+			want: []splitPartition{
+				{"power_region=ERCOT/account_id=a1/year=2024/month=2024-01", []float64{2, 5}},
+				{"power_region=ERCOT/account_id=a1/year=2024/month=2024-02", []float64{4}},
+				{"power_region=ERCOT/account_id=a2/year=2024/month=2024-02", []float64{1}},
+				{"power_region=NYISO/account_id=a1/year=2024/month=2024-01", []float64{3}},
+				{"power_region=null/account_id=a1/year=2023/month=2023-12", []float64{6}},
+			},
+		},
+		{
+			name:       "by day",
+			partitions: []iceberg.PartitionTransform{{Name: "day", Column: "interval_start", Transform: "day"}},
+			want: []splitPartition{
+				{"day=2023-12-31", []float64{6}},
+				{"day=2024-01-31", []float64{2, 3, 5}},
+				{"day=2024-02-01", []float64{1, 4}},
+			},
+		},
+		{
+			name:       "unpartitioned",
+			partitions: nil,
+			want:       []splitPartition{{"", []float64{1, 2, 3, 4, 5, 6}}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w, _ := newTestWriter(t, tt.partitions)
+			partitions, err := w.split(rows)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var got []splitPartition
+			for _, p := range partitions {
+				var quantities []float64
+				for _, r := range p.rows {
+					quantities = append(quantities, r.Quantity)
+				}
+				got = append(got, splitPartition{p.path, quantities})
+				if p.path != w.table.PartitionPath(p.partition) {
+					t.Errorf("partition %v has path %s", p.partition, p.path)
+				}
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("split = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWriterWrite(t *testing.T) {
+	ctx := context.Background()
+	w, store := newTestWriter(t, IntervalUsage.Partitions)
+	ercot := "ERCOT"
+	jan := time.Date(2024, 1, 15, 12, 0, 0, 0, time.UTC)
+	rows := []model.UsageRow{
+		usageRow("a1", &ercot, jan, 1),
+		usageRow("a1", &ercot, jan.AddDate(0, 1, 0), 2),
+		usageRow("a1", &ercot, jan.Add(15*time.Minute), 3),
+	}
+	files, snapshot, err := w.Write(ctx, rows, map[string]string{"batch": "1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) != 2 {
+		t.Fatalf("wrote %d files, want one per month", len(files))
+	}
+	wantDirs := []string{
+		"usage/interval_usage/data/power_region=ERCOT/account_id=a1/year=2024/month=2024-01/",
+		"usage/interval_usage/data/power_region=ERCOT/account_id=a1/year=2024/month=2024-02/",
+	}
+	for i, f := range files {
+		if !strings.HasPrefix(f.Key, wantDirs[i]) || !strings.HasSuffix(f.Key, ".parquet") {
+			t.Errorf("file %d key %s is not a parquet file in %s", i, f.Key, wantDirs[i])
+		}
+		if exists, err := store.Exists(ctx, f.Key); err != nil || !exists {
+			t.Errorf("file %s was not written: %v", f.Key, err)
+		}
+	}
+	if files[0].RowCount != 2 || files[1].RowCount != 1 {
+		t.Errorf("row counts %d and %d, want 2 and 1", files[0].RowCount, files[1].RowCount)
+	}
+	if snapshot.Summary["added-data-files"] != "2" || snapshot.Summary["added-records"] != "3" || snapshot.Summary["batch"] != "1" {
+		t.Errorf("snapshot summary = %v", snapshot.Summary)
+	}
+	current, err := w.CurrentSnapshot(ctx)
+	if err != nil || current == nil || current.SnapshotID != snapshot.SnapshotID {
+		t.Errorf("CurrentSnapshot = %v, %v, want snapshot %d", current, err, snapshot.SnapshotID)
+	}
+}
+
+func TestWriterWriteReplacesKeyedRows(t *testing.T) {
+	ctx := context.Background()
+	keyed := IntervalUsage
+	keyed.Key = []string{"account_id", "meter_code", "interval_start"}
+	store := objectstore.NewMemory()
+	w, err := NewWriter(iceberg.NewFilesystemCatalog(t.TempDir()), iceberg.NewWarehouse(store, "memory://warehouse"), keyed, keyed.Partitions)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ercot := "ERCOT"
+	jan := time.Date(2024, 1, 15, 12, 0, 0, 0, time.UTC)
+	rows := []model.UsageRow{usageRow("a1", &ercot, jan, 1), usageRow("a1", &ercot, jan.AddDate(0, 1, 0), 2)}
+	files, snapshot, err := w.Write(ctx, rows, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) != 2 {
+		t.Errorf("wrote %d data files, want one per month", len(files))
+	}
+	if snapshot.Summary["operation"] != "overwrite" || snapshot.Summary["added-records"] != "2" || snapshot.Summary["added-equality-deletes"] != "2" {
+		t.Errorf("snapshot summary = %v", snapshot.Summary)
+	}
+	// The delete file is outside the partition directories, as it applies to all of them.
+	objects, err := store.List(ctx, "usage/interval_usage/data/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var deleteFiles []string
+	for _, o := range objects {
+		if strings.HasSuffix(o.Key, "-deletes.parquet") {
+			deleteFiles = append(deleteFiles, o.Key)
+		}
+	}
+	if len(deleteFiles) != 1 || strings.Count(deleteFiles[0], "/") != 3 {
+		t.Errorf("delete files = %v, want one in the data directory", deleteFiles)
+	}
+
+	keyed.Key = []string{"meter_id"}
+	if _, err := NewWriter(iceberg.NewFilesystemCatalog(t.TempDir()), iceberg.NewWarehouse(store, "memory://warehouse"), keyed, nil); err == nil {
+		t.Error("NewWriter with a key column the rows do not have succeeded, want an error")
+	}
+}
@@ -0,0 +1,206 @@
+// Package lakeexport copies Postgres usage tables to their lake tables incrementally, so the same data
+// can be queried in both. Each run exports the rows updated since the source's high-water mark on
+// updated_dttm in batches, one lake commit per batch, and advances the watermark after each commit.
+// A failed run leaves the watermark at its last committed batch and the next run resumes there.
+// Each commit replaces the versions earlier commits exported of its rows, by the source's primary key,
+// so an updated row is in the lake once.
+package lakeexport
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+	dbentity "usage-lakehouse/internal/db/entity"
+	"usage-lakehouse/internal/repository"
+
+	"github.com/google/uuid"
+)
+
+// ErrRunning is returned when another exporter is already exporting the source.
+var ErrRunning = errors.New("lake export already running")
+
+type Config struct {
+	// BatchSize is how many rows one lake commit holds. Rows updated at the same instant, such as
+	// those of one transaction, are never split, so a batch can be larger.
+	BatchSize int
+	// Lag keeps exports this far behind the database clock. updated_dttm is when the updating
+	// transaction started, so Lag must exceed the longest write transaction or rows it commits late
+	// would fall behind the watermark and never be exported.
+	Lag time.Duration
+}
+
+type Exporter struct {
+	repo    repository.LakeExportRepository
+	sources []Source
+	config  Config
+}
+
+func New(repo repository.LakeExportRepository, config Config, sources ...Source) *Exporter {
+	if config.BatchSize <= 0 {
+		config.BatchSize = 50000
+	}
+	if config.Lag <= 0 {
+		config.Lag = 5 * time.Minute
+	}
+	return &Exporter{repo: repo, sources: sources, config: config}
+}
+
+// Run exports every source, or only those whose tables are named, and returns their runs. A source
+// that fails does not stop the others; the errors are joined.
+func (e *Exporter) Run(ctx context.Context, tables ...string) ([]*dbentity.LakeExportRun, error) {
+	sources, err := e.selectSources(tables)
+	if err != nil {
+		return nil, err
+	}
+	var runs []*dbentity.LakeExportRun
+	var errs []error
+	for _, s := range sources {
+		run, err := e.export(ctx, s)
+		if run != nil {
+			runs = append(runs, run)
+		}
+		if err != nil {
+			errs = append(errs, fmt.Errorf("export %s: %w", s.Table(), err))
+		}
+	}
+	return runs, errors.Join(errs...)
+}
+
+// Schedule runs every source now and then every interval until ctx is canceled. Sources another
+// exporter is running are skipped.
+func (e *Exporter) Schedule(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		for _, s := range e.sources {
+			if ctx.Err() != nil {
+				return
+			}
+			run, err := e.export(ctx, s)
+			if errors.Is(err, ErrRunning) {
+				continue
+			}
+			if err != nil {
+				log.Printf("lake export %s: %v", s.Table(), err)
+				continue
+			}
+			if run.RowCount > 0 {
+				log.Printf("lake export %s: %d rows in %d files up to %s", s.Table(), run.RowCount, run.FileCount, run.HighWaterMark.Format(watermarkLayout))
+			}
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (e *Exporter) selectSources(tables []string) ([]Source, error) {
+	if len(tables) == 0 {
+		return e.sources, nil
+	}
+	var sources []Source
+	for _, table := range tables {
+		found := false
+		for _, s := range e.sources {
+			if s.Table() == table {
+				sources = append(sources, s)
+				found = true
+			}
+		}
+		if !found {
+			return nil, fmt.Errorf("no lake export source %s", table)
+		}
+	}
+	return sources, nil
+}
+
+// export runs one export of s up to the horizon, recording it as a lake_export_run. It returns the
+// run, also when it failed, unless the run could not be started.
+func (e *Exporter) export(ctx context.Context, s Source) (*dbentity.LakeExportRun, error) {
+	unlock, ok, err := e.repo.TryLock(ctx, s.Table())
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, ErrRunning
+	}
+	defer unlock()
+
+	mark, err := e.watermark(ctx, s)
+	if err != nil {
+		return nil, err
+	}
+	horizon, err := e.repo.Horizon(ctx, e.config.Lag)
+	if err != nil {
+		return nil, err
+	}
+	run := &dbentity.LakeExportRun{ID: uuid.New().String(), SourceTable: s.Table(), LowWaterMark: mark}
+	if err := e.repo.CreateRun(ctx, run); err != nil {
+		return nil, err
+	}
+	for mark == nil || horizon.After(*mark) {
+		next, err := e.repo.NextWatermark(ctx, s.Table(), mark, horizon, e.config.BatchSize)
+		if err != nil {
+			return e.fail(run, err)
+		}
+		batch, err := s.Export(ctx, mark, next)
+		if err != nil {
+			return e.fail(run, err)
+		}
+		run.RowCount += int64(batch.Rows)
+		run.FileCount += batch.Files
+		if batch.SnapshotID != nil {
+			run.SnapshotID = batch.SnapshotID
+		}
+		if err := e.repo.SetWatermark(ctx, s.Table(), next); err != nil {
+			return e.fail(run, fmt.Errorf("advance watermark to %s: %w", next.Format(watermarkLayout), err))
+		}
+		mark = &next
+		run.HighWaterMark = mark
+		if err := e.repo.UpdateRun(ctx, run); err != nil {
+			return e.fail(run, err)
+		}
+	}
+	run.Status = dbentity.LakeExportRunStatusSucceeded
+	if err := e.repo.UpdateRun(ctx, run); err != nil {
+		return run, err
+	}
+	return run, nil
+}
+
+// watermark returns the source's high-water mark. A lake commit whose watermark was not stored,
+// because the export failed between the two, is already in the lake, so its watermark is adopted
+// rather than exporting its rows again.
+func (e *Exporter) watermark(ctx context.Context, s Source) (*time.Time, error) {
+	mark, err := e.repo.GetWatermark(ctx, s.Table())
+	if err != nil {
+		return nil, err
+	}
+	committed, err := s.Watermark(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if committed != nil && (mark == nil || committed.After(*mark)) {
+		if err := e.repo.SetWatermark(ctx, s.Table(), *committed); err != nil {
+			return nil, err
+		}
+		return committed, nil
+	}
+	return mark, nil
+}
+
+// fail records err on the run. It is recorded even when ctx was canceled, so an interrupted run
+// is not left RUNNING.
+func (e *Exporter) fail(run *dbentity.LakeExportRun, err error) (*dbentity.LakeExportRun, error) {
+	message := err.Error()
+	run.Status = dbentity.LakeExportRunStatusFailed
+	run.Error = &message
+	if updateErr := e.repo.UpdateRun(context.Background(), run); updateErr != nil {
+		log.Printf("record failed lake export run %s: %v", run.ID, updateErr)
+	}
+	return run, err
+}
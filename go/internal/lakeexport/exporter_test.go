@@ -0,0 +1,412 @@
+package lakeexport
+
+import (
+	"context"
+	"errors"
+	"io"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+	dbentity "usage-lakehouse/internal/db/entity"
+	"usage-lakehouse/internal/iceberg"
+	"usage-lakehouse/internal/lake"
+	"usage-lakehouse/internal/model"
+	"usage-lakehouse/internal/objectstore"
+	"usage-lakehouse/internal/repository"
+
+	"github.com/xitongsys/parquet-go-source/buffer"
+	"github.com/xitongsys/parquet-go/reader"
+)
+
+type meterUsageKey struct {
+	meterID string
+	start   time.Time
+}
+
+type listCall struct {
+	after   *time.Time
+	through time.Time
+}
+
+// fakeLakeExportRepository holds meter_usage_15_minute in memory, one version of each row by its
+// primary key, and answers the horizon from now. When failSetWatermark is set, the next
+// SetWatermark fails with it.
+type fakeLakeExportRepository struct {
+	repository.LakeExportRepository
+	now              time.Time
+	rows             map[meterUsageKey]dbentity.MeterUsageIntervalExport
+	watermarks       map[string]time.Time
+	failSetWatermark error
+	lists            []listCall
+	runs             []dbentity.LakeExportRun
+}
+
+func newFakeLakeExportRepository(now time.Time) *fakeLakeExportRepository {
+	return &fakeLakeExportRepository{now: now, rows: make(map[meterUsageKey]dbentity.MeterUsageIntervalExport), watermarks: make(map[string]time.Time)}
+}
+
+// upsert writes a version of the meter's interval starting at start, updated at updated.
+func (r *fakeLakeExportRepository) upsert(meterID string, start time.Time, consumption float64, updated time.Time) {
+	r.rows[meterUsageKey{meterID, start}] = dbentity.MeterUsageIntervalExport{
+		MeterID:            meterID,
+		MeterName:          "M-" + meterID,
+		PremiseID:          "premise-1",
+		PremiseCode:        "10443720004529147",
+		AccountID:          "account-1",
+		PowerRegion:        "ERCOT",
+		Start:              start,
+		End:                start.Add(15 * time.Minute),
+		ServicePeriodStart: time.Date(start.Year(), start.Month(), 1, 0, 0, 0, 0, time.UTC),
+		ServicePeriodEnd:   time.Date(start.Year(), start.Month()+1, 1, 0, 0, 0, 0, time.UTC),
+		Consumption:        &consumption,
+		Quality:            "ACTUAL",
+		Updated:            updated,
+	}
+}
+
+// updated answers the rows updated after after up to through, oldest update first.
+func (r *fakeLakeExportRepository) updated(after *time.Time, through time.Time) []dbentity.MeterUsageIntervalExport {
+	var rows []dbentity.MeterUsageIntervalExport
+	for _, row := range r.rows {
+		if (after == nil || row.Updated.After(*after)) && !row.Updated.After(through) {
+			rows = append(rows, row)
+		}
+	}
+	sort.Slice(rows, func(i, j int) bool {
+		if !rows[i].Updated.Equal(rows[j].Updated) {
+			return rows[i].Updated.Before(rows[j].Updated)
+		}
+		return rows[i].MeterID < rows[j].MeterID
+	})
+	return rows
+}
+
+func (r *fakeLakeExportRepository) TryLock(ctx context.Context, source string) (func(), bool, error) {
+	return func() {}, true, nil
+}
+
+func (r *fakeLakeExportRepository) GetWatermark(ctx context.Context, source string) (*time.Time, error) {
+	if mark, ok := r.watermarks[source]; ok {
+		return &mark, nil
+	}
+	return nil, nil
+}
+
+func (r *fakeLakeExportRepository) SetWatermark(ctx context.Context, source string, mark time.Time) error {
+	if err := r.failSetWatermark; err != nil {
+		r.failSetWatermark = nil
+		return err
+	}
+	r.watermarks[source] = mark
+	return nil
+}
+
+func (r *fakeLakeExportRepository) Horizon(ctx context.Context, lag time.Duration) (time.Time, error) {
+	return r.now.Add(-lag), nil
+}
+
+func (r *fakeLakeExportRepository) NextWatermark(ctx context.Context, source string, after *time.Time, through time.Time, batchSize int) (time.Time, error) {
+	rows := r.updated(after, through)
+	if len(rows) < batchSize {
+		return through, nil
+	}
+	return rows[batchSize-1].Updated, nil
+}
+
+func (r *fakeLakeExportRepository) ListMeterUsageIntervals(ctx context.Context, after *time.Time, through time.Time) ([]dbentity.MeterUsageIntervalExport, error) {
+	r.lists = append(r.lists, listCall{after, through})
+	return r.updated(after, through), nil
+}
+
+func (r *fakeLakeExportRepository) CreateRun(ctx context.Context, run *dbentity.LakeExportRun) error {
+	run.Status = dbentity.LakeExportRunStatusRunning
+	return nil
+}
+
+func (r *fakeLakeExportRepository) UpdateRun(ctx context.Context, run *dbentity.LakeExportRun) error {
+	if run.Status != dbentity.LakeExportRunStatusRunning {
+		r.runs = append(r.runs, *run)
+	}
+	return nil
+}
+
+// testLake exports the fake's meter_usage_15_minute to a lake table kept in a memory store and
+// committed through a filesystem catalog. Each run's files are recorded, in run order, so that live
+// can apply equality deletes only to the files of earlier runs, as readers do.
+type testLake struct {
+	t        *testing.T
+	repo     *fakeLakeExportRepository
+	store    objectstore.Store
+	source   Source
+	exporter *Exporter
+	seen     map[string]bool
+	runFiles [][]string
+}
+
+const meterUsageDataPrefix = "usage/meter_usage_15_minute/data/"
+
+func newTestLake(t *testing.T, repo *fakeLakeExportRepository, config Config) *testLake {
+	t.Helper()
+	store := objectstore.NewMemory()
+	w, err := lake.NewWriter(iceberg.NewFilesystemCatalog(t.TempDir()), iceberg.NewWarehouse(store, "memory://warehouse"), lake.MeterUsage, lake.MeterUsage.Partitions)
+	if err != nil {
+		t.Fatal(err)
+	}
+	source := MeterUsageSource(repo, w)
+	return &testLake{t: t, repo: repo, store: store, source: source, exporter: New(repo, config, source), seen: make(map[string]bool)}
+}
+
+// run exports the table once and returns its run.
+func (l *testLake) run() (*dbentity.LakeExportRun, error) {
+	l.t.Helper()
+	runs, err := l.exporter.Run(context.Background())
+	objects, listErr := l.store.List(context.Background(), meterUsageDataPrefix)
+	if listErr != nil {
+		l.t.Fatal(listErr)
+	}
+	var added []string
+	for _, o := range objects {
+		if !l.seen[o.Key] {
+			l.seen[o.Key] = true
+			added = append(added, o.Key)
+		}
+	}
+	l.runFiles = append(l.runFiles, added)
+	if len(runs) != 1 {
+		l.t.Fatalf("Run returned %d runs, want 1: %v", len(runs), err)
+	}
+	return runs[0], err
+}
+
+// committedWatermark is the watermark recorded in the lake table's current snapshot.
+func (l *testLake) committedWatermark() *time.Time {
+	l.t.Helper()
+	mark, err := l.source.Watermark(context.Background())
+	if err != nil {
+		l.t.Fatal(err)
+	}
+	return mark
+}
+
+func (l *testLake) readFile(key string) []model.MeterUsageRow {
+	l.t.Helper()
+	r, err := l.store.Get(context.Background(), key)
+	if err != nil {
+		l.t.Fatal(err)
+	}
+	defer r.Close()
+	b, err := io.ReadAll(r)
+	if err != nil {
+		l.t.Fatal(err)
+	}
+	pr, err := reader.NewParquetReader(buffer.NewBufferFileFromBytes(b), new(model.MeterUsageRow), 1)
+	if err != nil {
+		l.t.Fatalf("read %s: %v", key, err)
+	}
+	defer pr.ReadStop()
+	rows := make([]model.MeterUsageRow, pr.GetNumRows())
+	if err := pr.Read(&rows); err != nil {
+		l.t.Fatalf("read %s: %v", key, err)
+	}
+	return rows
+}
+
+// live returns the rows a reader sees: those of each run's data files that no later run's equality
+// delete file matches on the table's key, start_dttm and meter_id.
+func (l *testLake) live() []model.MeterUsageRow {
+	l.t.Helper()
+	type key struct {
+		start   int64
+		meterID string
+	}
+	var rows []model.MeterUsageRow
+	for i, files := range l.runFiles {
+		deleted := make(map[key]bool)
+		for _, later := range l.runFiles[i+1:] {
+			for _, f := range later {
+				if strings.HasSuffix(f, "-deletes.parquet") {
+					for _, row := range l.readFile(f) {
+						deleted[key{row.StartDttm, row.MeterID}] = true
+					}
+				}
+			}
+		}
+		for _, f := range files {
+			if strings.HasSuffix(f, "-deletes.parquet") {
+				continue
+			}
+			for _, row := range l.readFile(f) {
+				if !deleted[key{row.StartDttm, row.MeterID}] {
+					rows = append(rows, row)
+				}
+			}
+		}
+	}
+	return rows
+}
+
+func equalMark(got *time.Time, want *time.Time) bool {
+	if got == nil || want == nil {
+		return got == want
+	}
+	return got.Equal(*want)
+}
+
+func formatMark(mark *time.Time) string {
+	if mark == nil {
+		return "nil"
+	}
+	return mark.Format(watermarkLayout)
+}
+
+var (
+	exportStart = time.Date(2024, 3, 1, 6, 0, 0, 0, time.UTC)
+	exportNow   = time.Date(2024, 3, 2, 12, 0, 0, 0, time.UTC)
+)
+
+func TestExportBatchesOnUpdatedWatermark(t *testing.T) {
+	repo := newFakeLakeExportRepository(exportNow)
+	at := func(seconds int) time.Time {
+		return exportNow.Add(-time.Hour).Add(time.Duration(seconds) * time.Second)
+	}
+	// Two rows share an update, as those of one transaction do, and are never split across batches.
+	repo.upsert("m1", exportStart, 1, at(1))
+	repo.upsert("m2", exportStart, 2, at(2))
+	repo.upsert("m3", exportStart, 3, at(2))
+	repo.upsert("m4", exportStart, 4, at(3))
+	repo.upsert("m5", exportStart, 5, at(4))
+	l := newTestLake(t, repo, Config{BatchSize: 2, Lag: time.Minute})
+
+	run, err := l.run()
+	if err != nil {
+		t.Fatal(err)
+	}
+	horizon := exportNow.Add(-time.Minute)
+	if run.Status != dbentity.LakeExportRunStatusSucceeded || run.RowCount != 5 || run.LowWaterMark != nil || !equalMark(run.HighWaterMark, &horizon) {
+		t.Errorf("run = %s with %d rows from %s to %s, want SUCCEEDED with 5 rows from nil to %s", run.Status, run.RowCount, formatMark(run.LowWaterMark), formatMark(run.HighWaterMark), formatMark(&horizon))
+	}
+	a2, a4 := at(2), at(4)
+	wantLists := []listCall{{nil, a2}, {&a2, a4}, {&a4, horizon}}
+	if len(repo.lists) != len(wantLists) {
+		t.Fatalf("listed %d batches, want %d", len(repo.lists), len(wantLists))
+	}
+	for i, want := range wantLists {
+		if got := repo.lists[i]; !equalMark(got.after, want.after) || !got.through.Equal(want.through) {
+			t.Errorf("batch %d listed (%s, %s], want (%s, %s]", i, formatMark(got.after), formatMark(&got.through), formatMark(want.after), formatMark(&want.through))
+		}
+	}
+	if mark := repo.watermarks[dbentity.LakeExportSourceMeterUsage15Minute]; !mark.Equal(horizon) {
+		t.Errorf("stored watermark = %s, want the horizon %s", formatMark(&mark), formatMark(&horizon))
+	}
+	// The last batch had no rows, so the lake's latest commit is the batch through a4.
+	if mark := l.committedWatermark(); !equalMark(mark, &a4) {
+		t.Errorf("committed watermark = %s, want %s", formatMark(mark), formatMark(&a4))
+	}
+	if rows := l.live(); len(rows) != 5 {
+		t.Errorf("lake holds %d rows, want 5", len(rows))
+	}
+
+	again, err := l.run()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if again.RowCount != 0 || !equalMark(again.LowWaterMark, &horizon) || len(repo.lists) != len(wantLists) {
+		t.Errorf("second run = %d rows from %s after %d lists, want nothing to export", again.RowCount, formatMark(again.LowWaterMark), len(repo.lists))
+	}
+}
+
+func TestExportStaysBehindTheLagHorizon(t *testing.T) {
+	repo := newFakeLakeExportRepository(exportNow)
+	repo.upsert("m1", exportStart, 1, exportNow.Add(-10*time.Minute))
+	repo.upsert("m2", exportStart, 2, exportNow.Add(-time.Minute))
+	l := newTestLake(t, repo, Config{Lag: 5 * time.Minute})
+
+	run, err := l.run()
+	if err != nil {
+		t.Fatal(err)
+	}
+	horizon := exportNow.Add(-5 * time.Minute)
+	if run.RowCount != 1 || !equalMark(run.HighWaterMark, &horizon) {
+		t.Errorf("run exported %d rows up to %s, want 1 up to %s", run.RowCount, formatMark(run.HighWaterMark), formatMark(&horizon))
+	}
+
+	repo.now = exportNow.Add(10 * time.Minute)
+	run, err = l.run()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if run.RowCount != 1 || !equalMark(run.LowWaterMark, &horizon) {
+		t.Errorf("next run exported %d rows from %s, want the row inside the lag, from %s", run.RowCount, formatMark(run.LowWaterMark), formatMark(&horizon))
+	}
+	if rows := l.live(); len(rows) != 2 {
+		t.Errorf("lake holds %d rows, want 2", len(rows))
+	}
+}
+
+func TestExportAdoptsTheCommittedWatermark(t *testing.T) {
+	repo := newFakeLakeExportRepository(exportNow)
+	repo.upsert("m1", exportStart, 1, exportNow.Add(-time.Hour))
+	l := newTestLake(t, repo, Config{Lag: time.Minute})
+
+	// The lake commit succeeds but storing its watermark fails.
+	setErr := errors.New("connection reset")
+	repo.failSetWatermark = setErr
+	run, err := l.run()
+	if !errors.Is(err, setErr) || run.Status != dbentity.LakeExportRunStatusFailed {
+		t.Fatalf("run = %s, %v, want FAILED with %v", run.Status, err, setErr)
+	}
+	if _, ok := repo.watermarks[dbentity.LakeExportSourceMeterUsage15Minute]; ok {
+		t.Fatal("the watermark was stored")
+	}
+	committed := l.committedWatermark()
+	if want := exportNow.Add(-time.Minute); !equalMark(committed, &want) {
+		t.Fatalf("committed watermark = %s, want %s", formatMark(committed), formatMark(&want))
+	}
+
+	repo.now = exportNow.Add(time.Hour)
+	repo.upsert("m2", exportStart, 2, exportNow.Add(time.Minute))
+	run, err = l.run()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !equalMark(run.LowWaterMark, committed) || run.RowCount != 1 {
+		t.Errorf("resumed run = %d rows from %s, want only the new row, from the committed %s", run.RowCount, formatMark(run.LowWaterMark), formatMark(committed))
+	}
+	if rows := l.live(); len(rows) != 2 {
+		t.Errorf("lake holds %d rows, want 2 with none exported twice", len(rows))
+	}
+}
+
+func TestExportReplacesTheVersionOfAnUpdatedRow(t *testing.T) {
+	repo := newFakeLakeExportRepository(exportNow)
+	repo.upsert("m1", exportStart, 1, exportNow.Add(-time.Hour))
+	repo.upsert("m1", exportStart.Add(15*time.Minute), 5, exportNow.Add(-time.Hour))
+	l := newTestLake(t, repo, Config{Lag: time.Minute})
+	if _, err := l.run(); err != nil {
+		t.Fatal(err)
+	}
+
+	// VEE edits the first interval, a new version under the same key.
+	repo.now = exportNow.Add(time.Hour)
+	repo.upsert("m1", exportStart, 2, exportNow)
+	run, err := l.run()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if run.RowCount != 1 {
+		t.Errorf("second run exported %d rows, want the updated one", run.RowCount)
+	}
+
+	rows := l.live()
+	sort.Slice(rows, func(i, j int) bool { return rows[i].StartDttm < rows[j].StartDttm })
+	if len(rows) != 2 {
+		t.Fatalf("lake holds %d rows, want one per key: %+v", len(rows), rows)
+	}
+	if rows[0].StartDttm != exportStart.UnixMicro() || *rows[0].Consumption != 2 || rows[0].UpdatedDttm != exportNow.UnixMicro() {
+		t.Errorf("live version of the updated row = %v updated %d, want 2 updated %d", *rows[0].Consumption, rows[0].UpdatedDttm, exportNow.UnixMicro())
+	}
+	if *rows[1].Consumption != 5 {
+		t.Errorf("untouched row = %v, want 5", *rows[1].Consumption)
+	}
+}
@@ -0,0 +1,151 @@
+package lakeexport
+
+import (
+	"context"
+	"fmt"
+	"time"
+	dbentity "usage-lakehouse/internal/db/entity"
+	"usage-lakehouse/internal/lake"
+	"usage-lakehouse/internal/model"
+	"usage-lakehouse/internal/repository"
+)
+
+// Source is a Postgres table exported to a lake table.
+type Source interface {
+	// Table names the Postgres table.
+	Table() string
+	// Export writes the rows updated after after up to through to the lake table in one commit, which
+	// records through as the table's export watermark. It writes nothing when there are no rows.
+	Export(ctx context.Context, after *time.Time, through time.Time) (Batch, error)
+	// Watermark returns the export watermark of the lake table's latest commit, or nil before the first.
+	Watermark(ctx context.Context) (*time.Time, error)
+}
+
+// Batch is what one Export wrote. SnapshotID is nil when there were no rows.
+type Batch struct {
+	Rows       int
+	Files      int
+	SnapshotID *int64
+}
+
+// watermarkKey is the snapshot summary key of the watermark an export committed up to.
+const watermarkKey = "usage-lakehouse.export-watermark"
+
+// watermarkLayout formats watermarks, Postgres timestamps without time zone, to the microsecond.
+const watermarkLayout = "2006-01-02T15:04:05.999999"
+
+// source exports rows listed as E to a lake table of R.
+type source[E any, R any] struct {
+	table string
+	list  func(ctx context.Context, after *time.Time, through time.Time) ([]E, error)
+	row   func(E) R
+	lake  *lake.Writer[R]
+}
+
+// UsageTransactionDetailSource exports usage_transaction_detail to w.
+func UsageTransactionDetailSource(repo repository.LakeExportRepository, w *lake.Writer[model.UsageTransactionDetailRow]) Source {
+	return &source[dbentity.UsageTransactionDetailExport, model.UsageTransactionDetailRow]{
+		table: dbentity.LakeExportSourceUsageTransactionDetail,
+		list:  repo.ListUsageTransactionDetails,
+		row:   newUsageTransactionDetailRow,
+		lake:  w,
+	}
+}
+
+// MeterUsageSource exports meter_usage_15_minute to w.
+func MeterUsageSource(repo repository.LakeExportRepository, w *lake.Writer[model.MeterUsageRow]) Source {
+	return &source[dbentity.MeterUsageIntervalExport, model.MeterUsageRow]{
+		table: dbentity.LakeExportSourceMeterUsage15Minute,
+		list:  repo.ListMeterUsageIntervals,
+		row:   newMeterUsageRow,
+		lake:  w,
+	}
+}
+
+func (s *source[E, R]) Table() string {
+	return s.table
+}
+
+func (s *source[E, R]) Export(ctx context.Context, after *time.Time, through time.Time) (Batch, error) {
+	listed, err := s.list(ctx, after, through)
+	if err != nil {
+		return Batch{}, fmt.Errorf("list %s: %w", s.table, err)
+	}
+	if len(listed) == 0 {
+		return Batch{}, nil
+	}
+	rows := make([]R, len(listed))
+	for i, e := range listed {
+		rows[i] = s.row(e)
+	}
+	files, snapshot, err := s.lake.Write(ctx, rows, map[string]string{watermarkKey: through.Format(watermarkLayout)})
+	if err != nil {
+		return Batch{}, err
+	}
+	return Batch{Rows: len(rows), Files: len(files), SnapshotID: &snapshot.SnapshotID}, nil
+}
+
+func (s *source[E, R]) Watermark(ctx context.Context) (*time.Time, error) {
+	snapshot, err := s.lake.CurrentSnapshot(ctx)
+	if err != nil || snapshot == nil {
+		return nil, err
+	}
+	value, ok := snapshot.Summary[watermarkKey]
+	if !ok {
+		return nil, nil
+	}
+	mark, err := time.Parse(watermarkLayout, value)
+	if err != nil {
+		return nil, fmt.Errorf("snapshot %d of %s: %w", snapshot.SnapshotID, s.table, err)
+	}
+	return &mark, nil
+}
+
+func newUsageTransactionDetailRow(d dbentity.UsageTransactionDetailExport) model.UsageTransactionDetailRow {
+	return model.UsageTransactionDetailRow{
+		UsageTransactionID:   d.UsageTransactionID,
+		TransactionID:        d.TransactionID,
+		PowerRegion:          d.PowerRegion,
+		PremiseID:            d.PremiseID,
+		PremiseCode:          d.PremiseCode,
+		MeterID:              d.MeterID,
+		MeterName:            d.MeterName,
+		StartDttm:            d.Start.UnixMicro(),
+		EndDttm:              d.End.UnixMicro(),
+		ServicePeriodStartDt: epochDays(d.ServicePeriodStart),
+		ServicePeriodEndDt:   epochDays(d.ServicePeriodEnd),
+		IsCanceled:           d.IsCanceled,
+		IsInterval:           d.IsInterval,
+		Consumption:          d.Consumption,
+		Generation:           d.Generation,
+		UpdatedDttm:          d.Updated.UnixMicro(),
+	}
+}
+
+func newMeterUsageRow(i dbentity.MeterUsageIntervalExport) model.MeterUsageRow {
+	return model.MeterUsageRow{
+		MeterID:              i.MeterID,
+		MeterName:            i.MeterName,
+		PremiseID:            i.PremiseID,
+		PremiseCode:          i.PremiseCode,
+		AccountID:            i.AccountID,
+		PowerRegion:          i.PowerRegion,
+		UsageTransactionID:   i.UsageTransactionID,
+		StartDttm:            i.Start.UnixMicro(),
+		EndDttm:              i.End.UnixMicro(),
+		ServicePeriodStartDt: epochDays(i.ServicePeriodStart),
+		ServicePeriodEndDt:   epochDays(i.ServicePeriodEnd),
+		Consumption:          i.Consumption,
+		Generation:           i.Generation,
+		IsCanceled:           i.IsCanceled,
+		Quality:              i.Quality,
+		QualityRule:          i.QualityRule,
+		OriginalConsumption:  i.OriginalConsumption,
+		UpdatedDttm:          i.Updated.UnixMicro(),
+	}
+}
+
+// epochDays is the days from 1970-01-01 to the date of d, which a Postgres date scans to at midnight UTC.
+func epochDays(d time.Time) int32 {
+	return int32(time.Date(d.Year(), d.Month(), d.Day(), 0, 0, 0, 0, time.UTC).Unix() / 86400)
+}
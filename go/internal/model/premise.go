@@ -29,15 +29,42 @@ type PowerRegionPremiseType struct {
 }
 
 type Premise struct {
-	ID            string    `json:"id"`
-	Code          string    `json:"code"`
-	Name          string    `json:"name"`
-	CustomerName  string    `json:"customer_name"`
-	Address       Address   `json:"address"`
-	PremiseTypeID *string   `json:"premise_type_id,omitempty"`
-	PowerRegionID string    `json:"power_region_id"`
-	Created       time.Time `json:"created_dttm"`
-	Updated       time.Time `json:"updated_dttm"`
+	ID            string  `json:"id"`
+	Code          string  `json:"code"`
+	Name          string  `json:"name"`
+	CustomerName  string  `json:"customer_name"`
+	Address       Address `json:"address"`
+	PremiseTypeID *string `json:"premise_type_id,omitempty"`
+	PowerRegionID string  `json:"power_region_id"`
+	TDSPID        *string `json:"tdsp_id,omitempty"`
+	// AutoProvisioned marks premises created by ingestion rather than master data.
+	AutoProvisioned bool      `json:"is_auto_provisioned"`
+	Created         time.Time `json:"created_dttm"`
+	Updated         time.Time `json:"updated_dttm"`
+}
+
+type PremiseCodeViolation struct {
+	PremiseID       string `json:"premise_id"`
+	Code            string `json:"code"`
+	PowerRegionID   string `json:"power_region_id"`
+	TDSPID          string `json:"tdsp_id"`
+	TDSPName        string `json:"tdsp_name"`
+	ExpectedPattern string `json:"expected_pattern"`
+}
+
+// InvalidPremiseCodeExpression is a TDSP whose premise_code_validation_expression does not compile, so
+// its premises cannot be audited.
+type InvalidPremiseCodeExpression struct {
+	TDSPID     string `json:"tdsp_id"`
+	TDSPName   string `json:"tdsp_name"`
+	Expression string `json:"expression"`
+	Error      string `json:"error"`
+}
+
+// PremiseCodeAudit is the result of checking every premise's code against its TDSP's expression.
+type PremiseCodeAudit struct {
+	Violations         []PremiseCodeViolation         `json:"violations"`
+	InvalidExpressions []InvalidPremiseCodeExpression `json:"invalid_expressions"`
 }
 
 type PremiseAccountJunction struct {
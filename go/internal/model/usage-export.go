@@ -0,0 +1,56 @@
+package model
+
+// Schema versions of the lake tables exported from Postgres. Changing a row type's columns needs a
+// new version, written to a new table.
+const (
+	UsageTransactionDetailSchemaVersion = 1
+	MeterUsageSchemaVersion             = 1
+)
+
+// UsageTransactionDetailRow is one row of the lake's copy of usage_transaction_detail, keeping its
+// column names so the same queries run against both. start_dttm and end_dttm are UTC instants in
+// microseconds since the Unix epoch, dates are days since the epoch and updated_dttm is the row's
+// Postgres updated_dttm, a timestamp without time zone. An update exports the row again, replacing
+// the version exported before for its usage_transaction_id, meter_name and start_dttm.
+type UsageTransactionDetailRow struct {
+	UsageTransactionID   string   `parquet:"name=usage_transaction_id, type=BYTE_ARRAY, convertedtype=UTF8, fieldid=1"`
+	TransactionID        string   `parquet:"name=transaction_id, type=BYTE_ARRAY, convertedtype=UTF8, fieldid=2"`
+	PowerRegion          string   `parquet:"name=power_region, type=BYTE_ARRAY, convertedtype=UTF8, encoding=PLAIN_DICTIONARY, fieldid=3"`
+	PremiseID            string   `parquet:"name=premise_id, type=BYTE_ARRAY, convertedtype=UTF8, encoding=PLAIN_DICTIONARY, fieldid=4"`
+	PremiseCode          string   `parquet:"name=premise_code, type=BYTE_ARRAY, convertedtype=UTF8, encoding=PLAIN_DICTIONARY, fieldid=5"`
+	MeterID              *string  `parquet:"name=meter_id, type=BYTE_ARRAY, convertedtype=UTF8, repetitiontype=OPTIONAL, encoding=PLAIN_DICTIONARY, fieldid=6"`
+	MeterName            string   `parquet:"name=meter_name, type=BYTE_ARRAY, convertedtype=UTF8, encoding=PLAIN_DICTIONARY, fieldid=7"`
+	StartDttm            int64    `parquet:"name=start_dttm, type=INT64, convertedtype=TIMESTAMP_MICROS, logicaltype=TIMESTAMP, logicaltype.isadjustedtoutc=true, logicaltype.unit=MICROS, fieldid=8"`
+	EndDttm              int64    `parquet:"name=end_dttm, type=INT64, convertedtype=TIMESTAMP_MICROS, logicaltype=TIMESTAMP, logicaltype.isadjustedtoutc=true, logicaltype.unit=MICROS, fieldid=9"`
+	ServicePeriodStartDt int32    `parquet:"name=service_period_start_dt, type=INT32, convertedtype=DATE, logicaltype=DATE, fieldid=10"`
+	ServicePeriodEndDt   int32    `parquet:"name=service_period_end_dt, type=INT32, convertedtype=DATE, logicaltype=DATE, fieldid=11"`
+	IsCanceled           bool     `parquet:"name=is_canceled, type=BOOLEAN, fieldid=12"`
+	IsInterval           bool     `parquet:"name=is_interval, type=BOOLEAN, fieldid=13"`
+	Consumption          *float64 `parquet:"name=consumption, type=DOUBLE, repetitiontype=OPTIONAL, fieldid=14"`
+	Generation           *float64 `parquet:"name=generation, type=DOUBLE, repetitiontype=OPTIONAL, fieldid=15"`
+	UpdatedDttm          int64    `parquet:"name=updated_dttm, type=INT64, convertedtype=TIMESTAMP_MICROS, logicaltype=TIMESTAMP, logicaltype.isadjustedtoutc=false, logicaltype.unit=MICROS, fieldid=16"`
+}
+
+// MeterUsageRow is one row of the lake's copy of meter_usage_15_minute, encoded as
+// UsageTransactionDetailRow is. An update exports the row again, replacing the version exported before
+// for its meter_id and start_dttm.
+type MeterUsageRow struct {
+	MeterID              string   `parquet:"name=meter_id, type=BYTE_ARRAY, convertedtype=UTF8, encoding=PLAIN_DICTIONARY, fieldid=1"`
+	MeterName            string   `parquet:"name=meter_name, type=BYTE_ARRAY, convertedtype=UTF8, encoding=PLAIN_DICTIONARY, fieldid=2"`
+	PremiseID            string   `parquet:"name=premise_id, type=BYTE_ARRAY, convertedtype=UTF8, encoding=PLAIN_DICTIONARY, fieldid=3"`
+	PremiseCode          string   `parquet:"name=premise_code, type=BYTE_ARRAY, convertedtype=UTF8, encoding=PLAIN_DICTIONARY, fieldid=4"`
+	AccountID            string   `parquet:"name=account_id, type=BYTE_ARRAY, convertedtype=UTF8, encoding=PLAIN_DICTIONARY, fieldid=5"`
+	PowerRegion          string   `parquet:"name=power_region, type=BYTE_ARRAY, convertedtype=UTF8, encoding=PLAIN_DICTIONARY, fieldid=6"`
+	UsageTransactionID   *string  `parquet:"name=usage_transaction_id, type=BYTE_ARRAY, convertedtype=UTF8, repetitiontype=OPTIONAL, fieldid=7"`
+	StartDttm            int64    `parquet:"name=start_dttm, type=INT64, convertedtype=TIMESTAMP_MICROS, logicaltype=TIMESTAMP, logicaltype.isadjustedtoutc=true, logicaltype.unit=MICROS, fieldid=8"`
+	EndDttm              int64    `parquet:"name=end_dttm, type=INT64, convertedtype=TIMESTAMP_MICROS, logicaltype=TIMESTAMP, logicaltype.isadjustedtoutc=true, logicaltype.unit=MICROS, fieldid=9"`
+	ServicePeriodStartDt int32    `parquet:"name=service_period_start_dt, type=INT32, convertedtype=DATE, logicaltype=DATE, fieldid=10"`
+	ServicePeriodEndDt   int32    `parquet:"name=service_period_end_dt, type=INT32, convertedtype=DATE, logicaltype=DATE, fieldid=11"`
+	Consumption          *float64 `parquet:"name=consumption, type=DOUBLE, repetitiontype=OPTIONAL, fieldid=12"`
+	Generation           *float64 `parquet:"name=generation, type=DOUBLE, repetitiontype=OPTIONAL, fieldid=13"`
+	IsCanceled           bool     `parquet:"name=is_canceled, type=BOOLEAN, fieldid=14"`
+	Quality              string   `parquet:"name=quality, type=BYTE_ARRAY, convertedtype=UTF8, encoding=PLAIN_DICTIONARY, fieldid=15"`
+	QualityRule          *string  `parquet:"name=quality_rule, type=BYTE_ARRAY, convertedtype=UTF8, repetitiontype=OPTIONAL, encoding=PLAIN_DICTIONARY, fieldid=16"`
+	OriginalConsumption  *float64 `parquet:"name=original_consumption, type=DOUBLE, repetitiontype=OPTIONAL, fieldid=17"`
+	UpdatedDttm          int64    `parquet:"name=updated_dttm, type=INT64, convertedtype=TIMESTAMP_MICROS, logicaltype=TIMESTAMP, logicaltype.isadjustedtoutc=false, logicaltype.unit=MICROS, fieldid=18"`
+}
@@ -2,24 +2,79 @@ package model
 
 import "time"
 
+// UsageSchemaVersion is the version of the lake's interval usage schema, UsageRow. Version 1 held
+// only asset_id and usage_qty. Changing UsageRow's columns needs a new version, written to a new table.
+const UsageSchemaVersion = 2
+
+// UsageData is one interval read in a lake usage upload. Quantity is in UnitOfMeasure over the
+// interval; QualityFlag is ACTUAL, ESTIMATED or EDITED as in VEE.
 type UsageData struct {
-	AssetID  string  `json:"asset_id" parquet:"name=asset_id, type=BYTE_ARRAY, convertedtype=UTF8, encoding=PLAIN_DICTIONARY"`
-	UsageQty float64 `json:"usage_qty" parquet:"name=usage_qty, type=DOUBLE"`
+	MeterCode           string    `json:"meter_code" validate:"required"`
+	PremiseCode         *string   `json:"premise_code,omitempty"`
+	PowerRegion         *string   `json:"power_region,omitempty"`
+	Channel             *string   `json:"channel,omitempty"`
+	IntervalStart       time.Time `json:"interval_start" validate:"required"`
+	IntervalEnd         time.Time `json:"interval_end" validate:"required,gtfield=IntervalStart"`
+	UnitOfMeasure       string    `json:"unit_of_measure" validate:"required,unit_of_measure"`
+	Quantity            *float64  `json:"quantity" validate:"required"`
+	QualityFlag         *string   `json:"quality_flag,omitempty" validate:"omitempty,oneof=ACTUAL ESTIMATED EDITED"`
+	SourceTransactionID *string   `json:"source_transaction_id,omitempty"`
+}
+
+// UsageRow is one row of the lake's interval usage table, in schema version UsageSchemaVersion.
+// Interval bounds are UTC instants in microseconds since the Unix epoch. fieldid tags are the Iceberg
+// column IDs of the table schema.
+type UsageRow struct {
+	AccountID           string  `parquet:"name=account_id, type=BYTE_ARRAY, convertedtype=UTF8, encoding=PLAIN_DICTIONARY, fieldid=1"`
+	PremiseCode         *string `parquet:"name=premise_code, type=BYTE_ARRAY, convertedtype=UTF8, repetitiontype=OPTIONAL, encoding=PLAIN_DICTIONARY, fieldid=2"`
+	MeterCode           string  `parquet:"name=meter_code, type=BYTE_ARRAY, convertedtype=UTF8, encoding=PLAIN_DICTIONARY, fieldid=3"`
+	PowerRegion         *string `parquet:"name=power_region, type=BYTE_ARRAY, convertedtype=UTF8, repetitiontype=OPTIONAL, encoding=PLAIN_DICTIONARY, fieldid=4"`
+	Channel             *string `parquet:"name=channel, type=BYTE_ARRAY, convertedtype=UTF8, repetitiontype=OPTIONAL, encoding=PLAIN_DICTIONARY, fieldid=5"`
+	IntervalStart       int64   `parquet:"name=interval_start, type=INT64, convertedtype=TIMESTAMP_MICROS, logicaltype=TIMESTAMP, logicaltype.isadjustedtoutc=true, logicaltype.unit=MICROS, fieldid=6"`
+	IntervalEnd         int64   `parquet:"name=interval_end, type=INT64, convertedtype=TIMESTAMP_MICROS, logicaltype=TIMESTAMP, logicaltype.isadjustedtoutc=true, logicaltype.unit=MICROS, fieldid=7"`
+	UnitOfMeasure       string  `parquet:"name=unit_of_measure, type=BYTE_ARRAY, convertedtype=UTF8, encoding=PLAIN_DICTIONARY, fieldid=8"`
+	Quantity            float64 `parquet:"name=quantity, type=DOUBLE, fieldid=9"`
+	QualityFlag         *string `parquet:"name=quality_flag, type=BYTE_ARRAY, convertedtype=UTF8, repetitiontype=OPTIONAL, encoding=PLAIN_DICTIONARY, fieldid=10"`
+	SourceTransactionID *string `parquet:"name=source_transaction_id, type=BYTE_ARRAY, convertedtype=UTF8, repetitiontype=OPTIONAL, fieldid=11"`
+}
+
+// NewUsageRow is the lake row of an uploaded read for accountID. data must have passed validation.
+func NewUsageRow(accountID string, data UsageData) UsageRow {
+	return UsageRow{
+		AccountID:           accountID,
+		PremiseCode:         data.PremiseCode,
+		MeterCode:           data.MeterCode,
+		PowerRegion:         data.PowerRegion,
+		Channel:             data.Channel,
+		IntervalStart:       data.IntervalStart.UTC().UnixMicro(),
+		IntervalEnd:         data.IntervalEnd.UTC().UnixMicro(),
+		UnitOfMeasure:       data.UnitOfMeasure,
+		Quantity:            *data.Quantity,
+		QualityFlag:         data.QualityFlag,
+		SourceTransactionID: data.SourceTransactionID,
+	}
 }
 
 type TransactionSetPurposeCode string
 
 type ReportTypeCode string
 
+type TransactionTypeCode string
+
+type TransactionSubTypeCode string
+
 type ProductTransferDetailTypeCode string
 
 const (
 	TransactionSetPurposeCodeOriginal                                TransactionSetPurposeCode     = "00"
 	TransactionSetPurposeCodeCanceled                                TransactionSetPurposeCode     = "01"
-	TransactionSetPurposeCodeReplace                                 TransactionSetPurposeCode     = "05"
+	TransactionSetPurposeCodeReplace                                 TransactionSetPurposeCode     = "02"
 	TransactionSetPurposeCodePolledServices                          TransactionSetPurposeCode     = "EX"
 	ReportTypeCodeIDR                                                ReportTypeCode                = "C1"
 	ReportTypeCodeNIDR                                               ReportTypeCode                = "DD"
+	TransactionTypeCodeUsage                                         TransactionTypeCode           = "867"
+	TransactionSubTypeCodeHistoricUsage                              TransactionSubTypeCode        = "2"
+	TransactionSubTypeCodeMonthlyUsage                               TransactionSubTypeCode        = "3"
 	ProductTransferDetailTypeCodeNonIntervalDetail                   ProductTransferDetailTypeCode = "PL"
 	ProductTransferDetailTypeCodeNonIntervalUsage                    ProductTransferDetailTypeCode = "SU"
 	ProductTransferDetailTypeCodeUnmeteredServices                   ProductTransferDetailTypeCode = "BD"
@@ -29,12 +84,12 @@ const (
 	ProductTransferDetailTypeCodeNetIntervalUsageSummaryAcrossMeters ProductTransferDetailTypeCode = "PP"
 )
 
-type ErcotQuantityDelivered struct {
+type QuantityDelivered struct {
 	Quantity    float64   `json:"quantity"`
 	IntervalEnd time.Time `json:"interval_end"`
 }
 
-type ErcotProductTransferDetail struct {
+type ProductTransferDetail struct {
 	TransferType       ProductTransferDetailTypeCode `json:"product_transfer_detail_type_code"`
 	ServicePeriodStart *time.Time                    `json:"service_period_start,omitempty"`
 	ServicePeriodEnd   *time.Time                    `json:"service_period_end,omitempty"`
@@ -43,22 +98,28 @@ type ErcotProductTransferDetail struct {
 	MeterType          *string                       `json:"meter_type,omitempty"`
 	Channel            *string                       `json:"channel,omitempty"`
 	MeterName          *string                       `json:"meter_name,omitempty"`
-	Quantities         *[]ErcotQuantityDelivered     `json:"quantity_delivered,omitempty"`
+	UnitOfMeasure      *string                       `json:"unit_of_measure,omitempty"`
+	Quantities         *[]QuantityDelivered          `json:"quantity_delivered,omitempty"`
 }
 
-type ErcotMonthlyUsageTransaction struct {
-	TransactionID          string                       `json:"transaction_id"  validate:"required"`
-	Purpose                TransactionSetPurposeCode    `json:"transaction_set_purpose_code"   validate:"required"`
-	Date                   time.Time                    `json:"date"  validate:"required"`
-	EsiID                  string                       `json:"esi_id"  validate:"required"`
-	PowerRegion            string                       `json:"power_region"  validate:"required"`
-	ReportType             ReportTypeCode               `json:"report_type_code"  validate:"required"`
-	Final                  *string                      `json:"action_code,omitempty"`
-	TdspName               string                       `json:"tdsp_name"  validate:"required"`
-	TdspLegalID            string                       `json:"tdsp_legal_id"  validate:"required"`
-	CrName                 string                       `json:"cr_name"  validate:"required"`
-	CrLegalID              string                       `json:"cr_legal_id"  validate:"required"`
-	ProductTransferDetails []ErcotProductTransferDetail `json:"product_transfer_details"  validate:"required"`
+// EDIUsageTransaction is the region-neutral form of an 867 usage transaction. Region adapters map their
+// native format onto it; EsiID carries the region's premise identifier, e.g. the ERCOT ESI ID or a New
+// York utility account number.
+type EDIUsageTransaction struct {
+	TransactionID          string                    `json:"transaction_id"  validate:"required"`
+	Purpose                TransactionSetPurposeCode `json:"transaction_set_purpose_code"   validate:"required"`
+	OriginalTransactionID  *string                   `json:"original_transaction_id,omitempty"`
+	Date                   time.Time                 `json:"date"  validate:"required"`
+	EsiID                  string                    `json:"esi_id"  validate:"required"`
+	PowerRegion            string                    `json:"power_region"  validate:"required"`
+	ReportType             ReportTypeCode            `json:"report_type_code"  validate:"required"`
+	TransactionSubType     TransactionSubTypeCode    `json:"transaction_sub_type_code,omitempty"`
+	Final                  *string                   `json:"action_code,omitempty"`
+	TdspName               string                    `json:"tdsp_name"  validate:"required"`
+	TdspLegalID            string                    `json:"tdsp_legal_id"  validate:"required"`
+	CrName                 string                    `json:"cr_name"  validate:"required"`
+	CrLegalID              string                    `json:"cr_legal_id"  validate:"required"`
+	ProductTransferDetails []ProductTransferDetail   `json:"product_transfer_details"  validate:"required"`
 }
 
 type UsageTransaction struct {
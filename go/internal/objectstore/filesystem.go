@@ -0,0 +1,141 @@
+package objectstore
+
+import (
+	"context"
+	"errors"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+type filesystemStore struct {
+	root string
+}
+
+// NewFilesystem stores objects as files under root, with key path segments as directories.
+func NewFilesystem(root string) Store {
+	return &filesystemStore{root: root}
+}
+
+// tempPrefix marks files still being written, which List skips.
+const tempPrefix = ".upload-"
+
+func (s *filesystemStore) path(key string) string {
+	return filepath.Join(s.root, filepath.FromSlash(key))
+}
+
+func (s *filesystemStore) Put(ctx context.Context, key string, body io.Reader) error {
+	return put(ctx, s, key, body)
+}
+
+// Create writes to a temporary file and renames it into place on Close so a reader never sees a
+// partial object.
+func (s *filesystemStore) Create(ctx context.Context, key string) (Writer, error) {
+	path := s.path(key)
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return nil, err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(path), tempPrefix+"*")
+	if err != nil {
+		return nil, err
+	}
+	return &fileWriter{tmp: tmp, path: path}, nil
+}
+
+type fileWriter struct {
+	tmp  *os.File
+	path string
+	done bool
+}
+
+func (w *fileWriter) Write(p []byte) (int, error) {
+	return w.tmp.Write(p)
+}
+
+func (w *fileWriter) Close() error {
+	if w.done {
+		return nil
+	}
+	w.done = true
+	defer os.Remove(w.tmp.Name())
+	if err := w.tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(w.tmp.Name(), w.path)
+}
+
+func (w *fileWriter) Abort() error {
+	if w.done {
+		return nil
+	}
+	w.done = true
+	w.tmp.Close()
+	return os.Remove(w.tmp.Name())
+}
+
+func (s *filesystemStore) Get(ctx context.Context, key string) (io.ReadCloser, error) {
+	f, err := os.Open(s.path(key))
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, ErrNotFound
+	}
+	return f, err
+}
+
+func (s *filesystemStore) Exists(ctx context.Context, key string) (bool, error) {
+	_, err := os.Stat(s.path(key))
+	if errors.Is(err, fs.ErrNotExist) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+// List walks only the directory the prefix names, e.g. a/b for a/b/c.
+func (s *filesystemStore) List(ctx context.Context, prefix string) ([]Object, error) {
+	dir := prefix
+	if !strings.HasSuffix(prefix, "/") {
+		dir = path.Dir(prefix)
+	}
+	var objects []Object
+	err := filepath.WalkDir(s.path(dir), func(p string, d fs.DirEntry, err error) error {
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if d.IsDir() || strings.HasPrefix(d.Name(), tempPrefix) {
+			return nil
+		}
+		rel, err := filepath.Rel(s.root, p)
+		if err != nil {
+			return err
+		}
+		key := filepath.ToSlash(rel)
+		if !strings.HasPrefix(key, prefix) {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		objects = append(objects, Object{Key: key, Size: info.Size(), LastModified: info.ModTime()})
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	sort.Slice(objects, func(i, j int) bool { return objects[i].Key < objects[j].Key })
+	return objects, nil
+}
+
+func (s *filesystemStore) Delete(ctx context.Context, key string) error {
+	err := os.Remove(s.path(key))
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	return err
+}
@@ -0,0 +1,99 @@
+package objectstore
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+type memoryObject struct {
+	data         []byte
+	lastModified time.Time
+}
+
+type memoryStore struct {
+	mu      sync.RWMutex
+	objects map[string]memoryObject
+}
+
+// NewMemory keeps objects in process memory. It is meant for tests and local runs; nothing
+// outside the process can read what it stores.
+func NewMemory() Store {
+	return &memoryStore{objects: map[string]memoryObject{}}
+}
+
+func (s *memoryStore) Put(ctx context.Context, key string, body io.Reader) error {
+	return put(ctx, s, key, body)
+}
+
+func (s *memoryStore) Create(ctx context.Context, key string) (Writer, error) {
+	return &memoryWriter{store: s, key: key}, nil
+}
+
+type memoryWriter struct {
+	store *memoryStore
+	key   string
+	buf   bytes.Buffer
+	done  bool
+}
+
+func (w *memoryWriter) Write(p []byte) (int, error) {
+	return w.buf.Write(p)
+}
+
+func (w *memoryWriter) Close() error {
+	if w.done {
+		return nil
+	}
+	w.done = true
+	w.store.mu.Lock()
+	defer w.store.mu.Unlock()
+	w.store.objects[w.key] = memoryObject{data: w.buf.Bytes(), lastModified: time.Now()}
+	return nil
+}
+
+func (w *memoryWriter) Abort() error {
+	w.done = true
+	return nil
+}
+
+func (s *memoryStore) Get(ctx context.Context, key string) (io.ReadCloser, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	o, ok := s.objects[key]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return io.NopCloser(bytes.NewReader(o.data)), nil
+}
+
+func (s *memoryStore) Exists(ctx context.Context, key string) (bool, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	_, ok := s.objects[key]
+	return ok, nil
+}
+
+func (s *memoryStore) List(ctx context.Context, prefix string) ([]Object, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	var objects []Object
+	for key, o := range s.objects {
+		if strings.HasPrefix(key, prefix) {
+			objects = append(objects, Object{Key: key, Size: int64(len(o.data)), LastModified: o.lastModified})
+		}
+	}
+	sort.Slice(objects, func(i, j int) bool { return objects[i].Key < objects[j].Key })
+	return objects, nil
+}
+
+func (s *memoryStore) Delete(ctx context.Context, key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.objects, key)
+	return nil
+}
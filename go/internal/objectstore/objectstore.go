@@ -0,0 +1,89 @@
+// Package objectstore stores blobs by key on S3 or an S3-compatible service such as MinIO, on a
+// local filesystem or in memory.
+package objectstore
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+)
+
+// ErrNotFound is returned by Get when no object exists at the key.
+var ErrNotFound = errors.New("object not found")
+
+type Store interface {
+	Put(ctx context.Context, key string, body io.Reader) error
+	// Create streams a new object to key without holding it in memory; on S3 it is uploaded in
+	// parts as it is written. The object appears only once the writer is closed.
+	Create(ctx context.Context, key string) (Writer, error)
+	Get(ctx context.Context, key string) (io.ReadCloser, error)
+	Exists(ctx context.Context, key string) (bool, error)
+	// List returns the objects whose keys start with prefix, sorted by key.
+	List(ctx context.Context, prefix string) ([]Object, error)
+	// Delete removes the object at key. Deleting a key with no object is not an error.
+	Delete(ctx context.Context, key string) error
+}
+
+// Writer is an object being written by Store.Create. Close completes the object; Abort discards
+// what has been written. Every writer must be closed or aborted, after which the other does nothing.
+type Writer interface {
+	io.Writer
+	Close() error
+	Abort() error
+}
+
+type Object struct {
+	Key          string
+	Size         int64
+	LastModified time.Time
+}
+
+const (
+	BackendS3         = "s3"
+	BackendFilesystem = "filesystem"
+	BackendMemory     = "memory"
+)
+
+// Config selects and configures a store backend. Dir is the root of a filesystem store; the S3
+// fields apply to the s3 backend, where Endpoint points it at an S3-compatible service.
+type Config struct {
+	Backend string
+	Dir     string
+	Bucket  string
+	Prefix  string
+	S3      S3Options
+}
+
+// Open returns the store cfg describes.
+func Open(cfg Config) (Store, error) {
+	switch cfg.Backend {
+	case BackendS3:
+		if cfg.Bucket == "" {
+			return nil, errors.New("s3 object store needs a bucket")
+		}
+		return NewS3(cfg.Bucket, cfg.Prefix, cfg.S3)
+	case BackendFilesystem:
+		if cfg.Dir == "" {
+			return nil, errors.New("filesystem object store needs a directory")
+		}
+		return NewFilesystem(cfg.Dir), nil
+	case BackendMemory:
+		return NewMemory(), nil
+	}
+	return nil, fmt.Errorf("unknown object store backend %q", cfg.Backend)
+}
+
+// put writes body to a Writer from store.Create, aborting the object if body cannot be read.
+func put(ctx context.Context, store Store, key string, body io.Reader) error {
+	w, err := store.Create(ctx, key)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(w, body); err != nil {
+		w.Abort()
+		return err
+	}
+	return w.Close()
+}
@@ -0,0 +1,191 @@
+package objectstore
+
+import (
+	"context"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+)
+
+// testStores runs test against every backend that needs no external service.
+func testStores(t *testing.T, test func(t *testing.T, store Store)) {
+	t.Run("memory", func(t *testing.T) { test(t, NewMemory()) })
+	t.Run("filesystem", func(t *testing.T) { test(t, NewFilesystem(t.TempDir())) })
+}
+
+func readObject(t *testing.T, store Store, key string) string {
+	t.Helper()
+	r, err := store.Get(context.Background(), key)
+	if err != nil {
+		t.Fatalf("Get(%q): %v", key, err)
+	}
+	defer r.Close()
+	body, err := io.ReadAll(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(body)
+}
+
+func TestStorePutGetExists(t *testing.T) {
+	testStores(t, func(t *testing.T, store Store) {
+		ctx := context.Background()
+		if ok, err := store.Exists(ctx, "a/b.json"); err != nil || ok {
+			t.Fatalf("Exists before Put = %v, %v, want false", ok, err)
+		}
+		if err := store.Put(ctx, "a/b.json", strings.NewReader("first")); err != nil {
+			t.Fatal(err)
+		}
+		if ok, err := store.Exists(ctx, "a/b.json"); err != nil || !ok {
+			t.Errorf("Exists after Put = %v, %v, want true", ok, err)
+		}
+		if got := readObject(t, store, "a/b.json"); got != "first" {
+			t.Errorf("Get = %q, want %q", got, "first")
+		}
+		if err := store.Put(ctx, "a/b.json", strings.NewReader("second")); err != nil {
+			t.Fatal(err)
+		}
+		if got := readObject(t, store, "a/b.json"); got != "second" {
+			t.Errorf("Get after overwrite = %q, want %q", got, "second")
+		}
+	})
+}
+
+func TestStoreGetMissing(t *testing.T) {
+	testStores(t, func(t *testing.T, store Store) {
+		if _, err := store.Get(context.Background(), "missing/key"); !errors.Is(err, ErrNotFound) {
+			t.Errorf("Get of a missing key = %v, want ErrNotFound", err)
+		}
+	})
+}
+
+func TestStoreList(t *testing.T) {
+	testStores(t, func(t *testing.T, store Store) {
+		ctx := context.Background()
+		for _, key := range []string{"usage/2024/03/b", "usage/2024/02/a", "usage/2024/03/a", "usagex/c", "other/d"} {
+			if err := store.Put(ctx, key, strings.NewReader(key)); err != nil {
+				t.Fatal(err)
+			}
+		}
+		tests := []struct {
+			prefix string
+			want   []string
+		}{
+			{"usage/", []string{"usage/2024/02/a", "usage/2024/03/a", "usage/2024/03/b"}},
+			{"usage/2024/03/", []string{"usage/2024/03/a", "usage/2024/03/b"}},
+			{"usage", []string{"usage/2024/02/a", "usage/2024/03/a", "usage/2024/03/b", "usagex/c"}},
+			{"none/", nil},
+		}
+		for _, tt := range tests {
+			objects, err := store.List(ctx, tt.prefix)
+			if err != nil {
+				t.Fatalf("List(%q): %v", tt.prefix, err)
+			}
+			var keys []string
+			for _, o := range objects {
+				keys = append(keys, o.Key)
+				if o.Size != int64(len(o.Key)) {
+					t.Errorf("List(%q) %s size = %d, want %d", tt.prefix, o.Key, o.Size, len(o.Key))
+				}
+			}
+			if strings.Join(keys, ",") != strings.Join(tt.want, ",") {
+				t.Errorf("List(%q) = %v, want %v", tt.prefix, keys, tt.want)
+			}
+		}
+	})
+}
+
+func TestStoreDelete(t *testing.T) {
+	testStores(t, func(t *testing.T, store Store) {
+		ctx := context.Background()
+		if err := store.Delete(ctx, "missing/key"); err != nil {
+			t.Errorf("Delete of a missing key: %v", err)
+		}
+		if err := store.Put(ctx, "a/b", strings.NewReader("x")); err != nil {
+			t.Fatal(err)
+		}
+		if err := store.Delete(ctx, "a/b"); err != nil {
+			t.Fatal(err)
+		}
+		if ok, err := store.Exists(ctx, "a/b"); err != nil || ok {
+			t.Errorf("Exists after Delete = %v, %v, want false", ok, err)
+		}
+	})
+}
+
+func TestStoreCreate(t *testing.T) {
+	testStores(t, func(t *testing.T, store Store) {
+		ctx := context.Background()
+		w, err := store.Create(ctx, "streamed")
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, part := range []string{"one,", "two"} {
+			if _, err := io.WriteString(w, part); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if ok, _ := store.Exists(ctx, "streamed"); ok {
+			t.Error("object exists before its writer is closed")
+		}
+		if err := w.Close(); err != nil {
+			t.Fatal(err)
+		}
+		if err := w.Abort(); err != nil {
+			t.Errorf("Abort after Close: %v", err)
+		}
+		if got := readObject(t, store, "streamed"); got != "one,two" {
+			t.Errorf("Get = %q, want %q", got, "one,two")
+		}
+	})
+}
+
+func TestStoreCreateAbort(t *testing.T) {
+	testStores(t, func(t *testing.T, store Store) {
+		ctx := context.Background()
+		w, err := store.Create(ctx, "aborted/object")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := io.WriteString(w, "partial"); err != nil {
+			t.Fatal(err)
+		}
+		if err := w.Abort(); err != nil {
+			t.Fatal(err)
+		}
+		if err := w.Close(); err != nil {
+			t.Errorf("Close after Abort: %v", err)
+		}
+		if ok, err := store.Exists(ctx, "aborted/object"); err != nil || ok {
+			t.Errorf("Exists after Abort = %v, %v, want false", ok, err)
+		}
+		objects, err := store.List(ctx, "")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(objects) != 0 {
+			t.Errorf("List after Abort = %+v, want no objects", objects)
+		}
+	})
+}
+
+func TestOpen(t *testing.T) {
+	tests := []struct {
+		cfg Config
+		ok  bool
+	}{
+		{Config{Backend: BackendMemory}, true},
+		{Config{Backend: BackendFilesystem, Dir: t.TempDir()}, true},
+		{Config{Backend: BackendFilesystem}, false},
+		{Config{Backend: BackendS3}, false},
+		{Config{Backend: "gcs"}, false},
+		{Config{}, false},
+	}
+	for _, tt := range tests {
+		store, err := Open(tt.cfg)
+		if (err == nil) != tt.ok || (store != nil) != tt.ok {
+			t.Errorf("Open(%+v) = %v, %v, want ok %v", tt.cfg, store, err, tt.ok)
+		}
+	}
+}
@@ -0,0 +1,178 @@
+package objectstore
+
+import (
+	"context"
+	"errors"
+	"io"
+	"path"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/aws/aws-sdk-go/service/s3/s3manager"
+)
+
+// S3Options adjust the S3 client. Endpoint and ForcePathStyle point it at an S3-compatible service
+// such as MinIO, which usually needs path-style addressing. A zero PartSize uses the SDK's default.
+type S3Options struct {
+	Endpoint       string
+	Region         string
+	ForcePathStyle bool
+	PartSize       int64
+}
+
+type s3Store struct {
+	client   *s3.S3
+	uploader *s3manager.Uploader
+	bucket   string
+	prefix   string
+}
+
+// NewS3 stores objects in bucket under prefix, using the default AWS credential chain.
+func NewS3(bucket string, prefix string, opts S3Options) (Store, error) {
+	cfg := aws.NewConfig()
+	if opts.Endpoint != "" {
+		cfg = cfg.WithEndpoint(opts.Endpoint)
+	}
+	if opts.Region != "" {
+		cfg = cfg.WithRegion(opts.Region)
+	}
+	if opts.ForcePathStyle {
+		cfg = cfg.WithS3ForcePathStyle(true)
+	}
+	sess, err := session.NewSession(cfg)
+	if err != nil {
+		return nil, err
+	}
+	client := s3.New(sess)
+	uploader := s3manager.NewUploaderWithClient(client, func(u *s3manager.Uploader) {
+		if opts.PartSize > 0 {
+			u.PartSize = opts.PartSize
+		}
+	})
+	return &s3Store{client: client, uploader: uploader, bucket: bucket, prefix: strings.Trim(prefix, "/")}, nil
+}
+
+func (s *s3Store) key(key string) *string {
+	return aws.String(path.Join(s.prefix, key))
+}
+
+func (s *s3Store) Put(ctx context.Context, key string, body io.Reader) error {
+	_, err := s.uploader.UploadWithContext(ctx, &s3manager.UploadInput{
+		Bucket: aws.String(s.bucket),
+		Key:    s.key(key),
+		ACL:    aws.String("bucket-owner-full-control"),
+		Body:   body,
+	})
+	return err
+}
+
+// Create uploads what is written through a pipe, so the uploader sends a part each time PartSize
+// bytes have been written and switches to a multipart upload once there is more than one.
+func (s *s3Store) Create(ctx context.Context, key string) (Writer, error) {
+	pr, pw := io.Pipe()
+	w := &s3Writer{pw: pw, result: make(chan error, 1)}
+	go func() {
+		err := s.Put(ctx, key, pr)
+		pr.CloseWithError(err)
+		w.result <- err
+	}()
+	return w, nil
+}
+
+// errAborted fails the upload behind an aborted writer, which makes the uploader abort any
+// multipart upload it started.
+var errAborted = errors.New("object write aborted")
+
+type s3Writer struct {
+	pw     *io.PipeWriter
+	result chan error
+	err    error
+	done   bool
+}
+
+func (w *s3Writer) Write(p []byte) (int, error) {
+	return w.pw.Write(p)
+}
+
+func (w *s3Writer) Close() error {
+	return w.finish(nil)
+}
+
+// Abort ignores the upload's result: it fails on errAborted, wrapped in an SDK error.
+func (w *s3Writer) Abort() error {
+	w.finish(errAborted)
+	return nil
+}
+
+func (w *s3Writer) finish(cause error) error {
+	if w.done {
+		return w.err
+	}
+	w.done = true
+	w.pw.CloseWithError(cause)
+	w.err = <-w.result
+	return w.err
+}
+
+func (s *s3Store) Get(ctx context.Context, key string) (io.ReadCloser, error) {
+	out, err := s.client.GetObjectWithContext(ctx, &s3.GetObjectInput{Bucket: aws.String(s.bucket), Key: s.key(key)})
+	if isNotFound(err) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return out.Body, nil
+}
+
+func (s *s3Store) Exists(ctx context.Context, key string) (bool, error) {
+	_, err := s.client.HeadObjectWithContext(ctx, &s3.HeadObjectInput{Bucket: aws.String(s.bucket), Key: s.key(key)})
+	if isNotFound(err) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+// List pages through ListObjectsV2, which already returns keys in order.
+func (s *s3Store) List(ctx context.Context, prefix string) ([]Object, error) {
+	// path.Join would drop the trailing slash a directory-like prefix relies on.
+	full := prefix
+	if s.prefix != "" {
+		full = s.prefix + "/" + prefix
+	}
+	var objects []Object
+	err := s.client.ListObjectsV2PagesWithContext(ctx, &s3.ListObjectsV2Input{Bucket: aws.String(s.bucket), Prefix: aws.String(full)}, func(page *s3.ListObjectsV2Output, lastPage bool) bool {
+		for _, o := range page.Contents {
+			key := aws.StringValue(o.Key)
+			if s.prefix != "" {
+				key = strings.TrimPrefix(key, s.prefix+"/")
+			}
+			objects = append(objects, Object{Key: key, Size: aws.Int64Value(o.Size), LastModified: aws.TimeValue(o.LastModified)})
+		}
+		return true
+	})
+	if err != nil {
+		return nil, err
+	}
+	return objects, nil
+}
+
+func (s *s3Store) Delete(ctx context.Context, key string) error {
+	_, err := s.client.DeleteObjectWithContext(ctx, &s3.DeleteObjectInput{Bucket: aws.String(s.bucket), Key: s.key(key)})
+	if isNotFound(err) {
+		return nil
+	}
+	return err
+}
+
+func isNotFound(err error) bool {
+	var aerr awserr.Error
+	if !errors.As(err, &aerr) {
+		return false
+	}
+	// HeadObject has no body to carry NoSuchKey and reports a bare NotFound.
+	return aerr.Code() == s3.ErrCodeNoSuchKey || aerr.Code() == "NotFound"
+}
@@ -0,0 +1,106 @@
+// Package payloadarchive keeps every inbound request body, content-addressed by SHA-256, so what a
+// sender actually transmitted can be produced later.
+package payloadarchive
+
+import (
+	"context"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	dbentity "usage-lakehouse/internal/db/entity"
+	"usage-lakehouse/internal/objectstore"
+	"usage-lakehouse/internal/repository"
+
+	"github.com/jackc/pgx/v5"
+)
+
+type Archive struct {
+	store objectstore.Store
+	repo  repository.RawPayloadRepository
+}
+
+func New(store objectstore.Store, repo repository.RawPayloadRepository) *Archive {
+	return &Archive{store: store, repo: repo}
+}
+
+// objectKey fans payloads out over two directory levels so no prefix grows unbounded.
+func objectKey(sum string) string {
+	return fmt.Sprintf("payloads/sha256/%s/%s/%s", sum[:2], sum[2:4], sum)
+}
+
+// Put archives body and records it. A payload already archived is not uploaded again. body is
+// rewound to its start before Put returns so the caller can go on to process it.
+func (a *Archive) Put(ctx context.Context, contentType string, body io.ReadSeeker) (*dbentity.RawPayload, error) {
+	h := sha256.New()
+	size, err := io.Copy(h, body)
+	if err != nil {
+		return nil, err
+	}
+	sum := fmt.Sprintf("%x", h.Sum(nil))
+	if existing, err := a.repo.GetBySHA256(ctx, sum); err == nil {
+		_, err = body.Seek(0, io.SeekStart)
+		return existing, err
+	} else if !errors.Is(err, pgx.ErrNoRows) {
+		return nil, err
+	}
+	p := dbentity.RawPayload{SHA256: sum, ContentType: contentType, Size: size, ObjectKey: objectKey(sum)}
+	if _, err := body.Seek(0, io.SeekStart); err != nil {
+		return nil, err
+	}
+	if err := a.store.Put(ctx, p.ObjectKey, body); err != nil {
+		return nil, err
+	}
+	if err := a.repo.Create(ctx, &p); err != nil {
+		return nil, err
+	}
+	if _, err := body.Seek(0, io.SeekStart); err != nil {
+		return nil, err
+	}
+	return &p, nil
+}
+
+// Open returns the record and content of an archived payload. It returns pgx.ErrNoRows for an
+// unknown SHA-256.
+func (a *Archive) Open(ctx context.Context, sum string) (*dbentity.RawPayload, io.ReadCloser, error) {
+	p, err := a.repo.GetBySHA256(ctx, sum)
+	if err != nil {
+		return nil, nil, err
+	}
+	body, err := a.store.Get(ctx, p.ObjectKey)
+	if err != nil {
+		return nil, nil, err
+	}
+	return p, body, nil
+}
+
+// Spool copies a request body to a temporary file so it can be both archived and processed
+// without holding it in memory. Closing the returned file removes it.
+func Spool(r io.Reader) (*SpooledBody, error) {
+	f, err := os.CreateTemp("", "payload-*")
+	if err != nil {
+		return nil, err
+	}
+	if _, err := io.Copy(f, r); err != nil {
+		f.Close()
+		os.Remove(f.Name())
+		return nil, err
+	}
+	if _, err := f.Seek(0, io.SeekStart); err != nil {
+		f.Close()
+		os.Remove(f.Name())
+		return nil, err
+	}
+	return &SpooledBody{File: f}, nil
+}
+
+type SpooledBody struct {
+	*os.File
+}
+
+func (s *SpooledBody) Close() error {
+	err := s.File.Close()
+	os.Remove(s.Name())
+	return err
+}
@@ -0,0 +1,105 @@
+package payloadarchive
+
+import (
+	"context"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"testing"
+	dbentity "usage-lakehouse/internal/db/entity"
+	"usage-lakehouse/internal/objectstore"
+	"usage-lakehouse/internal/repository"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// fakeRawPayloadRepository records payloads in memory. Like the SQL repository, recording a SHA-256
+// again keeps the first row.
+type fakeRawPayloadRepository struct {
+	repository.RawPayloadRepository
+	payloads map[string]dbentity.RawPayload
+}
+
+func (r *fakeRawPayloadRepository) Create(ctx context.Context, p *dbentity.RawPayload) error {
+	if _, ok := r.payloads[p.SHA256]; !ok {
+		r.payloads[p.SHA256] = *p
+	}
+	return nil
+}
+
+func (r *fakeRawPayloadRepository) GetBySHA256(ctx context.Context, sum string) (*dbentity.RawPayload, error) {
+	p, ok := r.payloads[sum]
+	if !ok {
+		return nil, pgx.ErrNoRows
+	}
+	return &p, nil
+}
+
+// countingStore counts the objects written to a memory store.
+type countingStore struct {
+	objectstore.Store
+	puts int
+}
+
+func (s *countingStore) Put(ctx context.Context, key string, body io.Reader) error {
+	s.puts++
+	return s.Store.Put(ctx, key, body)
+}
+
+func TestArchivePutAndOpen(t *testing.T) {
+	ctx := context.Background()
+	store := &countingStore{Store: objectstore.NewMemory()}
+	archive := New(store, &fakeRawPayloadRepository{payloads: map[string]dbentity.RawPayload{}})
+	const content = "ISA*00*          *00*~"
+	sum := fmt.Sprintf("%x", sha256.Sum256([]byte(content)))
+
+	for i := 0; i < 2; i++ {
+		body := strings.NewReader(content)
+		p, err := archive.Put(ctx, "application/edi-x12", body)
+		if err != nil {
+			t.Fatal(err)
+		}
+		want := dbentity.RawPayload{SHA256: sum, ContentType: "application/edi-x12", Size: int64(len(content)), ObjectKey: "payloads/sha256/" + sum[:2] + "/" + sum[2:4] + "/" + sum}
+		if *p != want {
+			t.Errorf("put %d recorded %+v, want %+v", i+1, *p, want)
+		}
+		if rest, _ := io.ReadAll(body); string(rest) != content {
+			t.Errorf("put %d left the body at %q, want it rewound", i+1, rest)
+		}
+	}
+	if store.puts != 1 {
+		t.Errorf("uploaded %d objects, want the repeated payload uploaded once", store.puts)
+	}
+
+	p, body, err := archive.Open(ctx, sum)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer body.Close()
+	if got, _ := io.ReadAll(body); string(got) != content || p.SHA256 != sum {
+		t.Errorf("Open = %+v %q, want the archived payload", p, got)
+	}
+	if _, _, err := archive.Open(ctx, strings.Repeat("0", 64)); !errors.Is(err, pgx.ErrNoRows) {
+		t.Errorf("Open of an unknown SHA-256 = %v, want pgx.ErrNoRows", err)
+	}
+}
+
+func TestSpool(t *testing.T) {
+	body, err := Spool(strings.NewReader("line 1\nline 2\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, _ := io.ReadAll(body); string(got) != "line 1\nline 2\n" {
+		t.Errorf("spooled %q", got)
+	}
+	name := body.Name()
+	if err := body.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(name); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("spool file after Close: %v, want it removed", err)
+	}
+}
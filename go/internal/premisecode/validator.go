@@ -0,0 +1,68 @@
+package premisecode
+
+import (
+	"fmt"
+	"regexp"
+	"sync"
+	dbentity "usage-lakehouse/internal/db/entity"
+)
+
+// MismatchError reports a premise code (ESI ID) that does not match the pattern of its TDSP.
+type MismatchError struct {
+	Code     string
+	TDSPName string
+	Pattern  string
+}
+
+func (e *MismatchError) Error() string {
+	return fmt.Sprintf("premise code %q does not match pattern %s expected by TDSP %s", e.Code, e.Pattern, e.TDSPName)
+}
+
+type compiledExpression struct {
+	expression string
+	re         *regexp.Regexp
+}
+
+// Validator compiles each TDSP's premise_code_validation_expression once and reuses it until the
+// expression stored for that TDSP changes. It is safe for concurrent use.
+type Validator struct {
+	mu    sync.RWMutex
+	cache map[string]compiledExpression
+}
+
+func NewValidator() *Validator {
+	return &Validator{cache: make(map[string]compiledExpression)}
+}
+
+// Validate returns a *MismatchError when code does not match the TDSP's expression. TDSPs without an
+// expression accept any code.
+func (v *Validator) Validate(tdsp *dbentity.TDSP, code string) error {
+	if tdsp.PremiseCodeValidationExpression == "" {
+		return nil
+	}
+	re, err := v.compile(tdsp)
+	if err != nil {
+		return err
+	}
+	if !re.MatchString(code) {
+		return &MismatchError{Code: code, TDSPName: tdsp.Name, Pattern: tdsp.PremiseCodeValidationExpression}
+	}
+	return nil
+}
+
+func (v *Validator) compile(tdsp *dbentity.TDSP) (*regexp.Regexp, error) {
+	v.mu.RLock()
+	cached, ok := v.cache[tdsp.ID]
+	v.mu.RUnlock()
+	if ok && cached.expression == tdsp.PremiseCodeValidationExpression {
+		return cached.re, nil
+	}
+	re, err := regexp.Compile(tdsp.PremiseCodeValidationExpression)
+	if err != nil {
+		return nil, fmt.Errorf("tdsp %s has an invalid premise_code_validation_expression: %w", tdsp.Name, err)
+	}
+	v.mu.Lock()
+	v.cache[tdsp.ID] = compiledExpression{expression: tdsp.PremiseCodeValidationExpression, re: re}
+	v.mu.Unlock()
+	return re, nil
+}
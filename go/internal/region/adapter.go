@@ -0,0 +1,52 @@
+// Package region adapts each power region's native usage transaction format onto the shared
+// model.EDIUsageTransaction the usage handlers persist as usage_transaction and
+// usage_transaction_detail rows.
+package region
+
+import (
+	"time"
+	"usage-lakehouse/internal/model"
+	"usage-lakehouse/internal/x12"
+)
+
+// DefaultPowerRegion is the region X12 uploads are read as when the request does not name one.
+const DefaultPowerRegion = "ERCOT"
+
+// Channel classifies the readings of a product transfer detail.
+type Channel int
+
+const (
+	// ChannelIgnored readings are not stored, e.g. reactive power channels.
+	ChannelIgnored Channel = iota
+	ChannelConsumption
+	ChannelGeneration
+)
+
+// Adapter reads one power region's usage transactions.
+type Adapter interface {
+	// PowerRegion is the power_region name transactions read by the adapter are ingested under.
+	PowerRegion() string
+	// Location is the region's local time, used for dates and times sent without a time code.
+	Location() *time.Location
+	// ParseX12 maps one 867 transaction set onto the shared model.
+	ParseX12(ts x12.TransactionSet) (model.EDIUsageTransaction, error)
+	// LocateField points an ingestion failure on a JSON field at the segment and element it was read
+	// from so it can be reported in an AK3/AK4 pair.
+	LocateField(ts x12.TransactionSet, field string, elementCode string) x12.SegmentError
+	// Channel classifies a detail's channel code.
+	Channel(code string) Channel
+	// DefaultChannel is the channel summary loops sent without one are totals of.
+	DefaultChannel() string
+}
+
+var adapters = map[string]Adapter{}
+
+func register(a Adapter) {
+	adapters[a.PowerRegion()] = a
+}
+
+// Lookup returns the adapter for a power region name.
+func Lookup(powerRegion string) (Adapter, bool) {
+	a, ok := adapters[powerRegion]
+	return a, ok
+}
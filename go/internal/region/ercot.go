@@ -0,0 +1,15 @@
+package region
+
+// ercot reads the ERCOT 867_02/867_03 implementation guides: the ESI ID is sent as REF*Q5 and
+// each PTD loop names its channel in REF*PRT.
+var ercot = &guide867{
+	powerRegion:    "ERCOT",
+	location:       centralLocation,
+	premiseRef:     "Q5",
+	channels:       map[string]Channel{"1": ChannelConsumption, "4": ChannelGeneration},
+	defaultChannel: "1",
+}
+
+func init() {
+	register(ercot)
+}
@@ -0,0 +1,17 @@
+package region
+
+// nyiso reads the New York EDI 867 implementation guide used by the NYISO utilities: the premise is
+// identified by the utility account number in REF*12 and each QTY reports its direction in QTY01,
+// QD for delivered (consumption) and 87 for received (generation).
+var nyiso = &guide867{
+	powerRegion:      "NYISO",
+	location:         easternLocation,
+	premiseRef:       "12",
+	quantityChannels: true,
+	channels:         map[string]Channel{"QD": ChannelConsumption, "87": ChannelGeneration},
+	defaultChannel:   "QD",
+}
+
+func init() {
+	register(nyiso)
+}
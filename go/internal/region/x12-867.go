@@ -0,0 +1,345 @@
+package region
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+	_ "time/tzdata"
+	"usage-lakehouse/internal/model"
+	"usage-lakehouse/internal/uom"
+	"usage-lakehouse/internal/x12"
+)
+
+// REF qualifiers shared by the 867 implementation guides.
+const (
+	refMeterName = "MG"
+	refMeterRole = "JH"
+	refMeterType = "MT"
+	refChannel   = "PRT"
+)
+
+// DTM qualifiers shared by the 867 implementation guides.
+const (
+	dtmServicePeriodStart = "150"
+	dtmServicePeriodEnd   = "151"
+	dtmExchangeDate       = "514"
+	dtmIntervalEnd        = "582"
+)
+
+// guide867 is an Adapter for a region whose usage arrives as an X12 867. It holds what differs
+// between the regional implementation guides; the segment layout is common to all of them.
+type guide867 struct {
+	powerRegion string
+	location    *time.Location
+	// premiseRef is the REF01 qualifier of the header REF carrying the premise identifier.
+	premiseRef string
+	// quantityChannels reads the channel from QTY01 instead of a REF*PRT in the PTD loop. Each change
+	// of QTY01 within a loop starts a new detail so a detail always carries a single channel, as each
+	// change of the QTY03 unit does in every guide.
+	quantityChannels bool
+	channels         map[string]Channel
+	defaultChannel   string
+}
+
+func (g *guide867) PowerRegion() string {
+	return g.powerRegion
+}
+
+func (g *guide867) Location() *time.Location {
+	return g.location
+}
+
+func (g *guide867) Channel(code string) Channel {
+	return g.channels[code]
+}
+
+func (g *guide867) DefaultChannel() string {
+	return g.defaultChannel
+}
+
+// ParseX12 maps one 867 transaction set onto the JSON model accepted by the usage handlers.
+func (g *guide867) ParseX12(ts x12.TransactionSet) (model.EDIUsageTransaction, error) {
+	var out model.EDIUsageTransaction
+	if ts.Code != "867" {
+		return out, &x12.SegmentError{SegmentID: "ST", Position: 1, Element: 1, ElementReference: "143", Code: x12.SegmentHasElementErrors, ElementCode: x12.ElementInvalidCodeValue, BadData: ts.Code, Message: fmt.Sprintf("transaction set %q is not an 867", ts.Code)}
+	}
+	out.PowerRegion = g.powerRegion
+
+	var detail *model.ProductTransferDetail
+	var quantity *model.QuantityDelivered
+	var lastIntervalEnd time.Time
+	flush := func() {
+		if detail != nil {
+			out.ProductTransferDetails = append(out.ProductTransferDetails, *detail)
+		}
+		detail = nil
+		quantity = nil
+		lastIntervalEnd = time.Time{}
+	}
+	seenBPT := false
+	for _, seg := range ts.Segments {
+		switch seg.ID {
+		case "BPT":
+			seenBPT = true
+			out.Purpose = model.TransactionSetPurposeCode(seg.Element(1))
+			out.TransactionID = seg.Element(2)
+			date, err := g.parseDate(seg, 3)
+			if err != nil {
+				return out, err
+			}
+			out.Date = date
+			out.ReportType = model.ReportTypeCode(seg.Element(4))
+			if original := seg.Element(6); original != "" {
+				out.OriginalTransactionID = &original
+			}
+			if action := seg.Element(7); action != "" {
+				out.Final = &action
+			}
+		case "N1":
+			switch seg.Element(1) {
+			case "8S":
+				out.TdspName = seg.Element(2)
+				out.TdspLegalID = seg.Element(4)
+			case "SJ":
+				out.CrName = seg.Element(2)
+				out.CrLegalID = seg.Element(4)
+			}
+		case "PTD":
+			flush()
+			detail = &model.ProductTransferDetail{TransferType: model.ProductTransferDetailTypeCode(seg.Element(1))}
+		case "REF":
+			value := seg.Element(2)
+			if detail == nil {
+				if seg.Element(1) == g.premiseRef {
+					out.EsiID = value
+				}
+				continue
+			}
+			switch seg.Element(1) {
+			case refMeterName:
+				detail.MeterName = &value
+			case refMeterRole:
+				detail.MeterRole = &value
+			case refMeterType:
+				detail.MeterType = &value
+			case refChannel:
+				if !g.quantityChannels {
+					detail.Channel = &value
+				}
+			}
+		case "DTM":
+			if detail == nil {
+				continue
+			}
+			t, err := g.parseDateTime(seg)
+			if err != nil {
+				return out, err
+			}
+			switch seg.Element(1) {
+			case dtmServicePeriodStart:
+				detail.ServicePeriodStart = &t
+			case dtmServicePeriodEnd:
+				detail.ServicePeriodEnd = &t
+			case dtmExchangeDate:
+				detail.ExchangeDate = &t
+			case dtmIntervalEnd:
+				if quantity == nil {
+					return out, &x12.SegmentError{SegmentID: seg.ID, Position: seg.Position, Code: x12.SegmentNotInProperSequence, Message: "interval DTM must follow a QTY segment"}
+				}
+				// Intervals are sent in order, so a wall clock that does not advance is the second
+				// pass through the fall-back hour and is read at the later offset.
+				if !t.After(lastIntervalEnd) {
+					if later := t.Add(time.Hour); later.Format("15:04") == t.Format("15:04") {
+						t = later
+					}
+				}
+				quantity.IntervalEnd = t
+				lastIntervalEnd = t
+			}
+		case "QTY":
+			if detail == nil {
+				return out, &x12.SegmentError{SegmentID: seg.ID, Position: seg.Position, Code: x12.SegmentNotInProperSequence, Message: "QTY found outside of a PTD loop"}
+			}
+			q, err := strconv.ParseFloat(seg.Element(2), 64)
+			if err != nil {
+				return out, &x12.SegmentError{SegmentID: seg.ID, Position: seg.Position, Element: 2, ElementReference: "380", Code: x12.SegmentHasElementErrors, ElementCode: x12.ElementInvalidCharacter, BadData: seg.Element(2), Message: fmt.Sprintf("invalid quantity %q", seg.Element(2))}
+			}
+			channel := seg.Element(1)
+			unit, knownUnit := uom.FromX12(unitCode(seg.Element(3)))
+			channelChanged := g.quantityChannels && detail.Channel != nil && *detail.Channel != channel
+			unitChanged := knownUnit && detail.UnitOfMeasure != nil && *detail.UnitOfMeasure != unit
+			if detail.Quantities != nil && (channelChanged || unitChanged) {
+				next := *detail
+				next.Quantities = nil
+				flush()
+				detail = &next
+			}
+			if g.quantityChannels {
+				detail.Channel = &channel
+			}
+			if knownUnit {
+				detail.UnitOfMeasure = &unit
+			}
+			if detail.Quantities == nil {
+				detail.Quantities = &[]model.QuantityDelivered{}
+			}
+			*detail.Quantities = append(*detail.Quantities, model.QuantityDelivered{Quantity: q})
+			quantity = &(*detail.Quantities)[len(*detail.Quantities)-1]
+		}
+	}
+	flush()
+	if !seenBPT {
+		return out, &x12.SegmentError{SegmentID: "BPT", Position: 2, Code: x12.SegmentMandatoryMissing, Message: "mandatory BPT segment is missing"}
+	}
+	return out, nil
+}
+
+// unitCode is the unit code leading a QTY03 composite, whichever component separator it is sent with.
+func unitCode(element string) string {
+	end := strings.IndexFunc(element, func(r rune) bool {
+		return (r < 'A' || r > 'Z') && (r < '0' || r > '9')
+	})
+	if end < 0 {
+		return element
+	}
+	return element[:end]
+}
+
+func (g *guide867) parseDate(seg x12.Segment, i int) (time.Time, error) {
+	t, err := time.ParseInLocation("20060102", seg.Element(i), g.location)
+	if err != nil {
+		return time.Time{}, &x12.SegmentError{SegmentID: seg.ID, Position: seg.Position, Element: i, ElementReference: "373", Code: x12.SegmentHasElementErrors, ElementCode: x12.ElementInvalidDate, BadData: seg.Element(i), Message: fmt.Sprintf("invalid date %q", seg.Element(i))}
+	}
+	return t, nil
+}
+
+// parseDateTime reads DTM02 (CCYYMMDD), an optional DTM03 (HHMM or HHMMSS) and an optional DTM04 time code.
+func (g *guide867) parseDateTime(seg x12.Segment) (time.Time, error) {
+	loc := g.location
+	if code := seg.Element(4); code != "" {
+		l, ok := timeCodeLocation(code)
+		if !ok {
+			return time.Time{}, &x12.SegmentError{SegmentID: seg.ID, Position: seg.Position, Element: 4, ElementReference: "623", Code: x12.SegmentHasElementErrors, ElementCode: x12.ElementInvalidCodeValue, BadData: code, Message: fmt.Sprintf("unsupported time code %q", code)}
+		}
+		loc = l
+	}
+	value, layout := seg.Element(2), "20060102"
+	switch clock := seg.Element(3); len(clock) {
+	case 0:
+	case 4:
+		value, layout = value+clock, layout+"1504"
+	case 6:
+		value, layout = value+clock, layout+"150405"
+	default:
+		return time.Time{}, &x12.SegmentError{SegmentID: seg.ID, Position: seg.Position, Element: 3, ElementReference: "337", Code: x12.SegmentHasElementErrors, ElementCode: x12.ElementInvalidTime, BadData: clock, Message: fmt.Sprintf("invalid time %q", clock)}
+	}
+	t, err := time.ParseInLocation(layout, value, loc)
+	if err != nil {
+		return time.Time{}, &x12.SegmentError{SegmentID: seg.ID, Position: seg.Position, Element: 2, ElementReference: "373", Code: x12.SegmentHasElementErrors, ElementCode: x12.ElementInvalidDate, BadData: seg.Element(2), Message: fmt.Sprintf("invalid date %q", seg.Element(2))}
+	}
+	// time.ParseInLocation moves a wall clock skipped by spring-forward to a later hour, which would
+	// duplicate a real interval.
+	if t.Format(layout) != value {
+		return time.Time{}, &x12.SegmentError{SegmentID: seg.ID, Position: seg.Position, Element: 3, ElementReference: "337", Code: x12.SegmentHasElementErrors, ElementCode: x12.ElementInvalidTime, BadData: seg.Element(3), Message: fmt.Sprintf("%s %s does not exist in %s", seg.Element(2), seg.Element(3), loc)}
+	}
+	return t, nil
+}
+
+var (
+	centralLocation  = mustLoadLocation("America/Chicago")
+	easternLocation  = mustLoadLocation("America/New_York")
+	mountainLocation = mustLoadLocation("America/Denver")
+	pacificLocation  = mustLoadLocation("America/Los_Angeles")
+)
+
+func timeCodeLocation(code string) (*time.Location, bool) {
+	switch strings.ToUpper(code) {
+	case "UT", "GM":
+		return time.UTC, true
+	case "ES":
+		return time.FixedZone("EST", -5*3600), true
+	case "ED":
+		return time.FixedZone("EDT", -4*3600), true
+	case "ET":
+		return easternLocation, true
+	case "CS":
+		return time.FixedZone("CST", -6*3600), true
+	case "CD":
+		return time.FixedZone("CDT", -5*3600), true
+	case "CT":
+		return centralLocation, true
+	case "MS":
+		return time.FixedZone("MST", -7*3600), true
+	case "MD":
+		return time.FixedZone("MDT", -6*3600), true
+	case "MT":
+		return mountainLocation, true
+	case "PS":
+		return time.FixedZone("PST", -8*3600), true
+	case "PD":
+		return time.FixedZone("PDT", -7*3600), true
+	case "PT":
+		return pacificLocation, true
+	}
+	return nil, false
+}
+
+func mustLoadLocation(name string) *time.Location {
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		panic(err)
+	}
+	return loc
+}
+
+type x12Field struct {
+	segmentID        string
+	qualifier        string
+	element          int
+	elementReference string
+}
+
+// x12Fields maps JSON fields of model.EDIUsageTransaction back to the element they are read from.
+// esi_id is located through the guide's premiseRef.
+var x12Fields = map[string]x12Field{
+	"transaction_set_purpose_code": {segmentID: "BPT", element: 1, elementReference: "353"},
+	"transaction_id":               {segmentID: "BPT", element: 2, elementReference: "127"},
+	"date":                         {segmentID: "BPT", element: 3, elementReference: "373"},
+	"report_type_code":             {segmentID: "BPT", element: 4, elementReference: "755"},
+	"original_transaction_id":      {segmentID: "BPT", element: 6, elementReference: "127"},
+	"action_code":                  {segmentID: "BPT", element: 7, elementReference: "306"},
+	"tdsp_name":                    {segmentID: "N1", qualifier: "8S", element: 2, elementReference: "93"},
+	"tdsp_legal_id":                {segmentID: "N1", qualifier: "8S", element: 4, elementReference: "67"},
+	"cr_name":                      {segmentID: "N1", qualifier: "SJ", element: 2, elementReference: "93"},
+	"cr_legal_id":                  {segmentID: "N1", qualifier: "SJ", element: 4, elementReference: "67"},
+	"meter_name":                   {segmentID: "REF", qualifier: refMeterName, element: 2, elementReference: "127"},
+	"service_period_start":         {segmentID: "DTM", qualifier: dtmServicePeriodStart, element: 2, elementReference: "373"},
+	"service_period_end":           {segmentID: "DTM", qualifier: dtmServicePeriodEnd, element: 2, elementReference: "373"},
+}
+
+// LocateField reports fields that cannot be located against BPT.
+func (g *guide867) LocateField(ts x12.TransactionSet, field string, elementCode string) x12.SegmentError {
+	f, ok := x12Fields[field]
+	if field == "esi_id" {
+		f, ok = x12Field{segmentID: "REF", qualifier: g.premiseRef, element: 2, elementReference: "127"}, true
+	}
+	if !ok {
+		f = x12Fields["transaction_id"]
+	}
+	for _, seg := range ts.Segments {
+		if seg.ID != f.segmentID || (f.qualifier != "" && seg.Element(1) != f.qualifier) {
+			continue
+		}
+		return x12.SegmentError{
+			SegmentID:        seg.ID,
+			Position:         seg.Position,
+			Element:          f.element,
+			ElementReference: f.elementReference,
+			Code:             x12.SegmentHasElementErrors,
+			ElementCode:      elementCode,
+			BadData:          seg.Element(f.element),
+		}
+	}
+	return x12.SegmentError{SegmentID: f.segmentID, Position: len(ts.Segments), Code: x12.SegmentMandatoryMissing}
+}
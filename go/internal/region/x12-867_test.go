@@ -0,0 +1,231 @@
+package region
+
+import (
+	"errors"
+	"os"
+	"strings"
+	"testing"
+	"time"
+	"usage-lakehouse/internal/model"
+	"usage-lakehouse/internal/x12"
+)
+
+// parseFixture parses the x12 package's ERCOT 867_03 interchange after applying replacements, given
+// as old and new pairs.
+func parseFixture(t *testing.T, replacements ...string) *x12.Interchange {
+	t.Helper()
+	b, err := os.ReadFile("../x12/testdata/867_03.edi")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ic, err := x12.Parse(strings.NewReader(strings.NewReplacer(replacements...).Replace(string(b))))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return ic
+}
+
+func TestERCOTParseX12(t *testing.T) {
+	sets := parseFixture(t).FunctionalGroups[0].TransactionSets
+	nonInterval, err := ercot.ParseX12(sets[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if nonInterval.TransactionID != "2024020500001" || nonInterval.Purpose != model.TransactionSetPurposeCodeOriginal ||
+		nonInterval.ReportType != model.ReportTypeCodeNIDR || nonInterval.EsiID != "10443720000000001" || nonInterval.PowerRegion != "ERCOT" {
+		t.Errorf("header = %+v", nonInterval)
+	}
+	if want := time.Date(2024, 2, 5, 0, 0, 0, 0, centralLocation); !nonInterval.Date.Equal(want) {
+		t.Errorf("date = %s, want %s", nonInterval.Date, want)
+	}
+	if nonInterval.TdspName != "ONCOR ELECTRIC DELIVERY" || nonInterval.TdspLegalID != "1039940674000" ||
+		nonInterval.CrName != "RETAIL ENERGY CO" || nonInterval.CrLegalID != "1480555310000" {
+		t.Errorf("parties = %s %s, %s %s", nonInterval.TdspName, nonInterval.TdspLegalID, nonInterval.CrName, nonInterval.CrLegalID)
+	}
+	details := nonInterval.ProductTransferDetails
+	if len(details) != 2 {
+		t.Fatalf("got %d product transfer details, want 2", len(details))
+	}
+	summary, meter := details[0], details[1]
+	if summary.TransferType != model.ProductTransferDetailTypeCodeNonIntervalUsage || summary.MeterName != nil || summary.Channel != nil {
+		t.Errorf("summary loop = %+v", summary)
+	}
+	if meter.TransferType != model.ProductTransferDetailTypeCodeNonIntervalDetail || deref(meter.MeterName) != "123456789" ||
+		deref(meter.MeterType) != "KHMON" || deref(meter.Channel) != "1" || deref(meter.UnitOfMeasure) != "KWH" {
+		t.Errorf("meter loop = %+v", meter)
+	}
+	wantStart, wantEnd := time.Date(2024, 1, 2, 0, 0, 0, 0, centralLocation), time.Date(2024, 2, 1, 0, 0, 0, 0, centralLocation)
+	if meter.ServicePeriodStart == nil || !meter.ServicePeriodStart.Equal(wantStart) || meter.ServicePeriodEnd == nil || !meter.ServicePeriodEnd.Equal(wantEnd) {
+		t.Errorf("service period = %v to %v, want %s to %s", meter.ServicePeriodStart, meter.ServicePeriodEnd, wantStart, wantEnd)
+	}
+	if meter.Quantities == nil || len(*meter.Quantities) != 1 || (*meter.Quantities)[0].Quantity != 1234 {
+		t.Errorf("meter quantities = %v, want 1234", meter.Quantities)
+	}
+
+	interval, err := ercot.ParseX12(sets[1])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if interval.ReportType != model.ReportTypeCodeIDR || len(interval.ProductTransferDetails) != 2 {
+		t.Fatalf("interval set = %+v", interval)
+	}
+	pm := interval.ProductTransferDetails[1]
+	if pm.TransferType != model.ProductTransferDetailTypeCodeIntervalDetail || pm.Quantities == nil {
+		t.Fatalf("interval loop = %+v", pm)
+	}
+	want := []model.QuantityDelivered{
+		{Quantity: 1.00, IntervalEnd: time.Date(2024, 1, 1, 6, 15, 0, 0, time.UTC)},
+		{Quantity: 1.25, IntervalEnd: time.Date(2024, 1, 1, 6, 30, 0, 0, time.UTC)},
+		{Quantity: 1.00, IntervalEnd: time.Date(2024, 1, 1, 6, 45, 0, 0, time.UTC)},
+		{Quantity: 1.00, IntervalEnd: time.Date(2024, 1, 1, 7, 0, 0, 0, time.UTC)},
+	}
+	got := *pm.Quantities
+	if len(got) != len(want) {
+		t.Fatalf("got %d intervals, want %d", len(got), len(want))
+	}
+	for i := range want {
+		if got[i].Quantity != want[i].Quantity || !got[i].IntervalEnd.Equal(want[i].IntervalEnd) {
+			t.Errorf("interval %d = %v at %s, want %v at %s", i, got[i].Quantity, got[i].IntervalEnd, want[i].Quantity, want[i].IntervalEnd)
+		}
+	}
+}
+
+// TestERCOTParseX12Rejections checks that a set the adapter cannot read is rejected with the AK3/AK4
+// detail of a 997 and the IK3/IK4 detail of a 999, while the other set in the group is accepted.
+func TestERCOTParseX12Rejections(t *testing.T) {
+	tests := []struct {
+		name         string
+		replacements []string
+		set          int
+		want         x12.SegmentError
+		ack997       string
+		ack999       string
+	}{
+		{
+			name:         "invalid quantity",
+			replacements: []string{"QTY*QD*1.25*KH~", "QTY*QD*1.2S*KH~"},
+			set:          1,
+			want:         x12.SegmentError{SegmentID: "QTY", Position: 18, Element: 2, ElementReference: "380", Code: x12.SegmentHasElementErrors, ElementCode: x12.ElementInvalidCharacter, BadData: "1.2S"},
+			ack997:       "AK2*867*0002~\nAK3*QTY*18**8~\nAK4*2*380*6*1.2S~\nAK5*R*5~\n",
+			ack999:       "AK2*867*0002~\nIK3*QTY*18**8~\nIK4*2*380*6*1.2S~\nIK5*R*5~\n",
+		},
+		{
+			name:         "invalid date",
+			replacements: []string{"BPT*00*2024020500001*20240205*DD~", "BPT*00*2024020500001*20240231*DD~"},
+			set:          0,
+			want:         x12.SegmentError{SegmentID: "BPT", Position: 2, Element: 3, ElementReference: "373", Code: x12.SegmentHasElementErrors, ElementCode: x12.ElementInvalidDate, BadData: "20240231"},
+			ack997:       "AK2*867*0001~\nAK3*BPT*2**8~\nAK4*3*373*8*20240231~\nAK5*R*5~\n",
+			ack999:       "AK2*867*0001~\nIK3*BPT*2**8~\nIK4*3*373*8*20240231~\nIK5*R*5~\n",
+		},
+		{
+			name:         "interval before its quantity",
+			replacements: []string{"REF*PRT*1~\nQTY*QD*1.00*KH~\nDTM*582*20240101*0015~", "REF*PRT*1~\nDTM*582*20240101*0015~\nQTY*QD*1.00*KH~"},
+			set:          1,
+			want:         x12.SegmentError{SegmentID: "DTM", Position: 16, Code: x12.SegmentNotInProperSequence},
+			ack997:       "AK2*867*0002~\nAK3*DTM*16**7~\nAK5*R*5~\n",
+			ack999:       "AK2*867*0002~\nIK3*DTM*16**7~\nIK5*R*5~\n",
+		},
+		{
+			name:         "missing BPT",
+			replacements: []string{"BPT*00*2024020500001*20240205*DD~\n", "", "SE*17*0001~", "SE*16*0001~"},
+			set:          0,
+			want:         x12.SegmentError{SegmentID: "BPT", Position: 2, Code: x12.SegmentMandatoryMissing},
+			ack997:       "AK2*867*0001~\nAK3*BPT*2**3~\nAK5*R*5~\n",
+			ack999:       "AK2*867*0001~\nIK3*BPT*2**3~\nIK5*R*5~\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ic := parseFixture(t, tt.replacements...)
+			group := ic.FunctionalGroups[0]
+			var results []x12.SetResult
+			for i, set := range group.TransactionSets {
+				result := x12.SetResult{GroupControlNumber: group.ControlNumber, ControlNumber: set.ControlNumber}
+				_, err := ercot.ParseX12(set)
+				if i != tt.set {
+					if err != nil {
+						t.Fatalf("set %s: %v", set.ControlNumber, err)
+					}
+					result.Accepted = true
+					results = append(results, result)
+					continue
+				}
+				var segmentErr *x12.SegmentError
+				if !errors.As(err, &segmentErr) {
+					t.Fatalf("set %s: ParseX12 error = %v, want a SegmentError", set.ControlNumber, err)
+				}
+				got := *segmentErr
+				got.Message = ""
+				if got != tt.want {
+					t.Errorf("SegmentError = %+v, want %+v", got, tt.want)
+				}
+				result.Errors = []x12.SegmentError{*segmentErr}
+				results = append(results, result)
+			}
+
+			now := time.Date(2024, 2, 5, 9, 0, 0, 0, time.UTC)
+			for kind, want := range map[x12.AcknowledgementType]string{x12.Acknowledgement997: tt.ack997, x12.Acknowledgement999: tt.ack999} {
+				ack := x12.BuildAcknowledgement(ic, kind, results, []int64{1}, now)
+				if !strings.Contains(ack, want) {
+					t.Errorf("%s does not contain\n%s\ngot\n%s", kind, want, ack)
+				}
+				if !strings.Contains(ack, "AK9*P*2*2*1~") {
+					t.Errorf("%s does not report the group as partially accepted:\n%s", kind, ack)
+				}
+			}
+		})
+	}
+}
+
+func TestERCOTParseX12SplitsUnits(t *testing.T) {
+	ic := parseFixture(t, "REF*PRT*1~\nQTY*QD*1234*KH~\nSE*17*0001~", "REF*PRT*1~\nQTY*QD*1234*KH~\nQTY*QD*12.5*K1~\nQTY*QD*3*KH~\nSE*19*0001~")
+	set, err := ercot.ParseX12(ic.FunctionalGroups[0].TransactionSets[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	details := set.ProductTransferDetails
+	if len(details) != 4 {
+		t.Fatalf("got %d product transfer details, want the summary and a meter detail per change of unit", len(details))
+	}
+	want := []struct {
+		unit     string
+		quantity float64
+	}{{"KWH", 1234}, {"KW", 12.5}, {"KWH", 3}}
+	for i, w := range want {
+		d := details[i+1]
+		if deref(d.UnitOfMeasure) != w.unit || deref(d.MeterName) != "123456789" || deref(d.Channel) != "1" ||
+			d.Quantities == nil || len(*d.Quantities) != 1 || (*d.Quantities)[0].Quantity != w.quantity {
+			t.Errorf("detail %d = %+v, want %v %s", i+1, d, w.quantity, w.unit)
+		}
+	}
+}
+
+func TestERCOTLocateField(t *testing.T) {
+	set := parseFixture(t).FunctionalGroups[0].TransactionSets[0]
+	tests := []struct {
+		field    string
+		segment  string
+		position int
+		badData  string
+	}{
+		{"transaction_id", "BPT", 2, "2024020500001"},
+		{"esi_id", "REF", 5, "10443720000000001"},
+		{"service_period_start", "DTM", 7, "20240102"},
+		{"service_period_end", "DTM", 8, "20240201"},
+		// A field without an element of its own is reported against BPT.
+		{"meter_type", "BPT", 2, "2024020500001"},
+	}
+	for _, tt := range tests {
+		got := ercot.LocateField(set, tt.field, x12.ElementMandatoryMissing)
+		if got.SegmentID != tt.segment || got.Position != tt.position || got.BadData != tt.badData || got.ElementCode != x12.ElementMandatoryMissing {
+			t.Errorf("LocateField(%s) = %+v, want %s at %d holding %q", tt.field, got, tt.segment, tt.position, tt.badData)
+		}
+	}
+}
+
+func deref(s *string) string {
+	if s == nil {
+		return ""
+	}
+	return *s
+}
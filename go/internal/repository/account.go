@@ -8,6 +8,7 @@ import (
 	"usage-lakehouse/internal/model"
 
 	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
 )
 
@@ -46,14 +47,24 @@ func (r *accountRepositorySQL) GetByID(ctx context.Context, id string) (*model.A
 	return &a, nil
 }
 
+// Update returns pgx.ErrNoRows when the account does not exist.
 func (r *accountRepositorySQL) Update(ctx context.Context, a *model.Account) error {
-	_, err := r.db.Exec(ctx, `UPDATE account SET name=$1 WHERE id=$2`, a.Name, a.ID)
-	return err
+	return r.db.QueryRow(ctx,
+		`UPDATE account SET legal_id=$1, name=$2 WHERE id=$3 RETURNING created_dttm, updated_dttm`,
+		a.LegalID, a.Name, a.ID,
+	).Scan(&a.Created, &a.Updated)
 }
 
+// Delete returns pgx.ErrNoRows when the account does not exist.
 func (r *accountRepositorySQL) Delete(ctx context.Context, id string) error {
-	_, err := r.db.Exec(ctx, `DELETE FROM account WHERE id=$1`, id)
-	return err
+	tag, err := r.db.Exec(ctx, `DELETE FROM account WHERE id=$1`, id)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return pgx.ErrNoRows
+	}
+	return nil
 }
 
 func (r *accountRepositorySQL) List(ctx context.Context) ([]model.Account, error) {
@@ -76,13 +87,10 @@ func (r *accountRepositorySQL) List(ctx context.Context) ([]model.Account, error
 func (r *accountRepositorySQL) ExistsByName(ctx context.Context, name string, accountID *string) (bool, error) {
 	var exists bool
 	var err error
-	sql := `SELECT EXISTS(SELECT 1 FROM account WHERE name=$1)`
-
 	if accountID != nil {
-		sql += ` AND id != $2`
-		err = r.db.QueryRow(ctx, sql, name, accountID).Scan(&exists)
+		err = r.db.QueryRow(ctx, `SELECT EXISTS(SELECT 1 FROM account WHERE name=$1 AND id != $2)`, name, *accountID).Scan(&exists)
 	} else {
-		err = r.db.QueryRow(ctx, sql, name).Scan(&exists)
+		err = r.db.QueryRow(ctx, `SELECT EXISTS(SELECT 1 FROM account WHERE name=$1)`, name).Scan(&exists)
 	}
 	return exists, err
 }
@@ -90,13 +98,10 @@ func (r *accountRepositorySQL) ExistsByName(ctx context.Context, name string, ac
 func (r *accountRepositorySQL) ExistsByLegalID(ctx context.Context, legalID string, accountID *string) (bool, error) {
 	var exists bool
 	var err error
-	sql := `SELECT EXISTS(SELECT 1 FROM account WHERE legal_id=$1)`
-
 	if accountID != nil {
-		sql += ` AND id != $2`
-		err = r.db.QueryRow(ctx, sql, legalID, accountID).Scan(&exists)
+		err = r.db.QueryRow(ctx, `SELECT EXISTS(SELECT 1 FROM account WHERE legal_id=$1 AND id != $2)`, legalID, *accountID).Scan(&exists)
 	} else {
-		err = r.db.QueryRow(ctx, sql, legalID).Scan(&exists)
+		err = r.db.QueryRow(ctx, `SELECT EXISTS(SELECT 1 FROM account WHERE legal_id=$1)`, legalID).Scan(&exists)
 	}
 	return exists, err
 }
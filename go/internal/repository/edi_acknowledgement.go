@@ -0,0 +1,62 @@
+package repository
+
+import (
+	"context"
+	"slices"
+	dbentity "usage-lakehouse/internal/db/entity"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type EDIAcknowledgementRepository interface {
+	// NextControlNumbers draws n numbers from the acknowledgement control sequence, in ascending order.
+	NextControlNumbers(ctx context.Context, n int) ([]int64, error)
+	Create(ctx context.Context, a *dbentity.EDIAcknowledgement) error
+	GetByID(ctx context.Context, id string) (*dbentity.EDIAcknowledgement, error)
+}
+
+type ediAcknowledgementRepositorySQL struct {
+	db *pgxpool.Pool
+}
+
+func NewEDIAcknowledgementRepository(db *pgxpool.Pool) EDIAcknowledgementRepository {
+	return &ediAcknowledgementRepositorySQL{db: db}
+}
+
+func (r *ediAcknowledgementRepositorySQL) NextControlNumbers(ctx context.Context, n int) ([]int64, error) {
+	rows, err := r.db.Query(ctx, `SELECT nextval('edi_acknowledgement_control_number_seq') FROM generate_series(1, $1)`, n)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	numbers := make([]int64, 0, n)
+	for rows.Next() {
+		var number int64
+		if err := rows.Scan(&number); err != nil {
+			return nil, err
+		}
+		numbers = append(numbers, number)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	slices.Sort(numbers)
+	return numbers, nil
+}
+
+func (r *ediAcknowledgementRepositorySQL) Create(ctx context.Context, a *dbentity.EDIAcknowledgement) error {
+	return r.db.QueryRow(ctx,
+		`INSERT INTO edi_acknowledgement (id, acknowledgement_type, control_number, interchange_control_number, sender_id, receiver_id, accepted_count, rejected_count, content) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9) RETURNING created_dttm, updated_dttm`,
+		a.ID, a.Type, a.ControlNumber, a.InterchangeControlNumber, a.SenderID, a.ReceiverID, a.AcceptedCount, a.RejectedCount, a.Content,
+	).Scan(&a.Created, &a.Updated)
+}
+
+func (r *ediAcknowledgementRepositorySQL) GetByID(ctx context.Context, id string) (*dbentity.EDIAcknowledgement, error) {
+	var a dbentity.EDIAcknowledgement
+	err := r.db.QueryRow(ctx, `SELECT id, acknowledgement_type, control_number, interchange_control_number, sender_id, receiver_id, accepted_count, rejected_count, content, created_dttm, updated_dttm FROM edi_acknowledgement WHERE id=$1`, id).
+		Scan(&a.ID, &a.Type, &a.ControlNumber, &a.InterchangeControlNumber, &a.SenderID, &a.ReceiverID, &a.AcceptedCount, &a.RejectedCount, &a.Content, &a.Created, &a.Updated)
+	if err != nil {
+		return nil, err
+	}
+	return &a, nil
+}
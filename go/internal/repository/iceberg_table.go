@@ -0,0 +1,72 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	dbentity "usage-lakehouse/internal/db/entity"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type IcebergTableRepository interface {
+	Get(ctx context.Context, catalogName string, namespace string, name string) (*dbentity.IcebergTable, error)
+	// Create registers a table, returning ErrIcebergTableExists when the catalog already has it.
+	Create(ctx context.Context, t *dbentity.IcebergTable) error
+	// Swap points the table at metadataLocation if it still points at base, and returns
+	// pgx.ErrNoRows when it does not.
+	Swap(ctx context.Context, catalogName string, namespace string, name string, base string, metadataLocation string) error
+}
+
+// ErrIcebergTableExists is returned by Create when the catalog already has the table.
+var ErrIcebergTableExists = errors.New("iceberg table already exists")
+
+// icebergTableType marks rows as tables rather than views, as the JDBC catalog does.
+const icebergTableType = "TABLE"
+
+type icebergTableRepositorySQL struct {
+	db *pgxpool.Pool
+}
+
+func NewIcebergTableRepository(db *pgxpool.Pool) IcebergTableRepository {
+	return &icebergTableRepositorySQL{db: db}
+}
+
+func (r *icebergTableRepositorySQL) Get(ctx context.Context, catalogName string, namespace string, name string) (*dbentity.IcebergTable, error) {
+	var t dbentity.IcebergTable
+	err := r.db.QueryRow(ctx,
+		`SELECT catalog_name, table_namespace, table_name, metadata_location, previous_metadata_location, created_dttm, updated_dttm FROM iceberg_tables WHERE catalog_name=$1 AND table_namespace=$2 AND table_name=$3`,
+		catalogName, namespace, name,
+	).Scan(&t.CatalogName, &t.Namespace, &t.Name, &t.MetadataLocation, &t.PreviousMetadataLocation, &t.Created, &t.Updated)
+	if err != nil {
+		return nil, err
+	}
+	return &t, nil
+}
+
+func (r *icebergTableRepositorySQL) Create(ctx context.Context, t *dbentity.IcebergTable) error {
+	err := r.db.QueryRow(ctx,
+		`INSERT INTO iceberg_tables (catalog_name, table_namespace, table_name, metadata_location, iceberg_type) VALUES ($1, $2, $3, $4, $5) RETURNING created_dttm, updated_dttm`,
+		t.CatalogName, t.Namespace, t.Name, t.MetadataLocation, icebergTableType,
+	).Scan(&t.Created, &t.Updated)
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) && pgErr.Code == uniqueViolation {
+		return ErrIcebergTableExists
+	}
+	return err
+}
+
+func (r *icebergTableRepositorySQL) Swap(ctx context.Context, catalogName string, namespace string, name string, base string, metadataLocation string) error {
+	tag, err := r.db.Exec(ctx,
+		`UPDATE iceberg_tables SET metadata_location=$1, previous_metadata_location=$2 WHERE catalog_name=$3 AND table_namespace=$4 AND table_name=$5 AND metadata_location=$2`,
+		metadataLocation, base, catalogName, namespace, name,
+	)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return pgx.ErrNoRows
+	}
+	return nil
+}
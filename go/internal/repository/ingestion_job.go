@@ -0,0 +1,109 @@
+package repository
+
+import (
+	"context"
+	"time"
+	dbentity "usage-lakehouse/internal/db/entity"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type IngestionJobRepository interface {
+	Create(ctx context.Context, job *dbentity.IngestionJob) error
+	GetByID(ctx context.Context, id string) (*dbentity.IngestionJob, error)
+	// ClaimNext marks the oldest queued job, or a running job whose heartbeat is older than
+	// staleAfter and that has been claimed fewer than maxAttempts times, as running and returns it.
+	// It returns pgx.ErrNoRows when there is nothing to do.
+	ClaimNext(ctx context.Context, staleAfter time.Duration, maxAttempts int) (*dbentity.IngestionJob, error)
+	// FailAbandoned marks running jobs whose heartbeat is older than staleAfter as failed once they
+	// have been claimed maxAttempts times, so a job that keeps killing its worker is not retried
+	// forever. It returns how many jobs it failed.
+	FailAbandoned(ctx context.Context, staleAfter time.Duration, maxAttempts int) (int64, error)
+	// Heartbeat records that the worker holding the job's claim is still running it. It returns
+	// pgx.ErrNoRows when the job has since been reclaimed by another worker or finished.
+	Heartbeat(ctx context.Context, job *dbentity.IngestionJob) error
+	// Finish records the job's result. It returns pgx.ErrNoRows when the job has since been reclaimed
+	// by another worker or finished, leaving the row alone.
+	Finish(ctx context.Context, job *dbentity.IngestionJob) error
+}
+
+type ingestionJobRepositorySQL struct {
+	db *pgxpool.Pool
+}
+
+func NewIngestionJobRepository(db *pgxpool.Pool) IngestionJobRepository {
+	return &ingestionJobRepositorySQL{db: db}
+}
+
+const ingestionJobColumns = `id, kind, status, params, raw_payload_sha256, attempts, processed_count, succeeded_count, failed_count, errors, output, started_dttm, heartbeat_dttm, finished_dttm, created_dttm, updated_dttm`
+
+func (r *ingestionJobRepositorySQL) Create(ctx context.Context, job *dbentity.IngestionJob) error {
+	return r.db.QueryRow(ctx,
+		`INSERT INTO ingestion_job (id, kind, status, params, raw_payload_sha256) VALUES ($1, $2, $3, $4, $5) RETURNING status, created_dttm, updated_dttm`,
+		job.ID, job.Kind, dbentity.IngestionJobStatusQueued, job.Params, job.RawPayloadSHA256,
+	).Scan(&job.Status, &job.Created, &job.Updated)
+}
+
+func (r *ingestionJobRepositorySQL) GetByID(ctx context.Context, id string) (*dbentity.IngestionJob, error) {
+	var job dbentity.IngestionJob
+	err := r.db.QueryRow(ctx, `SELECT `+ingestionJobColumns+` FROM ingestion_job WHERE id=$1`, id).
+		Scan(&job.ID, &job.Kind, &job.Status, &job.Params, &job.RawPayloadSHA256, &job.Attempts, &job.ProcessedCount, &job.SucceededCount, &job.FailedCount, &job.Errors, &job.Output, &job.Started, &job.Heartbeat, &job.Finished, &job.Created, &job.Updated)
+	if err != nil {
+		return nil, err
+	}
+	return &job, nil
+}
+
+func (r *ingestionJobRepositorySQL) ClaimNext(ctx context.Context, staleAfter time.Duration, maxAttempts int) (*dbentity.IngestionJob, error) {
+	var job dbentity.IngestionJob
+	err := r.db.QueryRow(ctx, `
+		UPDATE ingestion_job SET status = 'RUNNING', attempts = attempts + 1, started_dttm = now(), heartbeat_dttm = now()
+		WHERE id = (
+			SELECT id FROM ingestion_job
+			WHERE status = 'QUEUED'
+				OR (status = 'RUNNING' AND COALESCE(heartbeat_dttm, started_dttm) < now() - make_interval(secs => $1) AND attempts < $2)
+			ORDER BY created_dttm
+			LIMIT 1
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING `+ingestionJobColumns, staleAfter.Seconds(), maxAttempts).
+		Scan(&job.ID, &job.Kind, &job.Status, &job.Params, &job.RawPayloadSHA256, &job.Attempts, &job.ProcessedCount, &job.SucceededCount, &job.FailedCount, &job.Errors, &job.Output, &job.Started, &job.Heartbeat, &job.Finished, &job.Created, &job.Updated)
+	if err != nil {
+		return nil, err
+	}
+	return &job, nil
+}
+
+func (r *ingestionJobRepositorySQL) FailAbandoned(ctx context.Context, staleAfter time.Duration, maxAttempts int) (int64, error) {
+	tag, err := r.db.Exec(ctx, `
+		UPDATE ingestion_job SET status = 'FAILED', finished_dttm = now(),
+			errors = errors || jsonb_build_array(jsonb_build_object('message', 'worker stopped responding after ' || attempts || ' attempts'))
+		WHERE status = 'RUNNING'
+			AND COALESCE(heartbeat_dttm, started_dttm) < now() - make_interval(secs => $1)
+			AND attempts >= $2`, staleAfter.Seconds(), maxAttempts)
+	if err != nil {
+		return 0, err
+	}
+	return tag.RowsAffected(), nil
+}
+
+// Heartbeat matches the claim on attempts, which every ClaimNext increments.
+func (r *ingestionJobRepositorySQL) Heartbeat(ctx context.Context, job *dbentity.IngestionJob) error {
+	err := r.db.QueryRow(ctx,
+		`UPDATE ingestion_job SET heartbeat_dttm = now() WHERE id=$1 AND attempts=$2 AND status='RUNNING' RETURNING heartbeat_dttm`,
+		job.ID, job.Attempts,
+	).Scan(&job.Heartbeat)
+	return err
+}
+
+func (r *ingestionJobRepositorySQL) Finish(ctx context.Context, job *dbentity.IngestionJob) error {
+	if job.Errors == nil {
+		job.Errors = []dbentity.IngestionJobError{}
+	}
+	return r.db.QueryRow(ctx, `
+		UPDATE ingestion_job SET status=$1, processed_count=$2, succeeded_count=$3, failed_count=$4, errors=$5, output=$6, finished_dttm=now()
+		WHERE id=$7 AND attempts=$8 AND status='RUNNING'
+		RETURNING finished_dttm, updated_dttm`,
+		job.Status, job.ProcessedCount, job.SucceededCount, job.FailedCount, job.Errors, job.Output, job.ID, job.Attempts,
+	).Scan(&job.Finished, &job.Updated)
+}
@@ -0,0 +1,160 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"testing"
+	"time"
+	dbentity "usage-lakehouse/internal/db/entity"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// testPool connects to the migrated database named by TEST_DATABASE_URL, skipping the test when it is
+// unset. The pool holds a single connection on which each of tables is shadowed by an empty temporary
+// copy, so the test neither sees nor changes real rows.
+func testPool(t *testing.T, tables ...string) *pgxpool.Pool {
+	t.Helper()
+	dsn := os.Getenv("TEST_DATABASE_URL")
+	if dsn == "" {
+		t.Skip("TEST_DATABASE_URL is not set")
+	}
+	config, err := pgxpool.ParseConfig(dsn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	config.MaxConns = 1
+	config.AfterConnect = func(ctx context.Context, conn *pgx.Conn) error {
+		for _, table := range tables {
+			_, err := conn.Exec(ctx, fmt.Sprintf(`
+				CREATE TEMPORARY TABLE %[1]s (LIKE public.%[1]s INCLUDING DEFAULTS INCLUDING CONSTRAINTS INCLUDING INDEXES);
+				CREATE TRIGGER set_created_dttm_trigger BEFORE INSERT ON %[1]s FOR EACH ROW EXECUTE FUNCTION public.set_created_dttm();
+				CREATE TRIGGER set_updated_dttm_trigger BEFORE INSERT OR UPDATE ON %[1]s FOR EACH ROW EXECUTE FUNCTION public.set_updated_dttm();`, table))
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	db, err := pgxpool.NewWithConfig(context.Background(), config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(db.Close)
+	return db
+}
+
+func createTestJob(t *testing.T, repo IngestionJobRepository) *dbentity.IngestionJob {
+	t.Helper()
+	job := dbentity.IngestionJob{ID: uuid.New().String(), Kind: dbentity.IngestionJobKindVEE, Params: []byte(`{}`)}
+	if err := repo.Create(context.Background(), &job); err != nil {
+		t.Fatal(err)
+	}
+	return &job
+}
+
+// ageHeartbeat moves the job's heartbeat back by age, as if its worker had stopped refreshing it.
+func ageHeartbeat(t *testing.T, db *pgxpool.Pool, id string, age time.Duration) {
+	t.Helper()
+	if _, err := db.Exec(context.Background(), `UPDATE ingestion_job SET heartbeat_dttm = heartbeat_dttm - make_interval(secs => $2) WHERE id = $1`, id, age.Seconds()); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestIngestionJobClaimNextReclaimsStaleJobs(t *testing.T) {
+	ctx := context.Background()
+	db := testPool(t, "ingestion_job")
+	repo := NewIngestionJobRepository(db)
+	job := createTestJob(t, repo)
+
+	first, err := repo.ClaimNext(ctx, time.Minute, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if first.ID != job.ID || first.Status != dbentity.IngestionJobStatusRunning || first.Attempts != 1 {
+		t.Fatalf("first claim = %+v, want job %s running on attempt 1", first, job.ID)
+	}
+	if _, err := repo.ClaimNext(ctx, time.Minute, 2); !errors.Is(err, pgx.ErrNoRows) {
+		t.Fatalf("claim of a running job with a fresh heartbeat = %v, want pgx.ErrNoRows", err)
+	}
+
+	ageHeartbeat(t, db, job.ID, 2*time.Minute)
+	second, err := repo.ClaimNext(ctx, time.Minute, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if second.ID != job.ID || second.Attempts != 2 {
+		t.Fatalf("reclaim = %+v, want job %s on attempt 2", second, job.ID)
+	}
+	if err := repo.Heartbeat(ctx, first); !errors.Is(err, pgx.ErrNoRows) {
+		t.Errorf("heartbeat of the lost claim = %v, want pgx.ErrNoRows", err)
+	}
+	if err := repo.Heartbeat(ctx, second); err != nil {
+		t.Errorf("heartbeat of the current claim: %v", err)
+	}
+
+	// Once claimed maxAttempts times a stale job is failed rather than reclaimed.
+	ageHeartbeat(t, db, job.ID, 2*time.Minute)
+	if _, err := repo.ClaimNext(ctx, time.Minute, 2); !errors.Is(err, pgx.ErrNoRows) {
+		t.Fatalf("claim of a job out of attempts = %v, want pgx.ErrNoRows", err)
+	}
+	failed, err := repo.FailAbandoned(ctx, time.Minute, 2)
+	if err != nil || failed != 1 {
+		t.Fatalf("FailAbandoned = %d, %v, want 1 job failed", failed, err)
+	}
+	stored, err := repo.GetByID(ctx, job.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stored.Status != dbentity.IngestionJobStatusFailed || len(stored.Errors) != 1 || stored.Finished == nil {
+		t.Errorf("abandoned job = %+v, want it failed with one error", stored)
+	}
+}
+
+func TestIngestionJobFinishRequiresTheCurrentClaim(t *testing.T) {
+	ctx := context.Background()
+	db := testPool(t, "ingestion_job")
+	repo := NewIngestionJobRepository(db)
+	job := createTestJob(t, repo)
+
+	first, err := repo.ClaimNext(ctx, time.Minute, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ageHeartbeat(t, db, job.ID, 2*time.Minute)
+	second, err := repo.ClaimNext(ctx, time.Minute, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The worker that lost the claim finishes late; its result is discarded.
+	first.Status = dbentity.IngestionJobStatusFailed
+	first.Errors = []dbentity.IngestionJobError{{Message: "stale worker"}}
+	if err := repo.Finish(ctx, first); !errors.Is(err, pgx.ErrNoRows) {
+		t.Fatalf("Finish of the lost claim = %v, want pgx.ErrNoRows", err)
+	}
+	second.Status = dbentity.IngestionJobStatusSucceeded
+	second.ProcessedCount, second.SucceededCount = 2, 2
+	second.Output = []byte(`{"usage_transaction_ids":["u1","u2"]}`)
+	if err := repo.Finish(ctx, second); err != nil {
+		t.Fatalf("Finish of the current claim: %v", err)
+	}
+	if err := repo.Finish(ctx, second); !errors.Is(err, pgx.ErrNoRows) {
+		t.Errorf("second Finish of the same claim = %v, want pgx.ErrNoRows", err)
+	}
+
+	stored, err := repo.GetByID(ctx, job.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stored.Status != dbentity.IngestionJobStatusSucceeded || stored.Attempts != 2 || stored.SucceededCount != 2 || len(stored.Errors) != 0 || stored.Finished == nil {
+		t.Errorf("finished job = %+v, want the current claim's result", stored)
+	}
+	if _, err := repo.ClaimNext(ctx, 0, 3); !errors.Is(err, pgx.ErrNoRows) {
+		t.Errorf("claim after the job finished = %v, want pgx.ErrNoRows", err)
+	}
+}
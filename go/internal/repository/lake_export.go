@@ -0,0 +1,180 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+	dbentity "usage-lakehouse/internal/db/entity"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// LakeExportRepository tracks the incremental export of source tables to the lake and reads the rows
+// each export writes. Watermarks are updated_dttm values, timestamps in the database's time zone.
+type LakeExportRepository interface {
+	// TryLock takes the exporter's lock on source, held until unlock is called. ok is false when
+	// another exporter holds it.
+	TryLock(ctx context.Context, source string) (unlock func(), ok bool, err error)
+	// GetWatermark returns the source's high-water mark, or nil before its first export.
+	GetWatermark(ctx context.Context, source string) (*time.Time, error)
+	SetWatermark(ctx context.Context, source string, mark time.Time) error
+	// Horizon is the database's current time less lag, the latest watermark an export may reach.
+	Horizon(ctx context.Context, lag time.Duration) (time.Time, error)
+	// NextWatermark returns the updated_dttm of the batchSize-th row of source updated after after up
+	// to through, or through when fewer rows remain.
+	NextWatermark(ctx context.Context, source string, after *time.Time, through time.Time, batchSize int) (time.Time, error)
+	// ListUsageTransactionDetails returns the usage_transaction_detail rows updated after after up to
+	// through, oldest update first. A nil after lists from the beginning.
+	ListUsageTransactionDetails(ctx context.Context, after *time.Time, through time.Time) ([]dbentity.UsageTransactionDetailExport, error)
+	// ListMeterUsageIntervals returns the meter_usage_15_minute rows updated after after up to through,
+	// oldest update first. A nil after lists from the beginning.
+	ListMeterUsageIntervals(ctx context.Context, after *time.Time, through time.Time) ([]dbentity.MeterUsageIntervalExport, error)
+	CreateRun(ctx context.Context, run *dbentity.LakeExportRun) error
+	// UpdateRun records a run's progress, and its finish once its status is no longer RUNNING.
+	UpdateRun(ctx context.Context, run *dbentity.LakeExportRun) error
+}
+
+// lakeExportSources are the tables an export may read; NextWatermark names them in its query.
+var lakeExportSources = map[string]bool{
+	dbentity.LakeExportSourceUsageTransactionDetail: true,
+	dbentity.LakeExportSourceMeterUsage15Minute:     true,
+}
+
+type lakeExportRepositorySQL struct {
+	db *pgxpool.Pool
+}
+
+func NewLakeExportRepository(db *pgxpool.Pool) LakeExportRepository {
+	return &lakeExportRepositorySQL{db: db}
+}
+
+// TryLock holds a session advisory lock, so it keeps one pooled connection until unlock.
+func (r *lakeExportRepositorySQL) TryLock(ctx context.Context, source string) (func(), bool, error) {
+	key := "lake_export:" + source
+	conn, err := r.db.Acquire(ctx)
+	if err != nil {
+		return nil, false, err
+	}
+	var locked bool
+	if err := conn.QueryRow(ctx, `SELECT pg_try_advisory_lock(hashtext($1))`, key).Scan(&locked); err != nil || !locked {
+		conn.Release()
+		return nil, false, err
+	}
+	unlock := func() {
+		// A connection that may still hold the lock is closed rather than returned to the pool.
+		if _, err := conn.Exec(context.Background(), `SELECT pg_advisory_unlock(hashtext($1))`, key); err != nil {
+			conn.Conn().Close(context.Background())
+		}
+		conn.Release()
+	}
+	return unlock, true, nil
+}
+
+func (r *lakeExportRepositorySQL) GetWatermark(ctx context.Context, source string) (*time.Time, error) {
+	var mark time.Time
+	err := r.db.QueryRow(ctx, `SELECT high_water_mark FROM lake_export_watermark WHERE source_table=$1`, source).Scan(&mark)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &mark, nil
+}
+
+func (r *lakeExportRepositorySQL) SetWatermark(ctx context.Context, source string, mark time.Time) error {
+	_, err := r.db.Exec(ctx, `
+		INSERT INTO lake_export_watermark (source_table, high_water_mark) VALUES ($1, $2)
+		ON CONFLICT (source_table) DO UPDATE SET high_water_mark = EXCLUDED.high_water_mark`,
+		source, mark)
+	return err
+}
+
+func (r *lakeExportRepositorySQL) Horizon(ctx context.Context, lag time.Duration) (time.Time, error) {
+	var horizon time.Time
+	err := r.db.QueryRow(ctx, `SELECT LOCALTIMESTAMP - make_interval(secs => $1)`, lag.Seconds()).Scan(&horizon)
+	return horizon, err
+}
+
+func (r *lakeExportRepositorySQL) NextWatermark(ctx context.Context, source string, after *time.Time, through time.Time, batchSize int) (time.Time, error) {
+	if !lakeExportSources[source] {
+		return time.Time{}, fmt.Errorf("unknown lake export source %s", source)
+	}
+	var mark time.Time
+	err := r.db.QueryRow(ctx, `
+		SELECT updated_dttm FROM `+source+`
+		WHERE ($1::timestamp IS NULL OR updated_dttm > $1) AND updated_dttm <= $2
+		ORDER BY updated_dttm
+		OFFSET $3 LIMIT 1`, after, through, batchSize-1).Scan(&mark)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return through, nil
+	}
+	return mark, err
+}
+
+func (r *lakeExportRepositorySQL) ListUsageTransactionDetails(ctx context.Context, after *time.Time, through time.Time) ([]dbentity.UsageTransactionDetailExport, error) {
+	rows, err := r.db.Query(ctx, `
+		SELECT utd.usage_transaction_id, ut.transaction_id, pr.name, utd.premise_id, p.code, utd.meter_id, utd.meter_name, utd.start_dttm, utd.end_dttm, utd.service_period_start_dt, utd.service_period_end_dt, utd.is_canceled, utd.is_interval, utd.consumption, utd.generation, utd.updated_dttm
+		FROM usage_transaction_detail utd
+		JOIN usage_transaction ut ON ut.id = utd.usage_transaction_id
+		JOIN power_region pr ON pr.id = utd.power_region_id
+		JOIN premise p ON p.id = utd.premise_id
+		WHERE ($1::timestamp IS NULL OR utd.updated_dttm > $1) AND utd.updated_dttm <= $2
+		ORDER BY utd.updated_dttm`, after, through)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var details []dbentity.UsageTransactionDetailExport
+	for rows.Next() {
+		var d dbentity.UsageTransactionDetailExport
+		if err := rows.Scan(&d.UsageTransactionID, &d.TransactionID, &d.PowerRegion, &d.PremiseID, &d.PremiseCode, &d.MeterID, &d.MeterName, &d.Start, &d.End, &d.ServicePeriodStart, &d.ServicePeriodEnd, &d.IsCanceled, &d.IsInterval, &d.Consumption, &d.Generation, &d.Updated); err != nil {
+			return nil, err
+		}
+		details = append(details, d)
+	}
+	return details, rows.Err()
+}
+
+func (r *lakeExportRepositorySQL) ListMeterUsageIntervals(ctx context.Context, after *time.Time, through time.Time) ([]dbentity.MeterUsageIntervalExport, error) {
+	rows, err := r.db.Query(ctx, `
+		SELECT mu.meter_id, m.name, mu.premise_id, p.code, mu.account_id, pr.name, mu.usage_transaction_id, mu.start_dttm, mu.end_dttm, mu.service_period_start_dt, mu.service_period_end_dt, mu.consumption, mu.generation, mu.is_canceled, mu.quality, mu.quality_rule, mu.original_consumption, mu.updated_dttm
+		FROM meter_usage_15_minute mu
+		JOIN meter m ON m.id = mu.meter_id
+		JOIN power_region pr ON pr.id = m.power_region_id
+		JOIN premise p ON p.id = mu.premise_id
+		WHERE ($1::timestamp IS NULL OR mu.updated_dttm > $1) AND mu.updated_dttm <= $2
+		ORDER BY mu.updated_dttm`, after, through)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var intervals []dbentity.MeterUsageIntervalExport
+	for rows.Next() {
+		var i dbentity.MeterUsageIntervalExport
+		if err := rows.Scan(&i.MeterID, &i.MeterName, &i.PremiseID, &i.PremiseCode, &i.AccountID, &i.PowerRegion, &i.UsageTransactionID, &i.Start, &i.End, &i.ServicePeriodStart, &i.ServicePeriodEnd, &i.Consumption, &i.Generation, &i.IsCanceled, &i.Quality, &i.QualityRule, &i.OriginalConsumption, &i.Updated); err != nil {
+			return nil, err
+		}
+		intervals = append(intervals, i)
+	}
+	return intervals, rows.Err()
+}
+
+func (r *lakeExportRepositorySQL) CreateRun(ctx context.Context, run *dbentity.LakeExportRun) error {
+	return r.db.QueryRow(ctx,
+		`INSERT INTO lake_export_run (id, source_table, status, low_water_mark, high_water_mark, started_dttm) VALUES ($1, $2, $3, $4, $4, now()) RETURNING status, high_water_mark, started_dttm, created_dttm, updated_dttm`,
+		run.ID, run.SourceTable, dbentity.LakeExportRunStatusRunning, run.LowWaterMark,
+	).Scan(&run.Status, &run.HighWaterMark, &run.Started, &run.Created, &run.Updated)
+}
+
+func (r *lakeExportRepositorySQL) UpdateRun(ctx context.Context, run *dbentity.LakeExportRun) error {
+	return r.db.QueryRow(ctx, `
+		UPDATE lake_export_run SET status=$1, high_water_mark=$2, row_count=$3, file_count=$4, snapshot_id=$5, error=$6,
+			finished_dttm = CASE WHEN $1 = 'RUNNING' THEN NULL ELSE now() END
+		WHERE id=$7
+		RETURNING finished_dttm, updated_dttm`,
+		run.Status, run.HighWaterMark, run.RowCount, run.FileCount, run.SnapshotID, run.Error, run.ID,
+	).Scan(&run.Finished, &run.Updated)
+}
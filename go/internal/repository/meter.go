@@ -14,7 +14,9 @@ type MeterRepository interface {
 	Update(ctx context.Context, m *dbentity.Meter) error
 	Delete(ctx context.Context, id string) error
 	List(ctx context.Context) ([]dbentity.Meter, error)
-	GetNameIDMap(ctx context.Context, names []string) (map[string]string, error)
+	GetNameIDMap(ctx context.Context, powerRegionID string, names []string) (map[string]string, error)
+	GetIntervalMinutesMap(ctx context.Context, powerRegionID string, names []string) (map[string]int, error)
+	Provision(ctx context.Context, m *dbentity.Meter) error
 }
 
 type meterRepositorySQL struct {
@@ -27,16 +29,16 @@ func NewMeterRepository(db *pgxpool.Pool) MeterRepository {
 
 func (r *meterRepositorySQL) Create(ctx context.Context, m *dbentity.Meter) error {
 	_, err := r.db.Exec(ctx,
-		`INSERT INTO meter (id, account_id, premise_id, power_region_id, name, type, load_profile, cycle_code, active) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)`,
-		m.ID, m.AccountID, m.PremiseID, m.PowerRegionID, m.Name, m.Type, m.LoadProfile, m.CycleCode, m.Active,
+		`INSERT INTO meter (id, premise_id, power_region_id, name, type, load_profile, cycle_code, is_active, is_auto_provisioned, interval_minutes, max_demand_kw) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, COALESCE(NULLIF($10, 0), 15), $11)`,
+		m.ID, m.PremiseID, m.PowerRegionID, m.Name, m.Type, m.LoadProfile, m.CycleCode, m.Active, m.AutoProvisioned, m.IntervalMinutes, m.MaxDemandKW,
 	)
 	return err
 }
 
 func (r *meterRepositorySQL) GetByID(ctx context.Context, id string) (*dbentity.Meter, error) {
 	var m dbentity.Meter
-	err := r.db.QueryRow(ctx, `SELECT id, account_id, premise_id, power_region_id, name, type, load_profile, cycle_code, active, created, updated FROM meter WHERE id=$1`, id).
-		Scan(&m.ID, &m.AccountID, &m.PremiseID, &m.PowerRegionID, &m.Name, &m.Type, &m.LoadProfile, &m.CycleCode, &m.Active, &m.Created, &m.Updated)
+	err := r.db.QueryRow(ctx, `SELECT id, COALESCE(premise_id::text, ''), power_region_id, name, COALESCE(type, ''), COALESCE(load_profile, ''), COALESCE(cycle_code, ''), is_active, is_auto_provisioned, interval_minutes, max_demand_kw, created_dttm, updated_dttm FROM meter WHERE id=$1`, id).
+		Scan(&m.ID, &m.PremiseID, &m.PowerRegionID, &m.Name, &m.Type, &m.LoadProfile, &m.CycleCode, &m.Active, &m.AutoProvisioned, &m.IntervalMinutes, &m.MaxDemandKW, &m.Created, &m.Updated)
 	if err != nil {
 		return nil, err
 	}
@@ -45,8 +47,8 @@ func (r *meterRepositorySQL) GetByID(ctx context.Context, id string) (*dbentity.
 
 func (r *meterRepositorySQL) GetByName(ctx context.Context, powerRegionID string, name string) (*dbentity.Meter, error) {
 	var m dbentity.Meter
-	err := r.db.QueryRow(ctx, `SELECT id, account_id, premise_id, power_region_id, name, type, load_profile, cycle_code, active, created, updated FROM meter WHERE name=$1 AND power_region_id=$2`, name, powerRegionID).
-		Scan(&m.ID, &m.AccountID, &m.PremiseID, &m.PowerRegionID, &m.Name, &m.Type, &m.LoadProfile, &m.CycleCode, &m.Active, &m.Created, &m.Updated)
+	err := r.db.QueryRow(ctx, `SELECT id, COALESCE(premise_id::text, ''), power_region_id, name, COALESCE(type, ''), COALESCE(load_profile, ''), COALESCE(cycle_code, ''), is_active, is_auto_provisioned, interval_minutes, max_demand_kw, created_dttm, updated_dttm FROM meter WHERE name=$1 AND power_region_id=$2`, name, powerRegionID).
+		Scan(&m.ID, &m.PremiseID, &m.PowerRegionID, &m.Name, &m.Type, &m.LoadProfile, &m.CycleCode, &m.Active, &m.AutoProvisioned, &m.IntervalMinutes, &m.MaxDemandKW, &m.Created, &m.Updated)
 	if err != nil {
 		return nil, err
 	}
@@ -54,7 +56,7 @@ func (r *meterRepositorySQL) GetByName(ctx context.Context, powerRegionID string
 }
 
 func (r *meterRepositorySQL) Update(ctx context.Context, m *dbentity.Meter) error {
-	_, err := r.db.Exec(ctx, `UPDATE meter SET account_id=$1, premise_id=$2, power_region_id=$3, name=$4, type=$5, load_profile=$6, cycle_code=$7, active=$8 WHERE id=$9`, m.AccountID, m.PremiseID, m.PowerRegionID, m.Name, m.Type, m.LoadProfile, m.CycleCode, m.Active, m.ID)
+	_, err := r.db.Exec(ctx, `UPDATE meter SET premise_id=$1, power_region_id=$2, name=$3, type=$4, load_profile=$5, cycle_code=$6, is_active=$7, interval_minutes=COALESCE(NULLIF($8, 0), interval_minutes), max_demand_kw=$9 WHERE id=$10`, m.PremiseID, m.PowerRegionID, m.Name, m.Type, m.LoadProfile, m.CycleCode, m.Active, m.IntervalMinutes, m.MaxDemandKW, m.ID)
 	return err
 }
 
@@ -64,7 +66,7 @@ func (r *meterRepositorySQL) Delete(ctx context.Context, id string) error {
 }
 
 func (r *meterRepositorySQL) List(ctx context.Context) ([]dbentity.Meter, error) {
-	rows, err := r.db.Query(ctx, `SELECT id, account_id, premise_id, power_region_id, name, type, load_profile, cycle_code, active, created, updated FROM meter`)
+	rows, err := r.db.Query(ctx, `SELECT id, COALESCE(premise_id::text, ''), power_region_id, name, COALESCE(type, ''), COALESCE(load_profile, ''), COALESCE(cycle_code, ''), is_active, is_auto_provisioned, interval_minutes, max_demand_kw, created_dttm, updated_dttm FROM meter`)
 	if err != nil {
 		return nil, err
 	}
@@ -72,7 +74,7 @@ func (r *meterRepositorySQL) List(ctx context.Context) ([]dbentity.Meter, error)
 	var meters []dbentity.Meter
 	for rows.Next() {
 		var m dbentity.Meter
-		if err := rows.Scan(&m.ID, &m.AccountID, &m.PremiseID, &m.PowerRegionID, &m.Name, &m.Type, &m.LoadProfile, &m.CycleCode, &m.Active, &m.Created, &m.Updated); err != nil {
+		if err := rows.Scan(&m.ID, &m.PremiseID, &m.PowerRegionID, &m.Name, &m.Type, &m.LoadProfile, &m.CycleCode, &m.Active, &m.AutoProvisioned, &m.IntervalMinutes, &m.MaxDemandKW, &m.Created, &m.Updated); err != nil {
 			return nil, err
 		}
 		meters = append(meters, m)
@@ -80,14 +82,14 @@ func (r *meterRepositorySQL) List(ctx context.Context) ([]dbentity.Meter, error)
 	return meters, nil
 }
 
-func (r *meterRepositorySQL) GetNameIDMap(ctx context.Context, names []string) (map[string]string, error) {
+func (r *meterRepositorySQL) GetNameIDMap(ctx context.Context, powerRegionID string, names []string) (map[string]string, error) {
 	if len(names) == 0 {
 		return map[string]string{}, nil
 	}
 
-	// Build the SQL IN clause dynamically
-	query := `SELECT DISTINCT name, id FROM meter WHERE name = ANY($1)`
-	rows, err := r.db.Query(ctx, query, names)
+	// Meter names are only unique within a power region
+	query := `SELECT name, id FROM meter WHERE power_region_id = $1 AND name = ANY($2)`
+	rows, err := r.db.Query(ctx, query, powerRegionID, names)
 	if err != nil {
 		return nil, err
 	}
@@ -102,3 +104,43 @@ func (r *meterRepositorySQL) GetNameIDMap(ctx context.Context, names []string) (
 	}
 	return result, nil
 }
+
+// GetIntervalMinutesMap returns the interval length of each named meter in the power region.
+func (r *meterRepositorySQL) GetIntervalMinutesMap(ctx context.Context, powerRegionID string, names []string) (map[string]int, error) {
+	result := make(map[string]int)
+	if len(names) == 0 {
+		return result, nil
+	}
+	rows, err := r.db.Query(ctx, `SELECT name, interval_minutes FROM meter WHERE power_region_id = $1 AND name = ANY($2)`, powerRegionID, names)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var name string
+		var minutes int
+		if err := rows.Scan(&name, &minutes); err != nil {
+			return nil, err
+		}
+		result[name] = minutes
+	}
+	return result, rows.Err()
+}
+
+// Provision creates the meter unless one with the same name already exists in the power region and
+// loads whichever row won into m.
+func (r *meterRepositorySQL) Provision(ctx context.Context, m *dbentity.Meter) error {
+	_, err := r.db.Exec(ctx,
+		`INSERT INTO meter (id, premise_id, power_region_id, name, type, is_active, is_auto_provisioned) VALUES ($1, $2, $3, $4, NULLIF($5, ''), TRUE, TRUE) ON CONFLICT (name, power_region_id) DO NOTHING`,
+		m.ID, m.PremiseID, m.PowerRegionID, m.Name, m.Type,
+	)
+	if err != nil {
+		return err
+	}
+	existing, err := r.GetByName(ctx, m.PowerRegionID, m.Name)
+	if err != nil {
+		return err
+	}
+	*m = *existing
+	return nil
+}
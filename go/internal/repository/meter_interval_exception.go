@@ -0,0 +1,132 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"time"
+	dbentity "usage-lakehouse/internal/db/entity"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// MeterIntervalExceptionFilter selects open exceptions whose interval starts within the From..To local
+// dates (inclusive) of the meter's power region.
+type MeterIntervalExceptionFilter struct {
+	PremiseID *string
+	MeterID   *string
+	Kind      *string
+	From      *time.Time
+	To        *time.Time
+	Limit     int
+}
+
+type MeterIntervalExceptionRepository interface {
+	List(ctx context.Context, filter MeterIntervalExceptionFilter) ([]dbentity.MeterIntervalException, error)
+	ListIncompleteMeters(ctx context.Context, filter MeterIntervalExceptionFilter) ([]dbentity.IncompleteMeter, error)
+}
+
+type meterIntervalExceptionRepositorySQL struct {
+	db *pgxpool.Pool
+}
+
+func NewMeterIntervalExceptionRepository(db *pgxpool.Pool) MeterIntervalExceptionRepository {
+	return &meterIntervalExceptionRepositorySQL{db: db}
+}
+
+// openMeterIntervalExceptions joins the exceptions of active transactions. A gap stops being open once
+// another transaction fills the interval in meter_usage_15_minute.
+const openMeterIntervalExceptions = `
+	FROM meter_interval_exception mie
+	JOIN usage_transaction ut ON ut.id = mie.usage_transaction_id
+	JOIN power_region pr ON pr.id = mie.power_region_id
+	WHERE NOT mie.is_canceled
+	AND (mie.kind <> 'GAP' OR NOT EXISTS (
+		SELECT 1 FROM meter_usage_15_minute mu
+		WHERE mu.meter_id = mie.meter_id AND mu.start_dttm = mie.start_dttm AND NOT mu.is_canceled
+	))`
+
+func meterIntervalExceptionConditions(filter MeterIntervalExceptionFilter) (string, []any) {
+	var query string
+	var args []any
+	if filter.PremiseID != nil {
+		args = append(args, *filter.PremiseID)
+		query += fmt.Sprintf(` AND mie.premise_id = $%d`, len(args))
+	}
+	if filter.MeterID != nil {
+		args = append(args, *filter.MeterID)
+		query += fmt.Sprintf(` AND mie.meter_id = $%d`, len(args))
+	}
+	if filter.Kind != nil {
+		args = append(args, *filter.Kind)
+		query += fmt.Sprintf(` AND mie.kind = $%d`, len(args))
+	}
+	if filter.From != nil {
+		args = append(args, *filter.From)
+		query += fmt.Sprintf(` AND (mie.start_dttm AT TIME ZONE 'UTC' AT TIME ZONE pr.time_zone)::date >= $%d`, len(args))
+	}
+	if filter.To != nil {
+		args = append(args, *filter.To)
+		query += fmt.Sprintf(` AND (mie.start_dttm AT TIME ZONE 'UTC' AT TIME ZONE pr.time_zone)::date <= $%d`, len(args))
+	}
+	return query, args
+}
+
+func (r *meterIntervalExceptionRepositorySQL) List(ctx context.Context, filter MeterIntervalExceptionFilter) ([]dbentity.MeterIntervalException, error) {
+	conditions, args := meterIntervalExceptionConditions(filter)
+	query := `
+	SELECT mie.id, mie.usage_transaction_id, ut.transaction_id, mie.premise_id, mie.power_region_id, mie.meter_id, mie.meter_name, mie.channel, mie.kind,
+		mie.start_dttm, mie.end_dttm, mie.service_period_start_dt, mie.service_period_end_dt, mie.reported_count, mie.is_canceled, mie.created_dttm, mie.updated_dttm` +
+		openMeterIntervalExceptions + conditions + ` ORDER BY mie.meter_name, mie.start_dttm`
+	if filter.Limit > 0 {
+		args = append(args, filter.Limit)
+		query += fmt.Sprintf(` LIMIT $%d`, len(args))
+	}
+	rows, err := r.db.Query(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var exceptions []dbentity.MeterIntervalException
+	for rows.Next() {
+		var e dbentity.MeterIntervalException
+		if err := rows.Scan(&e.ID, &e.UsageTransactionID, &e.TransactionID, &e.PremiseID, &e.PowerRegionID, &e.MeterID, &e.MeterName, &e.Channel, &e.Kind,
+			&e.Start, &e.End, &e.ServicePeriodStart, &e.ServicePeriodEnd, &e.ReportedCount, &e.IsCanceled, &e.Created, &e.Updated); err != nil {
+			return nil, err
+		}
+		exceptions = append(exceptions, e)
+	}
+	return exceptions, rows.Err()
+}
+
+// ListIncompleteMeters totals open exceptions per meter, most missing intervals first.
+func (r *meterIntervalExceptionRepositorySQL) ListIncompleteMeters(ctx context.Context, filter MeterIntervalExceptionFilter) ([]dbentity.IncompleteMeter, error) {
+	conditions, args := meterIntervalExceptionConditions(filter)
+	query := `
+	SELECT mie.meter_id, mie.meter_name, mie.premise_id, mie.power_region_id,
+		COUNT(DISTINCT mie.start_dttm) FILTER (WHERE mie.kind = 'GAP'),
+		COUNT(DISTINCT mie.start_dttm) FILTER (WHERE mie.kind = 'DUPLICATE'),
+		COUNT(DISTINCT mie.start_dttm) FILTER (WHERE mie.kind = 'OUT_OF_RANGE'),
+		MIN(mie.start_dttm) FILTER (WHERE mie.kind = 'GAP'),
+		MAX(mie.start_dttm) FILTER (WHERE mie.kind = 'GAP')` +
+		openMeterIntervalExceptions + conditions + `
+	GROUP BY mie.meter_id, mie.meter_name, mie.premise_id, mie.power_region_id
+	ORDER BY 5 DESC, mie.meter_name`
+	if filter.Limit > 0 {
+		args = append(args, filter.Limit)
+		query += fmt.Sprintf(` LIMIT $%d`, len(args))
+	}
+	rows, err := r.db.Query(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var meters []dbentity.IncompleteMeter
+	for rows.Next() {
+		var m dbentity.IncompleteMeter
+		if err := rows.Scan(&m.MeterID, &m.MeterName, &m.PremiseID, &m.PowerRegionID, &m.MissingIntervals, &m.DuplicateIntervals, &m.OutOfRangeIntervals, &m.FirstMissing, &m.LastMissing); err != nil {
+			return nil, err
+		}
+		meters = append(meters, m)
+	}
+	return meters, rows.Err()
+}
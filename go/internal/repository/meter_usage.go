@@ -0,0 +1,166 @@
+package repository
+
+import (
+	"context"
+	"time"
+	dbentity "usage-lakehouse/internal/db/entity"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type MeterUsageRepository interface {
+	// ListIntervals returns the meter's intervals starting in [from, to), both UTC instants.
+	ListIntervals(ctx context.Context, meterID string, from time.Time, to time.Time) ([]dbentity.MeterUsageInterval, error)
+	// ListChannelIntervals returns the meter's readings of channel, or of every channel when it is
+	// empty, starting in [from, to), both UTC instants.
+	ListChannelIntervals(ctx context.Context, meterID string, channel string, from time.Time, to time.Time) ([]dbentity.MeterUsageChannelInterval, error)
+	// ListSummaries returns the meter's active interval summaries whose service period overlaps the
+	// local dates from..to.
+	ListSummaries(ctx context.Context, meterID string, from time.Time, to time.Time) ([]dbentity.UsageTransactionSummary, error)
+	// ListVEEFlags returns the VEE flags on the meter overlapping [from, to), both UTC instants.
+	ListVEEFlags(ctx context.Context, meterID string, from time.Time, to time.Time) ([]dbentity.MeterUsageVEEFlag, error)
+	// ApplyVEE replaces the meter's VEE flags overlapping [from, to) with flags and writes the
+	// estimated and edited intervals. An estimate never overwrites an active actual read.
+	ApplyVEE(ctx context.Context, meterID string, from time.Time, to time.Time, flags []dbentity.MeterUsageVEEFlag, intervals []dbentity.MeterUsageInterval) error
+}
+
+// upsertVEEInterval writes one estimated or edited interval. Edits keep the actual read they replace
+// in original_consumption; estimates only fill intervals with no active actual read.
+const upsertVEEInterval = `
+	INSERT INTO meter_usage_15_minute (start_dttm, end_dttm, service_period_start_dt, service_period_end_dt, is_canceled, premise_id, meter_id, account_id, consumption, quality, quality_rule)
+	VALUES ($1, $2, $3, $4, FALSE, $5, $6, $7, $8, $9, $10)
+	ON CONFLICT (start_dttm, meter_id) DO UPDATE SET
+		original_consumption = CASE
+			WHEN meter_usage_15_minute.quality = 'ACTUAL' AND NOT meter_usage_15_minute.is_canceled THEN meter_usage_15_minute.consumption
+			ELSE meter_usage_15_minute.original_consumption
+		END,
+		is_canceled = FALSE,
+		consumption = EXCLUDED.consumption,
+		quality = EXCLUDED.quality,
+		quality_rule = EXCLUDED.quality_rule
+	WHERE meter_usage_15_minute.quality <> 'ACTUAL' OR meter_usage_15_minute.is_canceled OR EXCLUDED.quality = 'EDITED'`
+
+type meterUsageRepositorySQL struct {
+	db *pgxpool.Pool
+}
+
+func NewMeterUsageRepository(db *pgxpool.Pool) MeterUsageRepository {
+	return &meterUsageRepositorySQL{db: db}
+}
+
+func (r *meterUsageRepositorySQL) ListIntervals(ctx context.Context, meterID string, from time.Time, to time.Time) ([]dbentity.MeterUsageInterval, error) {
+	rows, err := r.db.Query(ctx, `
+		SELECT meter_id, premise_id, account_id, usage_transaction_id, start_dttm, end_dttm, service_period_start_dt, service_period_end_dt, consumption, generation, is_canceled, quality, quality_rule, original_consumption
+		FROM meter_usage_15_minute
+		WHERE meter_id = $1 AND start_dttm >= $2 AND start_dttm < $3
+		ORDER BY start_dttm`, meterID, from.UTC(), to.UTC())
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var intervals []dbentity.MeterUsageInterval
+	for rows.Next() {
+		var i dbentity.MeterUsageInterval
+		if err := rows.Scan(&i.MeterID, &i.PremiseID, &i.AccountID, &i.UsageTransactionID, &i.Start, &i.End, &i.ServicePeriodStart, &i.ServicePeriodEnd, &i.Consumption, &i.Generation, &i.IsCanceled, &i.Quality, &i.QualityRule, &i.OriginalConsumption); err != nil {
+			return nil, err
+		}
+		intervals = append(intervals, i)
+	}
+	return intervals, rows.Err()
+}
+
+func (r *meterUsageRepositorySQL) ListChannelIntervals(ctx context.Context, meterID string, channel string, from time.Time, to time.Time) ([]dbentity.MeterUsageChannelInterval, error) {
+	rows, err := r.db.Query(ctx, `
+		SELECT meter_id, channel, power_region_id, premise_id, usage_transaction_id, start_dttm, end_dttm, unit_of_measure, direction, quantity, is_canceled
+		FROM meter_usage_channel
+		WHERE meter_id = $1 AND ($2 = '' OR channel = $2) AND start_dttm >= $3 AND start_dttm < $4
+		ORDER BY channel, start_dttm`, meterID, channel, from.UTC(), to.UTC())
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var intervals []dbentity.MeterUsageChannelInterval
+	for rows.Next() {
+		var i dbentity.MeterUsageChannelInterval
+		if err := rows.Scan(&i.MeterID, &i.Channel, &i.PowerRegionID, &i.PremiseID, &i.UsageTransactionID, &i.Start, &i.End, &i.UnitOfMeasure, &i.Direction, &i.Quantity, &i.IsCanceled); err != nil {
+			return nil, err
+		}
+		intervals = append(intervals, i)
+	}
+	return intervals, rows.Err()
+}
+
+func (r *meterUsageRepositorySQL) ListSummaries(ctx context.Context, meterID string, from time.Time, to time.Time) ([]dbentity.UsageTransactionSummary, error) {
+	rows, err := r.db.Query(ctx, `
+		SELECT id, usage_transaction_id, product_transfer_detail_type_code, premise_id, meter_id, meter_name, channel, service_period_start_dt, service_period_end_dt, quantity, interval_quantity, is_reconciled, is_canceled, created_dttm, updated_dttm
+		FROM usage_transaction_summary
+		WHERE meter_id = $1 AND NOT is_canceled AND service_period_start_dt <= $3 AND service_period_end_dt >= $2
+		ORDER BY service_period_start_dt`, meterID, from, to)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var summaries []dbentity.UsageTransactionSummary
+	for rows.Next() {
+		var s dbentity.UsageTransactionSummary
+		if err := rows.Scan(&s.ID, &s.UsageTransactionID, &s.TransferType, &s.PremiseID, &s.MeterID, &s.MeterName, &s.Channel, &s.ServicePeriodStart, &s.ServicePeriodEnd, &s.Quantity, &s.IntervalQuantity, &s.IsReconciled, &s.IsCanceled, &s.Created, &s.Updated); err != nil {
+			return nil, err
+		}
+		summaries = append(summaries, s)
+	}
+	return summaries, rows.Err()
+}
+
+func (r *meterUsageRepositorySQL) ListVEEFlags(ctx context.Context, meterID string, from time.Time, to time.Time) ([]dbentity.MeterUsageVEEFlag, error) {
+	rows, err := r.db.Query(ctx, `
+		SELECT id, meter_id, rule, start_dttm, end_dttm, value, message, is_replaced, created_dttm, updated_dttm
+		FROM meter_usage_vee_flag
+		WHERE meter_id = $1 AND start_dttm < $3 AND end_dttm > $2
+		ORDER BY start_dttm, rule`, meterID, from.UTC(), to.UTC())
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var flags []dbentity.MeterUsageVEEFlag
+	for rows.Next() {
+		var f dbentity.MeterUsageVEEFlag
+		if err := rows.Scan(&f.ID, &f.MeterID, &f.Rule, &f.Start, &f.End, &f.Value, &f.Message, &f.IsReplaced, &f.Created, &f.Updated); err != nil {
+			return nil, err
+		}
+		flags = append(flags, f)
+	}
+	return flags, rows.Err()
+}
+
+func (r *meterUsageRepositorySQL) ApplyVEE(ctx context.Context, meterID string, from time.Time, to time.Time, flags []dbentity.MeterUsageVEEFlag, intervals []dbentity.MeterUsageInterval) (err error) {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			tx.Rollback(ctx)
+		} else {
+			err = tx.Commit(ctx)
+		}
+	}()
+	if _, err = tx.Exec(ctx, `DELETE FROM meter_usage_vee_flag WHERE meter_id = $1 AND start_dttm < $3 AND end_dttm > $2`, meterID, from.UTC(), to.UTC()); err != nil {
+		return err
+	}
+	for _, f := range flags {
+		_, err = tx.Exec(ctx,
+			`INSERT INTO meter_usage_vee_flag (id, meter_id, rule, start_dttm, end_dttm, value, message, is_replaced) VALUES ($1,$2,$3,$4,$5,$6,$7,$8)`,
+			f.ID, f.MeterID, f.Rule, f.Start.UTC(), f.End.UTC(), f.Value, f.Message, f.IsReplaced,
+		)
+		if err != nil {
+			return err
+		}
+	}
+	for _, i := range intervals {
+		_, err = tx.Exec(ctx, upsertVEEInterval, i.Start.UTC(), i.End.UTC(), i.ServicePeriodStart, i.ServicePeriodEnd, i.PremiseID, i.MeterID, i.AccountID, i.Consumption, i.Quality, i.QualityRule)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -26,16 +26,16 @@ func NewPowerRegionRepository(db *pgxpool.Pool) PowerRegionRepository {
 
 func (r *powerRegionRepositorySQL) Create(ctx context.Context, p *dbentity.PowerRegion) error {
 	_, err := r.db.Exec(ctx,
-		`INSERT INTO power_region (id, name) VALUES ($1, $2)`,
-		p.ID, p.Name,
+		`INSERT INTO power_region (id, name, provisioning_policy, time_zone) VALUES ($1, $2, COALESCE(NULLIF($3, ''), 'REJECT'), COALESCE(NULLIF($4, ''), 'UTC'))`,
+		p.ID, p.Name, p.ProvisioningPolicy, p.TimeZone,
 	)
 	return err
 }
 
 func (r *powerRegionRepositorySQL) GetByID(ctx context.Context, id string) (*dbentity.PowerRegion, error) {
 	var p dbentity.PowerRegion
-	err := r.db.QueryRow(ctx, `SELECT id, name, created_dttm, updated_dttm FROM power_region WHERE id=$1`, id).
-		Scan(&p.ID, &p.Name, &p.Created, &p.Updated)
+	err := r.db.QueryRow(ctx, `SELECT id, name, provisioning_policy, time_zone, created_dttm, updated_dttm FROM power_region WHERE id=$1`, id).
+		Scan(&p.ID, &p.Name, &p.ProvisioningPolicy, &p.TimeZone, &p.Created, &p.Updated)
 	if err != nil {
 		return nil, err
 	}
@@ -44,8 +44,8 @@ func (r *powerRegionRepositorySQL) GetByID(ctx context.Context, id string) (*dbe
 
 func (r *powerRegionRepositorySQL) GetByName(ctx context.Context, name string) (*dbentity.PowerRegion, error) {
 	var p dbentity.PowerRegion
-	err := r.db.QueryRow(ctx, `SELECT id, name, created_dttm, updated_dttm FROM power_region WHERE name=$1`, name).
-		Scan(&p.ID, &p.Name, &p.Created, &p.Updated)
+	err := r.db.QueryRow(ctx, `SELECT id, name, provisioning_policy, time_zone, created_dttm, updated_dttm FROM power_region WHERE name=$1`, name).
+		Scan(&p.ID, &p.Name, &p.ProvisioningPolicy, &p.TimeZone, &p.Created, &p.Updated)
 	if err != nil {
 		return nil, err
 	}
@@ -53,7 +53,7 @@ func (r *powerRegionRepositorySQL) GetByName(ctx context.Context, name string) (
 }
 
 func (r *powerRegionRepositorySQL) Update(ctx context.Context, p *dbentity.PowerRegion) error {
-	_, err := r.db.Exec(ctx, `UPDATE power_region SET name=$1 WHERE id=$2`, p.Name, p.ID)
+	_, err := r.db.Exec(ctx, `UPDATE power_region SET name=$1, provisioning_policy=$2, time_zone=$3 WHERE id=$4`, p.Name, p.ProvisioningPolicy, p.TimeZone, p.ID)
 	return err
 }
 
@@ -63,7 +63,7 @@ func (r *powerRegionRepositorySQL) Delete(ctx context.Context, id string) error
 }
 
 func (r *powerRegionRepositorySQL) List(ctx context.Context) ([]dbentity.PowerRegion, error) {
-	rows, err := r.db.Query(ctx, `SELECT id, name, created_dttm, updated_dttm FROM power_region`)
+	rows, err := r.db.Query(ctx, `SELECT id, name, provisioning_policy, time_zone, created_dttm, updated_dttm FROM power_region`)
 	if err != nil {
 		return nil, err
 	}
@@ -71,7 +71,7 @@ func (r *powerRegionRepositorySQL) List(ctx context.Context) ([]dbentity.PowerRe
 	var regions []dbentity.PowerRegion
 	for rows.Next() {
 		var p dbentity.PowerRegion
-		if err := rows.Scan(&p.ID, &p.Name, &p.Created, &p.Updated); err != nil {
+		if err := rows.Scan(&p.ID, &p.Name, &p.ProvisioningPolicy, &p.TimeZone, &p.Created, &p.Updated); err != nil {
 			return nil, err
 		}
 		regions = append(regions, p)
@@ -0,0 +1,80 @@
+package repository
+
+import (
+	"context"
+	dbentity "usage-lakehouse/internal/db/entity"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type PowerRegionChannelRepository interface {
+	// Upsert registers the channel or replaces its registration.
+	Upsert(ctx context.Context, c *dbentity.PowerRegionChannel) error
+	Get(ctx context.Context, powerRegionID string, code string) (*dbentity.PowerRegionChannel, error)
+	Delete(ctx context.Context, powerRegionID string, code string) error
+	List(ctx context.Context, powerRegionID string) ([]dbentity.PowerRegionChannel, error)
+	MapByCode(ctx context.Context, powerRegionID string) (map[string]dbentity.PowerRegionChannel, error)
+}
+
+type powerRegionChannelRepositorySQL struct {
+	db *pgxpool.Pool
+}
+
+func NewPowerRegionChannelRepository(db *pgxpool.Pool) PowerRegionChannelRepository {
+	return &powerRegionChannelRepositorySQL{db: db}
+}
+
+const powerRegionChannelColumns = `power_region_id, code, name, unit_of_measure, direction, description, created_dttm, updated_dttm`
+
+func (r *powerRegionChannelRepositorySQL) Upsert(ctx context.Context, c *dbentity.PowerRegionChannel) error {
+	return r.db.QueryRow(ctx, `
+		INSERT INTO power_region_channel (power_region_id, code, name, unit_of_measure, direction, description) VALUES ($1, $2, $3, $4, $5, $6)
+		ON CONFLICT (power_region_id, code) DO UPDATE SET name = EXCLUDED.name, unit_of_measure = EXCLUDED.unit_of_measure, direction = EXCLUDED.direction, description = EXCLUDED.description
+		RETURNING created_dttm, updated_dttm`,
+		c.PowerRegionID, c.Code, c.Name, c.UnitOfMeasure, c.Direction, c.Description,
+	).Scan(&c.Created, &c.Updated)
+}
+
+func (r *powerRegionChannelRepositorySQL) Get(ctx context.Context, powerRegionID string, code string) (*dbentity.PowerRegionChannel, error) {
+	var c dbentity.PowerRegionChannel
+	err := r.db.QueryRow(ctx, `SELECT `+powerRegionChannelColumns+` FROM power_region_channel WHERE power_region_id=$1 AND code=$2`, powerRegionID, code).
+		Scan(&c.PowerRegionID, &c.Code, &c.Name, &c.UnitOfMeasure, &c.Direction, &c.Description, &c.Created, &c.Updated)
+	if err != nil {
+		return nil, err
+	}
+	return &c, nil
+}
+
+func (r *powerRegionChannelRepositorySQL) Delete(ctx context.Context, powerRegionID string, code string) error {
+	_, err := r.db.Exec(ctx, `DELETE FROM power_region_channel WHERE power_region_id=$1 AND code=$2`, powerRegionID, code)
+	return err
+}
+
+func (r *powerRegionChannelRepositorySQL) List(ctx context.Context, powerRegionID string) ([]dbentity.PowerRegionChannel, error) {
+	rows, err := r.db.Query(ctx, `SELECT `+powerRegionChannelColumns+` FROM power_region_channel WHERE power_region_id=$1 ORDER BY code`, powerRegionID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var channels []dbentity.PowerRegionChannel
+	for rows.Next() {
+		var c dbentity.PowerRegionChannel
+		if err := rows.Scan(&c.PowerRegionID, &c.Code, &c.Name, &c.UnitOfMeasure, &c.Direction, &c.Description, &c.Created, &c.Updated); err != nil {
+			return nil, err
+		}
+		channels = append(channels, c)
+	}
+	return channels, rows.Err()
+}
+
+func (r *powerRegionChannelRepositorySQL) MapByCode(ctx context.Context, powerRegionID string) (map[string]dbentity.PowerRegionChannel, error) {
+	channels, err := r.List(ctx, powerRegionID)
+	if err != nil {
+		return nil, err
+	}
+	result := make(map[string]dbentity.PowerRegionChannel, len(channels))
+	for _, c := range channels {
+		result[c.Code] = c
+	}
+	return result, nil
+}
@@ -13,7 +13,7 @@ type PowerRegionUsageTransactionProductTransferDetailTypeRepository interface {
 	Update(ctx context.Context, t *dbentity.PowerRegionUsageTransactionProductTransferDetailType) error
 	Delete(ctx context.Context, code string) error
 	List(ctx context.Context) ([]dbentity.PowerRegionUsageTransactionProductTransferDetailType, error)
-	MapByCode(ctx context.Context) (map[string]dbentity.PowerRegionUsageTransactionProductTransferDetailType, error)
+	MapByCode(ctx context.Context, powerRegionID string) (map[string]dbentity.PowerRegionUsageTransactionProductTransferDetailType, error)
 }
 
 type powerRegionUsageTransactionProductTransferDetailTypeRepositorySQL struct {
@@ -26,7 +26,7 @@ func NewPowerRegionUsageTransactionProductTransferDetailTypeRepository(db *pgxpo
 
 func (r *powerRegionUsageTransactionProductTransferDetailTypeRepositorySQL) Create(ctx context.Context, t *dbentity.PowerRegionUsageTransactionProductTransferDetailType) error {
 	_, err := r.db.Exec(ctx,
-		`INSERT INTO power_region_usage_transaction_product_transfer_detail_type (code, power_region_id, interval, meter, summary, name, description) VALUES ($1, $2, $3, $4, $5, $6, $7)`,
+		`INSERT INTO power_region_usage_transaction_product_transfer_detail_type (code, power_region_id, is_interval, is_meter, is_summary, name, description) VALUES ($1, $2, $3, $4, $5, $6, $7)`,
 		t.Code, t.PowerRegionID, t.Interval, t.Meter, t.Summary, t.Name, t.Description,
 	)
 	return err
@@ -34,7 +34,7 @@ func (r *powerRegionUsageTransactionProductTransferDetailTypeRepositorySQL) Crea
 
 func (r *powerRegionUsageTransactionProductTransferDetailTypeRepositorySQL) GetByCode(ctx context.Context, code string) (*dbentity.PowerRegionUsageTransactionProductTransferDetailType, error) {
 	var t dbentity.PowerRegionUsageTransactionProductTransferDetailType
-	err := r.db.QueryRow(ctx, `SELECT code, power_region_id, interval, meter, summary, name, description, created, updated FROM power_region_usage_transaction_product_transfer_detail_type WHERE code=$1`, code).
+	err := r.db.QueryRow(ctx, `SELECT code, power_region_id, is_interval, is_meter, is_summary, name, description, created_dttm, updated_dttm FROM power_region_usage_transaction_product_transfer_detail_type WHERE code=$1`, code).
 		Scan(&t.Code, &t.PowerRegionID, &t.Interval, &t.Meter, &t.Summary, &t.Name, &t.Description, &t.Created, &t.Updated)
 	if err != nil {
 		return nil, err
@@ -43,7 +43,7 @@ func (r *powerRegionUsageTransactionProductTransferDetailTypeRepositorySQL) GetB
 }
 
 func (r *powerRegionUsageTransactionProductTransferDetailTypeRepositorySQL) Update(ctx context.Context, t *dbentity.PowerRegionUsageTransactionProductTransferDetailType) error {
-	_, err := r.db.Exec(ctx, `UPDATE power_region_usage_transaction_product_transfer_detail_type SET power_region_id=$1, interval=$2, meter=$3, summary=$4, name=$5, description=$6 WHERE code=$7`, t.PowerRegionID, t.Interval, t.Meter, t.Summary, t.Name, t.Description, t.Code)
+	_, err := r.db.Exec(ctx, `UPDATE power_region_usage_transaction_product_transfer_detail_type SET power_region_id=$1, is_interval=$2, is_meter=$3, is_summary=$4, name=$5, description=$6 WHERE code=$7`, t.PowerRegionID, t.Interval, t.Meter, t.Summary, t.Name, t.Description, t.Code)
 	return err
 }
 
@@ -53,7 +53,7 @@ func (r *powerRegionUsageTransactionProductTransferDetailTypeRepositorySQL) Dele
 }
 
 func (r *powerRegionUsageTransactionProductTransferDetailTypeRepositorySQL) List(ctx context.Context) ([]dbentity.PowerRegionUsageTransactionProductTransferDetailType, error) {
-	rows, err := r.db.Query(ctx, `SELECT code, power_region_id, interval, meter, summary, name, description, created, updated FROM power_region_usage_transaction_product_transfer_detail_type`)
+	rows, err := r.db.Query(ctx, `SELECT code, power_region_id, is_interval, is_meter, is_summary, name, description, created_dttm, updated_dttm FROM power_region_usage_transaction_product_transfer_detail_type`)
 	if err != nil {
 		return nil, err
 	}
@@ -69,8 +69,8 @@ func (r *powerRegionUsageTransactionProductTransferDetailTypeRepositorySQL) List
 	return types, nil
 }
 
-func (r *powerRegionUsageTransactionProductTransferDetailTypeRepositorySQL) MapByCode(ctx context.Context) (map[string]dbentity.PowerRegionUsageTransactionProductTransferDetailType, error) {
-	rows, err := r.db.Query(ctx, `SELECT code, power_region_id, interval, meter, summary, name, description, created, updated FROM power_region_usage_transaction_product_transfer_detail_type`)
+func (r *powerRegionUsageTransactionProductTransferDetailTypeRepositorySQL) MapByCode(ctx context.Context, powerRegionID string) (map[string]dbentity.PowerRegionUsageTransactionProductTransferDetailType, error) {
+	rows, err := r.db.Query(ctx, `SELECT code, power_region_id, is_interval, is_meter, is_summary, name, description, created_dttm, updated_dttm FROM power_region_usage_transaction_product_transfer_detail_type WHERE power_region_id=$1`, powerRegionID)
 	if err != nil {
 		return nil, err
 	}
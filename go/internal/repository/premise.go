@@ -2,6 +2,7 @@ package repository
 
 import (
 	"context"
+	dbentity "usage-lakehouse/internal/db/entity"
 	"usage-lakehouse/internal/model"
 
 	"github.com/jackc/pgx/v5/pgxpool"
@@ -10,10 +11,15 @@ import (
 type PremiseRepository interface {
 	Create(ctx context.Context, p *model.Premise) error
 	GetByID(ctx context.Context, id string) (*model.Premise, error)
-	GetByCode(ctx context.Context, code string) (*model.Premise, error)
+	GetByCode(ctx context.Context, powerRegionID string, code string) (*model.Premise, error)
 	Update(ctx context.Context, p *model.Premise) error
 	Delete(ctx context.Context, id string) error
 	List(ctx context.Context) ([]model.Premise, error)
+	AssignTDSP(ctx context.Context, id string, tdspID string) error
+	Provision(ctx context.Context, p *model.Premise) error
+	// ListCodeChecks lists the premises whose TDSP has a premise_code_validation_expression, with the
+	// TDSP, ordered by TDSP name and premise code.
+	ListCodeChecks(ctx context.Context) ([]dbentity.PremiseCodeCheck, error)
 }
 
 type premiseRepositorySQL struct {
@@ -26,26 +32,26 @@ func NewPremiseRepository(db *pgxpool.Pool) PremiseRepository {
 
 func (r *premiseRepositorySQL) Create(ctx context.Context, p *model.Premise) error {
 	_, err := r.db.Exec(ctx,
-		`INSERT INTO premise (id, code, name, customer_name, address_line_1, city, state, zip, country, premise_type_id, power_region_id) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)`,
-		p.ID, p.Code, p.Name, p.CustomerName, p.Address.AddressLine1, p.Address.City, p.Address.State, p.Address.Zip, p.Address.Country, p.PremiseTypeID, p.PowerRegionID,
+		`INSERT INTO premise (id, code, name, customer_name, address_line_1, city, state, zip, country, premise_type_code, power_region_id, tdsp_id, is_auto_provisioned) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13)`,
+		p.ID, p.Code, p.Name, p.CustomerName, p.Address.AddressLine1, p.Address.City, p.Address.State, p.Address.Zip, p.Address.Country, p.PremiseTypeID, p.PowerRegionID, p.TDSPID, p.AutoProvisioned,
 	)
 	return err
 }
 
 func (r *premiseRepositorySQL) GetByID(ctx context.Context, id string) (*model.Premise, error) {
 	var p model.Premise
-	err := r.db.QueryRow(ctx, `SELECT id, code, name, customer_name, address_line_1, city, state, zip, country, premise_type_id, power_region_id, created_dttm, updated_dttm FROM premise WHERE id=$1`, id).
-		Scan(&p.ID, &p.Code, &p.Name, &p.CustomerName, &p.Address.AddressLine1, &p.Address.City, &p.Address.State, &p.Address.Zip, &p.Address.Country, &p.PremiseTypeID, &p.PowerRegionID, &p.Created, &p.Updated)
+	err := r.db.QueryRow(ctx, `SELECT id, code, COALESCE(name, ''), customer_name, COALESCE(address_line_1, ''), COALESCE(city, ''), COALESCE(state, ''), COALESCE(zip, ''), COALESCE(country, ''), premise_type_code, power_region_id, tdsp_id, is_auto_provisioned, created_dttm, updated_dttm FROM premise WHERE id=$1`, id).
+		Scan(&p.ID, &p.Code, &p.Name, &p.CustomerName, &p.Address.AddressLine1, &p.Address.City, &p.Address.State, &p.Address.Zip, &p.Address.Country, &p.PremiseTypeID, &p.PowerRegionID, &p.TDSPID, &p.AutoProvisioned, &p.Created, &p.Updated)
 	if err != nil {
 		return nil, err
 	}
 	return &p, nil
 }
 
-func (r *premiseRepositorySQL) GetByCode(ctx context.Context, code string) (*model.Premise, error) {
+func (r *premiseRepositorySQL) GetByCode(ctx context.Context, powerRegionID string, code string) (*model.Premise, error) {
 	var p model.Premise
-	err := r.db.QueryRow(ctx, `SELECT id, code, name, customer_name, address_line_1, city, state, zip, country, premise_type_id, power_region_id, created_dttm, updated_dttm FROM premise WHERE code=$1`, code).
-		Scan(&p.ID, &p.Code, &p.Name, &p.CustomerName, &p.Address.AddressLine1, &p.Address.City, &p.Address.State, &p.Address.Zip, &p.Address.Country, &p.PremiseTypeID, &p.PowerRegionID, &p.Created, &p.Updated)
+	err := r.db.QueryRow(ctx, `SELECT id, code, COALESCE(name, ''), customer_name, COALESCE(address_line_1, ''), COALESCE(city, ''), COALESCE(state, ''), COALESCE(zip, ''), COALESCE(country, ''), premise_type_code, power_region_id, tdsp_id, is_auto_provisioned, created_dttm, updated_dttm FROM premise WHERE code=$1 AND power_region_id=$2`, code, powerRegionID).
+		Scan(&p.ID, &p.Code, &p.Name, &p.CustomerName, &p.Address.AddressLine1, &p.Address.City, &p.Address.State, &p.Address.Zip, &p.Address.Country, &p.PremiseTypeID, &p.PowerRegionID, &p.TDSPID, &p.AutoProvisioned, &p.Created, &p.Updated)
 	if err != nil {
 		return nil, err
 	}
@@ -53,7 +59,7 @@ func (r *premiseRepositorySQL) GetByCode(ctx context.Context, code string) (*mod
 }
 
 func (r *premiseRepositorySQL) Update(ctx context.Context, p *model.Premise) error {
-	_, err := r.db.Exec(ctx, `UPDATE premise SET code=$1, name=$2, customer_name=$3, address_line_1=$4, city=$5, state=$6, zip=$7, country=$8, premise_type_id=$9, power_region_id=$10 WHERE id=$11`, p.Code, p.Name, p.CustomerName, p.Address.AddressLine1, p.Address.City, p.Address.State, p.Address.Zip, p.Address.Country, p.PremiseTypeID, p.PowerRegionID, p.ID)
+	_, err := r.db.Exec(ctx, `UPDATE premise SET code=$1, name=$2, customer_name=$3, address_line_1=$4, city=$5, state=$6, zip=$7, country=$8, premise_type_code=$9, power_region_id=$10, tdsp_id=$11 WHERE id=$12`, p.Code, p.Name, p.CustomerName, p.Address.AddressLine1, p.Address.City, p.Address.State, p.Address.Zip, p.Address.Country, p.PremiseTypeID, p.PowerRegionID, p.TDSPID, p.ID)
 	return err
 }
 
@@ -63,7 +69,7 @@ func (r *premiseRepositorySQL) Delete(ctx context.Context, id string) error {
 }
 
 func (r *premiseRepositorySQL) List(ctx context.Context) ([]model.Premise, error) {
-	rows, err := r.db.Query(ctx, `SELECT id, code, name, customer_name, address_line_1, city, state, zip, country, premise_type_id, power_region_id, created_dttm, updated_dttm FROM premise`)
+	rows, err := r.db.Query(ctx, `SELECT id, code, COALESCE(name, ''), customer_name, COALESCE(address_line_1, ''), COALESCE(city, ''), COALESCE(state, ''), COALESCE(zip, ''), COALESCE(country, ''), premise_type_code, power_region_id, tdsp_id, is_auto_provisioned, created_dttm, updated_dttm FROM premise`)
 	if err != nil {
 		return nil, err
 	}
@@ -71,10 +77,57 @@ func (r *premiseRepositorySQL) List(ctx context.Context) ([]model.Premise, error
 	var premises []model.Premise
 	for rows.Next() {
 		var p model.Premise
-		if err := rows.Scan(&p.ID, &p.Code, &p.Name, &p.CustomerName, &p.Address.AddressLine1, &p.Address.City, &p.Address.State, &p.Address.Zip, &p.Address.Country, &p.PremiseTypeID, &p.PowerRegionID, &p.Created, &p.Updated); err != nil {
+		if err := rows.Scan(&p.ID, &p.Code, &p.Name, &p.CustomerName, &p.Address.AddressLine1, &p.Address.City, &p.Address.State, &p.Address.Zip, &p.Address.Country, &p.PremiseTypeID, &p.PowerRegionID, &p.TDSPID, &p.AutoProvisioned, &p.Created, &p.Updated); err != nil {
 			return nil, err
 		}
 		premises = append(premises, p)
 	}
 	return premises, nil
 }
+
+// AssignTDSP links a premise to the TDSP serving it unless it already has one.
+func (r *premiseRepositorySQL) AssignTDSP(ctx context.Context, id string, tdspID string) error {
+	_, err := r.db.Exec(ctx, `UPDATE premise SET tdsp_id=$2 WHERE id=$1 AND tdsp_id IS NULL`, id, tdspID)
+	return err
+}
+
+// Provision creates the premise unless its code already exists in the power region and loads
+// whichever row won into p.
+func (r *premiseRepositorySQL) Provision(ctx context.Context, p *model.Premise) error {
+	_, err := r.db.Exec(ctx,
+		`INSERT INTO premise (id, code, customer_name, premise_type_code, power_region_id, tdsp_id, is_auto_provisioned) VALUES ($1, $2, $3, $4, $5, $6, TRUE) ON CONFLICT (code, power_region_id) DO NOTHING`,
+		p.ID, p.Code, p.CustomerName, p.PremiseTypeID, p.PowerRegionID, p.TDSPID,
+	)
+	if err != nil {
+		return err
+	}
+	existing, err := r.GetByCode(ctx, p.PowerRegionID, p.Code)
+	if err != nil {
+		return err
+	}
+	*p = *existing
+	return nil
+}
+
+func (r *premiseRepositorySQL) ListCodeChecks(ctx context.Context) ([]dbentity.PremiseCodeCheck, error) {
+	rows, err := r.db.Query(ctx, `
+		SELECT p.id, p.code, p.power_region_id, t.id, t.name, t.premise_code_validation_expression
+		FROM premise p
+		JOIN tdsp t ON t.id = p.tdsp_id
+		WHERE COALESCE(t.premise_code_validation_expression, '') <> ''
+		ORDER BY t.name, p.code
+	`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var checks []dbentity.PremiseCodeCheck
+	for rows.Next() {
+		var c dbentity.PremiseCodeCheck
+		if err := rows.Scan(&c.PremiseID, &c.Code, &c.PowerRegionID, &c.TDSP.ID, &c.TDSP.Name, &c.TDSP.PremiseCodeValidationExpression); err != nil {
+			return nil, err
+		}
+		checks = append(checks, c)
+	}
+	return checks, rows.Err()
+}
@@ -0,0 +1,48 @@
+package repository
+
+import (
+	"context"
+	dbentity "usage-lakehouse/internal/db/entity"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type RawPayloadRepository interface {
+	// Create records an archived payload. Recording the same SHA-256 again keeps the first row.
+	Create(ctx context.Context, p *dbentity.RawPayload) error
+	GetBySHA256(ctx context.Context, sha256 string) (*dbentity.RawPayload, error)
+	CreateLakeObject(ctx context.Context, o *dbentity.LakeObject) error
+}
+
+type rawPayloadRepositorySQL struct {
+	db *pgxpool.Pool
+}
+
+func NewRawPayloadRepository(db *pgxpool.Pool) RawPayloadRepository {
+	return &rawPayloadRepositorySQL{db: db}
+}
+
+func (r *rawPayloadRepositorySQL) Create(ctx context.Context, p *dbentity.RawPayload) error {
+	_, err := r.db.Exec(ctx,
+		`INSERT INTO raw_payload (sha256, content_type, size_bytes, object_key) VALUES ($1, $2, $3, $4) ON CONFLICT (sha256) DO NOTHING`,
+		p.SHA256, p.ContentType, p.Size, p.ObjectKey,
+	)
+	return err
+}
+
+func (r *rawPayloadRepositorySQL) GetBySHA256(ctx context.Context, sha256 string) (*dbentity.RawPayload, error) {
+	var p dbentity.RawPayload
+	err := r.db.QueryRow(ctx, `SELECT sha256, content_type, size_bytes, object_key, created_dttm, updated_dttm FROM raw_payload WHERE sha256=$1`, sha256).
+		Scan(&p.SHA256, &p.ContentType, &p.Size, &p.ObjectKey, &p.Created, &p.Updated)
+	if err != nil {
+		return nil, err
+	}
+	return &p, nil
+}
+
+func (r *rawPayloadRepositorySQL) CreateLakeObject(ctx context.Context, o *dbentity.LakeObject) error {
+	return r.db.QueryRow(ctx,
+		`INSERT INTO lake_object (object_key, account_id, raw_payload_sha256, row_count) VALUES ($1, $2, $3, $4) RETURNING created_dttm, updated_dttm`,
+		o.ObjectKey, o.AccountID, o.RawPayloadSHA256, o.RowCount,
+	).Scan(&o.Created, &o.Updated)
+}
@@ -26,7 +26,7 @@ func NewTDSPRepository(db *pgxpool.Pool) TDSPRepository {
 
 func (r *tdspRepositorySQL) Create(ctx context.Context, t *dbentity.TDSP) error {
 	_, err := r.db.Exec(ctx,
-		`INSERT INTO tdsp (id, name, code, legal_id, premise_code_validation_expression) VALUES ($1, $2, $3, $4, $5)`,
+		`INSERT INTO tdsp (id, legal_entity_name, name, abbreviation, legal_id, premise_code_validation_expression) VALUES ($1, $2, $2, $3, $4, $5)`,
 		t.ID, t.Name, t.Code, t.LegalID, t.PremiseCodeValidationExpression,
 	)
 	return err
@@ -34,7 +34,7 @@ func (r *tdspRepositorySQL) Create(ctx context.Context, t *dbentity.TDSP) error
 
 func (r *tdspRepositorySQL) GetByID(ctx context.Context, id string) (*dbentity.TDSP, error) {
 	var t dbentity.TDSP
-	err := r.db.QueryRow(ctx, `SELECT id, name, code, legal_id, premise_code_validation_expression, created, updated FROM tdsp WHERE id=$1`, id).
+	err := r.db.QueryRow(ctx, `SELECT id, name, abbreviation, legal_id, COALESCE(premise_code_validation_expression, ''), created_dttm, updated_dttm FROM tdsp WHERE id=$1`, id).
 		Scan(&t.ID, &t.Name, &t.Code, &t.LegalID, &t.PremiseCodeValidationExpression, &t.Created, &t.Updated)
 	if err != nil {
 		return nil, err
@@ -44,7 +44,7 @@ func (r *tdspRepositorySQL) GetByID(ctx context.Context, id string) (*dbentity.T
 
 func (r *tdspRepositorySQL) GetByName(ctx context.Context, name string) (*dbentity.TDSP, error) {
 	var t dbentity.TDSP
-	err := r.db.QueryRow(ctx, `SELECT id, name, code, legal_id, premise_code_validation_expression, created, updated FROM tdsp WHERE name=$1`, name).
+	err := r.db.QueryRow(ctx, `SELECT id, name, abbreviation, legal_id, COALESCE(premise_code_validation_expression, ''), created_dttm, updated_dttm FROM tdsp WHERE name=$1`, name).
 		Scan(&t.ID, &t.Name, &t.Code, &t.LegalID, &t.PremiseCodeValidationExpression, &t.Created, &t.Updated)
 	if err != nil {
 		return nil, err
@@ -53,7 +53,7 @@ func (r *tdspRepositorySQL) GetByName(ctx context.Context, name string) (*dbenti
 }
 
 func (r *tdspRepositorySQL) Update(ctx context.Context, t *dbentity.TDSP) error {
-	_, err := r.db.Exec(ctx, `UPDATE tdsp SET name=$1, code=$2, legal_id=$3, premise_code_validation_expression=$4 WHERE id=$5`, t.Name, t.Code, t.LegalID, t.PremiseCodeValidationExpression, t.ID)
+	_, err := r.db.Exec(ctx, `UPDATE tdsp SET name=$1, abbreviation=$2, legal_id=$3, premise_code_validation_expression=$4 WHERE id=$5`, t.Name, t.Code, t.LegalID, t.PremiseCodeValidationExpression, t.ID)
 	return err
 }
 
@@ -63,7 +63,7 @@ func (r *tdspRepositorySQL) Delete(ctx context.Context, id string) error {
 }
 
 func (r *tdspRepositorySQL) List(ctx context.Context) ([]dbentity.TDSP, error) {
-	rows, err := r.db.Query(ctx, `SELECT id, name, code, legal_id, premise_code_validation_expression, created, updated FROM tdsp`)
+	rows, err := r.db.Query(ctx, `SELECT id, name, abbreviation, legal_id, COALESCE(premise_code_validation_expression, ''), created_dttm, updated_dttm FROM tdsp`)
 	if err != nil {
 		return nil, err
 	}
@@ -23,8 +23,8 @@ func NewUsageTransactionPurposeRepository(db *pgxpool.Pool) UsageTransactionPurp
 
 func (r *usageTransactionPurposeRepositorySQL) GetByCode(ctx context.Context, code string) (*dbentity.UsageTransactionPurpose, error) {
 	var p dbentity.UsageTransactionPurpose
-	err := r.db.QueryRow(ctx, `SELECT code, name, is_cancel, description, created_dttm, updated_dttm FROM usage_transaction_purpose WHERE code=$1`, code).
-		Scan(&p.Code, &p.Name, &p.IsCancel, &p.Description, &p.Created, &p.Updated)
+	err := r.db.QueryRow(ctx, `SELECT code, name, is_cancel, is_replace, description, created_dttm, updated_dttm FROM usage_transaction_purpose WHERE code=$1`, code).
+		Scan(&p.Code, &p.Name, &p.IsCancel, &p.IsReplace, &p.Description, &p.Created, &p.Updated)
 	if err != nil {
 		return nil, err
 	}
@@ -32,7 +32,7 @@ func (r *usageTransactionPurposeRepositorySQL) GetByCode(ctx context.Context, co
 }
 
 func (r *usageTransactionPurposeRepositorySQL) List(ctx context.Context) ([]dbentity.UsageTransactionPurpose, error) {
-	rows, err := r.db.Query(ctx, `SELECT code, name, is_cancel, description, created_dttm, updated_dttm FROM usage_transaction_purpose`)
+	rows, err := r.db.Query(ctx, `SELECT code, name, is_cancel, is_replace, description, created_dttm, updated_dttm FROM usage_transaction_purpose`)
 	if err != nil {
 		return nil, err
 	}
@@ -40,7 +40,7 @@ func (r *usageTransactionPurposeRepositorySQL) List(ctx context.Context) ([]dben
 	var purposes []dbentity.UsageTransactionPurpose
 	for rows.Next() {
 		var p dbentity.UsageTransactionPurpose
-		if err := rows.Scan(&p.Code, &p.Name, &p.IsCancel, &p.Description, &p.Created, &p.Updated); err != nil {
+		if err := rows.Scan(&p.Code, &p.Name, &p.IsCancel, &p.IsReplace, &p.Description, &p.Created, &p.Updated); err != nil {
 			return nil, err
 		}
 		purposes = append(purposes, p)
@@ -51,11 +51,11 @@ func (r *usageTransactionPurposeRepositorySQL) List(ctx context.Context) ([]dben
 func (r *usageTransactionPurposeRepositorySQL) GetByPowerRegionAndCode(ctx context.Context, powerRegionID string, code string) (*dbentity.UsageTransactionPurpose, error) {
 	var p dbentity.UsageTransactionPurpose
 	err := r.db.QueryRow(ctx, `
-		SELECT utp.code, utp.name, utp.is_cancel, utp.description, utp.created_dttm, utp.updated_dttm
+		SELECT utp.code, utp.name, utp.is_cancel, utp.is_replace, utp.description, utp.created_dttm, utp.updated_dttm
 		FROM usage_transaction_purpose utp
 		JOIN power_region_usage_transaction_purpose prutp ON utp.code = prutp.usage_transaction_purpose_code
 		WHERE prutp.power_region_id = $1 AND prutp.code = $2
-	`, powerRegionID, code).Scan(&p.Code, &p.Name, &p.IsCancel, &p.Description, &p.Created, &p.Updated)
+	`, powerRegionID, code).Scan(&p.Code, &p.Name, &p.IsCancel, &p.IsReplace, &p.Description, &p.Created, &p.Updated)
 	if err != nil {
 		return nil, err
 	}
@@ -34,7 +34,7 @@ func (r *transactionSubTypeRepositorySQL) Create(ctx context.Context, t *dbentit
 
 func (r *transactionSubTypeRepositorySQL) GetByCode(ctx context.Context, code string) (*dbentity.TransactionSubType, error) {
 	var t dbentity.TransactionSubType
-	err := r.db.QueryRow(ctx, `SELECT code, transaction_type_code, name, description, created, updated FROM transaction_sub_type WHERE code=$1`, code).
+	err := r.db.QueryRow(ctx, `SELECT code, transaction_type_code, name, description, created_dttm, updated_dttm FROM transaction_sub_type WHERE code=$1`, code).
 		Scan(&t.Code, &t.TransactionTypeCode, &t.Name, &t.Description, &t.Created, &t.Updated)
 	if err != nil {
 		return nil, err
@@ -53,7 +53,7 @@ func (r *transactionSubTypeRepositorySQL) Delete(ctx context.Context, code strin
 }
 
 func (r *transactionSubTypeRepositorySQL) List(ctx context.Context) ([]dbentity.TransactionSubType, error) {
-	rows, err := r.db.Query(ctx, `SELECT code, transaction_type_code, name, description, created, updated FROM transaction_sub_type`)
+	rows, err := r.db.Query(ctx, `SELECT code, transaction_type_code, name, description, created_dttm, updated_dttm FROM transaction_sub_type`)
 	if err != nil {
 		return nil, err
 	}
@@ -72,7 +72,7 @@ func (r *transactionSubTypeRepositorySQL) List(ctx context.Context) ([]dbentity.
 func (r *transactionSubTypeRepositorySQL) GetByPowerRegionSubTypeCode(ctx context.Context, powerRegionID string, powerRegionTransactionSubTypeCode string) (*dbentity.TransactionSubType, error) {
 	var t dbentity.TransactionSubType
 	query := `
-		SELECT tst.code, tst.transaction_type_code, tst.name, tst.description, tst.created, tst.updated
+		SELECT tst.code, tst.transaction_type_code, tst.name, tst.description, tst.created_dttm, tst.updated_dttm
 		FROM power_region_transaction_sub_type prtst
 		JOIN transaction_sub_type tst ON prtst.transaction_sub_type_code = tst.code
 		WHERE prtst.power_region_id = $1 AND prtst.code = $2
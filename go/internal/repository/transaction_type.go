@@ -34,7 +34,7 @@ func (r *transactionTypeRepositorySQL) Create(ctx context.Context, t *dbentity.T
 
 func (r *transactionTypeRepositorySQL) GetByCode(ctx context.Context, code string) (*dbentity.TransactionType, error) {
 	var t dbentity.TransactionType
-	err := r.db.QueryRow(ctx, `SELECT code, name, description, created, updated FROM transaction_type WHERE code=$1`, code).
+	err := r.db.QueryRow(ctx, `SELECT code, name, description, created_dttm, updated_dttm FROM transaction_type WHERE code=$1`, code).
 		Scan(&t.Code, &t.Name, &t.Description, &t.Created, &t.Updated)
 	if err != nil {
 		return nil, err
@@ -53,7 +53,7 @@ func (r *transactionTypeRepositorySQL) Delete(ctx context.Context, code string)
 }
 
 func (r *transactionTypeRepositorySQL) List(ctx context.Context) ([]dbentity.TransactionType, error) {
-	rows, err := r.db.Query(ctx, `SELECT code, name, description, created, updated FROM transaction_type`)
+	rows, err := r.db.Query(ctx, `SELECT code, name, description, created_dttm, updated_dttm FROM transaction_type`)
 	if err != nil {
 		return nil, err
 	}
@@ -72,7 +72,7 @@ func (r *transactionTypeRepositorySQL) List(ctx context.Context) ([]dbentity.Tra
 func (r *transactionTypeRepositorySQL) GetByPowerRegionAndCode(ctx context.Context, powerRegionID string, powerRegionTransactionTypeCode string) (*dbentity.TransactionType, error) {
 	var t dbentity.TransactionType
 	query := `
-		SELECT tt.code, tt.name, tt.description, tt.created, tt.updated
+		SELECT tt.code, tt.name, tt.description, tt.created_dttm, tt.updated_dttm
 		FROM power_region_transaction_type prtt
 		JOIN transaction_type tt ON prtt.transaction_type_code = tt.code
 		WHERE prtt.power_region_id = $1 AND prtt.code = $2
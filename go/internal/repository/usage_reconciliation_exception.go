@@ -0,0 +1,94 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"time"
+	dbentity "usage-lakehouse/internal/db/entity"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type UsageReconciliationExceptionFilter struct {
+	Status    *string
+	PremiseID *string
+	From      *time.Time
+	To        *time.Time
+	Limit     int
+}
+
+type UsageReconciliationExceptionRepository interface {
+	GetByID(ctx context.Context, id string) (*dbentity.UsageReconciliationException, error)
+	List(ctx context.Context, filter UsageReconciliationExceptionFilter) ([]dbentity.UsageReconciliationException, error)
+}
+
+type usageReconciliationExceptionRepositorySQL struct {
+	db *pgxpool.Pool
+}
+
+func NewUsageReconciliationExceptionRepository(db *pgxpool.Pool) UsageReconciliationExceptionRepository {
+	return &usageReconciliationExceptionRepositorySQL{db: db}
+}
+
+const selectUsageReconciliationException = `
+	SELECT ure.id, ure.usage_transaction_id, ut.transaction_id, ure.usage_transaction_summary_id, ure.premise_id, ure.status,
+		uts.product_transfer_detail_type_code, uts.meter_name, uts.channel, uts.service_period_start_dt, uts.service_period_end_dt,
+		ure.summary_quantity, ure.interval_quantity, ure.difference, ure.created_dttm, ure.updated_dttm
+	FROM usage_reconciliation_exception ure
+	JOIN usage_transaction ut ON ut.id = ure.usage_transaction_id
+	JOIN usage_transaction_summary uts ON uts.id = ure.usage_transaction_summary_id`
+
+func scanUsageReconciliationException(row interface{ Scan(...any) error }) (*dbentity.UsageReconciliationException, error) {
+	var e dbentity.UsageReconciliationException
+	err := row.Scan(&e.ID, &e.UsageTransactionID, &e.TransactionID, &e.UsageTransactionSummaryID, &e.PremiseID, &e.Status,
+		&e.TransferType, &e.MeterName, &e.Channel, &e.ServicePeriodStart, &e.ServicePeriodEnd,
+		&e.SummaryQuantity, &e.IntervalQuantity, &e.Difference, &e.Created, &e.Updated)
+	if err != nil {
+		return nil, err
+	}
+	return &e, nil
+}
+
+func (r *usageReconciliationExceptionRepositorySQL) GetByID(ctx context.Context, id string) (*dbentity.UsageReconciliationException, error) {
+	return scanUsageReconciliationException(r.db.QueryRow(ctx, selectUsageReconciliationException+` WHERE ure.id=$1`, id))
+}
+
+func (r *usageReconciliationExceptionRepositorySQL) List(ctx context.Context, filter UsageReconciliationExceptionFilter) ([]dbentity.UsageReconciliationException, error) {
+	query := selectUsageReconciliationException + ` WHERE TRUE`
+	var args []any
+	if filter.Status != nil {
+		args = append(args, *filter.Status)
+		query += fmt.Sprintf(` AND ure.status = $%d`, len(args))
+	}
+	if filter.PremiseID != nil {
+		args = append(args, *filter.PremiseID)
+		query += fmt.Sprintf(` AND ure.premise_id = $%d`, len(args))
+	}
+	if filter.From != nil {
+		args = append(args, *filter.From)
+		query += fmt.Sprintf(` AND uts.service_period_end_dt >= $%d`, len(args))
+	}
+	if filter.To != nil {
+		args = append(args, *filter.To)
+		query += fmt.Sprintf(` AND uts.service_period_start_dt <= $%d`, len(args))
+	}
+	query += ` ORDER BY ure.created_dttm DESC`
+	if filter.Limit > 0 {
+		args = append(args, filter.Limit)
+		query += fmt.Sprintf(` LIMIT $%d`, len(args))
+	}
+	rows, err := r.db.Query(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var exceptions []dbentity.UsageReconciliationException
+	for rows.Next() {
+		e, err := scanUsageReconciliationException(rows)
+		if err != nil {
+			return nil, err
+		}
+		exceptions = append(exceptions, *e)
+	}
+	return exceptions, rows.Err()
+}
@@ -2,20 +2,166 @@ package repository
 
 import (
 	"context"
+	"errors"
 	dbentity "usage-lakehouse/internal/db/entity"
 
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
 	"github.com/jackc/pgx/v5/pgxpool"
 )
 
 type UsageTransactionRepository interface {
 	Create(ctx context.Context, t *dbentity.UsageTransaction) error
 	GetByID(ctx context.Context, id string) (*dbentity.UsageTransaction, error)
+	GetByTransactionID(ctx context.Context, transactionID string) (*dbentity.UsageTransaction, error)
 	Update(ctx context.Context, t *dbentity.UsageTransaction) error
 	Delete(ctx context.Context, id string) error
 	List(ctx context.Context) ([]dbentity.UsageTransaction, error)
-	SaveWithDetails(ctx context.Context, t *dbentity.UsageTransaction, details []dbentity.UsageTransactionDetail) error
+	SaveWithDetails(ctx context.Context, t *dbentity.UsageTransaction, details []dbentity.UsageTransactionDetail, summaries []dbentity.UsageTransactionSummary, intervalExceptions []dbentity.MeterIntervalException, channelIntervals []dbentity.MeterUsageChannelInterval) error
+	SaveHistoricWithDetails(ctx context.Context, t *dbentity.UsageTransaction, details []dbentity.UsageTransactionDetail, summaries []dbentity.UsageTransactionSummary, intervalExceptions []dbentity.MeterIntervalException, channelIntervals []dbentity.MeterUsageChannelInterval) error
 }
 
+// ErrOriginalTransactionNotFound is returned when a cancel or replace refers to a transaction that
+// does not exist for the premise and service period or has already been voided.
+var ErrOriginalTransactionNotFound = errors.New("original usage transaction not found")
+
+// uniqueViolation is the PostgreSQL SQLSTATE for unique_violation.
+const uniqueViolation = "23505"
+
+// ErrDuplicateTransactionID is returned when another usage transaction already holds the transaction_id.
+var ErrDuplicateTransactionID = errors.New("usage transaction already exists for transaction_id")
+
+const voidOriginalUsageTransaction = `
+	UPDATE usage_transaction SET is_canceled = TRUE
+	WHERE id = (
+		SELECT id FROM usage_transaction
+		WHERE premise_id = $1 AND transaction_id = $2
+		AND service_period_start_dt = $3 AND service_period_end_dt = $4
+		AND NOT is_canceled
+		FOR UPDATE
+	)
+	RETURNING id`
+
+// refreshMeterUsage15Minute rebuilds the normalized interval rows touched by the given transactions.
+// For each meter and interval the effective reading is the latest active detail, preferring monthly
+// over historic usage, and replaces any VEE estimate or edit; intervals left without one are flagged
+// canceled. Interval timestamps are UTC, so the account holding the premise is matched on the
+// interval's local date in the power region. An interval whose effective reading no account held the
+// premise for is flagged canceled too, so an earlier transaction's reading does not stand in for it,
+// and is recorded in meter_usage_15_minute_unassigned, which only ever holds the intervals still
+// without an account.
+const refreshMeterUsage15Minute = `
+	WITH affected AS (
+		SELECT DISTINCT meter_id, start_dttm
+		FROM usage_transaction_detail
+		WHERE usage_transaction_id = ANY($1) AND is_interval AND meter_id IS NOT NULL
+	), effective AS (
+		SELECT DISTINCT ON (utd.meter_id, utd.start_dttm)
+			utd.start_dttm, utd.end_dttm, utd.service_period_start_dt, utd.service_period_end_dt,
+			utd.premise_id, utd.meter_id, utd.usage_transaction_id, utd.consumption, utd.generation,
+			(utd.start_dttm AT TIME ZONE 'UTC' AT TIME ZONE pr.time_zone)::date AS local_start_dt
+		FROM affected a
+		JOIN usage_transaction_detail utd ON utd.meter_id = a.meter_id AND utd.start_dttm = a.start_dttm
+		JOIN usage_transaction ut ON ut.id = utd.usage_transaction_id
+		JOIN power_region pr ON pr.id = utd.power_region_id
+		WHERE utd.is_interval AND NOT utd.is_canceled
+		ORDER BY utd.meter_id, utd.start_dttm, (ut.transaction_sub_type_code = $2) DESC, ut.transaction_dt DESC, ut.created_dttm DESC
+	), resolved AS (
+		SELECT e.*, acct.account_id
+		FROM effective e
+		CROSS JOIN LATERAL (
+			SELECT COALESCE(
+				(SELECT pah.account_id FROM premise_account_history pah
+				WHERE pah.premise_id = e.premise_id
+				AND COALESCE(pah.start_dt, pah.estimated_start_dt) <= e.local_start_dt
+				AND (COALESCE(pah.end_dt, pah.estimated_end_dt) IS NULL OR COALESCE(pah.end_dt, pah.estimated_end_dt) > e.local_start_dt)
+				ORDER BY COALESCE(pah.start_dt, pah.estimated_start_dt) DESC
+				LIMIT 1),
+				(SELECT paj.account_id FROM premise_account_junction paj
+				WHERE paj.premise_id = e.premise_id
+				AND paj.min_start_dt <= e.local_start_dt
+				AND (paj.max_end_dt IS NULL OR paj.max_end_dt > e.local_start_dt)
+				ORDER BY paj.min_start_dt DESC
+				LIMIT 1)
+			) AS account_id
+		) acct
+	), unassigned AS (
+		INSERT INTO meter_usage_15_minute_unassigned (start_dttm, end_dttm, service_period_start_dt, service_period_end_dt, premise_id, meter_id, usage_transaction_id)
+		SELECT r.start_dttm, r.end_dttm, r.service_period_start_dt, r.service_period_end_dt, r.premise_id, r.meter_id, r.usage_transaction_id
+		FROM resolved r
+		WHERE r.account_id IS NULL
+		ON CONFLICT (start_dttm, meter_id) DO UPDATE SET
+			end_dttm = EXCLUDED.end_dttm,
+			service_period_start_dt = EXCLUDED.service_period_start_dt,
+			service_period_end_dt = EXCLUDED.service_period_end_dt,
+			premise_id = EXCLUDED.premise_id,
+			usage_transaction_id = EXCLUDED.usage_transaction_id
+	), assigned AS (
+		DELETE FROM meter_usage_15_minute_unassigned mua
+		USING affected a
+		WHERE mua.meter_id = a.meter_id AND mua.start_dttm = a.start_dttm
+		AND NOT EXISTS (SELECT 1 FROM resolved r WHERE r.meter_id = a.meter_id AND r.start_dttm = a.start_dttm AND r.account_id IS NULL)
+	), upserted AS (
+		INSERT INTO meter_usage_15_minute (start_dttm, end_dttm, service_period_start_dt, service_period_end_dt, is_canceled, premise_id, meter_id, account_id, usage_transaction_id, consumption, generation)
+		SELECT r.start_dttm, r.end_dttm, r.service_period_start_dt, r.service_period_end_dt, FALSE, r.premise_id, r.meter_id, r.account_id, r.usage_transaction_id, r.consumption, r.generation
+		FROM resolved r
+		WHERE r.account_id IS NOT NULL
+		ON CONFLICT (start_dttm, meter_id) DO UPDATE SET
+			end_dttm = EXCLUDED.end_dttm,
+			service_period_start_dt = EXCLUDED.service_period_start_dt,
+			service_period_end_dt = EXCLUDED.service_period_end_dt,
+			is_canceled = FALSE,
+			premise_id = EXCLUDED.premise_id,
+			account_id = EXCLUDED.account_id,
+			usage_transaction_id = EXCLUDED.usage_transaction_id,
+			consumption = EXCLUDED.consumption,
+			generation = EXCLUDED.generation,
+			quality = 'ACTUAL',
+			quality_rule = NULL,
+			original_consumption = NULL
+		RETURNING start_dttm, meter_id
+	)
+	UPDATE meter_usage_15_minute mu SET is_canceled = TRUE
+	FROM affected a
+	WHERE mu.meter_id = a.meter_id AND mu.start_dttm = a.start_dttm AND NOT mu.is_canceled
+	AND NOT EXISTS (SELECT 1 FROM resolved r WHERE r.meter_id = a.meter_id AND r.start_dttm = a.start_dttm AND r.account_id IS NOT NULL)`
+
+const insertUsageTransactionDetail = `INSERT INTO usage_transaction_detail (start_dttm, end_dttm, usage_transaction_id, meter_id, meter_name, power_region_id, premise_id, is_canceled, service_period_start_dt, service_period_end_dt, consumption, generation, is_interval) VALUES ($1,$2,$3,$4,$5,$6,$7,$8,$9,$10,$11,$12,$13)`
+
+// Historic usage only fills periods that no active monthly usage transaction has reported for the meter.
+const insertHistoricUsageTransactionDetail = `
+	INSERT INTO usage_transaction_detail (start_dttm, end_dttm, usage_transaction_id, meter_id, meter_name, power_region_id, premise_id, is_canceled, service_period_start_dt, service_period_end_dt, consumption, generation, is_interval)
+	SELECT $1::timestamp, $2::timestamp, $3::uuid, $4::uuid, $5::varchar, $6::uuid, $7::uuid, $8::boolean, $9::date, $10::date, $11::decimal, $12::decimal, $13::boolean
+	WHERE NOT EXISTS (
+		SELECT 1
+		FROM usage_transaction_detail utd
+		JOIN usage_transaction ut ON ut.id = utd.usage_transaction_id
+		WHERE utd.premise_id = $7 AND utd.meter_name = $5
+		AND utd.start_dttm < $2 AND utd.end_dttm > $1
+		AND NOT utd.is_canceled
+		AND ut.transaction_sub_type_code = $14
+	)`
+
+// upsertMeterUsageChannel stores a channel reading, replacing whatever an earlier transaction reported
+// for the interval.
+const upsertMeterUsageChannel = `
+	INSERT INTO meter_usage_channel (start_dttm, end_dttm, meter_id, channel, power_region_id, premise_id, usage_transaction_id, unit_of_measure, direction, quantity)
+	VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+	ON CONFLICT (meter_id, channel, start_dttm) DO UPDATE SET
+		end_dttm = EXCLUDED.end_dttm,
+		power_region_id = EXCLUDED.power_region_id,
+		premise_id = EXCLUDED.premise_id,
+		usage_transaction_id = EXCLUDED.usage_transaction_id,
+		unit_of_measure = EXCLUDED.unit_of_measure,
+		direction = EXCLUDED.direction,
+		quantity = EXCLUDED.quantity,
+		is_canceled = FALSE`
+
+// Like its details, a historic channel reading only fills intervals no active transaction has reported.
+const upsertHistoricMeterUsageChannel = upsertMeterUsageChannel + `
+	WHERE meter_usage_channel.is_canceled`
+
 type usageTransactionRepositorySQL struct {
 	db *pgxpool.Pool
 }
@@ -26,16 +172,26 @@ func NewUsageTransactionRepository(db *pgxpool.Pool) UsageTransactionRepository
 
 func (r *usageTransactionRepositorySQL) Create(ctx context.Context, t *dbentity.UsageTransaction) error {
 	_, err := r.db.Exec(ctx,
-		`INSERT INTO usage_transaction (id, transaction_id, transaction_type, transaction_sub_type, transaction_date, service_period_start, service_period_end, is_final, is_canceled, purpose, power_region_id, tdspid, premise_id, created, updated) VALUES ($1,$2,$3,$4,$5,$6,$7,$8,$9,$10,$11,$12,$13,$14,$15)`,
-		t.ID, t.TransactionID, t.TransactionType, t.TransactionSubType, t.TransactionDate, t.ServicePeriodStart, t.ServicePeriodEnd, t.IsFinal, t.IsCanceled, t.Purpose, t.PowerRegionID, t.TDSPID, t.PremiseID, t.Created, t.Updated,
+		`INSERT INTO usage_transaction (id, transaction_id, transaction_type_code, transaction_sub_type_code, transaction_dt, service_period_start_dt, service_period_end_dt, is_final, is_canceled, usage_transaction_purpose_code, power_region_id, tdsp_id, premise_id, original_transaction_id, original_usage_transaction_id) VALUES ($1,$2,$3,$4,$5,$6,$7,$8,$9,$10,$11,$12,$13,$14,$15)`,
+		t.ID, t.TransactionID, t.TransactionType, t.TransactionSubType, t.TransactionDate, t.ServicePeriodStart, t.ServicePeriodEnd, t.IsFinal, t.IsCanceled, t.Purpose, t.PowerRegionID, t.TDSPID, t.PremiseID, t.OriginalTransactionID, t.OriginalUsageTransactionID,
 	)
 	return err
 }
 
 func (r *usageTransactionRepositorySQL) GetByID(ctx context.Context, id string) (*dbentity.UsageTransaction, error) {
 	var t dbentity.UsageTransaction
-	err := r.db.QueryRow(ctx, `SELECT id, transaction_id, transaction_type, transaction_sub_type, transaction_date, service_period_start, service_period_end, is_final, is_canceled, purpose, power_region_id, tdspid, premise_id, created, updated FROM usage_transaction WHERE id=$1`, id).
-		Scan(&t.ID, &t.TransactionID, &t.TransactionType, &t.TransactionSubType, &t.TransactionDate, &t.ServicePeriodStart, &t.ServicePeriodEnd, &t.IsFinal, &t.IsCanceled, &t.Purpose, &t.PowerRegionID, &t.TDSPID, &t.PremiseID, &t.Created, &t.Updated)
+	err := r.db.QueryRow(ctx, `SELECT id, transaction_id, transaction_type_code, transaction_sub_type_code, transaction_dt, service_period_start_dt, service_period_end_dt, is_final, is_canceled, usage_transaction_purpose_code, power_region_id, tdsp_id, premise_id, original_transaction_id, original_usage_transaction_id, raw_payload_sha256, created_dttm, updated_dttm FROM usage_transaction WHERE id=$1`, id).
+		Scan(&t.ID, &t.TransactionID, &t.TransactionType, &t.TransactionSubType, &t.TransactionDate, &t.ServicePeriodStart, &t.ServicePeriodEnd, &t.IsFinal, &t.IsCanceled, &t.Purpose, &t.PowerRegionID, &t.TDSPID, &t.PremiseID, &t.OriginalTransactionID, &t.OriginalUsageTransactionID, &t.RawPayloadSHA256, &t.Created, &t.Updated)
+	if err != nil {
+		return nil, err
+	}
+	return &t, nil
+}
+
+func (r *usageTransactionRepositorySQL) GetByTransactionID(ctx context.Context, transactionID string) (*dbentity.UsageTransaction, error) {
+	var t dbentity.UsageTransaction
+	err := r.db.QueryRow(ctx, `SELECT id, transaction_id, transaction_type_code, transaction_sub_type_code, transaction_dt, service_period_start_dt, service_period_end_dt, is_final, is_canceled, usage_transaction_purpose_code, power_region_id, tdsp_id, premise_id, original_transaction_id, original_usage_transaction_id, payload, COALESCE(payload_sha256, ''), raw_payload_sha256, created_dttm, updated_dttm FROM usage_transaction WHERE transaction_id=$1`, transactionID).
+		Scan(&t.ID, &t.TransactionID, &t.TransactionType, &t.TransactionSubType, &t.TransactionDate, &t.ServicePeriodStart, &t.ServicePeriodEnd, &t.IsFinal, &t.IsCanceled, &t.Purpose, &t.PowerRegionID, &t.TDSPID, &t.PremiseID, &t.OriginalTransactionID, &t.OriginalUsageTransactionID, &t.Payload, &t.PayloadSHA256, &t.RawPayloadSHA256, &t.Created, &t.Updated)
 	if err != nil {
 		return nil, err
 	}
@@ -43,7 +199,7 @@ func (r *usageTransactionRepositorySQL) GetByID(ctx context.Context, id string)
 }
 
 func (r *usageTransactionRepositorySQL) Update(ctx context.Context, t *dbentity.UsageTransaction) error {
-	_, err := r.db.Exec(ctx, `UPDATE usage_transaction SET transaction_id=$1, transaction_type=$2, transaction_sub_type=$3, transaction_date=$4, service_period_start=$5, service_period_end=$6, is_final=$7, is_canceled=$8, purpose=$9, power_region_id=$10, tdspid=$11, premise_id=$12, updated=$13 WHERE id=$14`, t.TransactionID, t.TransactionType, t.TransactionSubType, t.TransactionDate, t.ServicePeriodStart, t.ServicePeriodEnd, t.IsFinal, t.IsCanceled, t.Purpose, t.PowerRegionID, t.TDSPID, t.PremiseID, t.Updated, t.ID)
+	_, err := r.db.Exec(ctx, `UPDATE usage_transaction SET transaction_id=$1, transaction_type_code=$2, transaction_sub_type_code=$3, transaction_dt=$4, service_period_start_dt=$5, service_period_end_dt=$6, is_final=$7, is_canceled=$8, usage_transaction_purpose_code=$9, power_region_id=$10, tdsp_id=$11, premise_id=$12, original_transaction_id=$13, original_usage_transaction_id=$14 WHERE id=$15`, t.TransactionID, t.TransactionType, t.TransactionSubType, t.TransactionDate, t.ServicePeriodStart, t.ServicePeriodEnd, t.IsFinal, t.IsCanceled, t.Purpose, t.PowerRegionID, t.TDSPID, t.PremiseID, t.OriginalTransactionID, t.OriginalUsageTransactionID, t.ID)
 	return err
 }
 
@@ -53,7 +209,7 @@ func (r *usageTransactionRepositorySQL) Delete(ctx context.Context, id string) e
 }
 
 func (r *usageTransactionRepositorySQL) List(ctx context.Context) ([]dbentity.UsageTransaction, error) {
-	rows, err := r.db.Query(ctx, `SELECT id, transaction_id, transaction_type, transaction_sub_type, transaction_date, service_period_start, service_period_end, is_final, is_canceled, purpose, power_region_id, tdspid, premise_id, created, updated FROM usage_transaction`)
+	rows, err := r.db.Query(ctx, `SELECT id, transaction_id, transaction_type_code, transaction_sub_type_code, transaction_dt, service_period_start_dt, service_period_end_dt, is_final, is_canceled, usage_transaction_purpose_code, power_region_id, tdsp_id, premise_id, original_transaction_id, original_usage_transaction_id, raw_payload_sha256, created_dttm, updated_dttm FROM usage_transaction`)
 	if err != nil {
 		return nil, err
 	}
@@ -61,7 +217,7 @@ func (r *usageTransactionRepositorySQL) List(ctx context.Context) ([]dbentity.Us
 	var txs []dbentity.UsageTransaction
 	for rows.Next() {
 		var t dbentity.UsageTransaction
-		if err := rows.Scan(&t.ID, &t.TransactionID, &t.TransactionType, &t.TransactionSubType, &t.TransactionDate, &t.ServicePeriodStart, &t.ServicePeriodEnd, &t.IsFinal, &t.IsCanceled, &t.Purpose, &t.PowerRegionID, &t.TDSPID, &t.PremiseID, &t.Created, &t.Updated); err != nil {
+		if err := rows.Scan(&t.ID, &t.TransactionID, &t.TransactionType, &t.TransactionSubType, &t.TransactionDate, &t.ServicePeriodStart, &t.ServicePeriodEnd, &t.IsFinal, &t.IsCanceled, &t.Purpose, &t.PowerRegionID, &t.TDSPID, &t.PremiseID, &t.OriginalTransactionID, &t.OriginalUsageTransactionID, &t.RawPayloadSHA256, &t.Created, &t.Updated); err != nil {
 			return nil, err
 		}
 		txs = append(txs, t)
@@ -69,7 +225,15 @@ func (r *usageTransactionRepositorySQL) List(ctx context.Context) ([]dbentity.Us
 	return txs, nil
 }
 
-func (r *usageTransactionRepositorySQL) SaveWithDetails(ctx context.Context, t *dbentity.UsageTransaction, details []dbentity.UsageTransactionDetail) error {
+func (r *usageTransactionRepositorySQL) SaveWithDetails(ctx context.Context, t *dbentity.UsageTransaction, details []dbentity.UsageTransactionDetail, summaries []dbentity.UsageTransactionSummary, intervalExceptions []dbentity.MeterIntervalException, channelIntervals []dbentity.MeterUsageChannelInterval) error {
+	return r.saveWithDetails(ctx, t, details, summaries, intervalExceptions, channelIntervals, upsertMeterUsageChannel, insertUsageTransactionDetail)
+}
+
+func (r *usageTransactionRepositorySQL) SaveHistoricWithDetails(ctx context.Context, t *dbentity.UsageTransaction, details []dbentity.UsageTransactionDetail, summaries []dbentity.UsageTransactionSummary, intervalExceptions []dbentity.MeterIntervalException, channelIntervals []dbentity.MeterUsageChannelInterval) error {
+	return r.saveWithDetails(ctx, t, details, summaries, intervalExceptions, channelIntervals, upsertHistoricMeterUsageChannel, insertHistoricUsageTransactionDetail, dbentity.TransactionSubTypeMonthlyUsage)
+}
+
+func (r *usageTransactionRepositorySQL) saveWithDetails(ctx context.Context, t *dbentity.UsageTransaction, details []dbentity.UsageTransactionDetail, summaries []dbentity.UsageTransactionSummary, intervalExceptions []dbentity.MeterIntervalException, channelIntervals []dbentity.MeterUsageChannelInterval, channelUpsert string, detailInsert string, extraArgs ...any) (err error) {
 	tx, err := r.db.Begin(ctx)
 	if err != nil {
 		return err
@@ -78,24 +242,98 @@ func (r *usageTransactionRepositorySQL) SaveWithDetails(ctx context.Context, t *
 		if err != nil {
 			tx.Rollback(ctx)
 		} else {
-			tx.Commit(ctx)
+			err = tx.Commit(ctx)
 		}
 	}()
-	_, err = tx.Exec(ctx,
-		`INSERT INTO usage_transaction (id, transaction_id, transaction_type, transaction_sub_type, transaction_date, service_period_start, service_period_end, is_final, is_canceled, purpose, power_region_id, tdspid, premise_id, created, updated) VALUES ($1,$2,$3,$4,$5,$6,$7,$8,$9,$10,$11,$12,$13,$14,$15)`,
-		t.ID, t.TransactionID, t.TransactionType, t.TransactionSubType, t.TransactionDate, t.ServicePeriodStart, t.ServicePeriodEnd, t.IsFinal, t.IsCanceled, t.Purpose, t.PowerRegionID, t.TDSPID, t.PremiseID, t.Created, t.Updated,
-	)
+	if t.OriginalTransactionID != nil {
+		var originalID string
+		err = tx.QueryRow(ctx, voidOriginalUsageTransaction, t.PremiseID, *t.OriginalTransactionID, t.ServicePeriodStart, t.ServicePeriodEnd).Scan(&originalID)
+		if errors.Is(err, pgx.ErrNoRows) {
+			err = ErrOriginalTransactionNotFound
+		}
+		if err != nil {
+			return err
+		}
+		if _, err = tx.Exec(ctx, `UPDATE usage_transaction_detail SET is_canceled = TRUE WHERE usage_transaction_id = $1`, originalID); err != nil {
+			return err
+		}
+		if _, err = tx.Exec(ctx, `UPDATE usage_transaction_summary SET is_canceled = TRUE WHERE usage_transaction_id = $1`, originalID); err != nil {
+			return err
+		}
+		if _, err = tx.Exec(ctx, `UPDATE usage_reconciliation_exception SET status = $2 WHERE usage_transaction_id = $1 AND status = $3`, originalID, dbentity.ReconciliationExceptionStatusSuperseded, dbentity.ReconciliationExceptionStatusOpen); err != nil {
+			return err
+		}
+		if _, err = tx.Exec(ctx, `UPDATE meter_interval_exception SET is_canceled = TRUE WHERE usage_transaction_id = $1`, originalID); err != nil {
+			return err
+		}
+		if _, err = tx.Exec(ctx, `UPDATE meter_usage_channel SET is_canceled = TRUE WHERE usage_transaction_id = $1`, originalID); err != nil {
+			return err
+		}
+		t.OriginalUsageTransactionID = &originalID
+	}
+	err = tx.QueryRow(ctx,
+		`INSERT INTO usage_transaction (id, transaction_id, transaction_type_code, transaction_sub_type_code, transaction_dt, service_period_start_dt, service_period_end_dt, is_final, is_canceled, usage_transaction_purpose_code, power_region_id, tdsp_id, premise_id, original_transaction_id, original_usage_transaction_id, payload, payload_sha256, raw_payload_sha256) VALUES ($1,$2,$3,$4,$5,$6,$7,$8,$9,$10,$11,$12,$13,$14,$15,$16,$17,$18) RETURNING created_dttm, updated_dttm`,
+		t.ID, t.TransactionID, t.TransactionType, t.TransactionSubType, t.TransactionDate, t.ServicePeriodStart, t.ServicePeriodEnd, t.IsFinal, t.IsCanceled, t.Purpose, t.PowerRegionID, t.TDSPID, t.PremiseID, t.OriginalTransactionID, t.OriginalUsageTransactionID, t.Payload, nullIfEmpty(t.PayloadSHA256), t.RawPayloadSHA256,
+	).Scan(&t.Created, &t.Updated)
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) && pgErr.Code == uniqueViolation && pgErr.ConstraintName == "usage_transaction_transaction_id_key" {
+		err = ErrDuplicateTransactionID
+	}
 	if err != nil {
 		return err
 	}
 	for _, d := range details {
+		args := append([]any{d.Start, d.End, d.UsageTransactionID, d.MeterID, d.MeterName, d.PowerRegionID, d.PremiseID, d.IsCanceled, d.ServicePeriodStart, d.ServicePeriodEnd, d.Consumption, d.Production, d.IsInterval}, extraArgs...)
+		_, err = tx.Exec(ctx, detailInsert, args...)
+		if err != nil {
+			return err
+		}
+	}
+	for _, summary := range summaries {
+		_, err = tx.Exec(ctx,
+			`INSERT INTO usage_transaction_summary (id, usage_transaction_id, product_transfer_detail_type_code, premise_id, meter_id, meter_name, channel, service_period_start_dt, service_period_end_dt, quantity, interval_quantity, is_reconciled, is_canceled) VALUES ($1,$2,$3,$4,$5,$6,$7,$8,$9,$10,$11,$12,$13)`,
+			summary.ID, summary.UsageTransactionID, summary.TransferType, summary.PremiseID, summary.MeterID, summary.MeterName, summary.Channel, summary.ServicePeriodStart, summary.ServicePeriodEnd, summary.Quantity, summary.IntervalQuantity, summary.IsReconciled, summary.IsCanceled,
+		)
+		if err != nil {
+			return err
+		}
+		if summary.IsReconciled || summary.IsCanceled {
+			continue
+		}
+		_, err = tx.Exec(ctx,
+			`INSERT INTO usage_reconciliation_exception (id, usage_transaction_id, usage_transaction_summary_id, premise_id, status, summary_quantity, interval_quantity, difference) VALUES ($1,$2,$3,$4,$5,$6,$7,$8)`,
+			uuid.New().String(), summary.UsageTransactionID, summary.ID, summary.PremiseID, dbentity.ReconciliationExceptionStatusOpen, summary.Quantity, summary.IntervalQuantity, summary.Quantity-summary.IntervalQuantity,
+		)
+		if err != nil {
+			return err
+		}
+	}
+	for _, e := range intervalExceptions {
 		_, err = tx.Exec(ctx,
-			`INSERT INTO usage_transaction_detail (usage_transaction_id, meter_id, meter_name, power_region_id, premise_id, is_canceled, service_period_start, service_period_end, consumption, production, created, updated) VALUES ($1,$2,$3,$4,$5,$6,$7,$8,$9,$10,$11,$12)`,
-			d.UsageTransactionID, d.MeterID, d.MeterName, d.PowerRegionID, d.PremiseID, d.IsCanceled, d.ServicePeriodStart, d.ServicePeriodEnd, d.Consumption, d.Production, d.Created, d.Updated,
+			`INSERT INTO meter_interval_exception (id, usage_transaction_id, premise_id, power_region_id, meter_id, meter_name, channel, kind, start_dttm, end_dttm, service_period_start_dt, service_period_end_dt, reported_count, is_canceled) VALUES ($1,$2,$3,$4,$5,$6,$7,$8,$9,$10,$11,$12,$13,$14)`,
+			e.ID, e.UsageTransactionID, e.PremiseID, e.PowerRegionID, e.MeterID, e.MeterName, e.Channel, e.Kind, e.Start, e.End, e.ServicePeriodStart, e.ServicePeriodEnd, e.ReportedCount, e.IsCanceled,
 		)
 		if err != nil {
 			return err
 		}
 	}
-	return nil
+	for _, c := range channelIntervals {
+		_, err = tx.Exec(ctx, channelUpsert, c.Start, c.End, c.MeterID, c.Channel, c.PowerRegionID, c.PremiseID, c.UsageTransactionID, c.UnitOfMeasure, c.Direction, c.Quantity)
+		if err != nil {
+			return err
+		}
+	}
+	affected := []string{t.ID}
+	if t.OriginalUsageTransactionID != nil {
+		affected = append(affected, *t.OriginalUsageTransactionID)
+	}
+	_, err = tx.Exec(ctx, refreshMeterUsage15Minute, affected, dbentity.TransactionSubTypeMonthlyUsage)
+	return err
+}
+
+func nullIfEmpty(s string) *string {
+	if s == "" {
+		return nil
+	}
+	return &s
 }
@@ -0,0 +1,137 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	dbentity "usage-lakehouse/internal/db/entity"
+
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// ErrQuarantineTransactionIDHeld is returned when an edit would give a held transaction the
+// transaction_id of another held transaction.
+var ErrQuarantineTransactionIDHeld = errors.New("another quarantined transaction holds the transaction_id")
+
+type UsageTransactionQuarantineFilter struct {
+	Status        *string
+	Stage         *string
+	PowerRegionID *string
+	TransactionID *string
+	Limit         int
+}
+
+type UsageTransactionQuarantineRepository interface {
+	Create(ctx context.Context, q *dbentity.UsageTransactionQuarantine) error
+	GetByID(ctx context.Context, id string) (*dbentity.UsageTransactionQuarantine, error)
+	// List returns held transactions, newest first, without their payloads.
+	List(ctx context.Context, filter UsageTransactionQuarantineFilter) ([]dbentity.UsageTransactionQuarantine, error)
+	// UpdatePayload replaces the payload of a transaction that is still QUARANTINED.
+	UpdatePayload(ctx context.Context, q *dbentity.UsageTransactionQuarantine) error
+	MarkReplayed(ctx context.Context, id string, usageTransactionID string) error
+	Discard(ctx context.Context, id string) error
+}
+
+type usageTransactionQuarantineRepositorySQL struct {
+	db *pgxpool.Pool
+}
+
+func NewUsageTransactionQuarantineRepository(db *pgxpool.Pool) UsageTransactionQuarantineRepository {
+	return &usageTransactionQuarantineRepositorySQL{db: db}
+}
+
+// Create holds a transaction. When the transaction_id is already held, the held row takes the new
+// payload, stage and reason and keeps its id.
+func (r *usageTransactionQuarantineRepositorySQL) Create(ctx context.Context, q *dbentity.UsageTransactionQuarantine) error {
+	return r.db.QueryRow(ctx, `
+		INSERT INTO usage_transaction_quarantine (id, transaction_id, transaction_sub_type_code, power_region_id, tdsp_id, esi_id, stage, reason, status, payload, payload_sha256, raw_payload_sha256)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12)
+		ON CONFLICT (transaction_id) WHERE status = 'QUARANTINED' DO UPDATE SET
+			transaction_sub_type_code = EXCLUDED.transaction_sub_type_code,
+			power_region_id = EXCLUDED.power_region_id,
+			tdsp_id = EXCLUDED.tdsp_id,
+			esi_id = EXCLUDED.esi_id,
+			stage = EXCLUDED.stage,
+			reason = EXCLUDED.reason,
+			payload = EXCLUDED.payload,
+			payload_sha256 = EXCLUDED.payload_sha256,
+			raw_payload_sha256 = EXCLUDED.raw_payload_sha256
+		RETURNING id, status, created_dttm, updated_dttm`,
+		q.ID, q.TransactionID, q.TransactionSubType, q.PowerRegionID, q.TDSPID, q.EsiID, q.Stage, q.Reason, dbentity.QuarantineStatusQuarantined, q.Payload, q.PayloadSHA256, q.RawPayloadSHA256,
+	).Scan(&q.ID, &q.Status, &q.Created, &q.Updated)
+}
+
+func (r *usageTransactionQuarantineRepositorySQL) GetByID(ctx context.Context, id string) (*dbentity.UsageTransactionQuarantine, error) {
+	var q dbentity.UsageTransactionQuarantine
+	err := r.db.QueryRow(ctx, `SELECT id, transaction_id, transaction_sub_type_code, power_region_id, tdsp_id, esi_id, stage, reason, status, replayed_usage_transaction_id, payload, payload_sha256, raw_payload_sha256, created_dttm, updated_dttm FROM usage_transaction_quarantine WHERE id=$1`, id).
+		Scan(&q.ID, &q.TransactionID, &q.TransactionSubType, &q.PowerRegionID, &q.TDSPID, &q.EsiID, &q.Stage, &q.Reason, &q.Status, &q.ReplayedUsageTransactionID, &q.Payload, &q.PayloadSHA256, &q.RawPayloadSHA256, &q.Created, &q.Updated)
+	if err != nil {
+		return nil, err
+	}
+	return &q, nil
+}
+
+func (r *usageTransactionQuarantineRepositorySQL) List(ctx context.Context, filter UsageTransactionQuarantineFilter) ([]dbentity.UsageTransactionQuarantine, error) {
+	query := `SELECT id, transaction_id, transaction_sub_type_code, power_region_id, tdsp_id, esi_id, stage, reason, status, replayed_usage_transaction_id, payload_sha256, raw_payload_sha256, created_dttm, updated_dttm FROM usage_transaction_quarantine WHERE TRUE`
+	var args []any
+	if filter.Status != nil {
+		args = append(args, *filter.Status)
+		query += fmt.Sprintf(` AND status = $%d`, len(args))
+	}
+	if filter.Stage != nil {
+		args = append(args, *filter.Stage)
+		query += fmt.Sprintf(` AND stage = $%d`, len(args))
+	}
+	if filter.PowerRegionID != nil {
+		args = append(args, *filter.PowerRegionID)
+		query += fmt.Sprintf(` AND power_region_id = $%d`, len(args))
+	}
+	if filter.TransactionID != nil {
+		args = append(args, *filter.TransactionID)
+		query += fmt.Sprintf(` AND transaction_id = $%d`, len(args))
+	}
+	query += ` ORDER BY created_dttm DESC`
+	if filter.Limit > 0 {
+		args = append(args, filter.Limit)
+		query += fmt.Sprintf(` LIMIT $%d`, len(args))
+	}
+	rows, err := r.db.Query(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var held []dbentity.UsageTransactionQuarantine
+	for rows.Next() {
+		var q dbentity.UsageTransactionQuarantine
+		if err := rows.Scan(&q.ID, &q.TransactionID, &q.TransactionSubType, &q.PowerRegionID, &q.TDSPID, &q.EsiID, &q.Stage, &q.Reason, &q.Status, &q.ReplayedUsageTransactionID, &q.PayloadSHA256, &q.RawPayloadSHA256, &q.Created, &q.Updated); err != nil {
+			return nil, err
+		}
+		held = append(held, q)
+	}
+	return held, rows.Err()
+}
+
+func (r *usageTransactionQuarantineRepositorySQL) UpdatePayload(ctx context.Context, q *dbentity.UsageTransactionQuarantine) error {
+	err := r.db.QueryRow(ctx, `
+		UPDATE usage_transaction_quarantine SET transaction_id=$1, esi_id=$2, payload=$3, payload_sha256=$4
+		WHERE id=$5 AND status = 'QUARANTINED'
+		RETURNING updated_dttm`,
+		q.TransactionID, q.EsiID, q.Payload, q.PayloadSHA256, q.ID,
+	).Scan(&q.Updated)
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) && pgErr.Code == uniqueViolation {
+		return ErrQuarantineTransactionIDHeld
+	}
+	return err
+}
+
+func (r *usageTransactionQuarantineRepositorySQL) MarkReplayed(ctx context.Context, id string, usageTransactionID string) error {
+	_, err := r.db.Exec(ctx, `UPDATE usage_transaction_quarantine SET status = 'REPLAYED', replayed_usage_transaction_id=$1 WHERE id=$2`, usageTransactionID, id)
+	return err
+}
+
+func (r *usageTransactionQuarantineRepositorySQL) Discard(ctx context.Context, id string) error {
+	_, err := r.db.Exec(ctx, `UPDATE usage_transaction_quarantine SET status = 'DISCARDED' WHERE id=$1 AND status = 'QUARANTINED'`, id)
+	return err
+}
@@ -0,0 +1,52 @@
+package repository
+
+import (
+	"context"
+	"testing"
+	dbentity "usage-lakehouse/internal/db/entity"
+
+	"github.com/google/uuid"
+)
+
+func TestUsageTransactionQuarantineCreateKeepsTheHeldID(t *testing.T) {
+	ctx := context.Background()
+	repo := NewUsageTransactionQuarantineRepository(testPool(t, "usage_transaction_quarantine"))
+	hold := func(stage string) *dbentity.UsageTransactionQuarantine {
+		t.Helper()
+		q := dbentity.UsageTransactionQuarantine{
+			ID:                 uuid.New().String(),
+			TransactionID:      "867-1",
+			TransactionSubType: dbentity.TransactionSubTypeMonthlyUsage,
+			EsiID:              "10443720004529147",
+			Stage:              stage,
+			Reason:             stage + " does not exist",
+			Payload:            []byte(`{}`),
+			PayloadSHA256:      "44136fa355b3678a1146ad16f7e8649e94fb4fc21fe77e8310c060f61caaff8a",
+		}
+		if err := repo.Create(ctx, &q); err != nil {
+			t.Fatal(err)
+		}
+		return &q
+	}
+
+	first := hold(dbentity.QuarantineStagePowerRegion)
+	second := hold(dbentity.QuarantineStageTDSP)
+	if second.ID != first.ID {
+		t.Fatalf("second hold got id %s, want the held id %s", second.ID, first.ID)
+	}
+	stored, err := repo.GetByID(ctx, first.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stored.Stage != dbentity.QuarantineStageTDSP || stored.Status != dbentity.QuarantineStatusQuarantined {
+		t.Errorf("held transaction = %+v, want it still held at the latest stage", stored)
+	}
+
+	// Once discarded the transaction_id is no longer held, and a new failure is a new hold.
+	if err := repo.Discard(ctx, first.ID); err != nil {
+		t.Fatal(err)
+	}
+	if third := hold(dbentity.QuarantineStageMeter); third.ID == first.ID {
+		t.Errorf("hold after discard reused id %s", first.ID)
+	}
+}
@@ -0,0 +1,110 @@
+// Package uom converts meter readings between units of measure. Readings are active, reactive or
+// apparent power, either as energy over an interval (e.g. kWh) or as demand (e.g. kW).
+package uom
+
+import (
+	"fmt"
+	"math"
+	"time"
+)
+
+type quantity int
+
+const (
+	active quantity = iota
+	reactive
+	apparent
+)
+
+type unit struct {
+	quantity quantity
+	energy   bool
+	scale    float64
+}
+
+var units = map[string]unit{
+	"WH":    {active, true, 1e-3},
+	"KWH":   {active, true, 1},
+	"MWH":   {active, true, 1e3},
+	"W":     {active, false, 1e-3},
+	"KW":    {active, false, 1},
+	"MW":    {active, false, 1e3},
+	"VARH":  {reactive, true, 1e-3},
+	"KVARH": {reactive, true, 1},
+	"MVARH": {reactive, true, 1e3},
+	"VAR":   {reactive, false, 1e-3},
+	"KVAR":  {reactive, false, 1},
+	"MVAR":  {reactive, false, 1e3},
+	"VAH":   {apparent, true, 1e-3},
+	"KVAH":  {apparent, true, 1},
+	"MVAH":  {apparent, true, 1e3},
+	"VA":    {apparent, false, 1e-3},
+	"KVA":   {apparent, false, 1},
+	"MVA":   {apparent, false, 1e3},
+}
+
+// Valid reports whether code is a supported unit of measure.
+func Valid(code string) bool {
+	_, ok := units[code]
+	return ok
+}
+
+// IsEnergy reports whether code measures energy over an interval rather than demand.
+func IsEnergy(code string) bool {
+	return units[code].energy
+}
+
+// Convert converts a reading over an interval of length from one unit to another of the same
+// quantity. Energy converts to demand as its average over the interval, and demand to energy as if
+// it were held for the whole interval.
+func Convert(value float64, from string, to string, length time.Duration) (float64, error) {
+	f, ok := units[from]
+	if !ok {
+		return 0, fmt.Errorf("unknown unit of measure %q", from)
+	}
+	t, ok := units[to]
+	if !ok {
+		return 0, fmt.Errorf("unknown unit of measure %q", to)
+	}
+	if f.quantity != t.quantity {
+		return 0, fmt.Errorf("cannot convert %s to %s", from, to)
+	}
+	value *= f.scale / t.scale
+	if f.energy != t.energy {
+		hours := length.Hours()
+		if hours <= 0 {
+			return 0, fmt.Errorf("cannot convert %s to %s without an interval length", from, to)
+		}
+		if f.energy {
+			value /= hours
+		} else {
+			value *= hours
+		}
+	}
+	return value, nil
+}
+
+// PowerFactor is the ratio of active to apparent energy for kWh and kVARh totals over the same
+// period. It is false when there is no energy to compare.
+func PowerFactor(kwh float64, kvarh float64) (float64, bool) {
+	kvah := math.Hypot(kwh, kvarh)
+	if kvah == 0 {
+		return 0, false
+	}
+	return math.Abs(kwh) / kvah, true
+}
+
+// x12Units maps X12 unit of measurement codes (data element 355) sent in QTY03.
+var x12Units = map[string]string{
+	"KH": "KWH",
+	"K1": "KW",
+	"K2": "KVAR",
+	"K3": "KVARH",
+	"K4": "KVA",
+}
+
+// FromX12 returns the unit of measure for an X12 unit code.
+func FromX12(code string) (string, bool) {
+	u, ok := x12Units[code]
+	return u, ok
+}
@@ -0,0 +1,132 @@
+package uom
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+func TestConvert(t *testing.T) {
+	tests := []struct {
+		value  float64
+		from   string
+		to     string
+		length time.Duration
+		want   float64
+	}{
+		{1500, "WH", "KWH", 0, 1.5},
+		{2, "MWH", "KWH", 0, 2000},
+		{3, "KVARH", "VARH", 0, 3000},
+		{4, "KVA", "MVA", 0, 0.004},
+		{5, "KWH", "KWH", 0, 5},
+		// Energy over 15 minutes averages to four times the demand.
+		{1, "KWH", "KW", 15 * time.Minute, 4},
+		{250, "WH", "KW", 15 * time.Minute, 1},
+		{2, "KVARH", "KVAR", 30 * time.Minute, 4},
+		{3, "KVAH", "KVA", time.Hour, 3},
+		// Demand held for the interval.
+		{4, "KW", "KWH", 15 * time.Minute, 1},
+		{2, "MW", "KWH", 15 * time.Minute, 500},
+		{1000, "VAR", "KVARH", time.Hour, 1},
+	}
+	for _, tt := range tests {
+		got, err := Convert(tt.value, tt.from, tt.to, tt.length)
+		if err != nil {
+			t.Errorf("Convert(%v %s to %s over %s): %v", tt.value, tt.from, tt.to, tt.length, err)
+			continue
+		}
+		if math.Abs(got-tt.want) > 1e-9 {
+			t.Errorf("Convert(%v %s to %s over %s) = %v, want %v", tt.value, tt.from, tt.to, tt.length, got, tt.want)
+		}
+	}
+}
+
+func TestConvertRejections(t *testing.T) {
+	tests := []struct {
+		from   string
+		to     string
+		length time.Duration
+	}{
+		{"KWH", "KVARH", 0},
+		{"KW", "KVA", 0},
+		{"KVARH", "KVAH", time.Hour},
+		{"KWH", "KVAR", 15 * time.Minute},
+		{"KWH", "KW", 0},
+		{"KW", "KWH", -time.Minute},
+		{"KH", "KWH", 0},
+		{"KWH", "", 0},
+	}
+	for _, tt := range tests {
+		if got, err := Convert(1, tt.from, tt.to, tt.length); err == nil {
+			t.Errorf("Convert(1 %s to %s over %s) = %v, want an error", tt.from, tt.to, tt.length, got)
+		}
+	}
+}
+
+func TestValidAndIsEnergy(t *testing.T) {
+	tests := []struct {
+		code   string
+		valid  bool
+		energy bool
+	}{
+		{"KWH", true, true},
+		{"KVARH", true, true},
+		{"VAH", true, true},
+		{"KW", true, false},
+		{"MVAR", true, false},
+		{"KH", false, false},
+		{"kwh", false, false},
+	}
+	for _, tt := range tests {
+		if Valid(tt.code) != tt.valid || IsEnergy(tt.code) != tt.energy {
+			t.Errorf("%s: Valid = %v, IsEnergy = %v, want %v, %v", tt.code, Valid(tt.code), IsEnergy(tt.code), tt.valid, tt.energy)
+		}
+	}
+}
+
+func TestPowerFactor(t *testing.T) {
+	tests := []struct {
+		kwh   float64
+		kvarh float64
+		want  float64
+		ok    bool
+	}{
+		{3, 4, 0.6, true},
+		{10, 0, 1, true},
+		{0, 5, 0, true},
+		{-3, 4, 0.6, true},
+		{3, -4, 0.6, true},
+		{0, 0, 0, false},
+	}
+	for _, tt := range tests {
+		got, ok := PowerFactor(tt.kwh, tt.kvarh)
+		if ok != tt.ok || math.Abs(got-tt.want) > 1e-9 {
+			t.Errorf("PowerFactor(%v, %v) = %v, %v, want %v, %v", tt.kwh, tt.kvarh, got, ok, tt.want, tt.ok)
+		}
+	}
+}
+
+func TestFromX12(t *testing.T) {
+	tests := []struct {
+		code string
+		want string
+		ok   bool
+	}{
+		{"KH", "KWH", true},
+		{"K1", "KW", true},
+		{"K2", "KVAR", true},
+		{"K3", "KVARH", true},
+		{"K4", "KVA", true},
+		{"KWH", "", false},
+		{"", "", false},
+	}
+	for _, tt := range tests {
+		got, ok := FromX12(tt.code)
+		if got != tt.want || ok != tt.ok {
+			t.Errorf("FromX12(%q) = %q, %v, want %q, %v", tt.code, got, ok, tt.want, tt.ok)
+		}
+		if ok && !Valid(got) {
+			t.Errorf("FromX12(%q) = %q, which is not a supported unit", tt.code, got)
+		}
+	}
+}
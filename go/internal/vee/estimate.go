@@ -0,0 +1,77 @@
+package vee
+
+import "time"
+
+const (
+	EstimatorLinearInterpolation = "LINEAR_INTERPOLATION"
+	EstimatorLikeDay             = "LIKE_DAY"
+)
+
+// linearInterpolation fills runs of up to maxIntervals missing intervals that have an actual reading
+// on both sides, on a straight line between the two.
+type linearInterpolation struct {
+	maxIntervals int
+}
+
+func (linearInterpolation) Name() string { return EstimatorLinearInterpolation }
+
+func (e linearInterpolation) Estimate(s *Series, known map[time.Time]float64, slots []time.Time) map[time.Time]float64 {
+	estimates := make(map[time.Time]float64)
+	for i := 0; i < len(slots); {
+		j := i + 1
+		for j < len(slots) && slots[j].Equal(slots[j-1].Add(s.Length)) {
+			j++
+		}
+		before, okBefore := known[slots[i].Add(-s.Length)]
+		after, okAfter := known[slots[j-1].Add(s.Length)]
+		if run := j - i; okBefore && okAfter && run <= e.maxIntervals {
+			step := (after - before) / float64(run+1)
+			for k := i; k < j; k++ {
+				estimates[slots[k]] = before + step*float64(k-i+1)
+			}
+		}
+		i = j
+	}
+	return estimates
+}
+
+// likeDay estimates an interval as the average of the same local clock time on the most recent like
+// days with an actual reading. Weekdays are like other weekdays and weekend days like weekend days.
+type likeDay struct {
+	days     int
+	lookback int
+}
+
+func (likeDay) Name() string { return EstimatorLikeDay }
+
+func (e likeDay) Estimate(s *Series, known map[time.Time]float64, slots []time.Time) map[time.Time]float64 {
+	estimates := make(map[time.Time]float64)
+	loc := s.Location
+	if loc == nil {
+		loc = time.UTC
+	}
+	for _, slot := range slots {
+		local := slot.In(loc)
+		weekend := isWeekend(local.Weekday())
+		var total float64
+		var count int
+		for back := 1; back <= e.lookback && count < e.days; back++ {
+			day := time.Date(local.Year(), local.Month(), local.Day()-back, local.Hour(), local.Minute(), 0, 0, loc)
+			if isWeekend(day.Weekday()) != weekend {
+				continue
+			}
+			if v, ok := known[day.UTC()]; ok {
+				total += v
+				count++
+			}
+		}
+		if count > 0 {
+			estimates[slot] = total / float64(count)
+		}
+	}
+	return estimates
+}
+
+func isWeekend(day time.Weekday) bool {
+	return day == time.Saturday || day == time.Sunday
+}
@@ -0,0 +1,117 @@
+package vee
+
+import (
+	"reflect"
+	"testing"
+	"time"
+	_ "time/tzdata"
+)
+
+func TestLinearInterpolation(t *testing.T) {
+	tests := []struct {
+		name  string
+		known map[int]float64
+		slots []int
+		want  map[int]float64
+	}{
+		{"single interval", map[int]float64{0: 1, 2: 3}, []int{1}, map[int]float64{1: 2}},
+		{"run", map[int]float64{0: 1, 4: 5}, []int{1, 2, 3}, map[int]float64{1: 2, 2: 3, 3: 4}},
+		{"falling", map[int]float64{0: 4, 3: 1}, []int{1, 2}, map[int]float64{1: 3, 2: 2}},
+		{"two runs", map[int]float64{0: 1, 2: 3, 3: 3, 5: 1}, []int{1, 4}, map[int]float64{1: 2, 4: 2}},
+		{"longer than the maximum", map[int]float64{0: 1, 5: 6}, []int{1, 2, 3, 4}, map[int]float64{}},
+		{"no reading before", map[int]float64{2: 1}, []int{0, 1}, map[int]float64{}},
+		{"no reading after", map[int]float64{0: 1}, []int{1, 2}, map[int]float64{}},
+	}
+	s := &Series{Length: 15 * time.Minute}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			known := make(map[time.Time]float64)
+			for i, v := range tt.known {
+				known[slot(i)] = v
+			}
+			var slots []time.Time
+			for _, i := range tt.slots {
+				slots = append(slots, slot(i))
+			}
+			got := make(map[int]float64)
+			for start, v := range (linearInterpolation{maxIntervals: 3}).Estimate(s, known, slots) {
+				got[int(start.Sub(testStart)/(15*time.Minute))] = v
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("estimates = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLikeDay(t *testing.T) {
+	central, err := time.LoadLocation("America/Chicago")
+	if err != nil {
+		t.Fatal(err)
+	}
+	at := func(year int, month time.Month, day, hour, minute int) time.Time {
+		return time.Date(year, month, day, hour, minute, 0, 0, central).UTC()
+	}
+	// Readings at 08:00 local on the days around the 2024-03-10 spring-forward, and at 09:00 local
+	// on the weekdays after it, the same UTC time as 08:00 before it.
+	known := map[time.Time]float64{
+		at(2024, 3, 5, 8, 0):  8,
+		at(2024, 3, 6, 8, 0):  6,
+		at(2024, 3, 7, 8, 0):  4,
+		at(2024, 3, 8, 8, 0):  2,
+		at(2024, 3, 2, 8, 0):  20,
+		at(2024, 3, 3, 8, 0):  30,
+		at(2024, 3, 9, 8, 0):  40,
+		at(2024, 3, 10, 8, 0): 50,
+		at(2024, 3, 11, 9, 0): 100,
+		at(2024, 3, 12, 9, 0): 100,
+	}
+	tests := []struct {
+		name string
+		days int
+		slot time.Time
+		want float64
+		ok   bool
+	}{
+		// Monday after the change: the previous Friday and Thursday at the same local clock time,
+		// not 24 hours earlier.
+		{"weekday after spring-forward", 2, at(2024, 3, 11, 8, 0), 3, true},
+		{"more like days", 4, at(2024, 3, 11, 8, 0), 5, true},
+		// The spring-forward Sunday itself is like the weekend days before it.
+		{"spring-forward day", 2, at(2024, 3, 10, 8, 0), 35, true},
+		{"weekend day", 3, at(2024, 3, 16, 8, 0), (50 + 40 + 30) / 3.0, true},
+		// Only the 09:00 readings are like days; the 08:00 readings before the change are at the same
+		// UTC time but not the same clock time.
+		{"same UTC time before the change", 4, at(2024, 3, 13, 9, 0), 100, true},
+		{"no like day", 2, at(2024, 3, 11, 7, 0), 0, false},
+	}
+	s := &Series{Length: 15 * time.Minute, Location: central}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			estimates := likeDay{days: tt.days, lookback: 14}.Estimate(s, known, []time.Time{tt.slot})
+			got, ok := estimates[tt.slot]
+			if ok != tt.ok || got != tt.want {
+				t.Errorf("estimate = %v, %v, want %v, %v", got, ok, tt.want, tt.ok)
+			}
+		})
+	}
+}
+
+func TestLikeDayFallBack(t *testing.T) {
+	central, err := time.LoadLocation("America/Chicago")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 2024-11-03 has 25 hours in Chicago; the Monday after draws on the Friday before it at the
+	// same local clock time even though the offset changed.
+	friday := time.Date(2024, 11, 1, 17, 0, 0, 0, central).UTC()
+	monday := time.Date(2024, 11, 4, 17, 0, 0, 0, central).UTC()
+	if monday.Sub(friday) != 73*time.Hour {
+		t.Fatalf("fixture spans %s, want 73h", monday.Sub(friday))
+	}
+	s := &Series{Length: 15 * time.Minute, Location: central}
+	estimates := likeDay{days: 1, lookback: 7}.Estimate(s, map[time.Time]float64{friday: 7}, []time.Time{monday})
+	if got, ok := estimates[monday]; !ok || got != 7 {
+		t.Errorf("estimate = %v, %v, want 7", got, ok)
+	}
+}
@@ -0,0 +1,173 @@
+package vee
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"time"
+)
+
+const (
+	RuleNegative  = "NEGATIVE"
+	RuleZeroRun   = "ZERO_RUN"
+	RuleSpike     = "SPIKE"
+	RuleMaxDemand = "MAX_DEMAND"
+	RuleSummary   = "SUMMARY_TOLERANCE"
+)
+
+// actual returns the series' actual readings in the window.
+func actual(s *Series) []Interval {
+	var intervals []Interval
+	for _, i := range s.Intervals {
+		if i.Quality == QualityActual && i.Consumption != nil {
+			intervals = append(intervals, i)
+		}
+	}
+	return intervals
+}
+
+func flagInterval(rule string, i Interval, replace bool, format string, args ...any) Flag {
+	value := *i.Consumption
+	return Flag{Rule: rule, Start: i.Start, End: i.End, Value: &value, Message: fmt.Sprintf(format, args...), Replace: replace}
+}
+
+// negativeRule rejects negative consumption.
+type negativeRule struct{}
+
+func (negativeRule) Name() string { return RuleNegative }
+
+func (negativeRule) Check(s *Series) []Flag {
+	var flags []Flag
+	for _, i := range actual(s) {
+		if *i.Consumption < 0 {
+			flags = append(flags, flagInterval(RuleNegative, i, true, "consumption %g is negative", *i.Consumption))
+		}
+	}
+	return flags
+}
+
+// zeroRunRule reports runs of zero readings long enough to suggest a stopped meter. The readings may
+// be genuine, e.g. a vacant premise, so they are kept.
+type zeroRunRule struct {
+	run int
+}
+
+func (zeroRunRule) Name() string { return RuleZeroRun }
+
+func (r zeroRunRule) Check(s *Series) []Flag {
+	if r.run <= 0 {
+		return nil
+	}
+	var flags []Flag
+	intervals := actual(s)
+	for start := 0; start < len(intervals); {
+		end := start
+		for end < len(intervals) && *intervals[end].Consumption == 0 && (end == start || intervals[end].Start.Equal(intervals[end-1].End)) {
+			end++
+		}
+		if end-start >= r.run {
+			flags = append(flags, Flag{
+				Rule:    RuleZeroRun,
+				Start:   intervals[start].Start,
+				End:     intervals[end-1].End,
+				Message: fmt.Sprintf("%d consecutive zero readings", end-start),
+			})
+		}
+		if end == start {
+			end++
+		}
+		start = end
+	}
+	return flags
+}
+
+// spikeRule rejects readings far above the window's median nonzero reading.
+type spikeRule struct {
+	factor  float64
+	minimum float64
+}
+
+func (spikeRule) Name() string { return RuleSpike }
+
+func (r spikeRule) Check(s *Series) []Flag {
+	intervals := actual(s)
+	var nonzero []float64
+	for _, i := range intervals {
+		if *i.Consumption > 0 {
+			nonzero = append(nonzero, *i.Consumption)
+		}
+	}
+	if r.factor <= 0 || len(nonzero) == 0 {
+		return nil
+	}
+	sort.Float64s(nonzero)
+	median := nonzero[len(nonzero)/2]
+	if len(nonzero)%2 == 0 {
+		median = (nonzero[len(nonzero)/2-1] + median) / 2
+	}
+	limit := math.Max(median*r.factor, r.minimum)
+	var flags []Flag
+	for _, i := range intervals {
+		if *i.Consumption > limit {
+			flags = append(flags, flagInterval(RuleSpike, i, true, "consumption %g exceeds %g times the median reading %g", *i.Consumption, r.factor, median))
+		}
+	}
+	return flags
+}
+
+// maxDemandRule rejects readings whose average demand over the interval exceeds the meter's
+// configured maximum.
+type maxDemandRule struct{}
+
+func (maxDemandRule) Name() string { return RuleMaxDemand }
+
+func (maxDemandRule) Check(s *Series) []Flag {
+	if s.MaxDemandKW == nil || s.Length <= 0 {
+		return nil
+	}
+	perHour := float64(time.Hour) / float64(s.Length)
+	var flags []Flag
+	for _, i := range actual(s) {
+		if demand := *i.Consumption * perHour; demand > *s.MaxDemandKW {
+			flags = append(flags, flagInterval(RuleMaxDemand, i, true, "demand %g kW exceeds the meter maximum of %g kW", demand, *s.MaxDemandKW))
+		}
+	}
+	return flags
+}
+
+// summaryRule reports summaries whose quantity differs from the meter's actual interval readings over
+// the same period by more than the tolerance. It cannot tell which readings are wrong, so none are
+// replaced.
+type summaryRule struct {
+	tolerance float64
+}
+
+func (summaryRule) Name() string { return RuleSummary }
+
+func (r summaryRule) Check(s *Series) []Flag {
+	intervals := actual(s)
+	var flags []Flag
+	for _, summary := range s.Summaries {
+		if summary.Start.Before(s.From) || summary.End.After(s.To) {
+			continue
+		}
+		var total float64
+		for _, i := range intervals {
+			if !i.Start.Before(summary.Start) && i.Start.Before(summary.End) {
+				total += *i.Consumption
+			}
+		}
+		if math.Abs(total-summary.Quantity) <= r.tolerance*math.Abs(summary.Quantity) {
+			continue
+		}
+		quantity := summary.Quantity
+		flags = append(flags, Flag{
+			Rule:    RuleSummary,
+			Start:   summary.Start,
+			End:     summary.End,
+			Value:   &quantity,
+			Message: fmt.Sprintf("intervals total %g but the summary reports %g", total, summary.Quantity),
+		})
+	}
+	return flags
+}
@@ -0,0 +1,167 @@
+package vee
+
+import (
+	"math"
+	"reflect"
+	"testing"
+	"time"
+)
+
+var testStart = time.Date(2024, 1, 8, 6, 0, 0, 0, time.UTC)
+
+// readings returns actual 15-minute readings from testStart. NaN marks an interval with no reading,
+// which is left out of the series.
+func readings(values ...float64) []Interval {
+	var intervals []Interval
+	for i, v := range values {
+		if math.IsNaN(v) {
+			continue
+		}
+		start := testStart.Add(time.Duration(i) * 15 * time.Minute)
+		v := v
+		intervals = append(intervals, Interval{Start: start, End: start.Add(15 * time.Minute), Consumption: &v, Quality: QualityActual})
+	}
+	return intervals
+}
+
+func testSeries(values ...float64) *Series {
+	return &Series{
+		Length:    15 * time.Minute,
+		From:      testStart,
+		To:        testStart.Add(time.Duration(len(values)) * 15 * time.Minute),
+		Intervals: readings(values...),
+	}
+}
+
+// slot is the start of the i-th 15-minute interval from testStart.
+func slot(i int) time.Time {
+	return testStart.Add(time.Duration(i) * 15 * time.Minute)
+}
+
+// flagged is the [start, end) interval indexes and Replace of a flag.
+type flagged struct {
+	start, end int
+	replace    bool
+}
+
+func flagIndexes(flags []Flag) []flagged {
+	var out []flagged
+	for _, f := range flags {
+		out = append(out, flagged{int(f.Start.Sub(testStart) / (15 * time.Minute)), int(f.End.Sub(testStart) / (15 * time.Minute)), f.Replace})
+	}
+	return out
+}
+
+func TestNegativeRule(t *testing.T) {
+	got := flagIndexes(negativeRule{}.Check(testSeries(1, -0.5, 0, -2)))
+	want := []flagged{{1, 2, true}, {3, 4, true}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("flags = %v, want %v", got, want)
+	}
+}
+
+func TestZeroRunRule(t *testing.T) {
+	nan := math.NaN()
+	tests := []struct {
+		name   string
+		run    int
+		values []float64
+		want   []flagged
+	}{
+		{"run in the middle", 3, []float64{1, 0, 0, 0, 1}, []flagged{{1, 4, false}}},
+		{"run at the end", 3, []float64{1, 0, 0, 0, 0}, []flagged{{1, 5, false}}},
+		{"two runs", 2, []float64{0, 0, 1, 0, 0, 0}, []flagged{{0, 2, false}, {3, 6, false}}},
+		{"too short", 3, []float64{0, 0, 1, 0, 0}, nil},
+		{"broken by a missing reading", 3, []float64{0, 0, nan, 0}, nil},
+		{"disabled", 0, []float64{0, 0, 0, 0}, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			flags := zeroRunRule{run: tt.run}.Check(testSeries(tt.values...))
+			if got := flagIndexes(flags); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("flags = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestZeroRunRuleSkipsEstimates(t *testing.T) {
+	s := testSeries(0, 0, 0)
+	s.Intervals[1].Quality = QualityEstimated
+	if flags := (zeroRunRule{run: 2}).Check(s); flags != nil {
+		t.Errorf("flags = %v, want none: an estimate breaks the run", flags)
+	}
+}
+
+func TestSpikeRule(t *testing.T) {
+	tests := []struct {
+		name    string
+		factor  float64
+		minimum float64
+		values  []float64
+		want    []flagged
+	}{
+		// The median of 1, 2 and 30 is 2.
+		{"odd count median", 10, 1, []float64{1, 2, 30}, []flagged{{2, 3, true}}},
+		// The median of 1, 2, 3 and 26 is 2.5, so 25 is the limit.
+		{"even count median", 10, 1, []float64{1, 2, 3, 26}, []flagged{{3, 4, true}}},
+		// Zeros are left out of the median, which would otherwise be 0.
+		{"zeros ignored", 10, 1, []float64{0, 0, 0, 0, 1, 1, 1, 50}, []flagged{{7, 8, true}}},
+		{"below the minimum", 10, 5, []float64{0.1, 0.1, 0.1, 2}, nil},
+		{"all zero", 10, 1, []float64{0, 0, 0}, nil},
+		{"disabled", 0, 1, []float64{1, 1, 100}, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			flags := spikeRule{factor: tt.factor, minimum: tt.minimum}.Check(testSeries(tt.values...))
+			if got := flagIndexes(flags); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("flags = %v, want %v", got, tt.want)
+			}
+			for _, f := range flags {
+				if f.Value == nil || *f.Value != tt.values[len(tt.values)-1] {
+					t.Errorf("flag value = %v, want the spike's reading", f.Value)
+				}
+			}
+		})
+	}
+}
+
+func TestMaxDemandRule(t *testing.T) {
+	maxKW := 10.0
+	tests := []struct {
+		name   string
+		length time.Duration
+		max    *float64
+		values []float64
+		want   []flagged
+	}{
+		// 2.5 kWh over 15 minutes is 10 kW, exactly the maximum.
+		{"15 minute", 15 * time.Minute, &maxKW, []float64{2.5, 2.6, 1}, []flagged{{1, 2, true}}},
+		{"hourly", time.Hour, &maxKW, []float64{10, 10.5}, []flagged{{1, 2, true}}},
+		{"no maximum", 15 * time.Minute, nil, []float64{100}, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := testSeries(tt.values...)
+			s.Length = tt.length
+			s.MaxDemandKW = tt.max
+			if got := flagIndexes(maxDemandRule{}.Check(s)); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("flags = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSummaryRule(t *testing.T) {
+	s := testSeries(1, 1, 1, 1)
+	s.Summaries = []Summary{
+		{Start: slot(0), End: slot(4), Quantity: 4.05},
+		{Start: slot(0), End: slot(2), Quantity: 3},
+		// Summaries reaching outside the window cannot be checked against it.
+		{Start: slot(-1), End: slot(4), Quantity: 100},
+	}
+	flags := summaryRule{tolerance: 0.02}.Check(s)
+	if len(flags) != 1 || !flags[0].Start.Equal(slot(0)) || !flags[0].End.Equal(slot(2)) || flags[0].Replace {
+		t.Errorf("flags = %+v, want the second summary only, not replaced", flags)
+	}
+}
@@ -0,0 +1,216 @@
+// Package vee validates, estimates and edits a meter's interval consumption after ingestion. Rules
+// flag readings that fail validation; estimators fill intervals with no reading and replace readings
+// a rule rejects. Every value written back records whether it is an estimate or an edit and the rule
+// that produced it.
+package vee
+
+import (
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	QualityActual    = "ACTUAL"
+	QualityEstimated = "ESTIMATED"
+	QualityEdited    = "EDITED"
+)
+
+// Interval is one reading of the meter's consumption channel. Start and End are UTC instants and
+// Consumption is nil when the interval has no valid reading. Rules only validate actual readings.
+type Interval struct {
+	Start       time.Time
+	End         time.Time
+	Consumption *float64
+	Quality     string
+	QualityRule string
+}
+
+// Summary is a consumption total the utility reported for the meter over [Start, End).
+type Summary struct {
+	Start    time.Time
+	End      time.Time
+	Quantity float64
+}
+
+// Series is one meter's readings over the window [From, To) being validated, in start order.
+// History holds the readings before From that like-day estimation draws on.
+type Series struct {
+	MeterID     string
+	Length      time.Duration
+	Location    *time.Location
+	MaxDemandKW *float64
+	From        time.Time
+	To          time.Time
+	Intervals   []Interval
+	History     []Interval
+	Summaries   []Summary
+}
+
+// Flag is one validation failure. Replace marks readings that must be re-estimated rather than only
+// reported.
+type Flag struct {
+	Rule    string
+	Start   time.Time
+	End     time.Time
+	Value   *float64
+	Message string
+	Replace bool
+}
+
+// Value is an estimated or edited reading to write back for the interval starting at Start.
+type Value struct {
+	Start       time.Time
+	End         time.Time
+	Consumption float64
+	Quality     string
+	Rule        string
+}
+
+type Result struct {
+	Flags  []Flag
+	Values []Value
+}
+
+// Rule validates a series.
+type Rule interface {
+	Name() string
+	Check(s *Series) []Flag
+}
+
+// Estimator fills intervals. known holds the series' actual readings by UTC start, excluding those a
+// rule rejected; Estimate returns values for the slots it can fill and leaves the rest to the next
+// estimator.
+type Estimator interface {
+	Name() string
+	Estimate(s *Series, known map[time.Time]float64, slots []time.Time) map[time.Time]float64
+}
+
+type Config struct {
+	// SpikeFactor flags readings above this multiple of the window's median nonzero reading.
+	SpikeFactor float64
+	// SpikeMinimum is the smallest reading, in kWh, treated as a spike.
+	SpikeMinimum float64
+	// ZeroRun is the number of consecutive zero readings flagged as a possible stopped meter.
+	ZeroRun int
+	// SummaryTolerance is the fraction a summary may differ from the sum of its intervals.
+	SummaryTolerance float64
+	// MaxInterpolation is the longest run of missing intervals filled by linear interpolation.
+	MaxInterpolation int
+	// LikeDays is how many like days are averaged for a like-day estimate, searching back LikeDayLookback days.
+	LikeDays        int
+	LikeDayLookback int
+}
+
+func DefaultConfig() Config {
+	return Config{
+		SpikeFactor:      10,
+		SpikeMinimum:     1,
+		ZeroRun:          96,
+		SummaryTolerance: 0.02,
+		MaxInterpolation: 8,
+		LikeDays:         4,
+		LikeDayLookback:  28,
+	}
+}
+
+type Engine struct {
+	config     Config
+	rules      []Rule
+	estimators []Estimator
+}
+
+// New returns an engine running the standard rules, then filling intervals by linear interpolation
+// where the gap is short and by like-day estimation otherwise.
+func New(config Config) *Engine {
+	return &Engine{
+		config: config,
+		rules: []Rule{
+			negativeRule{},
+			zeroRunRule{run: config.ZeroRun},
+			spikeRule{factor: config.SpikeFactor, minimum: config.SpikeMinimum},
+			maxDemandRule{},
+			summaryRule{tolerance: config.SummaryTolerance},
+		},
+		estimators: []Estimator{
+			linearInterpolation{maxIntervals: config.MaxInterpolation},
+			likeDay{days: config.LikeDays, lookback: config.LikeDayLookback},
+		},
+	}
+}
+
+// Lookback is how far before a window the history passed in Series.History should reach.
+func (e *Engine) Lookback() time.Duration {
+	return time.Duration(e.config.LikeDayLookback+1) * 24 * time.Hour
+}
+
+// Run validates the series and estimates every interval in the window that has no actual reading or
+// whose reading a rule rejected. Estimates and edits already stored are recomputed so that they
+// follow the readings around them. Intervals no estimator can fill are left as they are.
+func (e *Engine) Run(s *Series) Result {
+	var result Result
+	replaced := make(map[time.Time]string)
+	for _, rule := range e.rules {
+		for _, flag := range rule.Check(s) {
+			result.Flags = append(result.Flags, flag)
+			if _, ok := replaced[flag.Start]; flag.Replace && !ok {
+				replaced[flag.Start] = flag.Rule
+			}
+		}
+	}
+
+	edited := make(map[time.Time]string)
+	for _, i := range s.Intervals {
+		if i.Quality == QualityEdited {
+			edited[i.Start], _, _ = strings.Cut(i.QualityRule, "/")
+		}
+	}
+	known := make(map[time.Time]float64)
+	for _, intervals := range [][]Interval{s.History, s.Intervals} {
+		for _, i := range intervals {
+			if _, ok := replaced[i.Start]; ok || i.Consumption == nil || i.Quality != QualityActual {
+				continue
+			}
+			known[i.Start] = *i.Consumption
+		}
+	}
+	var slots []time.Time
+	for t := s.From; t.Before(s.To); t = t.Add(s.Length) {
+		if _, ok := known[t]; !ok {
+			slots = append(slots, t)
+		}
+	}
+
+	filled := make(map[time.Time]Value)
+	for _, estimator := range e.estimators {
+		if len(slots) == 0 {
+			break
+		}
+		estimates := estimator.Estimate(s, known, slots)
+		remaining := slots[:0]
+		for _, t := range slots {
+			v, ok := estimates[t]
+			if !ok {
+				remaining = append(remaining, t)
+				continue
+			}
+			value := Value{Start: t, End: t.Add(s.Length), Consumption: v, Quality: QualityEstimated, Rule: estimator.Name()}
+			// An edit stays an edit when it is re-estimated.
+			rule, ok := replaced[t]
+			if !ok {
+				rule, ok = edited[t]
+			}
+			if ok {
+				value.Quality = QualityEdited
+				value.Rule = rule + "/" + estimator.Name()
+			}
+			filled[t] = value
+		}
+		slots = remaining
+	}
+	for _, v := range filled {
+		result.Values = append(result.Values, v)
+	}
+	sort.Slice(result.Values, func(i, j int) bool { return result.Values[i].Start.Before(result.Values[j].Start) })
+	return result
+}
@@ -0,0 +1,163 @@
+package vee
+
+import (
+	"math"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func testConfig() Config {
+	config := DefaultConfig()
+	config.ZeroRun = 4
+	config.MaxInterpolation = 2
+	config.LikeDays = 1
+	config.LikeDayLookback = 7
+	return config
+}
+
+// estimated is the interval index, consumption, quality and rule of a Value.
+type estimated struct {
+	slot        int
+	consumption float64
+	quality     string
+	rule        string
+}
+
+func TestEngineRun(t *testing.T) {
+	nan := math.NaN()
+	tests := []struct {
+		name    string
+		values  []float64
+		history []float64
+		// edit sets the quality and rule the series' interval 2 was stored with.
+		edit []string
+		want []estimated
+	}{
+		{
+			name:   "missing reading",
+			values: []float64{1, nan, 3, 3, 3, 3},
+			want:   []estimated{{1, 2, QualityEstimated, EstimatorLinearInterpolation}},
+		},
+		{
+			name:   "spike",
+			values: []float64{1, 1, 40, 1, 1, 1},
+			want:   []estimated{{2, 1, QualityEdited, RuleSpike + "/" + EstimatorLinearInterpolation}},
+		},
+		{
+			name:   "negative reading",
+			values: []float64{1, 2, -5, 4, 4, 4},
+			want:   []estimated{{2, 3, QualityEdited, RuleNegative + "/" + EstimatorLinearInterpolation}},
+		},
+		{
+			// The stored edit of 1 no longer fits between 2 and 4 and is re-estimated, staying an edit
+			// of the rule that first rejected it.
+			name:   "edited interval",
+			values: []float64{1, 2, 1, 4, 4, 4},
+			edit:   []string{QualityEdited, RuleSpike + "/" + EstimatorLinearInterpolation},
+			want:   []estimated{{2, 3, QualityEdited, RuleSpike + "/" + EstimatorLinearInterpolation}},
+		},
+		{
+			name:   "edited interval now filled by like day",
+			values: []float64{1, nan, 1, nan, 4, 4},
+			// The same slots on Friday, the last weekday before testStart.
+			history: []float64{7, 7, 5, 7, 7, 7},
+			edit:    []string{QualityEdited, RuleMaxDemand + "/" + EstimatorLinearInterpolation},
+			want: []estimated{
+				{1, 7, QualityEstimated, EstimatorLikeDay},
+				{2, 5, QualityEdited, RuleMaxDemand + "/" + EstimatorLikeDay},
+				{3, 7, QualityEstimated, EstimatorLikeDay},
+			},
+		},
+		{
+			name:   "estimated interval",
+			values: []float64{1, 2, 9, 4, 4, 4},
+			edit:   []string{QualityEstimated, EstimatorLinearInterpolation},
+			want:   []estimated{{2, 3, QualityEstimated, EstimatorLinearInterpolation}},
+		},
+		{
+			name:   "gap no estimator can fill",
+			values: []float64{1, nan, nan, nan, 4, 4},
+			want:   nil,
+		},
+		{
+			// A zero run is flagged but its readings stand.
+			name:   "zero run kept",
+			values: []float64{0, 0, 0, 0, 0, 0},
+			want:   nil,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := testSeries(tt.values...)
+			if tt.edit != nil {
+				for i := range s.Intervals {
+					if s.Intervals[i].Start.Equal(slot(2)) {
+						s.Intervals[i].Quality, s.Intervals[i].QualityRule = tt.edit[0], tt.edit[1]
+					}
+				}
+			}
+			friday := -3 * 24 * 4
+			for i, v := range tt.history {
+				start := slot(friday + i)
+				v := v
+				s.History = append(s.History, Interval{Start: start, End: start.Add(15 * time.Minute), Consumption: &v, Quality: QualityActual})
+			}
+			result := New(testConfig()).Run(s)
+			var got []estimated
+			for _, v := range result.Values {
+				if !v.End.Equal(v.Start.Add(s.Length)) {
+					t.Errorf("value at %s ends at %s", v.Start, v.End)
+				}
+				got = append(got, estimated{int(v.Start.Sub(testStart) / (15 * time.Minute)), v.Consumption, v.Quality, v.Rule})
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("values = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestEngineRunFallBackDay(t *testing.T) {
+	central, err := time.LoadLocation("America/Chicago")
+	if err != nil {
+		t.Fatal(err)
+	}
+	from := time.Date(2024, 11, 3, 0, 0, 0, 0, central).UTC()
+	to := time.Date(2024, 11, 4, 0, 0, 0, 0, central).UTC()
+	// 01:00 is read twice, at 06:00 and 07:00 UTC, and neither reading arrived.
+	firstOne, secondOne := from.Add(time.Hour), from.Add(2*time.Hour)
+	s := &Series{Length: time.Hour, Location: central, From: from, To: to}
+	for start := from; start.Before(to); start = start.Add(time.Hour) {
+		if start.Equal(firstOne) || start.Equal(secondOne) {
+			continue
+		}
+		v := 1.0
+		s.Intervals = append(s.Intervals, Interval{Start: start, End: start.Add(time.Hour), Consumption: &v, Quality: QualityActual})
+	}
+	if len(s.Intervals) != 23 {
+		t.Fatalf("series has %d readings, want the 25 hours of the day less 2", len(s.Intervals))
+	}
+	saturday := time.Date(2024, 11, 2, 1, 0, 0, 0, central).UTC()
+	v := 4.0
+	s.History = []Interval{{Start: saturday, End: saturday.Add(time.Hour), Consumption: &v, Quality: QualityActual}}
+
+	config := testConfig()
+	config.MaxInterpolation = 1
+	result := New(config).Run(s)
+	// Both 01:00 hours are like Saturday's 01:00.
+	want := []Value{
+		{Start: firstOne, End: firstOne.Add(time.Hour), Consumption: 4, Quality: QualityEstimated, Rule: EstimatorLikeDay},
+		{Start: secondOne, End: secondOne.Add(time.Hour), Consumption: 4, Quality: QualityEstimated, Rule: EstimatorLikeDay},
+	}
+	if !reflect.DeepEqual(result.Values, want) {
+		t.Errorf("values = %+v, want %+v", result.Values, want)
+	}
+
+	// With interpolation allowed across two hours, the gap is bridged instead.
+	config.MaxInterpolation = 2
+	result = New(config).Run(s)
+	if len(result.Values) != 2 || result.Values[0].Rule != EstimatorLinearInterpolation || result.Values[0].Consumption != 1 {
+		t.Errorf("values = %+v, want two interpolated hours of 1", result.Values)
+	}
+}
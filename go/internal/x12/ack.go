@@ -0,0 +1,173 @@
+package x12
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type AcknowledgementType string
+
+const (
+	Acknowledgement997 AcknowledgementType = "997"
+	Acknowledgement999 AcknowledgementType = "999"
+)
+
+// Segment syntax error codes (AK304/IK304).
+const (
+	SegmentUnrecognized        = "1"
+	SegmentUnexpected          = "2"
+	SegmentMandatoryMissing    = "3"
+	SegmentNotInProperSequence = "7"
+	SegmentHasElementErrors    = "8"
+)
+
+// Element syntax error codes (AK403/IK403).
+const (
+	ElementMandatoryMissing = "1"
+	ElementInvalidCharacter = "6"
+	ElementInvalidCodeValue = "7"
+	ElementInvalidDate      = "8"
+	ElementInvalidTime      = "9"
+)
+
+const (
+	setAccepted          = "A"
+	setRejected          = "R"
+	setSyntaxErrorsFound = "5"
+	groupPartial         = "P"
+	implementation999    = "005010X231A1"
+)
+
+// SetResult is the outcome of processing one ST/SE transaction set.
+type SetResult struct {
+	GroupControlNumber string
+	ControlNumber      string
+	Accepted           bool
+	Errors             []SegmentError
+}
+
+// ControlNumbersNeeded is how many control numbers BuildAcknowledgement takes to answer ic: one for
+// each functional group, and at least one for the ISA envelope.
+func ControlNumbersNeeded(ic *Interchange) int {
+	return max(1, len(ic.FunctionalGroups))
+}
+
+// BuildAcknowledgement renders a 997 or 999 interchange answering every functional group of ic.
+// The response reverses sender and receiver. controlNumbers, ControlNumbersNeeded(ic) of them from a
+// persisted sequence, number its groups in order; the first also numbers its ISA envelope, so no two
+// acknowledgements share an interchange or group control number.
+func BuildAcknowledgement(ic *Interchange, kind AcknowledgementType, results []SetResult, controlNumbers []int64, now time.Time) string {
+	e := string(ic.Delimiters.Element)
+	t := string(ic.Delimiters.Segment)
+	var b strings.Builder
+	write := func(elements ...string) int {
+		for len(elements) > 1 && elements[len(elements)-1] == "" {
+			elements = elements[:len(elements)-1]
+		}
+		b.WriteString(strings.Join(elements, e))
+		b.WriteString(t)
+		b.WriteString("\n")
+		return 1
+	}
+	byControlNumber := make(map[string]SetResult, len(results))
+	for _, result := range results {
+		byControlNumber[result.GroupControlNumber+"/"+result.ControlNumber] = result
+	}
+	isaControl := fmt.Sprintf("%09d", controlNumbers[0])
+	write("ISA", "00", pad("", 10), "00", pad("", 10),
+		ic.ReceiverQualifier, pad(ic.ReceiverID, 15), ic.SenderQualifier, pad(ic.SenderID, 15),
+		now.Format("060102"), now.Format("1504"), isa11(ic), ic.Version, isaControl, "0", ic.UsageIndicator, string(ic.Delimiters.Component))
+
+	for i, group := range ic.FunctionalGroups {
+		groupControl := strconv.FormatInt(controlNumbers[i], 10)
+		version := group.Version
+		if kind == Acknowledgement999 {
+			version = implementation999
+		}
+		write("GS", "FA", group.ReceiverID, group.SenderID, now.Format("20060102"), now.Format("1504"), groupControl, "X", version)
+		setControl := fmt.Sprintf("%04d", i+1)
+		count := write("ST", string(kind), setControl)
+		if kind == Acknowledgement999 {
+			count += write("AK1", group.FunctionalID, group.ControlNumber, group.Version)
+		} else {
+			count += write("AK1", group.FunctionalID, group.ControlNumber)
+		}
+		accepted := 0
+		for _, set := range group.TransactionSets {
+			result, ok := byControlNumber[group.ControlNumber+"/"+set.ControlNumber]
+			if !ok {
+				result = SetResult{ControlNumber: set.ControlNumber}
+			}
+			count += write("AK2", set.Code, set.ControlNumber)
+			for _, se := range result.Errors {
+				count += writeSegmentError(write, kind, se)
+			}
+			if result.Accepted {
+				accepted++
+				count += write(trailerID(kind), setAccepted)
+			} else {
+				count += write(trailerID(kind), setRejected, setSyntaxErrorsFound)
+			}
+		}
+		status := setAccepted
+		if accepted == 0 && len(group.TransactionSets) > 0 {
+			status = setRejected
+		} else if accepted < len(group.TransactionSets) {
+			status = groupPartial
+		}
+		n := strconv.Itoa(len(group.TransactionSets))
+		count += write("AK9", status, n, n, strconv.Itoa(accepted))
+		write("SE", strconv.Itoa(count+1), setControl)
+		write("GE", "1", groupControl)
+	}
+	write("IEA", strconv.Itoa(len(ic.FunctionalGroups)), isaControl)
+	return b.String()
+}
+
+func writeSegmentError(write func(...string) int, kind AcknowledgementType, se SegmentError) int {
+	segmentTag, elementTag := "AK3", "AK4"
+	if kind == Acknowledgement999 {
+		segmentTag, elementTag = "IK3", "IK4"
+	}
+	code := se.Code
+	if code == "" {
+		code = SegmentHasElementErrors
+	}
+	count := write(segmentTag, se.SegmentID, strconv.Itoa(se.Position), "", code)
+	if se.Element > 0 {
+		elementCode := se.ElementCode
+		if elementCode == "" {
+			elementCode = ElementInvalidCodeValue
+		}
+		count += write(elementTag, strconv.Itoa(se.Element), se.ElementReference, elementCode, se.BadData)
+	}
+	return count
+}
+
+// isa11 is the standards identifier U before version 00501 and the interchange's repetition
+// separator from then on, ^ when it did not send one.
+func isa11(ic *Interchange) string {
+	if ic.Version < repetitionVersion {
+		return "U"
+	}
+	if ic.Delimiters.Repetition != 0 {
+		return string(ic.Delimiters.Repetition)
+	}
+	return "^"
+}
+
+func trailerID(kind AcknowledgementType) string {
+	if kind == Acknowledgement999 {
+		return "IK5"
+	}
+	return "AK5"
+}
+
+func pad(s string, n int) string {
+	if len(s) >= n {
+		return s[:n]
+	}
+	return s + strings.Repeat(" ", n-len(s))
+}
@@ -0,0 +1,124 @@
+package x12
+
+import (
+	"flag"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+var update = flag.Bool("update", false, "rewrite the golden acknowledgements in testdata")
+
+func TestBuildAcknowledgement(t *testing.T) {
+	fixture := readFixture(t)
+	// The same interchange sent under version 00501, whose ISA11 is the repetition separator.
+	fixture5010 := strings.NewReplacer("*U*00401*", "*^*00501*", "*X*004010~", "*X*005010~").Replace(fixture)
+	results := []SetResult{
+		{GroupControlNumber: "4512", ControlNumber: "0001", Accepted: true},
+		{GroupControlNumber: "4512", ControlNumber: "0002", Errors: []SegmentError{
+			{SegmentID: "QTY", Position: 18, Element: 2, ElementReference: "380", Code: SegmentHasElementErrors, ElementCode: ElementInvalidCharacter, BadData: "1.2S"},
+			{SegmentID: "DTM", Position: 19, Code: SegmentNotInProperSequence},
+		}},
+	}
+	allRejected := []SetResult{
+		{GroupControlNumber: "4512", ControlNumber: "0001", Errors: []SegmentError{{SegmentID: "BPT", Position: 2, Code: SegmentMandatoryMissing}}},
+		// A set without a result is rejected without detail.
+	}
+	tests := []struct {
+		name    string
+		data    string
+		kind    AcknowledgementType
+		results []SetResult
+		golden  string
+	}{
+		{"997", fixture, Acknowledgement997, results, "997.golden"},
+		{"999", fixture5010, Acknowledgement999, results, "999.golden"},
+		{"997 rejecting the group", fixture, Acknowledgement997, allRejected, "997_rejected.golden"},
+		{"999 of a 00401 interchange", fixture, Acknowledgement999, allRejected, "999_00401.golden"},
+	}
+	now := time.Date(2024, 2, 5, 15, 4, 0, 0, time.UTC)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ic, err := Parse(strings.NewReader(tt.data))
+			if err != nil {
+				t.Fatal(err)
+			}
+			got := BuildAcknowledgement(ic, tt.kind, tt.results, []int64{17}, now)
+			path := "testdata/" + tt.golden
+			if *update {
+				if err := os.WriteFile(path, []byte(got), 0o644); err != nil {
+					t.Fatal(err)
+				}
+			}
+			want, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != string(want) {
+				t.Errorf("acknowledgement =\n%s\nwant\n%s", got, want)
+			}
+
+			// The acknowledgement is itself a well-formed interchange.
+			ack, err := Parse(strings.NewReader(got))
+			if err != nil {
+				t.Fatalf("parse acknowledgement: %v", err)
+			}
+			if ack.SenderID != ic.ReceiverID || ack.ReceiverID != ic.SenderID || ack.ControlNumber != "000000017" || ack.Version != ic.Version {
+				t.Errorf("acknowledgement envelope = %s to %s control %s version %s", ack.SenderID, ack.ReceiverID, ack.ControlNumber, ack.Version)
+			}
+			if len(ack.FunctionalGroups) != 1 || ack.FunctionalGroups[0].ControlNumber != "17" {
+				t.Errorf("acknowledgement groups = %+v, want one numbered 17 from the control sequence", ack.FunctionalGroups)
+			}
+			if ack.Delimiters != ic.Delimiters {
+				t.Errorf("acknowledgement delimiters = %+v, want %+v", ack.Delimiters, ic.Delimiters)
+			}
+		})
+	}
+}
+
+func TestBuildAcknowledgementNumbersEachGroup(t *testing.T) {
+	fixture := readFixture(t)
+	gs := strings.Index(fixture, "GS*")
+	iea := strings.Index(fixture, "IEA*")
+	second := strings.NewReplacer("*4512~", "*4513~", "*4512*", "*4513*").Replace(fixture[gs:iea])
+	ic, err := Parse(strings.NewReader(fixture[:iea] + second + "IEA*2*000004512~\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n := ControlNumbersNeeded(ic); n != 2 {
+		t.Fatalf("ControlNumbersNeeded = %d, want 2", n)
+	}
+	got := BuildAcknowledgement(ic, Acknowledgement997, nil, []int64{41, 42}, time.Date(2024, 2, 5, 15, 4, 0, 0, time.UTC))
+	ack, err := Parse(strings.NewReader(got))
+	if err != nil {
+		t.Fatalf("parse acknowledgement: %v\n%s", err, got)
+	}
+	if ack.ControlNumber != "000000041" || len(ack.FunctionalGroups) != 2 || ack.FunctionalGroups[0].ControlNumber != "41" || ack.FunctionalGroups[1].ControlNumber != "42" {
+		t.Errorf("acknowledgement numbered %s with groups %+v, want 41 with groups 41 and 42", ack.ControlNumber, ack.FunctionalGroups)
+	}
+	if n := ControlNumbersNeeded(&Interchange{}); n != 1 {
+		t.Errorf("ControlNumbersNeeded of an interchange without groups = %d, want 1", n)
+	}
+}
+
+func TestParseRepetitionSeparator(t *testing.T) {
+	tests := []struct {
+		isa  string
+		want byte
+	}{
+		{"*U*00401*", 0},
+		{"*^*00501*", '^'},
+		{"*!*00501*", '!'},
+	}
+	for _, tt := range tests {
+		data := strings.Replace(readFixture(t), "*U*00401*", tt.isa, 1)
+		ic, err := Parse(strings.NewReader(data))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if ic.Delimiters.Repetition != tt.want {
+			t.Errorf("ISA %s: repetition separator %q, want %q", tt.isa, ic.Delimiters.Repetition, tt.want)
+		}
+	}
+}
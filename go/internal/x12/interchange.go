@@ -0,0 +1,216 @@
+package x12
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+const isaLength = 106
+
+// repetitionVersion is the first ISA12 version whose ISA11 is a repetition separator.
+const repetitionVersion = "00501"
+
+type Delimiters struct {
+	Element   byte
+	Component byte
+	Segment   byte
+	// Repetition is the ISA11 repetition separator of version 00501 and later interchanges, or 0
+	// for earlier versions, where ISA11 is the standards identifier.
+	Repetition byte
+}
+
+type Segment struct {
+	ID       string
+	Elements []string
+	// Position is the 1-based ordinal of the segment within its transaction set, where ST is 1.
+	Position int
+}
+
+// Element returns the 1-based element of the segment, or an empty string when it was not sent.
+func (s Segment) Element(i int) string {
+	if i < 1 || i > len(s.Elements) {
+		return ""
+	}
+	return s.Elements[i-1]
+}
+
+type TransactionSet struct {
+	Code          string
+	ControlNumber string
+	Segments      []Segment
+}
+
+type FunctionalGroup struct {
+	FunctionalID    string
+	SenderID        string
+	ReceiverID      string
+	Date            string
+	Time            string
+	ControlNumber   string
+	Version         string
+	TransactionSets []TransactionSet
+}
+
+type Interchange struct {
+	Delimiters        Delimiters
+	SenderQualifier   string
+	SenderID          string
+	ReceiverQualifier string
+	ReceiverID        string
+	Date              string
+	Time              string
+	Version           string
+	ControlNumber     string
+	UsageIndicator    string
+	FunctionalGroups  []FunctionalGroup
+}
+
+// SegmentError locates a problem within a transaction set. Code and ElementCode carry the
+// AK304/IK304 segment syntax error code and the AK403/IK403 element syntax error code.
+type SegmentError struct {
+	SegmentID        string
+	Position         int
+	Element          int
+	ElementReference string
+	Code             string
+	ElementCode      string
+	BadData          string
+	Message          string
+}
+
+func (e *SegmentError) Error() string {
+	if e.Element > 0 {
+		return fmt.Sprintf("segment %s (position %d) element %d: %s", e.SegmentID, e.Position, e.Element, e.Message)
+	}
+	return fmt.Sprintf("segment %s (position %d): %s", e.SegmentID, e.Position, e.Message)
+}
+
+// Parse reads a single ISA/IEA interchange. Delimiters are taken from the fixed-width ISA segment.
+func Parse(r io.Reader) (*Interchange, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	data = bytes.TrimLeft(data, " \t\r\n")
+	if len(data) < isaLength || string(data[:3]) != "ISA" {
+		return nil, errors.New("interchange must begin with a 106 character ISA segment")
+	}
+	delims := Delimiters{
+		Element:   data[3],
+		Component: data[104],
+		Segment:   data[105],
+	}
+
+	var segments [][]string
+	for _, raw := range bytes.Split(data, []byte{delims.Segment}) {
+		raw = bytes.Trim(raw, " \t\r\n")
+		if len(raw) == 0 {
+			continue
+		}
+		segments = append(segments, strings.Split(string(raw), string(delims.Element)))
+	}
+
+	ic := &Interchange{Delimiters: delims}
+	isa := segments[0]
+	if len(isa) < 17 {
+		return nil, errors.New("ISA segment must have 16 elements")
+	}
+	ic.SenderQualifier = isa[5]
+	ic.SenderID = strings.TrimSpace(isa[6])
+	ic.ReceiverQualifier = isa[7]
+	ic.ReceiverID = strings.TrimSpace(isa[8])
+	ic.Date = isa[9]
+	ic.Time = isa[10]
+	ic.Version = isa[12]
+	ic.ControlNumber = isa[13]
+	ic.UsageIndicator = isa[15]
+	if ic.Version >= repetitionVersion && len(isa[11]) == 1 {
+		ic.Delimiters.Repetition = isa[11][0]
+	}
+
+	var group *FunctionalGroup
+	var set *TransactionSet
+	closed := false
+	for i, elements := range segments[1:] {
+		id := elements[0]
+		seg := Segment{ID: id, Elements: elements[1:]}
+		if closed {
+			return nil, fmt.Errorf("unexpected segment %s after IEA", id)
+		}
+		switch id {
+		case "GS":
+			if group != nil {
+				return nil, fmt.Errorf("GS %s opened before GS %s was closed", seg.Element(6), group.ControlNumber)
+			}
+			group = &FunctionalGroup{
+				FunctionalID:  seg.Element(1),
+				SenderID:      seg.Element(2),
+				ReceiverID:    seg.Element(3),
+				Date:          seg.Element(4),
+				Time:          seg.Element(5),
+				ControlNumber: seg.Element(6),
+				Version:       seg.Element(8),
+			}
+		case "ST":
+			if group == nil {
+				return nil, fmt.Errorf("ST %s found outside of a functional group", seg.Element(2))
+			}
+			if set != nil {
+				return nil, fmt.Errorf("ST %s opened before ST %s was closed", seg.Element(2), set.ControlNumber)
+			}
+			seg.Position = 1
+			set = &TransactionSet{Code: seg.Element(1), ControlNumber: seg.Element(2), Segments: []Segment{seg}}
+		case "SE":
+			if set == nil {
+				return nil, fmt.Errorf("SE found without a matching ST at segment %d", i+2)
+			}
+			seg.Position = len(set.Segments) + 1
+			set.Segments = append(set.Segments, seg)
+			if seg.Element(2) != set.ControlNumber {
+				return nil, &SegmentError{SegmentID: "SE", Position: seg.Position, Element: 2, ElementReference: "329", Code: SegmentHasElementErrors, ElementCode: ElementInvalidCodeValue, BadData: seg.Element(2), Message: fmt.Sprintf("control number %q does not match ST %q", seg.Element(2), set.ControlNumber)}
+			}
+			if n, err := strconv.Atoi(seg.Element(1)); err != nil || n != len(set.Segments) {
+				return nil, &SegmentError{SegmentID: "SE", Position: seg.Position, Element: 1, ElementReference: "96", Code: SegmentHasElementErrors, ElementCode: ElementInvalidCodeValue, BadData: seg.Element(1), Message: fmt.Sprintf("segment count %q does not match %d included segments", seg.Element(1), len(set.Segments))}
+			}
+			group.TransactionSets = append(group.TransactionSets, *set)
+			set = nil
+		case "GE":
+			if group == nil || set != nil {
+				return nil, errors.New("GE found without a matching GS or inside a transaction set")
+			}
+			if seg.Element(2) != group.ControlNumber {
+				return nil, fmt.Errorf("GE control number %q does not match GS %q", seg.Element(2), group.ControlNumber)
+			}
+			if n, err := strconv.Atoi(seg.Element(1)); err != nil || n != len(group.TransactionSets) {
+				return nil, fmt.Errorf("GE transaction set count %q does not match %d included sets", seg.Element(1), len(group.TransactionSets))
+			}
+			ic.FunctionalGroups = append(ic.FunctionalGroups, *group)
+			group = nil
+		case "IEA":
+			if group != nil {
+				return nil, errors.New("IEA found before GE closed the functional group")
+			}
+			if seg.Element(2) != ic.ControlNumber {
+				return nil, fmt.Errorf("IEA control number %q does not match ISA %q", seg.Element(2), ic.ControlNumber)
+			}
+			if n, err := strconv.Atoi(seg.Element(1)); err != nil || n != len(ic.FunctionalGroups) {
+				return nil, fmt.Errorf("IEA group count %q does not match %d included groups", seg.Element(1), len(ic.FunctionalGroups))
+			}
+			closed = true
+		default:
+			if set == nil {
+				return nil, fmt.Errorf("segment %s found outside of a transaction set", id)
+			}
+			seg.Position = len(set.Segments) + 1
+			set.Segments = append(set.Segments, seg)
+		}
+	}
+	if !closed {
+		return nil, errors.New("interchange is missing its IEA trailer")
+	}
+	return ic, nil
+}
@@ -0,0 +1,242 @@
+package x12
+
+import (
+	"errors"
+	"os"
+	"strings"
+	"testing"
+)
+
+// readFixture reads the ERCOT 867_03 interchange in testdata: a non-interval set and an interval set.
+func readFixture(t *testing.T) string {
+	t.Helper()
+	b, err := os.ReadFile("testdata/867_03.edi")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(b)
+}
+
+func TestParse867(t *testing.T) {
+	ic, err := Parse(strings.NewReader(readFixture(t)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ic.Delimiters != (Delimiters{Element: '*', Component: '>', Segment: '~'}) {
+		t.Errorf("delimiters = %+v", ic.Delimiters)
+	}
+	envelope := []struct{ field, got, want string }{
+		{"SenderQualifier", ic.SenderQualifier, "01"},
+		{"SenderID", ic.SenderID, "1039940674000"},
+		{"ReceiverQualifier", ic.ReceiverQualifier, "01"},
+		{"ReceiverID", ic.ReceiverID, "148055531"},
+		{"Date", ic.Date, "240205"},
+		{"Time", ic.Time, "0832"},
+		{"Version", ic.Version, "00401"},
+		{"ControlNumber", ic.ControlNumber, "000004512"},
+		{"UsageIndicator", ic.UsageIndicator, "P"},
+	}
+	for _, e := range envelope {
+		if e.got != e.want {
+			t.Errorf("%s = %q, want %q", e.field, e.got, e.want)
+		}
+	}
+	if len(ic.FunctionalGroups) != 1 {
+		t.Fatalf("got %d functional groups, want 1", len(ic.FunctionalGroups))
+	}
+	group := ic.FunctionalGroups[0]
+	if group.FunctionalID != "PT" || group.ControlNumber != "4512" || group.Version != "004010" || group.SenderID != "1039940674000" {
+		t.Errorf("group = %+v", group)
+	}
+	sets := []struct {
+		controlNumber string
+		segments      int
+	}{
+		{"0001", 17},
+		{"0002", 24},
+	}
+	if len(group.TransactionSets) != len(sets) {
+		t.Fatalf("got %d transaction sets, want %d", len(group.TransactionSets), len(sets))
+	}
+	for i, want := range sets {
+		set := group.TransactionSets[i]
+		if set.Code != "867" || set.ControlNumber != want.controlNumber || len(set.Segments) != want.segments {
+			t.Errorf("set %d: code %s control %s with %d segments, want 867 %s with %d", i, set.Code, set.ControlNumber, len(set.Segments), want.controlNumber, want.segments)
+			continue
+		}
+		for j, seg := range set.Segments {
+			if seg.Position != j+1 {
+				t.Errorf("set %d: segment %d %s has position %d", i, j, seg.ID, seg.Position)
+			}
+		}
+		if first, last := set.Segments[0], set.Segments[len(set.Segments)-1]; first.ID != "ST" || last.ID != "SE" {
+			t.Errorf("set %d runs from %s to %s, want ST to SE", i, first.ID, last.ID)
+		}
+	}
+
+	interval := group.TransactionSets[1]
+	qty := interval.Segments[17]
+	if qty.ID != "QTY" || qty.Element(1) != "QD" || qty.Element(2) != "1.25" || qty.Element(3) != "KH" {
+		t.Errorf("segment 18 = %+v, want QTY*QD*1.25*KH", qty)
+	}
+	if qty.Element(0) != "" || qty.Element(4) != "" {
+		t.Error("elements outside the segment should be empty")
+	}
+}
+
+func TestParseIgnoresLineBreaks(t *testing.T) {
+	fixture := readFixture(t)
+	oneLine := strings.ReplaceAll(fixture, "~\n", "~")
+	crlf := "\r\n" + strings.ReplaceAll(fixture, "~\n", "~\r\n")
+	for name, data := range map[string]string{"one line": oneLine, "CRLF": crlf} {
+		ic, err := Parse(strings.NewReader(data))
+		if err != nil {
+			t.Errorf("%s: %v", name, err)
+			continue
+		}
+		if n := len(ic.FunctionalGroups[0].TransactionSets); n != 2 {
+			t.Errorf("%s: got %d transaction sets, want 2", name, n)
+		}
+	}
+}
+
+func TestParseDelimitersFromISA(t *testing.T) {
+	// Pipe elements, a caret component separator and newline-terminated segments; the ">" in the
+	// DTM element is data under these delimiters, not a component separator.
+	data := strings.NewReplacer("*P*>~\n", "|P|^\n", "*", "|", "~\n", "\n").Replace(readFixture(t))
+	data = strings.Replace(data, "DTM|582|20240101|0015", "DTM|582|20240101|0015|>^ES", 1)
+	ic, err := Parse(strings.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ic.Delimiters != (Delimiters{Element: '|', Component: '^', Segment: '\n'}) {
+		t.Errorf("delimiters = %+v", ic.Delimiters)
+	}
+	sets := ic.FunctionalGroups[0].TransactionSets
+	if len(sets) != 2 || len(sets[1].Segments) != 24 {
+		t.Fatalf("got %+v, want two sets, the second with 24 segments", sets)
+	}
+	dtm := sets[1].Segments[16]
+	if dtm.ID != "DTM" || dtm.Element(3) != "0015" || dtm.Element(4) != ">^ES" {
+		t.Errorf("segment 17 = %+v, want its fourth element kept whole", dtm)
+	}
+}
+
+func TestParseMultipleGroups(t *testing.T) {
+	fixture := readFixture(t)
+	gs := strings.Index(fixture, "GS*")
+	iea := strings.Index(fixture, "IEA*")
+	second := strings.NewReplacer("*4512~", "*4513~", "*4512*", "*4513*", "ST*867*0001~", "ST*867*0003~", "SE*17*0001~", "SE*17*0003~", "ST*867*0002~", "ST*867*0004~", "SE*24*0002~", "SE*24*0004~").Replace(fixture[gs:iea])
+	data := fixture[:iea] + second + "IEA*2*000004512~\n"
+	ic, err := Parse(strings.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(ic.FunctionalGroups) != 2 {
+		t.Fatalf("got %d functional groups, want 2", len(ic.FunctionalGroups))
+	}
+	for i, want := range []struct {
+		controlNumber string
+		sets          []string
+	}{
+		{"4512", []string{"0001", "0002"}},
+		{"4513", []string{"0003", "0004"}},
+	} {
+		group := ic.FunctionalGroups[i]
+		if group.ControlNumber != want.controlNumber || len(group.TransactionSets) != len(want.sets) {
+			t.Errorf("group %d = %s with %d sets, want %s with %d", i, group.ControlNumber, len(group.TransactionSets), want.controlNumber, len(want.sets))
+			continue
+		}
+		for j, set := range group.TransactionSets {
+			if set.ControlNumber != want.sets[j] || set.Segments[0].Position != 1 {
+				t.Errorf("group %d set %d = %s starting at position %d, want %s at 1", i, j, set.ControlNumber, set.Segments[0].Position, want.sets[j])
+			}
+		}
+	}
+}
+
+// TestParseSetTrailerErrors covers the SE errors a 997/999 reports against the set: AK3/IK3 code 8
+// with an AK4/IK4 on the bad element.
+func TestParseSetTrailerErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		old  string
+		new  string
+		want SegmentError
+	}{
+		{
+			name: "segment count",
+			old:  "SE*17*0001~",
+			new:  "SE*16*0001~",
+			want: SegmentError{SegmentID: "SE", Position: 17, Element: 1, ElementReference: "96", Code: SegmentHasElementErrors, ElementCode: ElementInvalidCodeValue, BadData: "16"},
+		},
+		{
+			name: "non-numeric segment count",
+			old:  "SE*24*0002~",
+			new:  "SE*2A*0002~",
+			want: SegmentError{SegmentID: "SE", Position: 24, Element: 1, ElementReference: "96", Code: SegmentHasElementErrors, ElementCode: ElementInvalidCodeValue, BadData: "2A"},
+		},
+		{
+			name: "control number",
+			old:  "SE*24*0002~",
+			new:  "SE*24*0003~",
+			want: SegmentError{SegmentID: "SE", Position: 24, Element: 2, ElementReference: "329", Code: SegmentHasElementErrors, ElementCode: ElementInvalidCodeValue, BadData: "0003"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data := strings.Replace(readFixture(t), tt.old, tt.new, 1)
+			_, err := Parse(strings.NewReader(data))
+			var segmentErr *SegmentError
+			if !errors.As(err, &segmentErr) {
+				t.Fatalf("Parse error = %v, want a SegmentError", err)
+			}
+			got := *segmentErr
+			got.Message = ""
+			if got != tt.want {
+				t.Errorf("SegmentError = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseEnvelopeErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		old  string
+		new  string
+	}{
+		{"not an interchange", "ISA*00*", "ISB*00*"},
+		{"short ISA", "*P*>~", "*P>~"},
+		{"GE count", "GE*2*4512~", "GE*1*4512~"},
+		{"GE control number", "GE*2*4512~", "GE*2*4513~"},
+		{"IEA count", "IEA*1*000004512~", "IEA*2*000004512~"},
+		{"IEA control number", "IEA*1*000004512~", "IEA*1*000004513~"},
+		{"missing IEA", "IEA*1*000004512~", ""},
+		{"missing GE", "GE*2*4512~", ""},
+		{"missing SE", "SE*17*0001~", ""},
+		{"segment after IEA", "IEA*1*000004512~", "IEA*1*000004512~\nGS*PT~"},
+		{"segment outside a set", "ST*867*0002~", "REF*Q5*1~\nST*867*0002~"},
+		{"ST outside a group", "GS*PT*1039940674000*148055531*20240205*0832*4512*X*004010~", ""},
+		{"empty", readFixture(t), ""},
+		{"GS inside a group", "ST*867*0002~", "GS*PT*1*2*20240205*0832*4513*X*004010~\nST*867*0002~"},
+		{"ST inside a set", "ST*867*0002~", "ST*867*0003~\nST*867*0002~"},
+		{"SE without ST", "ST*867*0002~", ""},
+		{"GE inside a set", "SE*24*0002~", ""},
+		{"IEA inside a group", "GE*2*4512~", "IEA*1*000004512~\nGE*2*4512~"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data := strings.Replace(readFixture(t), tt.old, tt.new, 1)
+			ic, err := Parse(strings.NewReader(data))
+			if err == nil {
+				t.Fatalf("Parse succeeded with %d groups, want an error", len(ic.FunctionalGroups))
+			}
+			// Envelope errors reject the whole interchange and cannot be acknowledged per set.
+			var segmentErr *SegmentError
+			if errors.As(err, &segmentErr) {
+				t.Errorf("Parse error %v is a SegmentError", err)
+			}
+		})
+	}
+}
@@ -9,6 +9,15 @@ case "$1" in
     cd go
     go run cmd/api/main.go
     ;;
+  export)
+    cd go
+    shift
+    go run cmd/api/main.go export "$@"
+    ;;
+  test)
+    cd go
+    TEST_DATABASE_URL="postgres://${POSTGRES_USER}:${POSTGRES_PASSWORD}@${POSTGRES_HOST}:${POSTGRES_PORT}/${POSTGRES_DB}?sslmode=disable" go test ./...
+    ;;
   migrate-up)
     cd go
     go run cmd/migrations/*.go
@@ -31,7 +40,7 @@ case "$1" in
     docker build --progress=plain --no-cache -f Postgres.dockerfile -t postgres-custom .
     ;;
   *)
-    echo "Usage: $0 {docker-up|docker-down|docker-build|migrate-up|migrate-init}"
+    echo "Usage: $0 {export|test|docker-up|docker-down|docker-build|migrate-up|migrate-init}"
     exit 1
     ;;
 esac 
\ No newline at end of file